@@ -8,10 +8,17 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"qwiklip/internal/cache"
 	"qwiklip/internal/config"
+	"qwiklip/internal/errorreport"
 	"qwiklip/internal/instagram"
+	"qwiklip/internal/metrics"
 	"qwiklip/internal/server"
+	"qwiklip/internal/stats"
+	"qwiklip/internal/telegram"
+	"qwiklip/internal/webhook"
 )
 
 // Version information set at build time
@@ -24,6 +31,8 @@ var (
 func main() {
 	// Parse command line flags
 	versionFlag := flag.Bool("version", false, "Print version information and exit")
+	configFlag := flag.String("config", os.Getenv("QWIKLIP_CONFIG"), "TOML config file; environment variables override its values")
+	settingFlags := config.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
 	// Print version and exit if requested
@@ -34,15 +43,36 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Load configuration
-	cfg, err := config.Load()
+	// Select the run mode: the HTTP server unless told otherwise
+	command := flag.Arg(0)
+	switch command {
+	case "", "serve":
+		command = "serve"
+	case "telegram-bot":
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q (expected serve or telegram-bot)\n", command)
+		os.Exit(2)
+	}
+
+	// Flags may also follow the command (qwiklip serve --port 9000)
+	if flag.NArg() > 0 {
+		flag.CommandLine.Parse(flag.Args()[1:])
+		if flag.NArg() > 0 {
+			fmt.Fprintf(os.Stderr, "Unexpected argument %q\n", flag.Arg(0))
+			os.Exit(2)
+		}
+	}
+
+	// Load configuration; flags override the environment, which overrides the config file
+	cfg, err := config.LoadWithFlags(*configFlag, settingFlags)
 	if err != nil {
 		slog.Error("Failed to load configuration", "error", err)
 		os.Exit(1)
 	}
 
-	// Configure structured logging
-	level := getLogLevel(cfg.Logging.Level)
+	// Configure structured logging; the level can change on reload, the format cannot
+	level := new(slog.LevelVar)
+	level.Set(getLogLevel(cfg.Logging.Level))
 	var handler slog.Handler
 	if cfg.Logging.Format == "json" {
 		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
@@ -51,34 +81,120 @@ func main() {
 	}
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
+	for _, warning := range cfg.Warnings {
+		slog.Warn("Configuration warning", "warning", warning)
+	}
 
-	slog.Info("Starting Qwiklip server", "port", cfg.Server.Port)
+	if command == "telegram-bot" {
+		slog.Info("Starting Qwiklip Telegram bot", "env", cfg.Env)
+	} else {
+		slog.Info("Starting Qwiklip server", "port", cfg.Server.Port, "env", cfg.Env)
+	}
 
-	igClient := instagram.NewClient(&cfg.Instagram, logger)
+	// Initialize metrics sink (no-op unless configured)
+	sink, err := metrics.New(&cfg.Metrics)
+	if err != nil {
+		slog.Error("Failed to initialize metrics", "error", err)
+		os.Exit(1)
+	}
 
-	// Initialize HTTP server
-	versionInfo := &server.VersionInfo{
-		Version:   version,
-		Commit:    commit,
-		BuildTime: buildTime,
+	// The admin dashboard reads the same metrics from an in-process collector
+	collector := stats.New()
+	recorder := metrics.Multi(sink, collector)
+
+	// Initialize error reporting (no-op unless a DSN is configured)
+	reporter, err := errorreport.New(&cfg.Errors, version, logger)
+	if err != nil {
+		slog.Error("Failed to initialize error reporting", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize webhook notifications (no-op unless URLs are configured)
+	notifier := webhook.New(&cfg.Webhooks, logger)
+	if len(cfg.Webhooks.URLs) > 0 {
+		slog.Info("Webhooks enabled", "urls", len(cfg.Webhooks.URLs), "events", cfg.Webhooks.Events)
+	}
+
+	// Initialize metadata cache (memory or redis)
+	mediaCache, err := cache.New(context.Background(), &cfg.Cache)
+	if err != nil {
+		slog.Error("Failed to initialize metadata cache", "error", err)
+		os.Exit(1)
 	}
-	srv, err := server.New(cfg, igClient, logger, versionInfo)
+	slog.Info("Metadata cache ready", "backend", cfg.Cache.Backend, "ttl", cfg.Cache.TTL, "negative_ttl", cfg.Cache.NegativeTTL)
+
+	igClient, err := instagram.NewClient(&cfg.Instagram, mediaCache, logger, recorder, reporter, notifier)
 	if err != nil {
-		slog.Error("Failed to create server", "error", err)
+		slog.Error("Failed to initialize Instagram client", "error", err)
 		os.Exit(1)
 	}
+	slog.Info("Instagram extractors enabled", "extractors", igClient.Extractors())
 
 	// Setup graceful shutdown context
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	// Start server (blocks until shutdown signal)
-	if err := srv.Start(ctx); err != nil {
-		slog.Error("Server shutdown with error", "error", err)
+	// Run the selected mode (blocks until shutdown signal); SIGHUP reloads the configuration
+	reload := &reloader{path: *configFlag, flags: settingFlags, level: level, igClient: igClient, logger: logger}
+	if command == "telegram-bot" {
+		err = runTelegramBot(ctx, cfg, igClient, recorder, logger, reload)
+	} else {
+		err = runServer(ctx, cfg, igClient, recorder, collector, reporter, notifier, logger, reload)
+	}
+
+	// Persist cookies Instagram refreshed while running
+	if closeErr := igClient.Close(); closeErr != nil {
+		slog.Warn("Failed to save cookies", "error", closeErr)
+	}
+
+	// Send error reports still queued
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if closeErr := reporter.Close(flushCtx); closeErr != nil {
+		slog.Warn("Failed to flush error reports", "error", closeErr)
+	}
+	flushCancel()
+
+	// Deliver webhook events still queued
+	flushCtx, flushCancel = context.WithTimeout(context.Background(), 10*time.Second)
+	if closeErr := notifier.Close(flushCtx); closeErr != nil {
+		slog.Warn("Failed to deliver queued webhook events", "error", closeErr)
+	}
+	flushCancel()
+
+	if err != nil {
+		slog.Error("Shutdown with error", "mode", command, "error", err)
 		os.Exit(1)
 	}
 }
 
+// runServer serves HTTP until ctx is cancelled
+func runServer(ctx context.Context, cfg *config.Config, igClient *instagram.Client, recorder metrics.Recorder, collector *stats.Collector, reporter errorreport.Reporter, notifier webhook.Notifier, logger *slog.Logger, reload *reloader) error {
+	versionInfo := &server.VersionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+	}
+	srv, err := server.New(cfg, igClient, logger, reload.level, recorder, collector, reporter, notifier, versionInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create server: %w", err)
+	}
+	reload.server = srv
+	go reload.watch(ctx)
+	return srv.Start(ctx)
+}
+
+// runTelegramBot answers Telegram messages until ctx is cancelled, reusing the
+// server's CDN streamer so uploads get the same headers and retries as proxied videos
+func runTelegramBot(ctx context.Context, cfg *config.Config, igClient *instagram.Client, recorder metrics.Recorder, logger *slog.Logger, reload *reloader) error {
+	streamer := server.NewVideoStreamer(igClient, &cfg.Stream, nil, recorder, logger)
+	bot, err := telegram.New(&cfg.Telegram, igClient, streamer, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create Telegram bot: %w", err)
+	}
+	go reload.watch(ctx)
+	return bot.Run(ctx)
+}
+
 func getLogLevel(level string) slog.Level {
 	switch level {
 	case "debug":
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"qwiklip/internal/config"
+	"qwiklip/internal/instagram"
+	"qwiklip/internal/server"
+)
+
+// reloader re-reads the configuration on SIGHUP and applies the settings that are safe to
+// change while running: the log level, outbound proxies, Instagram accounts and cookies,
+// the Instagram concurrency limit, request budget and user agent, and (when serving) the
+// stream limits. Everything else needs a restart.
+type reloader struct {
+	path     string       // Config file, re-read on every reload (empty uses the environment only)
+	flags    config.Flags // Command-line settings, which keep overriding the file
+	level    *slog.LevelVar
+	igClient *instagram.Client
+	server   *server.Server // nil in telegram-bot mode
+	logger   *slog.Logger
+}
+
+// watch reloads on every SIGHUP until ctx is cancelled
+func (r *reloader) watch(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			r.reload()
+		}
+	}
+}
+
+// reload applies a freshly loaded configuration. An invalid configuration is logged and
+// the running settings are kept.
+func (r *reloader) reload() {
+	cfg, err := config.LoadWithFlags(r.path, r.flags)
+	if err != nil {
+		r.logger.Error("Configuration reload failed, keeping current settings", "error", err)
+		r.failed(err)
+		return
+	}
+
+	if err := r.igClient.Reload(&cfg.Instagram); err != nil {
+		r.logger.Error("Instagram settings reload failed, keeping current settings", "error", err)
+		r.failed(err)
+		return
+	}
+	r.level.Set(getLogLevel(cfg.Logging.Level))
+	if r.server != nil {
+		r.server.Reload(cfg)
+	}
+	for _, warning := range cfg.Warnings {
+		r.logger.Warn("Configuration warning", "warning", warning)
+	}
+	r.logger.Info("Configuration reloaded", "config_file", r.path, "log_level", cfg.Logging.Level)
+}
+
+// failed reports a rejected reload to the server's readiness check
+func (r *reloader) failed(err error) {
+	if r.server != nil {
+		r.server.ReloadFailed(err)
+	}
+}
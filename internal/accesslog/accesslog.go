@@ -0,0 +1,78 @@
+// Package accesslog writes one JSON line per HTTP request to a file that is rotated
+// by size and age. It is separate from the application log on stdout, so request
+// records can be shipped or kept on their own.
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"qwiklip/internal/config"
+)
+
+// Entry is one line of the access log
+type Entry struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Proto      string    `json:"proto"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"` // Response body bytes, including streams cut short
+	DurationMS int64     `json:"duration_ms"`
+	ClientIP   string    `json:"client_ip"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	Referer    string    `json:"referer,omitempty"`
+}
+
+// Logger appends entries to the access log file
+type Logger struct {
+	mu     sync.Mutex
+	file   *rotatingFile
+	logger *slog.Logger
+}
+
+// New opens the configured access log file, or returns nil (access log disabled) without one
+func New(cfg *config.LoggingConfig, logger *slog.Logger) (*Logger, error) {
+	if cfg.AccessLogFile == "" {
+		return nil, nil
+	}
+	file, err := openRotatingFile(cfg.AccessLogFile, cfg.AccessLogMaxSize, cfg.AccessLogMaxAge, cfg.AccessLogMaxBackups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access log: %w", err)
+	}
+	return &Logger{file: file, logger: logger}, nil
+}
+
+// Log writes entry as one line. Write errors are reported to the application log,
+// since a full disk must not fail the request.
+func (l *Logger) Log(entry Entry) {
+	if l == nil {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		l.logger.Error("Failed to encode access log entry", "error", err)
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(line); err != nil {
+		l.logger.Error("Failed to write access log", "path", l.file.path, "error", err)
+	}
+}
+
+// Close closes the access log file
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
@@ -0,0 +1,339 @@
+// Package acme provides automatic HTTPS: it obtains a certificate for the configured
+// domains from an ACME CA such as Let's Encrypt, answers the CA's HTTP-01 challenges,
+// keeps the certificate on disk across restarts and renews it before it expires.
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"qwiklip/internal/config"
+)
+
+const (
+	accountKeyFile  = "account.key"
+	certificateFile = "certificate.pem"
+
+	// challengePath is where the CA fetches HTTP-01 responses
+	challengePath = "/.well-known/acme-challenge/"
+	// renewCheckInterval is how often the certificate's expiry is checked
+	renewCheckInterval = 12 * time.Hour
+	// renewRetryInterval is the wait after a failed issuance; Let's Encrypt rate limits failures
+	renewRetryInterval = 10 * time.Minute
+	// issueTimeout bounds one issuance, challenges included
+	issueTimeout = 5 * time.Minute
+)
+
+// Manager keeps a valid certificate for the configured domains
+type Manager struct {
+	domains     []string
+	hosts       map[string]bool
+	email       string
+	cacheDir    string
+	renewBefore time.Duration
+	client      *client
+	challenges  *challengeStore
+	logger      *slog.Logger
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// New creates a manager, loading the account key and any certificate cached in cfg.CacheDir
+func New(cfg *config.ACMEConfig, logger *slog.Logger) (*Manager, error) {
+	if err := os.MkdirAll(cfg.CacheDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create ACME cache directory: %w", err)
+	}
+
+	key, err := loadAccountKey(filepath.Join(cfg.CacheDir, accountKeyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		domains:     cfg.Domains,
+		hosts:       make(map[string]bool, len(cfg.Domains)),
+		email:       cfg.Email,
+		cacheDir:    cfg.CacheDir,
+		renewBefore: cfg.RenewBefore,
+		client:      newClient(cfg.DirectoryURL, key),
+		challenges:  &challengeStore{tokens: make(map[string]string)},
+		logger:      logger,
+	}
+	for _, domain := range cfg.Domains {
+		m.hosts[strings.ToLower(domain)] = true
+	}
+
+	// A cached certificate is reused only if it still covers every configured domain
+	if cert, err := m.loadCertificate(); err == nil {
+		m.cert = cert
+		logger.Info("Loaded cached certificate", "domains", cert.Leaf.DNSNames, "expires", cert.Leaf.NotAfter)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("Ignoring cached certificate", "error", err)
+	}
+	return m, nil
+}
+
+// TLSConfig returns the server TLS configuration serving the managed certificate
+func (m *Manager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: m.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+}
+
+// GetCertificate implements tls.Config.GetCertificate. Unknown server names are refused
+// so that scanners cannot make the CA issue for names that point here by accident;
+// clients sending no name get the certificate anyway.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+	if name != "" && !m.hosts[name] {
+		return nil, fmt.Errorf("acme: no certificate for host %q", name)
+	}
+
+	// Only Run issues, so handshakes cannot start orders faster than its retry interval
+	// or abort one by disconnecting; until the first certificate arrives they fail
+	cert := m.current()
+	if cert == nil {
+		return nil, errors.New("acme: certificate not issued yet")
+	}
+	return cert, nil
+}
+
+// HTTPHandler answers HTTP-01 challenges and redirects every other request for a
+// configured domain to HTTPS on httpsPort
+func (m *Manager) HTTPHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := strings.CutPrefix(r.URL.Path, challengePath); ok {
+			keyAuth, found := m.challenges.get(token)
+			if !found {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(keyAuth))
+			return
+		}
+
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !m.hosts[strings.ToLower(host)] {
+			http.NotFound(w, r)
+			return
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusFound)
+	})
+}
+
+// Run obtains the certificate if none is cached and renews it before expiry until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	for {
+		wait := renewCheckInterval
+		if err := m.issue(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			m.logger.Error("Failed to obtain certificate", "domains", m.domains, "retry_in", renewRetryInterval, "error", err)
+			wait = renewRetryInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (m *Manager) current() *tls.Certificate {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cert
+}
+
+// due reports whether cert is missing or within the renewal window
+func (m *Manager) due(cert *tls.Certificate) bool {
+	return cert == nil || time.Until(cert.Leaf.NotAfter) < m.renewBefore
+}
+
+// issue obtains a new certificate when the current one is due, then caches it.
+// The old certificate keeps being served until the new one is in place. Only Run calls
+// it, so issuances never overlap.
+func (m *Manager) issue(ctx context.Context) error {
+	if !m.due(m.current()) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, issueTimeout)
+	defer cancel()
+	m.logger.Info("Requesting certificate", "domains", m.domains)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: m.domains[0]},
+		DNSNames: m.domains,
+	}, key)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate request: %w", err)
+	}
+
+	chain, err := m.client.obtain(ctx, m.email, m.domains, csr, m.challenges)
+	if err != nil {
+		return err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	data := append(chain, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})...)
+	cert, err := parseCertificate(data)
+	if err != nil {
+		return fmt.Errorf("CA returned an unusable certificate: %w", err)
+	}
+	if err := writeFile(filepath.Join(m.cacheDir, certificateFile), data); err != nil {
+		m.logger.Warn("Failed to cache certificate", "error", err)
+	}
+
+	m.mu.Lock()
+	m.cert = cert
+	m.mu.Unlock()
+	m.logger.Info("Certificate obtained", "domains", m.domains, "expires", cert.Leaf.NotAfter)
+	return nil
+}
+
+func (m *Manager) loadCertificate() (*tls.Certificate, error) {
+	data, err := os.ReadFile(filepath.Join(m.cacheDir, certificateFile))
+	if err != nil {
+		return nil, err
+	}
+	cert, err := parseCertificate(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, domain := range m.domains {
+		if err := cert.Leaf.VerifyHostname(domain); err != nil {
+			return nil, fmt.Errorf("cached certificate does not cover %s", domain)
+		}
+	}
+	return cert, nil
+}
+
+// parseCertificate reads a PEM chain followed by its private key
+func parseCertificate(data []byte) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(data, data)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	return &cert, nil
+}
+
+// loadAccountKey reads the ACME account key, creating it on first use
+func loadAccountKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})); err != nil {
+			return nil, fmt.Errorf("failed to save ACME account key: %w", err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ACME account key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid ACME account key %s", path)
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ACME account key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// writeFile replaces path atomically with a file only the service user can read
+func writeFile(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), ".acme-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name()) // No-op after a successful rename
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Chmod(0o600); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// challengeStore holds the HTTP-01 responses of challenges in progress
+type challengeStore struct {
+	mu     sync.Mutex
+	tokens map[string]string // Token to key authorization
+}
+
+func (s *challengeStore) put(token, keyAuth string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token] = keyAuth
+}
+
+func (s *challengeStore) get(token string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keyAuth, ok := s.tokens[token]
+	return keyAuth, ok
+}
+
+func (s *challengeStore) remove(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, token)
+}
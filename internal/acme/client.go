@@ -0,0 +1,454 @@
+package acme
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxResponseSize bounds ACME responses; certificate chains are a few KB
+	maxResponseSize = 1 << 20
+	// defaultPollInterval is used when the CA sends no Retry-After while an order is processed
+	defaultPollInterval = 2 * time.Second
+	// problemBadNonce asks the client to retry with the fresh nonce sent alongside it
+	problemBadNonce = "urn:ietf:params:acme:error:badNonce"
+)
+
+// client speaks the part of RFC 8555 needed to obtain a certificate with HTTP-01 challenges
+type client struct {
+	directoryURL string
+	key          *ecdsa.PrivateKey // Account key, which also signs every request
+	httpClient   *http.Client
+
+	mu     sync.Mutex
+	dir    *directory
+	kid    string // Account URL, known once registered
+	nonces []string
+}
+
+type directory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+}
+
+type identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type order struct {
+	Status         string   `json:"status"`
+	Authorizations []string `json:"authorizations"`
+	Finalize       string   `json:"finalize"`
+	Certificate    string   `json:"certificate"`
+	Error          *problem `json:"error"`
+}
+
+type authorization struct {
+	Identifier identifier  `json:"identifier"`
+	Status     string      `json:"status"`
+	Challenges []challenge `json:"challenges"`
+}
+
+type challenge struct {
+	Type   string   `json:"type"`
+	URL    string   `json:"url"`
+	Token  string   `json:"token"`
+	Status string   `json:"status"`
+	Error  *problem `json:"error"`
+}
+
+// problem is an RFC 7807 error document returned by the CA
+type problem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+	Status int    `json:"status"`
+}
+
+func (p *problem) Error() string {
+	return fmt.Sprintf("acme: %s: %s", p.Type, p.Detail)
+}
+
+// jwk is the account public key; the fields are in the lexicographic order RFC 7638
+// requires for computing its thumbprint
+type jwk struct {
+	Crv string `json:"crv"`
+	Kty string `json:"kty"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func newClient(directoryURL string, key *ecdsa.PrivateKey) *client {
+	return &client{
+		directoryURL: directoryURL,
+		key:          key,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// obtain registers the account if needed, orders a certificate for domains, has solver
+// answer the HTTP-01 challenges and returns the PEM certificate chain for csr
+func (c *client) obtain(ctx context.Context, email string, domains []string, csr []byte, solver *challengeStore) ([]byte, error) {
+	if err := c.register(ctx, email); err != nil {
+		return nil, fmt.Errorf("failed to register ACME account: %w", err)
+	}
+
+	identifiers := make([]identifier, len(domains))
+	for i, domain := range domains {
+		identifiers[i] = identifier{Type: "dns", Value: domain}
+	}
+	resp, body, err := c.post(ctx, c.dir.NewOrder, map[string]any{"identifiers": identifiers})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+	orderURL := resp.Header.Get("Location")
+	var o order
+	if err := json.Unmarshal(body, &o); err != nil {
+		return nil, fmt.Errorf("invalid order: %w", err)
+	}
+
+	for _, authzURL := range o.Authorizations {
+		if err := c.authorize(ctx, authzURL, solver); err != nil {
+			return nil, err
+		}
+	}
+
+	// Finalize once every authorization is valid, then wait for the CA to issue
+	if resp, body, err = c.post(ctx, o.Finalize, map[string]string{"csr": encode(csr)}); err != nil {
+		return nil, fmt.Errorf("failed to finalize order: %w", err)
+	}
+	if err := json.Unmarshal(body, &o); err != nil {
+		return nil, fmt.Errorf("invalid order: %w", err)
+	}
+	wait := retryAfter(resp)
+	for o.Status != "valid" {
+		if o.Status == "invalid" {
+			if o.Error != nil {
+				return nil, fmt.Errorf("order failed: %w", o.Error)
+			}
+			return nil, errors.New("order failed")
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		if wait, err = c.fetch(ctx, orderURL, &o); err != nil {
+			return nil, fmt.Errorf("failed to check order: %w", err)
+		}
+	}
+
+	// POST-as-GET of the certificate URL returns application/pem-certificate-chain by default
+	_, chain, err := c.post(ctx, o.Certificate, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download certificate: %w", err)
+	}
+	return chain, nil
+}
+
+// register fetches the directory and creates (or looks up) the account for the key
+func (c *client) register(ctx context.Context, email string) error {
+	c.mu.Lock()
+	registered := c.kid != ""
+	c.mu.Unlock()
+	if registered {
+		return nil
+	}
+
+	if err := c.discover(ctx); err != nil {
+		return err
+	}
+
+	account := map[string]any{"termsOfServiceAgreed": true}
+	if email != "" {
+		account["contact"] = []string{"mailto:" + email}
+	}
+	resp, _, err := c.post(ctx, c.dir.NewAccount, account)
+	if err != nil {
+		return err
+	}
+
+	kid := resp.Header.Get("Location")
+	if kid == "" {
+		return errors.New("CA returned no account URL")
+	}
+	c.mu.Lock()
+	c.kid = kid
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *client) discover(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.directoryURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch ACME directory: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ACME directory returned status %d", resp.StatusCode)
+	}
+
+	var dir directory
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&dir); err != nil {
+		return fmt.Errorf("invalid ACME directory: %w", err)
+	}
+	if dir.NewNonce == "" || dir.NewAccount == "" || dir.NewOrder == "" {
+		return errors.New("ACME directory is missing endpoints")
+	}
+	c.dir = &dir
+	return nil
+}
+
+// authorize proves control of one identifier with its HTTP-01 challenge
+func (c *client) authorize(ctx context.Context, authzURL string, solver *challengeStore) error {
+	var authz authorization
+	if _, err := c.fetch(ctx, authzURL, &authz); err != nil {
+		return fmt.Errorf("failed to fetch authorization: %w", err)
+	}
+	if authz.Status == "valid" {
+		return nil // Still valid from an earlier order
+	}
+
+	var chal *challenge
+	for i := range authz.Challenges {
+		if authz.Challenges[i].Type == "http-01" {
+			chal = &authz.Challenges[i]
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("CA offered no http-01 challenge for %s", authz.Identifier.Value)
+	}
+
+	keyAuth, err := c.keyAuthorization(chal.Token)
+	if err != nil {
+		return err
+	}
+	solver.put(chal.Token, keyAuth)
+	defer solver.remove(chal.Token)
+
+	// An empty object tells the CA the response is ready to be fetched
+	resp, _, err := c.post(ctx, chal.URL, struct{}{})
+	if err != nil {
+		return fmt.Errorf("failed to start challenge for %s: %w", authz.Identifier.Value, err)
+	}
+	wait := retryAfter(resp)
+	for authz.Status == "pending" || authz.Status == "processing" {
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+		if wait, err = c.fetch(ctx, authzURL, &authz); err != nil {
+			return fmt.Errorf("failed to check authorization: %w", err)
+		}
+	}
+	if authz.Status != "valid" {
+		for _, ch := range authz.Challenges {
+			if ch.Error != nil {
+				return fmt.Errorf("challenge for %s failed: %w", authz.Identifier.Value, ch.Error)
+			}
+		}
+		return fmt.Errorf("authorization for %s is %s", authz.Identifier.Value, authz.Status)
+	}
+	return nil
+}
+
+// fetch reads the resource at url into v and returns how long to wait before polling it again
+func (c *client) fetch(ctx context.Context, url string, v any) (time.Duration, error) {
+	resp, body, err := c.post(ctx, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return 0, err
+	}
+	return retryAfter(resp), nil
+}
+
+// retryAfter is the polling interval the CA asked for in resp
+func retryAfter(resp *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultPollInterval
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// post sends a JWS-signed request; a nil payload makes it a POST-as-GET.
+// A rejected nonce is retried once with the fresh nonce the CA returned.
+func (c *client) post(ctx context.Context, url string, payload any) (*http.Response, []byte, error) {
+	var data []byte
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, body, err := c.send(ctx, url, data)
+		var prob *problem
+		if errors.As(err, &prob) && prob.Type == problemBadNonce && attempt == 0 {
+			continue
+		}
+		return resp, body, err
+	}
+}
+
+func (c *client) send(ctx context.Context, url string, payload []byte) (*http.Response, []byte, error) {
+	nonce, err := c.nonce(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	signed, err := c.sign(url, nonce, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(signed))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/jose+json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if nonce := resp.Header.Get("Replay-Nonce"); nonce != "" {
+		c.mu.Lock()
+		c.nonces = append(c.nonces, nonce)
+		c.mu.Unlock()
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode >= 400 {
+		prob := &problem{Status: resp.StatusCode}
+		if json.Unmarshal(body, prob) != nil || prob.Type == "" {
+			return nil, nil, fmt.Errorf("ACME request failed with status %d", resp.StatusCode)
+		}
+		return nil, nil, prob
+	}
+	return resp, body, nil
+}
+
+// nonce returns a nonce left over from an earlier response, or fetches a new one
+func (c *client) nonce(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	if n := len(c.nonces); n > 0 {
+		nonce := c.nonces[n-1]
+		c.nonces = c.nonces[:n-1]
+		c.mu.Unlock()
+		return nonce, nil
+	}
+	c.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.dir.NewNonce, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch nonce: %w", err)
+	}
+	resp.Body.Close()
+
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", errors.New("CA returned no nonce")
+	}
+	return nonce, nil
+}
+
+// sign wraps payload in a flattened JWS signed with ES256. Requests before registration
+// carry the public key; later ones refer to the account URL instead.
+func (c *client) sign(url, nonce string, payload []byte) ([]byte, error) {
+	protected := map[string]any{"alg": "ES256", "nonce": nonce, "url": url}
+	c.mu.Lock()
+	if c.kid != "" {
+		protected["kid"] = c.kid
+	} else {
+		key, err := c.jwk()
+		if err != nil {
+			c.mu.Unlock()
+			return nil, err
+		}
+		protected["jwk"] = key
+	}
+	c.mu.Unlock()
+
+	header, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+	signingInput := encode(header) + "." + encode(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// JWS wants the raw 32-byte r and s, not the ASN.1 encoding
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return json.Marshal(map[string]string{
+		"protected": encode(header),
+		"payload":   encode(payload),
+		"signature": encode(signature),
+	})
+}
+
+func (c *client) jwk() (*jwk, error) {
+	point, err := c.key.PublicKey.Bytes() // 0x04 || X || Y
+	if err != nil {
+		return nil, err
+	}
+	return &jwk{Crv: "P-256", Kty: "EC", X: encode(point[1:33]), Y: encode(point[33:])}, nil
+}
+
+// keyAuthorization is the HTTP-01 response for token: the token and the account key thumbprint
+func (c *client) keyAuthorization(token string) (string, error) {
+	key, err := c.jwk()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	thumbprint := sha256.Sum256(data)
+	return token + "." + encode(thumbprint[:]), nil
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
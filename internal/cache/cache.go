@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"qwiklip/internal/config"
+	"qwiklip/internal/models"
+)
+
+// Supported cache backends
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// ErrMiss is returned by Get when a key is absent or expired
+var ErrMiss = errors.New("cache miss")
+
+// cloneAppError copies appErr and its details, without the cause, which is not stored
+func cloneAppError(appErr *models.AppError) *models.AppError {
+	return &models.AppError{Type: appErr.Type, Message: appErr.Message, Details: maps.Clone(appErr.Details)}
+}
+
+// Cache stores extracted media info keyed by shortcode, and the errors of shortcodes
+// that could not be extracted (negative results) for a shorter TTL.
+// Implementations must be safe for concurrent use and must not share
+// returned values between callers.
+type Cache interface {
+	Get(ctx context.Context, key string) (*models.InstagramMediaInfo, error)
+	Set(ctx context.Context, key string, mediaInfo *models.InstagramMediaInfo) error
+	// GetNegative returns the error remembered for key, or ErrMiss
+	GetNegative(ctx context.Context, key string) (*models.AppError, error)
+	// SetNegative remembers the error of key with the negative TTL, replacing its media info
+	SetNegative(ctx context.Context, key string, appErr *models.AppError) error
+	Delete(ctx context.Context, key string) error
+	Keys(ctx context.Context) ([]string, error)
+	Purge(ctx context.Context) (int, error)
+}
+
+// New creates the configured cache backend; a non-positive TTL disables caching
+func New(ctx context.Context, cfg *config.CacheConfig) (Cache, error) {
+	if cfg.TTL <= 0 {
+		return Nop{}, nil
+	}
+
+	switch cfg.Backend {
+	case BackendMemory:
+		return NewMemory(cfg.TTL, cfg.NegativeTTL, cfg.MaxEntries), nil
+	case BackendRedis:
+		return NewRedis(ctx, cfg.RedisURL, cfg.TTL, cfg.NegativeTTL)
+	default:
+		return nil, fmt.Errorf("unknown cache backend: %s", cfg.Backend)
+	}
+}
+
+// Nop is a cache that never stores anything
+type Nop struct{}
+
+func (Nop) Get(context.Context, string) (*models.InstagramMediaInfo, error) { return nil, ErrMiss }
+func (Nop) Set(context.Context, string, *models.InstagramMediaInfo) error   { return nil }
+func (Nop) GetNegative(context.Context, string) (*models.AppError, error)   { return nil, ErrMiss }
+func (Nop) SetNegative(context.Context, string, *models.AppError) error     { return nil }
+func (Nop) Delete(context.Context, string) error                            { return nil }
+func (Nop) Keys(context.Context) ([]string, error)                          { return nil, nil }
+func (Nop) Purge(context.Context) (int, error)                              { return 0, nil }
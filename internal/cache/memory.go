@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"qwiklip/internal/models"
+)
+
+// Memory is an in-process TTL cache bounded by entry count
+type Memory struct {
+	mu          sync.Mutex
+	entries     map[string]memoryEntry
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+}
+
+// memoryEntry holds a cached media info, or the error of a negative result, and its expiry time
+type memoryEntry struct {
+	mediaInfo *models.InstagramMediaInfo
+	failure   *models.AppError
+	expiresAt time.Time
+}
+
+// NewMemory creates an in-memory cache; a non-positive negativeTTL disables negative results
+func NewMemory(ttl, negativeTTL time.Duration, maxEntries int) *Memory {
+	return &Memory{
+		entries:     make(map[string]memoryEntry),
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		maxEntries:  maxEntries,
+	}
+}
+
+// Get returns a copy of the cached media info if present and not expired
+func (m *Memory) Get(_ context.Context, key string) (*models.InstagramMediaInfo, error) {
+	entry, ok := m.lookup(key)
+	if !ok || entry.mediaInfo == nil {
+		return nil, ErrMiss
+	}
+	return entry.mediaInfo.Clone(), nil
+}
+
+// Set stores a copy of the media info, evicting entries when the cache is full
+func (m *Memory) Set(_ context.Context, key string, mediaInfo *models.InstagramMediaInfo) error {
+	m.store(key, memoryEntry{mediaInfo: mediaInfo.Clone(), expiresAt: time.Now().Add(m.ttl)})
+	return nil
+}
+
+// GetNegative returns a copy of the error remembered for key if present and not expired
+func (m *Memory) GetNegative(_ context.Context, key string) (*models.AppError, error) {
+	entry, ok := m.lookup(key)
+	if !ok || entry.failure == nil {
+		return nil, ErrMiss
+	}
+	return cloneAppError(entry.failure), nil
+}
+
+// SetNegative remembers a copy of the error for the negative TTL
+func (m *Memory) SetNegative(_ context.Context, key string, appErr *models.AppError) error {
+	if m.negativeTTL <= 0 {
+		return nil
+	}
+	m.store(key, memoryEntry{failure: cloneAppError(appErr), expiresAt: time.Now().Add(m.negativeTTL)})
+	return nil
+}
+
+// lookup returns the unexpired entry of key
+func (m *Memory) lookup(key string) (memoryEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		return memoryEntry{}, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(m.entries, key)
+		return memoryEntry{}, false
+	}
+	return entry, true
+}
+
+// store sets the entry of key, evicting entries when the cache is full
+func (m *Memory) store(key string, entry memoryEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.entries[key]; !exists && len(m.entries) >= m.maxEntries {
+		m.evictLocked()
+	}
+	m.entries[key] = entry
+}
+
+// Delete removes a key from the cache
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// Keys returns the unexpired keys of media info in no particular order
+func (m *Memory) Keys(_ context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	keys := make([]string, 0, len(m.entries))
+	for key, entry := range m.entries {
+		if entry.mediaInfo != nil && now.Before(entry.expiresAt) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Purge removes every entry and returns how many were held
+func (m *Memory) Purge(_ context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := len(m.entries)
+	m.entries = make(map[string]memoryEntry)
+	return count, nil
+}
+
+// evictLocked drops expired entries, or the entry closest to expiry if none have expired
+func (m *Memory) evictLocked() {
+	now := time.Now()
+	var oldestKey string
+	var oldestExpiry time.Time
+
+	for key, entry := range m.entries {
+		if now.After(entry.expiresAt) {
+			delete(m.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.expiresAt.Before(oldestExpiry) {
+			oldestKey = key
+			oldestExpiry = entry.expiresAt
+		}
+	}
+
+	if len(m.entries) >= m.maxEntries && oldestKey != "" {
+		delete(m.entries, oldestKey)
+	}
+}
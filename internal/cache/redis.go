@@ -0,0 +1,385 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"qwiklip/internal/models"
+)
+
+const (
+	redisKeyPrefix      = "qwiklip:media:"
+	redisNegativePrefix = "qwiklip:negative:"
+	redisPoolSize       = 8
+	redisDialTimeout    = 3 * time.Second
+	redisCommandTimeout = 2 * time.Second
+	redisScanCount      = 500 // Keys requested per SCAN and deleted per DEL
+)
+
+// Redis is a cache shared between instances, stored as JSON in Redis.
+// It speaks the RESP protocol directly for the handful of commands it needs.
+type Redis struct {
+	addr     string
+	username string
+	password string
+	db       int
+	useTLS   bool
+	ttl      time.Duration
+	negative time.Duration // TTL of negative results
+	pool     chan *redisConn
+}
+
+// redisConn is a pooled connection with its buffered reader
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// redisError is an error reply sent by the server
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// NewRedis creates a Redis cache from a redis:// or rediss:// URL and verifies connectivity.
+// A non-positive negativeTTL disables negative results.
+func NewRedis(ctx context.Context, rawURL string, ttl, negativeTTL time.Duration) (*Redis, error) {
+	r, err := parseRedisURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	r.ttl = ttl
+	r.negative = negativeTTL
+	r.pool = make(chan *redisConn, redisPoolSize)
+
+	pingCtx, cancel := context.WithTimeout(ctx, redisDialTimeout)
+	defer cancel()
+	if _, err := r.do(pingCtx, "PING"); err != nil {
+		return nil, fmt.Errorf("failed to reach redis at %s: %w", r.addr, err)
+	}
+
+	return r, nil
+}
+
+// parseRedisURL parses redis://[user:password@]host:port[/db]
+func parseRedisURL(rawURL string) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("invalid redis URL scheme '%s', must be redis or rediss", u.Scheme)
+	}
+
+	r := &Redis{
+		addr:   u.Host,
+		useTLS: u.Scheme == "rediss",
+	}
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		r.username = u.User.Username()
+		r.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if r.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database number: %s", db)
+		}
+	}
+
+	return r, nil
+}
+
+// Get returns the cached media info for key
+func (r *Redis) Get(ctx context.Context, key string) (*models.InstagramMediaInfo, error) {
+	var mediaInfo models.InstagramMediaInfo
+	if err := r.getJSON(ctx, redisKeyPrefix+key, &mediaInfo); err != nil {
+		return nil, err
+	}
+	return &mediaInfo, nil
+}
+
+// Set stores media info with the cache TTL. A negative result for key is dropped, so
+// its later expiry cannot hide the fresh media info.
+func (r *Redis) Set(ctx context.Context, key string, mediaInfo *models.InstagramMediaInfo) error {
+	if err := r.setJSON(ctx, redisKeyPrefix+key, mediaInfo, r.ttl); err != nil {
+		return err
+	}
+	if r.negative <= 0 {
+		return nil
+	}
+	_, err := r.do(ctx, "DEL", redisNegativePrefix+key)
+	return err
+}
+
+// GetNegative returns the error remembered for key
+func (r *Redis) GetNegative(ctx context.Context, key string) (*models.AppError, error) {
+	var appErr models.AppError
+	if err := r.getJSON(ctx, redisNegativePrefix+key, &appErr); err != nil {
+		return nil, err
+	}
+	return &appErr, nil
+}
+
+// SetNegative remembers the error of key with the negative TTL, dropping its media info
+func (r *Redis) SetNegative(ctx context.Context, key string, appErr *models.AppError) error {
+	if r.negative <= 0 {
+		return nil
+	}
+	if err := r.setJSON(ctx, redisNegativePrefix+key, cloneAppError(appErr), r.negative); err != nil {
+		return err
+	}
+	_, err := r.do(ctx, "DEL", redisKeyPrefix+key)
+	return err
+}
+
+// getJSON decodes the value stored at redisKey into v
+func (r *Redis) getJSON(ctx context.Context, redisKey string, v interface{}) error {
+	reply, err := r.do(ctx, "GET", redisKey)
+	if err != nil {
+		return err
+	}
+	if reply == nil {
+		return ErrMiss
+	}
+
+	data, ok := reply.(string)
+	if !ok {
+		return fmt.Errorf("redis: unexpected GET reply type %T", reply)
+	}
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		return fmt.Errorf("redis: invalid cached value: %w", err)
+	}
+	return nil
+}
+
+// setJSON stores v as JSON at redisKey for ttl
+func (r *Redis) setJSON(ctx context.Context, redisKey string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	ttlMillis := strconv.FormatInt(ttl.Milliseconds(), 10)
+	_, err = r.do(ctx, "SET", redisKey, string(data), "PX", ttlMillis)
+	return err
+}
+
+// Delete removes key and its negative result from the cache
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	_, err := r.do(ctx, "DEL", redisKeyPrefix+key, redisNegativePrefix+key)
+	return err
+}
+
+// Keys returns the keys of cached media info
+func (r *Redis) Keys(ctx context.Context) ([]string, error) {
+	return r.scan(ctx, redisKeyPrefix)
+}
+
+// scan returns the keys with prefix, without it. SCAN walks the keyspace in steps, so keys
+// written meanwhile may or may not be included.
+func (r *Redis) scan(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := r.do(ctx, "SCAN", cursor, "MATCH", prefix+"*", "COUNT", strconv.Itoa(redisScanCount))
+		if err != nil {
+			return nil, err
+		}
+		items, ok := reply.([]interface{})
+		if !ok || len(items) != 2 {
+			return nil, fmt.Errorf("redis: unexpected SCAN reply %v", reply)
+		}
+		next, _ := items[0].(string)
+		batch, _ := items[1].([]interface{})
+		for _, item := range batch {
+			if key, ok := item.(string); ok {
+				keys = append(keys, strings.TrimPrefix(key, prefix))
+			}
+		}
+		if next == "0" || next == "" {
+			return keys, nil
+		}
+		cursor = next
+	}
+}
+
+// Purge deletes every cached key, including negative results, and returns how many were removed
+func (r *Redis) Purge(ctx context.Context) (int, error) {
+	removed := 0
+	for _, prefix := range []string{redisKeyPrefix, redisNegativePrefix} {
+		keys, err := r.scan(ctx, prefix)
+		if err != nil {
+			return removed, err
+		}
+
+		for start := 0; start < len(keys); start += redisScanCount {
+			batch := keys[start:min(start+redisScanCount, len(keys))]
+			args := make([]string, 0, len(batch)+1)
+			args = append(args, "DEL")
+			for _, key := range batch {
+				args = append(args, prefix+key)
+			}
+			reply, err := r.do(ctx, args...)
+			if err != nil {
+				return removed, err
+			}
+			if n, ok := reply.(int64); ok {
+				removed += int(n)
+			}
+		}
+	}
+	return removed, nil
+}
+
+// do runs a single command on a pooled connection
+func (r *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := r.getConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisCommandTimeout)
+	}
+	conn.conn.SetDeadline(deadline)
+
+	reply, err := conn.command(args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		// Protocol or network failure - the connection state is unknown
+		conn.conn.Close()
+		return nil, err
+	}
+
+	r.putConn(conn)
+	return reply, err
+}
+
+// getConn takes an idle connection from the pool or dials a new one
+func (r *Redis) getConn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.pool:
+		return conn, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: redisDialTimeout}
+	var netConn net.Conn
+	var err error
+	if r.useTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer}
+		netConn, err = tlsDialer.DialContext(ctx, "tcp", r.addr)
+	} else {
+		netConn, err = dialer.DialContext(ctx, "tcp", r.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+	netConn.SetDeadline(time.Now().Add(redisDialTimeout))
+
+	if r.password != "" {
+		authArgs := []string{"AUTH", r.password}
+		if r.username != "" {
+			authArgs = []string{"AUTH", r.username, r.password}
+		}
+		if _, err := conn.command(authArgs...); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if r.db != 0 {
+		if _, err := conn.command("SELECT", strconv.Itoa(r.db)); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// putConn returns a healthy connection to the pool, closing it when the pool is full
+func (r *Redis) putConn(conn *redisConn) {
+	select {
+	case r.pool <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+// command writes a RESP array and reads the reply
+func (rc *redisConn) command(args ...string) (interface{}, error) {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(rc.conn, buf.String()); err != nil {
+		return nil, err
+	}
+	return rc.readReply()
+}
+
+// readReply parses one RESP reply; bulk strings become string, nil bulk becomes nil
+func (rc *redisConn) readReply() (interface{}, error) {
+	line, err := rc.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length: %w", err)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2) // Payload plus trailing CRLF
+		if _, err := io.ReadFull(rc.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid array length: %w", err)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = rc.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply type %q", line[0])
+	}
+}
@@ -2,57 +2,537 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// Environment profiles selectable via QWIKLIP_ENV
+const (
+	EnvDevelopment = "dev"
+	EnvProduction  = "prod"
+)
+
 // Config holds all configuration for the application
 type Config struct {
-	Server    ServerConfig
-	Instagram InstagramConfig
-	Logging   LoggingConfig
+	Env        string // Active environment profile (empty, dev or prod)
+	Server     ServerConfig
+	Instagram  InstagramConfig
+	Logging    LoggingConfig
+	Cache      CacheConfig
+	Stream     StreamConfig
+	Metrics    MetricsConfig
+	VideoCache VideoCacheConfig
+	Auth       AuthConfig
+	ACME       ACMEConfig
+	Admin      AdminConfig
+	Tracing    TracingConfig
+	Errors     ErrorReportingConfig
+	Jobs       JobsConfig
+	Feed       FeedConfig
+	Branding   BrandingConfig
+	Telegram   TelegramConfig
+	Webhooks   WebhookConfig
+
+	Warnings []string // Settings that are valid but probably a mistake, set by Validate and logged
 }
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
 	Port         string
+	Listen       string      // Listen address: host:port or unix:/path/to.sock (empty listens on Port)
+	SocketMode   os.FileMode // Permissions of a Unix socket
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+	DrainTimeout time.Duration // How long shutdown waits for in-flight streams before closing them
+
+	CORSAllowedOrigins []string // Origins allowed for cross-origin requests ("*" allows any)
+	SecurityHeaders    bool     // Add hardening headers (nosniff, frame options, referrer policy)
+	LinkPreviewAgents  []string // User agent substrings of link unfurlers that get an Open Graph page (empty disables)
+	AltSvc             string   // Alt-Svc header advertising a front proxy's HTTP/3 endpoint (empty sends none)
+
+	DebugEndpoints bool   // Serve /debug/pprof/ and /debug/vars
+	DebugAddr      string // Separate listen address for debug endpoints (empty uses the main port)
 }
 
 // InstagramConfig holds Instagram client configuration
 type InstagramConfig struct {
-	Timeout   time.Duration
-	UserAgent string
-	Debug     bool
+	Timeout     time.Duration
+	UserAgent   string
+	Debug       bool
+	Extractors  []string // Ordered extraction strategies to try (empty means all built-ins)
+	SessionID   string   // sessionid cookie of a logged-in account, needed for stories
+	CookiesFile string   // Netscape cookies.txt loaded at startup and updated as cookies refresh
+
+	// Additional accounts rotated per extraction, with cool-down after rate limits or challenges
+	SessionIDs      []string
+	CookiesFiles    []string
+	AccountCooldown time.Duration
+
+	ProxyURL string // Upstream http(s):// or socks5(h):// proxy for Instagram and CDN requests
+
+	// Proxy pool: entries are "<url> [weight]", from env and/or a file with one entry per line
+	ProxyURLs     []string
+	ProxiesFile   string
+	ProxyStrategy string        // round-robin, weighted
+	ProxyCooldown time.Duration // Bench time for a failing or rate-limited proxy
+
+	// Concurrency limit on requests to Instagram (0 disables it)
+	MaxConcurrent int
+	QueueSize     int
+	QueueTimeout  time.Duration
+
+	// Pause of anonymous requests after a 429 without Retry-After; with one, Instagram's value is kept
+	RateLimitCooldown time.Duration
+
+	// Budget of requests to Instagram per minute for each account and proxy (0 disables it);
+	// excess requests wait up to QueueTimeout for a slot and are shed otherwise
+	RequestsPerMinute int
+	RequestBurst      int
+
+	// Retries of a post fetch after network errors or 5xx responses
+	MaxRetries    int
+	RetryBackoff  time.Duration // Base delay, doubled per retry with jitter
+	RetryDeadline time.Duration // No retry starts after this much time since the first attempt
+
+	// Connection pooling of the shared transport used for Instagram and CDN requests
+	MaxIdleConns        int           // Idle connections kept across all hosts (0 = unlimited)
+	MaxIdleConnsPerHost int           // Idle connections kept per host, i.e. per CDN edge
+	IdleConnTimeout     time.Duration // How long an idle connection stays open
+	TLSHandshakeTimeout time.Duration
+	HTTP2               bool // Negotiate HTTP/2 with servers that support it
+
+	// Mock mode: extraction results, post pages and media are served from fixture files
+	// instead of Instagram, for integration tests and frontend development
+	MockMode        bool
+	MockFixturesDir string
+
+	// Sanitized copies of fetched post pages are saved here in the fixture format, to
+	// replay with mock mode in extraction regression tests (empty disables recording)
+	RecordDir string
+
+	// Headless Chrome render when every extractor fails, sniffing the video request
+	HeadlessFallback   bool
+	HeadlessChromePath string        // Chrome or Chromium binary (empty searches PATH)
+	HeadlessTimeout    time.Duration // Longest render, including browser startup
+	HeadlessMaxProcs   int           // Browser processes running at once
+
+	// doc_id of the web GraphQL post query used by the graphql strategy; Meta rotates it
+	GraphQLDocID string
+
+	// Adaptive ordering tries the recently most successful strategy first instead of the
+	// configured order, and another one first with probability AdaptiveExplore
+	AdaptiveOrder   bool
+	AdaptiveExplore float64
+
+	// Extra headers set on requests to instagram.com and to the media CDN, for workarounds
+	// when Instagram starts checking new headers; a Cookie entry is added to the jar's cookies
+	ExtraHeaders map[string]string
+	CDNHeaders   map[string]string
 }
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level  string
-	Format string
+	Level               string
+	Format              string
+	AccessLogFile       string        // JSON access log, one line per request (empty disables)
+	AccessLogMaxSize    int64         // Rotate once the file would grow past this many bytes (0 disables)
+	AccessLogMaxAge     time.Duration // Rotate once the file is older than this (0 disables)
+	AccessLogMaxBackups int           // Rotated files to keep; older ones are deleted (0 keeps all)
+}
+
+// CacheConfig holds extracted metadata cache configuration
+type CacheConfig struct {
+	Backend     string        // memory or redis
+	TTL         time.Duration // How long extracted media info is reused (0 disables)
+	NegativeTTL time.Duration // How long missing, expired and login-walled posts are remembered (0 disables)
+	MaxEntries  int           // Upper bound on cached shortcodes (memory backend)
+	RedisURL    string        // redis://[user:password@]host:port[/db] (redis backend)
+}
+
+// StreamConfig holds video streaming configuration
+type StreamConfig struct {
+	PrefetchSize     int64         // Bytes fetched ahead while response headers are written (0 disables)
+	WriteIdleTimeout time.Duration // Sliding write deadline on streams, refreshed as bytes flow
+	FFmpegPath       string        // ffmpeg binary used for audio extraction and GIFs (name on PATH or absolute path)
+	MetadataTags     bool          // Remux reel streams and downloads through ffmpeg to tag them with the post's metadata
+
+	// Concurrent video responses; further requests get 503 with Retry-After (0 = unlimited)
+	MaxStreams     int
+	BusyRetryAfter time.Duration
+
+	// Bandwidth limits on response bodies in bytes per second (0 = unlimited)
+	MaxBandwidth     int64 // Shared by all clients
+	MaxConnBandwidth int64 // Per client connection
+
+	// Defaults for /reel/{shortcode}.gif, overridable per request
+	GIFSeconds int
+	GIFFPS     int
+	GIFWidth   int
+
+	// HLS packaging for /reel/{shortcode}/index.m3u8
+	HLSDir            string        // Segments are written here and removed after HLSRetention
+	HLSSegmentSeconds int           // Target segment length
+	HLSRetention      time.Duration // How long packaged reels are kept for further requests
+}
+
+// MetricsConfig holds metrics sink configuration
+type MetricsConfig struct {
+	StatsDAddr string // host:port of a StatsD/DogStatsD agent (empty disables)
+	Prefix     string // Prefix prepended to every metric name
+	DogStatsD  bool   // Emit DogStatsD tags
 }
 
-// Load loads configuration from environment variables with sensible defaults
+// ErrorReportingConfig holds error tracking configuration
+type ErrorReportingConfig struct {
+	DSN         string // Sentry-compatible DSN (empty disables reporting)
+	Environment string // Environment tag attached to events
+}
+
+// JobsConfig holds background job configuration
+type JobsConfig struct {
+	Workers   int           // Jobs run concurrently (0 disables the job API)
+	QueueSize int           // Jobs allowed to wait for a worker
+	Retention time.Duration // How long finished jobs and their files are kept
+	Dir       string        // Directory for job result files
+	StateFile string        // JSON file jobs are persisted to (empty keeps them in memory only)
+}
+
+// FeedConfig holds RSS feed configuration
+type FeedConfig struct {
+	CacheDir string        // Directory feed pages are cached in
+	TTL      time.Duration // How long a cached feed page is served before it is refreshed
+	Items    int           // Posts requested from the profile feed per refresh
+}
+
+// BrandingConfig holds the operator's name, logo and contact shown on the HTML pages
+type BrandingConfig struct {
+	Name       string // Replaces "Qwiklip" in page headers and titles
+	LogoURL    string // Header logo, a path on this server or an absolute URL
+	Footer     string // Text under the index, watch and error pages (empty shows none)
+	ContactURL string // Contact link in the footer, https:// or mailto: (empty shows none)
+}
+
+// TelegramConfig holds configuration for the `qwiklip telegram-bot` mode
+type TelegramConfig struct {
+	BotToken      string        // Token from @BotFather (required in bot mode)
+	APIURL        string        // Bot API base URL, for a self-hosted Bot API server
+	PollTimeout   time.Duration // Long-polling timeout of getUpdates
+	MaxUploadSize int64         // Larger videos are answered with a link instead of an upload
+	PublicURL     string        // Public base URL of the proxy for links in replies (optional)
+	AllowedChats  []string      // Chat IDs the bot answers (empty answers everyone)
+}
+
+// WebhookConfig holds webhook notification configuration
+type WebhookConfig struct {
+	URLs       []string      // Endpoints every event is POSTed to (empty disables webhooks)
+	Secret     string        // HMAC-SHA256 key for the X-Qwiklip-Signature header (empty sends unsigned)
+	Events     []string      // Event types delivered (empty delivers all)
+	Timeout    time.Duration // Timeout of one delivery attempt
+	MaxRetries int           // Further attempts after a failed delivery
+}
+
+// TracingConfig holds OpenTelemetry trace export configuration
+type TracingConfig struct {
+	Endpoint    string            // OTLP/HTTP collector base URL (empty disables tracing)
+	ServiceName string            // service.name resource attribute
+	SampleRatio float64           // Fraction of new traces recorded (0-1)
+	Headers     map[string]string // Extra export request headers, e.g. auth tokens
+}
+
+// profile holds the bundle of defaults selected by QWIKLIP_ENV.
+// Individual environment variables still override any profile value.
+type profile struct {
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	idleTimeout     time.Duration
+	corsOrigins     string
+	securityHeaders bool
+	debug           bool
+	logLevel        string
+	logFormat       string
+}
+
+// profileDefaults returns the defaults for an environment profile
+func profileDefaults(env string) profile {
+	base := profile{
+		readTimeout:  30 * time.Second,
+		writeTimeout: 60 * time.Second, // Streams use their own sliding deadline
+		idleTimeout:  120 * time.Second,
+		corsOrigins:  "*",
+		logLevel:     "info",
+		logFormat:    "text",
+	}
+
+	switch env {
+	case EnvDevelopment:
+		// Pretty logs, verbose debugging and permissive CORS
+		base.debug = true
+		base.logLevel = "debug"
+	case EnvProduction:
+		// Machine-readable logs, tighter limits and hardening headers
+		base.readTimeout = 15 * time.Second
+		base.idleTimeout = 60 * time.Second
+		base.corsOrigins = ""
+		base.securityHeaders = true
+		base.logFormat = "json"
+	}
+
+	return base
+}
+
+// VideoCacheConfig holds video cache configuration
+type VideoCacheConfig struct {
+	Backend string   // disk or s3
+	Dir     string   // Directory for cached videos (disk backend, empty disables)
+	MaxSize int64    // Total bytes kept on disk before LRU eviction (disk backend)
+	S3      S3Config // Object storage settings (s3 backend)
+}
+
+// S3Config holds S3-compatible object storage configuration
+type S3Config struct {
+	Endpoint        string // e.g. https://s3.us-east-1.amazonaws.com or http://minio:9000
+	Region          string
+	Bucket          string
+	Prefix          string // Key prefix inside the bucket
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool // Address buckets as endpoint/bucket (required by MinIO)
+}
+
+// AuthConfig holds request authentication configuration.
+// Authentication is disabled unless a JWKS URL is configured.
+type AuthConfig struct {
+	JWKSURL     string        // JSON Web Key Set of the identity provider
+	Issuer      string        // Required "iss" claim (empty skips the check)
+	Audience    string        // Required "aud" claim (empty skips the check)
+	JWKSRefresh time.Duration // How often signing keys are re-fetched
+	ClockSkew   time.Duration // Leeway applied to exp/nbf
+}
+
+// ACMEConfig holds automatic HTTPS configuration. When domains are listed the server
+// serves HTTPS with a certificate obtained from an ACME CA (Let's Encrypt by default).
+type ACMEConfig struct {
+	Domains      []string      // Host names the certificate covers (empty disables ACME)
+	Email        string        // Account contact for expiry notices (optional)
+	CacheDir     string        // Where the account key and certificate are kept
+	DirectoryURL string        // ACME directory of the CA
+	HTTPAddr     string        // Listener for HTTP-01 challenges; other requests are redirected to HTTPS
+	RenewBefore  time.Duration // How long before expiry the certificate is renewed
+}
+
+// AdminConfig holds the admin API configuration. The API is served only when a token is set.
+type AdminConfig struct {
+	Token string // Bearer token required on every admin request
+	Addr  string // Separate admin listener, e.g. 127.0.0.1:9090 (empty serves /admin/ on the main port)
+}
+
+// Load loads configuration from environment variables with sensible defaults, reading
+// the config file named by QWIKLIP_CONFIG first when it is set
 func Load() (*Config, error) {
+	return LoadFile(os.Getenv("QWIKLIP_CONFIG"))
+}
+
+// LoadFile loads configuration from a TOML file and the environment. Precedence, highest
+// first: environment variables, the file, the QWIKLIP_ENV profile, built-in defaults.
+// An empty path reads the environment alone.
+func LoadFile(path string) (*Config, error) {
+	return LoadWithFlags(path, nil)
+}
+
+// LoadWithFlags is LoadFile with command-line flags (see RegisterFlags) taking
+// precedence over the environment
+func LoadWithFlags(path string, flags Flags) (*Config, error) {
+	src := source{flags: flags}
+	if path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		src.file = values
+	}
+
+	env := strings.ToLower(src.getEnv("QWIKLIP_ENV", ""))
+	defaults := profileDefaults(env)
+
 	config := &Config{
+		Env: env,
 		Server: ServerConfig{
-			Port:         getEnv("PORT", "8080"),
-			ReadTimeout:  30 * time.Second,
-			WriteTimeout: 300 * time.Second, // Longer for video streaming
-			IdleTimeout:  120 * time.Second,
+			Port:               src.getEnv("PORT", "8080"),
+			Listen:             src.getEnv("LISTEN", ""),
+			SocketMode:         src.getEnvAsFileMode("SERVER_SOCKET_MODE", 0o660),
+			ReadTimeout:        src.getEnvAsDuration("SERVER_READ_TIMEOUT", defaults.readTimeout),
+			WriteTimeout:       src.getEnvAsDuration("SERVER_WRITE_TIMEOUT", defaults.writeTimeout),
+			IdleTimeout:        src.getEnvAsDuration("SERVER_IDLE_TIMEOUT", defaults.idleTimeout),
+			DrainTimeout:       src.getEnvAsDuration("SERVER_DRAIN_TIMEOUT", 30*time.Second),
+			CORSAllowedOrigins: src.getEnvAsSlice("CORS_ALLOWED_ORIGINS", defaults.corsOrigins),
+			SecurityHeaders:    src.getEnvAsBool("SECURITY_HEADERS", defaults.securityHeaders),
+			LinkPreviewAgents:  src.getEnvAsSlice("LINK_PREVIEW_AGENTS", "Discordbot,TelegramBot,Slackbot,Twitterbot,facebookexternalhit,WhatsApp"),
+			AltSvc:             src.getEnv("ALT_SVC", ""),
+			DebugEndpoints:     src.getEnvAsBool("DEBUG_ENDPOINTS", false),
+			DebugAddr:          src.getEnv("DEBUG_ADDR", ""),
 		},
 		Instagram: InstagramConfig{
-			Timeout:   30 * time.Second,
-			UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-			Debug:     getEnvAsBool("DEBUG", false),
+			Timeout:             src.getEnvAsDuration("INSTAGRAM_TIMEOUT", 30*time.Second),
+			UserAgent:           src.getEnv("INSTAGRAM_USER_AGENT", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
+			Debug:               src.getEnvAsBool("DEBUG", defaults.debug),
+			SessionID:           src.getEnv("INSTAGRAM_SESSION_ID", ""),
+			CookiesFile:         src.getEnv("INSTAGRAM_COOKIES_FILE", ""),
+			SessionIDs:          src.getEnvAsSlice("INSTAGRAM_SESSION_IDS", ""),
+			CookiesFiles:        src.getEnvAsSlice("INSTAGRAM_COOKIES_FILES", ""),
+			AccountCooldown:     src.getEnvAsDuration("INSTAGRAM_ACCOUNT_COOLDOWN", 15*time.Minute),
+			ProxyURL:            src.getEnv("OUTBOUND_PROXY_URL", ""),
+			ProxyURLs:           src.getEnvAsSlice("OUTBOUND_PROXY_URLS", ""),
+			ProxiesFile:         src.getEnv("OUTBOUND_PROXIES_FILE", ""),
+			ProxyStrategy:       src.getEnv("OUTBOUND_PROXY_STRATEGY", "round-robin"),
+			ProxyCooldown:       src.getEnvAsDuration("OUTBOUND_PROXY_COOLDOWN", time.Minute),
+			MaxConcurrent:       src.getEnvAsInt("INSTAGRAM_MAX_CONCURRENT", 4),
+			QueueSize:           src.getEnvAsInt("INSTAGRAM_QUEUE_SIZE", 64),
+			QueueTimeout:        src.getEnvAsDuration("INSTAGRAM_QUEUE_TIMEOUT", 10*time.Second),
+			RateLimitCooldown:   src.getEnvAsDuration("INSTAGRAM_RATE_LIMIT_COOLDOWN", time.Minute),
+			RequestsPerMinute:   src.getEnvAsInt("INSTAGRAM_REQUESTS_PER_MINUTE", 0),
+			RequestBurst:        src.getEnvAsInt("INSTAGRAM_REQUEST_BURST", 5),
+			MaxRetries:          src.getEnvAsInt("INSTAGRAM_MAX_RETRIES", 2),
+			RetryBackoff:        src.getEnvAsDuration("INSTAGRAM_RETRY_BACKOFF", 500*time.Millisecond),
+			RetryDeadline:       src.getEnvAsDuration("INSTAGRAM_RETRY_DEADLINE", 20*time.Second),
+			MaxIdleConns:        src.getEnvAsInt("INSTAGRAM_MAX_IDLE_CONNS", 100),
+			MaxIdleConnsPerHost: src.getEnvAsInt("INSTAGRAM_MAX_IDLE_CONNS_PER_HOST", 16),
+			IdleConnTimeout:     src.getEnvAsDuration("INSTAGRAM_IDLE_CONN_TIMEOUT", 90*time.Second),
+			TLSHandshakeTimeout: src.getEnvAsDuration("INSTAGRAM_TLS_HANDSHAKE_TIMEOUT", 10*time.Second),
+			HTTP2:               src.getEnvAsBool("INSTAGRAM_HTTP2", true),
+			Extractors:          src.getEnvAsSlice("INSTAGRAM_EXTRACTORS", "json,direct,fallback,preloader,image,embed,graphql"),
+			MockMode:            src.getEnvAsBool("MOCK_MODE", false),
+			MockFixturesDir:     src.getEnv("MOCK_FIXTURES_DIR", "fixtures"),
+			RecordDir:           src.getEnv("INSTAGRAM_RECORD_DIR", ""),
+			HeadlessFallback:    src.getEnvAsBool("HEADLESS_FALLBACK", false),
+			HeadlessChromePath:  src.getEnv("HEADLESS_CHROME_PATH", ""),
+			HeadlessTimeout:     src.getEnvAsDuration("HEADLESS_TIMEOUT", 45*time.Second),
+			HeadlessMaxProcs:    src.getEnvAsInt("HEADLESS_MAX_PROCS", 1),
+			GraphQLDocID:        src.getEnv("INSTAGRAM_GRAPHQL_DOC_ID", "8845758582119845"),
+			AdaptiveOrder:       src.getEnvAsBool("INSTAGRAM_ADAPTIVE_EXTRACTORS", false),
+			AdaptiveExplore:     src.getEnvAsFloat("INSTAGRAM_ADAPTIVE_EXPLORE", 0.1),
+			ExtraHeaders:        src.getEnvAsHeaders("INSTAGRAM_EXTRA_HEADERS"),
+			CDNHeaders:          src.getEnvAsHeaders("CDN_EXTRA_HEADERS"),
+		},
+		Cache: CacheConfig{
+			Backend:     strings.ToLower(src.getEnv("METADATA_CACHE_BACKEND", "memory")),
+			TTL:         src.getEnvAsDuration("METADATA_CACHE_TTL", 10*time.Minute),
+			NegativeTTL: src.getEnvAsDuration("METADATA_CACHE_NEGATIVE_TTL", time.Minute),
+			MaxEntries:  src.getEnvAsInt("METADATA_CACHE_MAX_ENTRIES", 1000),
+			RedisURL:    src.getEnv("REDIS_URL", ""),
 		},
 		Logging: LoggingConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "text"), // text or json
+			Level:               src.getEnv("LOG_LEVEL", defaults.logLevel),
+			Format:              src.getEnv("LOG_FORMAT", defaults.logFormat), // text or json
+			AccessLogFile:       src.getEnv("ACCESS_LOG_FILE", ""),
+			AccessLogMaxSize:    src.getEnvAsInt64("ACCESS_LOG_MAX_SIZE", 100*1024*1024), // 100MB
+			AccessLogMaxAge:     src.getEnvAsDuration("ACCESS_LOG_MAX_AGE", 24*time.Hour),
+			AccessLogMaxBackups: src.getEnvAsInt("ACCESS_LOG_MAX_BACKUPS", 7),
+		},
+		Stream: StreamConfig{
+			PrefetchSize:     src.getEnvAsInt64("STREAM_PREFETCH_SIZE", 2*1024*1024), // 2MB
+			WriteIdleTimeout: src.getEnvAsDuration("STREAM_WRITE_IDLE_TIMEOUT", 30*time.Second),
+			FFmpegPath:       src.getEnv("FFMPEG_PATH", "ffmpeg"),
+			MetadataTags:     src.getEnvAsBool("STREAM_METADATA_TAGS", false),
+			MaxStreams:       src.getEnvAsInt("STREAM_MAX_CONCURRENT", 0),
+			BusyRetryAfter:   src.getEnvAsDuration("STREAM_BUSY_RETRY_AFTER", 5*time.Second),
+			MaxBandwidth:     src.getEnvAsInt64("STREAM_MAX_BANDWIDTH", 0),
+			MaxConnBandwidth: src.getEnvAsInt64("STREAM_MAX_CONN_BANDWIDTH", 0),
+			GIFSeconds:       src.getEnvAsInt("GIF_SECONDS", 5),
+			GIFFPS:           src.getEnvAsInt("GIF_FPS", 10),
+			GIFWidth:         src.getEnvAsInt("GIF_WIDTH", 320),
+
+			HLSDir:            src.getEnv("HLS_DIR", filepath.Join(os.TempDir(), "qwiklip-hls")),
+			HLSSegmentSeconds: src.getEnvAsInt("HLS_SEGMENT_SECONDS", 4),
+			HLSRetention:      src.getEnvAsDuration("HLS_RETENTION", 10*time.Minute),
+		},
+		Metrics: MetricsConfig{
+			StatsDAddr: src.getEnv("STATSD_ADDR", ""),
+			Prefix:     src.getEnv("METRICS_PREFIX", "qwiklip"),
+			DogStatsD:  src.getEnvAsBool("STATSD_DOGSTATSD", false),
+		},
+		Errors: ErrorReportingConfig{
+			DSN:         src.getEnv("SENTRY_DSN", ""),
+			Environment: src.getEnv("SENTRY_ENVIRONMENT", env),
+		},
+		Jobs: JobsConfig{
+			Workers:   src.getEnvAsInt("JOBS_WORKERS", 2),
+			QueueSize: src.getEnvAsInt("JOBS_QUEUE_SIZE", 100),
+			Retention: src.getEnvAsDuration("JOBS_RETENTION", time.Hour),
+			Dir:       src.getEnv("JOBS_DIR", filepath.Join(os.TempDir(), "qwiklip-jobs")),
+			StateFile: src.getEnv("JOBS_STATE_FILE", ""),
+		},
+		Telegram: TelegramConfig{
+			BotToken:      src.getEnv("TELEGRAM_BOT_TOKEN", ""),
+			APIURL:        strings.TrimSuffix(src.getEnv("TELEGRAM_API_URL", "https://api.telegram.org"), "/"),
+			PollTimeout:   src.getEnvAsDuration("TELEGRAM_POLL_TIMEOUT", 30*time.Second),
+			MaxUploadSize: src.getEnvAsInt64("TELEGRAM_MAX_UPLOAD_SIZE", 50*1024*1024), // Bot API upload limit
+			PublicURL:     strings.TrimSuffix(src.getEnv("TELEGRAM_PUBLIC_URL", ""), "/"),
+			AllowedChats:  src.getEnvAsSlice("TELEGRAM_ALLOWED_CHATS", ""),
+		},
+		Webhooks: WebhookConfig{
+			URLs:       src.getEnvAsSlice("WEBHOOK_URLS", ""),
+			Secret:     src.getEnv("WEBHOOK_SECRET", ""),
+			Events:     src.getEnvAsSlice("WEBHOOK_EVENTS", ""),
+			Timeout:    src.getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+			MaxRetries: src.getEnvAsInt("WEBHOOK_MAX_RETRIES", 3),
+		},
+		Feed: FeedConfig{
+			CacheDir: src.getEnv("FEED_CACHE_DIR", filepath.Join(os.TempDir(), "qwiklip-feeds")),
+			TTL:      src.getEnvAsDuration("FEED_TTL", 30*time.Minute),
+			Items:    src.getEnvAsInt("FEED_ITEMS", 12),
+		},
+		Branding: BrandingConfig{
+			Name:       strings.TrimSpace(src.getEnv("BRAND_NAME", "Qwiklip")),
+			LogoURL:    src.getEnv("BRAND_LOGO_URL", "/static/svg/favicon.svg"),
+			Footer:     src.getEnv("BRAND_FOOTER", ""),
+			ContactURL: src.getEnv("BRAND_CONTACT_URL", ""),
+		},
+		Tracing: TracingConfig{
+			Endpoint:    src.getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			ServiceName: src.getEnv("OTEL_SERVICE_NAME", "qwiklip"),
+			SampleRatio: src.getEnvAsFloat("OTEL_TRACES_SAMPLER_ARG", 1.0),
+			Headers:     src.getEnvAsMap("OTEL_EXPORTER_OTLP_HEADERS"),
+		},
+		VideoCache: VideoCacheConfig{
+			Backend: strings.ToLower(src.getEnv("VIDEO_CACHE_BACKEND", "disk")),
+			Dir:     src.getEnv("VIDEO_CACHE_DIR", ""),
+			MaxSize: src.getEnvAsInt64("VIDEO_CACHE_MAX_SIZE", 1024*1024*1024), // 1GB
+			S3: S3Config{
+				Endpoint:        src.getEnv("S3_ENDPOINT", ""),
+				Region:          src.getEnv("S3_REGION", "us-east-1"),
+				Bucket:          src.getEnv("S3_BUCKET", ""),
+				Prefix:          src.getEnv("S3_PREFIX", "videos"),
+				AccessKeyID:     src.getEnv("S3_ACCESS_KEY_ID", ""),
+				SecretAccessKey: src.getEnv("S3_SECRET_ACCESS_KEY", ""),
+				PathStyle:       src.getEnvAsBool("S3_PATH_STYLE", true),
+			},
+		},
+		Auth: AuthConfig{
+			JWKSURL:     src.getEnv("AUTH_JWKS_URL", ""),
+			Issuer:      src.getEnv("AUTH_JWT_ISSUER", ""),
+			Audience:    src.getEnv("AUTH_JWT_AUDIENCE", ""),
+			JWKSRefresh: src.getEnvAsDuration("AUTH_JWKS_REFRESH", time.Hour),
+			ClockSkew:   src.getEnvAsDuration("AUTH_JWT_CLOCK_SKEW", time.Minute),
+		},
+		Admin: AdminConfig{
+			Token: src.getEnv("ADMIN_TOKEN", ""),
+			Addr:  src.getEnv("ADMIN_ADDR", ""),
+		},
+		ACME: ACMEConfig{
+			Domains:      src.getEnvAsSlice("ACME_DOMAINS", ""),
+			Email:        src.getEnv("ACME_EMAIL", ""),
+			CacheDir:     src.getEnv("ACME_CACHE_DIR", "acme"),
+			DirectoryURL: src.getEnv("ACME_DIRECTORY_URL", "https://acme-v02.api.letsencrypt.org/directory"),
+			HTTPAddr:     src.getEnv("ACME_HTTP_ADDR", ":80"),
+			RenewBefore:  src.getEnvAsDuration("ACME_RENEW_BEFORE", 30*24*time.Hour),
 		},
 	}
 
@@ -64,8 +544,35 @@ func Load() (*Config, error) {
 	return config, nil
 }
 
+// ListenAddr returns the network ("tcp" or "unix") and address the server listens on
+func (s ServerConfig) ListenAddr() (network, address string) {
+	if path, ok := strings.CutPrefix(s.Listen, "unix:"); ok {
+		return "unix", path
+	}
+	if s.Listen != "" {
+		return "tcp", s.Listen
+	}
+	return "tcp", ":" + s.Port
+}
+
+// ListenPort returns the TCP port the server listens on, or "" for a Unix socket
+func (s ServerConfig) ListenPort() string {
+	network, address := s.ListenAddr()
+	if network != "tcp" {
+		return ""
+	}
+	_, port, _ := net.SplitHostPort(address)
+	return port
+}
+
 // Validate performs comprehensive validation of all configuration values
 func (c *Config) Validate() error {
+	c.Warnings = nil
+
+	if c.Env != "" && c.Env != EnvDevelopment && c.Env != EnvProduction {
+		return fmt.Errorf("invalid environment '%s', must be one of: %s, %s", c.Env, EnvDevelopment, EnvProduction)
+	}
+
 	if err := c.validateServerConfig(); err != nil {
 		return fmt.Errorf("server config: %w", err)
 	}
@@ -78,6 +585,62 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("logging config: %w", err)
 	}
 
+	if err := c.validateCacheConfig(); err != nil {
+		return fmt.Errorf("cache config: %w", err)
+	}
+
+	if err := c.validateStreamConfig(); err != nil {
+		return fmt.Errorf("stream config: %w", err)
+	}
+
+	if err := c.validateMetricsConfig(); err != nil {
+		return fmt.Errorf("metrics config: %w", err)
+	}
+
+	if err := c.validateErrorReportingConfig(); err != nil {
+		return fmt.Errorf("error reporting config: %w", err)
+	}
+
+	if err := c.validateJobsConfig(); err != nil {
+		return fmt.Errorf("jobs config: %w", err)
+	}
+
+	if err := c.validateFeedConfig(); err != nil {
+		return fmt.Errorf("feed config: %w", err)
+	}
+
+	if err := c.validateBrandingConfig(); err != nil {
+		return fmt.Errorf("branding config: %w", err)
+	}
+
+	if err := c.validateTelegramConfig(); err != nil {
+		return fmt.Errorf("telegram config: %w", err)
+	}
+
+	if err := c.validateWebhookConfig(); err != nil {
+		return fmt.Errorf("webhook config: %w", err)
+	}
+
+	if err := c.validateTracingConfig(); err != nil {
+		return fmt.Errorf("tracing config: %w", err)
+	}
+
+	if err := c.validateAuthConfig(); err != nil {
+		return fmt.Errorf("auth config: %w", err)
+	}
+
+	if err := c.validateVideoCacheConfig(); err != nil {
+		return fmt.Errorf("video cache config: %w", err)
+	}
+
+	if err := c.validateACMEConfig(); err != nil {
+		return fmt.Errorf("acme config: %w", err)
+	}
+
+	if err := c.validateAdminConfig(); err != nil {
+		return fmt.Errorf("admin config: %w", err)
+	}
+
 	return nil
 }
 
@@ -102,20 +665,104 @@ func (c *Config) validateServerConfig() error {
 	if c.Server.IdleTimeout <= 0 {
 		return fmt.Errorf("idle timeout must be positive, got %v", c.Server.IdleTimeout)
 	}
+	if c.Server.DrainTimeout < 0 || c.Server.DrainTimeout > time.Hour {
+		return fmt.Errorf("drain timeout must be between 0 and 1h, got %v", c.Server.DrainTimeout)
+	}
 
 	// Read timeout should be reasonable (not too long for security)
 	if c.Server.ReadTimeout > 5*time.Minute {
 		return fmt.Errorf("read timeout too long (max 5m), got %v", c.Server.ReadTimeout)
 	}
 
-	// Write timeout should be reasonable for video streaming
+	// Write timeout must leave room for Instagram extraction before the response is written
 	if c.Server.WriteTimeout < 30*time.Second {
-		return fmt.Errorf("write timeout too short for video streaming (min 30s), got %v", c.Server.WriteTimeout)
+		return fmt.Errorf("write timeout too short (min 30s), got %v", c.Server.WriteTimeout)
+	}
+
+	// Validate listen address
+	if path, ok := strings.CutPrefix(c.Server.Listen, "unix:"); ok {
+		if path == "" {
+			return fmt.Errorf("unix socket path is required in LISTEN=unix:<path>")
+		}
+		if len(path) > 104 {
+			return fmt.Errorf("unix socket path too long (max 104 bytes), got %d", len(path))
+		}
+	} else if c.Server.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.Server.Listen); err != nil {
+			return fmt.Errorf("invalid listen address '%s', must be host:port or unix:<path>", c.Server.Listen)
+		}
+	}
+	if c.Server.SocketMode == 0 || c.Server.SocketMode > 0o777 {
+		return fmt.Errorf("socket mode must be between 0001 and 0777, got %04o", c.Server.SocketMode)
+	}
+
+	// Validate debug listener
+	if c.Server.DebugAddr != "" {
+		if !c.Server.DebugEndpoints {
+			return fmt.Errorf("debug address is set but DEBUG_ENDPOINTS is disabled")
+		}
+		if _, _, err := net.SplitHostPort(c.Server.DebugAddr); err != nil {
+			return fmt.Errorf("invalid debug address '%s', must be host:port", c.Server.DebugAddr)
+		}
+	}
+
+	if c.Server.AltSvc != "" {
+		warning, err := validateAltSvc(c.Server.AltSvc, len(c.ACME.Domains) > 0)
+		if err != nil {
+			return fmt.Errorf("invalid ALT_SVC '%s': %w", c.Server.AltSvc, err)
+		}
+		if warning != "" {
+			c.Warnings = append(c.Warnings, warning)
+		}
 	}
 
 	return nil
 }
 
+// validateAltSvc checks an Alt-Svc header value (RFC 7838): "clear", or comma-separated
+// alternatives such as h3=":443"; ma=86400. It warns when HTTP/3 is advertised while
+// Qwiklip terminates TLS itself, since then no front proxy serves the alternative.
+func validateAltSvc(value string, terminatesTLS bool) (string, error) {
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return "", fmt.Errorf("contains a line break")
+	}
+	if strings.TrimSpace(value) == "clear" {
+		return "", nil
+	}
+	var warning string
+	for _, entry := range strings.Split(value, ",") {
+		alternative, params, _ := strings.Cut(entry, ";")
+		alternative = strings.TrimSpace(alternative)
+		protocol, authority, ok := strings.Cut(alternative, "=")
+		if !ok || protocol == "" || strings.ContainsAny(protocol, " \t\"") {
+			return "", fmt.Errorf("alternative %q must be protocol=\"host:port\"", alternative)
+		}
+		unquoted, err := strconv.Unquote(authority)
+		if err != nil || authority[0] != '"' {
+			return "", fmt.Errorf("authority of %s must be quoted, e.g. \":443\"", protocol)
+		}
+		if terminatesTLS && (protocol == "h3" || strings.HasPrefix(protocol, "h3-")) {
+			warning = "ALT_SVC advertises HTTP/3 but ACME_DOMAINS is set, so Qwiklip terminates TLS and nothing serves QUIC"
+		}
+		_, port, err := net.SplitHostPort(unquoted)
+		if err != nil {
+			return "", fmt.Errorf("authority of %s must be host:port, got %q", protocol, unquoted)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("port of %s out of range (1-65535): %s", protocol, port)
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == "" {
+				continue
+			}
+			if name, _, ok := strings.Cut(param, "="); !ok || strings.TrimSpace(name) == "" {
+				return "", fmt.Errorf("parameter %q of %s must be name=value", strings.TrimSpace(param), protocol)
+			}
+		}
+	}
+	return warning, nil
+}
+
 // validateInstagramConfig validates Instagram client configuration
 func (c *Config) validateInstagramConfig() error {
 	// Validate timeout
@@ -137,6 +784,184 @@ func (c *Config) validateInstagramConfig() error {
 		return fmt.Errorf("user agent too long (max 500 chars), got %d", len(c.Instagram.UserAgent))
 	}
 
+	// Validate cookies files
+	cookiesFiles := c.Instagram.CookiesFiles
+	if c.Instagram.CookiesFile != "" {
+		cookiesFiles = append([]string{c.Instagram.CookiesFile}, cookiesFiles...)
+	}
+	for _, path := range cookiesFiles {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("cookies file: %w", err)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("cookies file '%s' is a directory", path)
+		}
+	}
+
+	// Validate outbound proxies
+	if c.Instagram.ProxyURL != "" {
+		if err := ValidateProxyURL(c.Instagram.ProxyURL); err != nil {
+			return err
+		}
+	}
+	for _, entry := range c.Instagram.ProxyURLs {
+		if _, _, err := ParseProxyEntry(entry); err != nil {
+			return err
+		}
+	}
+	if c.Instagram.ProxiesFile != "" {
+		info, err := os.Stat(c.Instagram.ProxiesFile)
+		if err != nil {
+			return fmt.Errorf("proxies file: %w", err)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("proxies file '%s' is a directory", c.Instagram.ProxiesFile)
+		}
+	}
+	switch c.Instagram.ProxyStrategy {
+	case "round-robin", "weighted":
+	default:
+		return fmt.Errorf("invalid proxy strategy '%s', must be one of: round-robin, weighted", c.Instagram.ProxyStrategy)
+	}
+	if c.Instagram.ProxyCooldown < time.Second {
+		return fmt.Errorf("proxy cooldown too short (min 1s), got %v", c.Instagram.ProxyCooldown)
+	}
+	if c.Instagram.ProxyCooldown > time.Hour {
+		return fmt.Errorf("proxy cooldown too long (max 1h), got %v", c.Instagram.ProxyCooldown)
+	}
+
+	// Validate concurrency limit
+	if c.Instagram.MaxConcurrent < 0 || c.Instagram.MaxConcurrent > 256 {
+		return fmt.Errorf("max concurrent Instagram requests must be between 0 and 256, got %d", c.Instagram.MaxConcurrent)
+	}
+	if c.Instagram.QueueSize < 0 || c.Instagram.QueueSize > 10000 {
+		return fmt.Errorf("Instagram queue size must be between 0 and 10000, got %d", c.Instagram.QueueSize)
+	}
+	if c.Instagram.QueueTimeout < 100*time.Millisecond {
+		return fmt.Errorf("Instagram queue timeout too short (min 100ms), got %v", c.Instagram.QueueTimeout)
+	}
+	if c.Instagram.QueueTimeout > 5*time.Minute {
+		return fmt.Errorf("Instagram queue timeout too long (max 5m), got %v", c.Instagram.QueueTimeout)
+	}
+	if c.Instagram.RateLimitCooldown < time.Second || c.Instagram.RateLimitCooldown > time.Hour {
+		return fmt.Errorf("rate limit cooldown must be between 1s and 1h, got %v", c.Instagram.RateLimitCooldown)
+	}
+	if c.Instagram.RequestsPerMinute < 0 || c.Instagram.RequestsPerMinute > 6000 {
+		return fmt.Errorf("Instagram requests per minute must be between 0 and 6000, got %d", c.Instagram.RequestsPerMinute)
+	}
+	if c.Instagram.RequestBurst < 1 || c.Instagram.RequestBurst > 100 {
+		return fmt.Errorf("Instagram request burst must be between 1 and 100, got %d", c.Instagram.RequestBurst)
+	}
+
+	// Validate retries
+	if c.Instagram.MaxRetries < 0 || c.Instagram.MaxRetries > 10 {
+		return fmt.Errorf("Instagram max retries must be between 0 and 10, got %d", c.Instagram.MaxRetries)
+	}
+	if c.Instagram.RetryBackoff < 10*time.Millisecond || c.Instagram.RetryBackoff > 30*time.Second {
+		return fmt.Errorf("Instagram retry backoff must be between 10ms and 30s, got %v", c.Instagram.RetryBackoff)
+	}
+	if c.Instagram.RetryDeadline < time.Second || c.Instagram.RetryDeadline > 5*time.Minute {
+		return fmt.Errorf("Instagram retry deadline must be between 1s and 5m, got %v", c.Instagram.RetryDeadline)
+	}
+
+	// Validate connection pooling
+	if c.Instagram.MaxIdleConns < 0 || c.Instagram.MaxIdleConns > 10000 {
+		return fmt.Errorf("max idle connections must be between 0 and 10000, got %d", c.Instagram.MaxIdleConns)
+	}
+	if c.Instagram.MaxIdleConnsPerHost < 1 || c.Instagram.MaxIdleConnsPerHost > 1000 {
+		return fmt.Errorf("max idle connections per host must be between 1 and 1000, got %d", c.Instagram.MaxIdleConnsPerHost)
+	}
+	if c.Instagram.MaxIdleConns > 0 && c.Instagram.MaxIdleConnsPerHost > c.Instagram.MaxIdleConns {
+		return fmt.Errorf("max idle connections per host (%d) cannot exceed max idle connections (%d)",
+			c.Instagram.MaxIdleConnsPerHost, c.Instagram.MaxIdleConns)
+	}
+	if c.Instagram.IdleConnTimeout < time.Second || c.Instagram.IdleConnTimeout > time.Hour {
+		return fmt.Errorf("idle connection timeout must be between 1s and 1h, got %v", c.Instagram.IdleConnTimeout)
+	}
+	if c.Instagram.TLSHandshakeTimeout < time.Second || c.Instagram.TLSHandshakeTimeout > time.Minute {
+		return fmt.Errorf("TLS handshake timeout must be between 1s and 1m, got %v", c.Instagram.TLSHandshakeTimeout)
+	}
+
+	// Validate mock mode
+	if c.Instagram.MockMode {
+		info, err := os.Stat(c.Instagram.MockFixturesDir)
+		if err != nil {
+			return fmt.Errorf("mock fixtures directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("mock fixtures directory '%s' is not a directory", c.Instagram.MockFixturesDir)
+		}
+	}
+
+	if c.Instagram.RecordDir != "" {
+		if c.Instagram.MockMode {
+			return fmt.Errorf("recording post pages and mock mode cannot be enabled together")
+		}
+		info, err := os.Stat(c.Instagram.RecordDir)
+		if err != nil {
+			return fmt.Errorf("record directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("record directory '%s' is not a directory", c.Instagram.RecordDir)
+		}
+	}
+
+	// Validate headless fallback
+	if c.Instagram.HeadlessFallback {
+		if c.Instagram.MockMode {
+			return fmt.Errorf("headless fallback and mock mode cannot be enabled together")
+		}
+		if c.Instagram.HeadlessTimeout < 10*time.Second || c.Instagram.HeadlessTimeout > 5*time.Minute {
+			return fmt.Errorf("headless timeout must be between 10s and 5m, got %v", c.Instagram.HeadlessTimeout)
+		}
+		if c.Instagram.HeadlessMaxProcs < 1 || c.Instagram.HeadlessMaxProcs > 16 {
+			return fmt.Errorf("headless max processes must be between 1 and 16, got %d", c.Instagram.HeadlessMaxProcs)
+		}
+	}
+
+	// Validate account cool-down
+	if c.Instagram.AccountCooldown < time.Minute {
+		return fmt.Errorf("account cooldown too short (min 1m), got %v", c.Instagram.AccountCooldown)
+	}
+	if c.Instagram.AccountCooldown > 24*time.Hour {
+		return fmt.Errorf("account cooldown too long (max 24h), got %v", c.Instagram.AccountCooldown)
+	}
+
+	// Validate extraction strategies
+	validExtractors := map[string]bool{
+		"json":      true,
+		"direct":    true,
+		"fallback":  true,
+		"preloader": true,
+		"image":     true,
+		"embed":     true,
+		"graphql":   true,
+	}
+	seen := make(map[string]bool)
+	for _, name := range c.Instagram.Extractors {
+		name = strings.ToLower(name)
+		if !validExtractors[name] {
+			return fmt.Errorf("invalid extractor '%s', must be one of: json, direct, fallback, preloader, image, embed, graphql", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("extractor '%s' listed more than once", name)
+		}
+		seen[name] = true
+	}
+	if docID := c.Instagram.GraphQLDocID; seen["graphql"] && (docID == "" || strings.Trim(docID, "0123456789") != "") {
+		return fmt.Errorf("GraphQL doc_id must be numeric, got '%s'", c.Instagram.GraphQLDocID)
+	}
+	if c.Instagram.AdaptiveExplore < 0 || c.Instagram.AdaptiveExplore > 0.5 {
+		return fmt.Errorf("adaptive exploration rate must be between 0 and 0.5, got %v", c.Instagram.AdaptiveExplore)
+	}
+	if err := validateExtraHeaders("INSTAGRAM_EXTRA_HEADERS", c.Instagram.ExtraHeaders); err != nil {
+		return err
+	}
+	if err := validateExtraHeaders("CDN_EXTRA_HEADERS", c.Instagram.CDNHeaders); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -162,23 +987,627 @@ func (c *Config) validateLoggingConfig() error {
 		return fmt.Errorf("invalid log format '%s', must be one of: text, json", c.Logging.Format)
 	}
 
+	// Validate access log rotation
+	if c.Logging.AccessLogMaxSize < 0 {
+		return fmt.Errorf("access log max size cannot be negative, got %d", c.Logging.AccessLogMaxSize)
+	}
+	if c.Logging.AccessLogMaxAge < 0 {
+		return fmt.Errorf("access log max age cannot be negative, got %v", c.Logging.AccessLogMaxAge)
+	}
+	if c.Logging.AccessLogMaxBackups < 0 {
+		return fmt.Errorf("access log max backups cannot be negative, got %d", c.Logging.AccessLogMaxBackups)
+	}
+
+	return nil
+}
+
+// validateCacheConfig validates metadata cache configuration
+func (c *Config) validateCacheConfig() error {
+	// Validate backend
+	switch c.Cache.Backend {
+	case "memory":
+		if c.Cache.MaxEntries < 1 {
+			return fmt.Errorf("max entries must be positive, got %d", c.Cache.MaxEntries)
+		}
+	case "redis":
+		if c.Cache.RedisURL == "" {
+			return fmt.Errorf("redis URL is required for the redis backend")
+		}
+	default:
+		return fmt.Errorf("invalid cache backend '%s', must be one of: memory, redis", c.Cache.Backend)
+	}
+
+	// Validate TTL
+	if c.Cache.TTL < 0 {
+		return fmt.Errorf("TTL cannot be negative, got %v", c.Cache.TTL)
+	}
+	if c.Cache.TTL > 24*time.Hour {
+		return fmt.Errorf("TTL too long (max 24h), got %v", c.Cache.TTL)
+	}
+	if c.Cache.NegativeTTL < 0 || c.Cache.NegativeTTL > 24*time.Hour {
+		return fmt.Errorf("negative TTL must be between 0 and 24h, got %v", c.Cache.NegativeTTL)
+	}
+
 	return nil
 }
 
-// getEnv gets an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
+// validateStreamConfig validates video streaming configuration
+func (c *Config) validateStreamConfig() error {
+	// Validate prefetch size
+	if c.Stream.PrefetchSize < 0 {
+		return fmt.Errorf("prefetch size cannot be negative, got %d", c.Stream.PrefetchSize)
+	}
+	if c.Stream.PrefetchSize > 64*1024*1024 {
+		return fmt.Errorf("prefetch size too large (max 64MB), got %d", c.Stream.PrefetchSize)
+	}
+
+	// Validate write idle timeout
+	if c.Stream.WriteIdleTimeout <= 0 {
+		return fmt.Errorf("write idle timeout must be positive, got %v", c.Stream.WriteIdleTimeout)
+	}
+	if c.Stream.WriteIdleTimeout > 10*time.Minute {
+		return fmt.Errorf("write idle timeout too long (max 10m), got %v", c.Stream.WriteIdleTimeout)
+	}
+
+	// Validate stream cap
+	if c.Stream.MaxStreams < 0 || c.Stream.MaxStreams > 100000 {
+		return fmt.Errorf("max concurrent streams must be between 0 and 100000, got %d", c.Stream.MaxStreams)
+	}
+	if c.Stream.BusyRetryAfter < time.Second || c.Stream.BusyRetryAfter > time.Hour {
+		return fmt.Errorf("busy retry after must be between 1s and 1h, got %v", c.Stream.BusyRetryAfter)
+	}
+
+	// Validate bandwidth limits
+	if c.Stream.MaxBandwidth != 0 && c.Stream.MaxBandwidth < 1024 {
+		return fmt.Errorf("max bandwidth must be 0 (unlimited) or at least 1024 bytes/s, got %d", c.Stream.MaxBandwidth)
+	}
+	if c.Stream.MaxConnBandwidth != 0 && c.Stream.MaxConnBandwidth < 1024 {
+		return fmt.Errorf("max connection bandwidth must be 0 (unlimited) or at least 1024 bytes/s, got %d", c.Stream.MaxConnBandwidth)
+	}
+
+	// Validate GIF defaults (same bounds as the query parameters)
+	if c.Stream.GIFSeconds < 1 || c.Stream.GIFSeconds > 15 {
+		return fmt.Errorf("GIF seconds must be between 1 and 15, got %d", c.Stream.GIFSeconds)
+	}
+	if c.Stream.GIFFPS < 1 || c.Stream.GIFFPS > 30 {
+		return fmt.Errorf("GIF fps must be between 1 and 30, got %d", c.Stream.GIFFPS)
+	}
+	if c.Stream.GIFWidth < 32 || c.Stream.GIFWidth > 720 {
+		return fmt.Errorf("GIF width must be between 32 and 720, got %d", c.Stream.GIFWidth)
+	}
+
+	// Validate HLS packaging
+	if c.Stream.HLSDir == "" {
+		return fmt.Errorf("HLS directory cannot be empty")
+	}
+	if c.Stream.HLSSegmentSeconds < 1 || c.Stream.HLSSegmentSeconds > 30 {
+		return fmt.Errorf("HLS segment seconds must be between 1 and 30, got %d", c.Stream.HLSSegmentSeconds)
+	}
+	if c.Stream.HLSRetention < time.Minute {
+		return fmt.Errorf("HLS retention must be at least 1m, got %v", c.Stream.HLSRetention)
+	}
+
+	return nil
+}
+
+// validateMetricsConfig validates metrics sink configuration
+func (c *Config) validateMetricsConfig() error {
+	if c.Metrics.StatsDAddr == "" {
+		return nil
+	}
+
+	// Validate StatsD address
+	if _, _, err := net.SplitHostPort(c.Metrics.StatsDAddr); err != nil {
+		return fmt.Errorf("invalid statsd address '%s', must be host:port", c.Metrics.StatsDAddr)
+	}
+
+	return nil
+}
+
+// validateVideoCacheConfig validates video cache configuration
+func (c *Config) validateVideoCacheConfig() error {
+	switch c.VideoCache.Backend {
+	case "disk":
+		if c.VideoCache.Dir == "" {
+			return nil
+		}
+		// Validate cache size
+		if c.VideoCache.MaxSize < 16*1024*1024 {
+			return fmt.Errorf("max size too small (min 16MB), got %d", c.VideoCache.MaxSize)
+		}
+	case "s3":
+		s3 := c.VideoCache.S3
+		if s3.Endpoint == "" || s3.Bucket == "" {
+			return fmt.Errorf("S3 endpoint and bucket are required for the s3 backend")
+		}
+		if s3.AccessKeyID == "" || s3.SecretAccessKey == "" {
+			return fmt.Errorf("S3 access key ID and secret access key are required for the s3 backend")
+		}
+	default:
+		return fmt.Errorf("invalid backend '%s', must be one of: disk, s3", c.VideoCache.Backend)
+	}
+
+	return nil
+}
+
+// validateErrorReportingConfig validates error tracking configuration
+func (c *Config) validateErrorReportingConfig() error {
+	if c.Errors.DSN == "" {
+		return nil
+	}
+
+	// Validate DSN: https://<key>@<host>/<project>
+	dsn, err := url.Parse(c.Errors.DSN)
+	if err != nil {
+		return fmt.Errorf("invalid DSN: %w", err)
+	}
+	if (dsn.Scheme != "https" && dsn.Scheme != "http") || dsn.Host == "" {
+		return fmt.Errorf("DSN must be an absolute http(s) URL")
+	}
+	if dsn.User == nil || dsn.User.Username() == "" {
+		return fmt.Errorf("DSN must include the public key, e.g. https://<key>@host/<project>")
+	}
+	if strings.Trim(dsn.Path, "/") == "" {
+		return fmt.Errorf("DSN must end with the project ID")
+	}
+
+	return nil
+}
+
+// validateJobsConfig validates background job configuration
+func (c *Config) validateJobsConfig() error {
+	// Validate worker count
+	if c.Jobs.Workers < 0 || c.Jobs.Workers > 32 {
+		return fmt.Errorf("workers must be between 0 and 32, got %d", c.Jobs.Workers)
+	}
+	if c.Jobs.Workers == 0 {
+		return nil
+	}
+
+	// Validate queue size and retention
+	if c.Jobs.QueueSize < 1 || c.Jobs.QueueSize > 10000 {
+		return fmt.Errorf("queue size must be between 1 and 10000, got %d", c.Jobs.QueueSize)
+	}
+	if c.Jobs.Retention < time.Minute {
+		return fmt.Errorf("retention too short (min 1m), got %v", c.Jobs.Retention)
+	}
+	if c.Jobs.Dir == "" {
+		return fmt.Errorf("jobs directory cannot be empty")
+	}
+
+	return nil
+}
+
+// validateFeedConfig validates RSS feed configuration
+func (c *Config) validateFeedConfig() error {
+	if c.Feed.CacheDir == "" {
+		return fmt.Errorf("feed cache directory cannot be empty")
+	}
+	if c.Feed.TTL < time.Minute {
+		return fmt.Errorf("feed TTL too short (min 1m), got %v", c.Feed.TTL)
+	}
+	if c.Feed.Items < 1 || c.Feed.Items > 50 {
+		return fmt.Errorf("feed items must be between 1 and 50, got %d", c.Feed.Items)
+	}
+	return nil
+}
+
+// validateBrandingConfig validates the branding shown on the HTML pages
+func (c *Config) validateBrandingConfig() error {
+	if c.Branding.Name == "" || len(c.Branding.Name) > 64 {
+		return fmt.Errorf("brand name must be 1 to 64 characters, got %q", c.Branding.Name)
+	}
+	if len(c.Branding.Footer) > 500 {
+		return fmt.Errorf("brand footer too long (max 500 characters), got %d", len(c.Branding.Footer))
+	}
+	if !strings.HasPrefix(c.Branding.LogoURL, "/") || strings.HasPrefix(c.Branding.LogoURL, "//") {
+		if u, err := url.Parse(c.Branding.LogoURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("brand logo URL must be a path or an http(s) URL, got %q", c.Branding.LogoURL)
+		}
+	}
+	if c.Branding.ContactURL != "" {
+		u, err := url.Parse(c.Branding.ContactURL)
+		valid := err == nil && (((u.Scheme == "http" || u.Scheme == "https") && u.Host != "") || (u.Scheme == "mailto" && u.Opaque != ""))
+		if !valid {
+			return fmt.Errorf("brand contact URL must be an http(s) or mailto: URL, got %q", c.Branding.ContactURL)
+		}
+	}
+	return nil
+}
+
+// validateTelegramConfig validates bot settings; the token itself is only required in bot mode
+func (c *Config) validateTelegramConfig() error {
+	if u, err := url.Parse(c.Telegram.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid Telegram API URL: %s", c.Telegram.APIURL)
+	}
+	if c.Telegram.PollTimeout < time.Second || c.Telegram.PollTimeout > 5*time.Minute {
+		return fmt.Errorf("poll timeout must be between 1s and 5m, got %v", c.Telegram.PollTimeout)
+	}
+	if c.Telegram.MaxUploadSize < 1024*1024 || c.Telegram.MaxUploadSize > 2*1024*1024*1024 {
+		return fmt.Errorf("max upload size must be between 1MB and 2GB, got %d", c.Telegram.MaxUploadSize)
+	}
+	if c.Telegram.PublicURL != "" {
+		if u, err := url.Parse(c.Telegram.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid public URL: %s", c.Telegram.PublicURL)
+		}
+	}
+	for _, chat := range c.Telegram.AllowedChats {
+		if _, err := strconv.ParseInt(chat, 10, 64); err != nil {
+			return fmt.Errorf("invalid chat ID in allowed chats: %s", chat)
+		}
+	}
+	return nil
+}
+
+// WebhookEvents lists the event types webhooks can subscribe to
+var WebhookEvents = []string{"extraction.succeeded", "extraction.failed", "rate_limited", "cache.evicted"}
+
+// validateWebhookConfig validates webhook notification configuration
+func (c *Config) validateWebhookConfig() error {
+	if len(c.Webhooks.URLs) == 0 {
+		return nil
+	}
+
+	for _, endpoint := range c.Webhooks.URLs {
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook URL must be an absolute http(s) URL, got '%s'", endpoint)
+		}
+	}
+	for _, event := range c.Webhooks.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			return fmt.Errorf("unknown webhook event '%s' (expected one of %s)", event, strings.Join(WebhookEvents, ", "))
+		}
+	}
+	if c.Webhooks.Timeout < time.Second || c.Webhooks.Timeout > time.Minute {
+		return fmt.Errorf("timeout must be between 1s and 1m, got %v", c.Webhooks.Timeout)
+	}
+	if c.Webhooks.MaxRetries < 0 || c.Webhooks.MaxRetries > 10 {
+		return fmt.Errorf("max retries must be between 0 and 10, got %d", c.Webhooks.MaxRetries)
+	}
+	return nil
+}
+
+// validateTracingConfig validates trace export configuration
+func (c *Config) validateTracingConfig() error {
+	if c.Tracing.Endpoint == "" {
+		return nil
+	}
+
+	// Validate collector endpoint
+	endpoint, err := url.Parse(c.Tracing.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+	if (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" {
+		return fmt.Errorf("OTLP endpoint must be an absolute http(s) URL, got '%s'", c.Tracing.Endpoint)
+	}
+
+	// Validate sampling ratio
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("sample ratio must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+	if c.Tracing.ServiceName == "" {
+		return fmt.Errorf("service name cannot be empty")
+	}
+
+	return nil
+}
+
+// validateAuthConfig validates request authentication configuration
+func (c *Config) validateAuthConfig() error {
+	if c.Auth.JWKSURL == "" {
+		if c.Auth.Issuer != "" || c.Auth.Audience != "" {
+			return fmt.Errorf("JWKS URL is required when an issuer or audience is set")
+		}
+		return nil
+	}
+
+	// Validate JWKS URL
+	jwksURL, err := url.Parse(c.Auth.JWKSURL)
+	if err != nil {
+		return fmt.Errorf("invalid JWKS URL: %w", err)
+	}
+	if (jwksURL.Scheme != "https" && jwksURL.Scheme != "http") || jwksURL.Host == "" {
+		return fmt.Errorf("JWKS URL must be an absolute http(s) URL, got '%s'", c.Auth.JWKSURL)
+	}
+
+	// Validate key refresh and clock skew
+	if c.Auth.JWKSRefresh < time.Minute {
+		return fmt.Errorf("JWKS refresh too short (min 1m), got %v", c.Auth.JWKSRefresh)
+	}
+	if c.Auth.ClockSkew < 0 || c.Auth.ClockSkew > 10*time.Minute {
+		return fmt.Errorf("clock skew must be between 0 and 10m, got %v", c.Auth.ClockSkew)
+	}
+
+	return nil
+}
+
+// validateACMEConfig validates automatic HTTPS configuration
+func (c *Config) validateACMEConfig() error {
+	if len(c.ACME.Domains) == 0 {
+		return nil
+	}
+
+	// HTTP-01 challenges can only prove control of plain host names
+	for _, domain := range c.ACME.Domains {
+		if strings.Contains(domain, "*") {
+			return fmt.Errorf("wildcard domain '%s' needs a DNS-01 challenge, which is not supported", domain)
+		}
+		if net.ParseIP(domain) != nil || !strings.Contains(domain, ".") || strings.ContainsAny(domain, ":/ ") {
+			return fmt.Errorf("invalid domain '%s', must be a fully qualified host name", domain)
+		}
+	}
+
+	if c.ACME.Email != "" && !strings.Contains(c.ACME.Email, "@") {
+		return fmt.Errorf("invalid email '%s'", c.ACME.Email)
+	}
+	if strings.TrimSpace(c.ACME.CacheDir) == "" {
+		return fmt.Errorf("cache directory is required")
+	}
+
+	directoryURL, err := url.Parse(c.ACME.DirectoryURL)
+	if err != nil {
+		return fmt.Errorf("invalid directory URL: %w", err)
+	}
+	if directoryURL.Scheme != "https" || directoryURL.Host == "" {
+		return fmt.Errorf("directory URL must be an absolute https URL, got '%s'", c.ACME.DirectoryURL)
+	}
+
+	// A proxy in front of a Unix socket terminates TLS itself
+	network, _ := c.Server.ListenAddr()
+	if network == "unix" {
+		return fmt.Errorf("automatic HTTPS cannot be used with a Unix socket listener")
+	}
+
+	// The CA always connects to port 80, so the challenge listener cannot share the HTTPS port
+	_, port, err := net.SplitHostPort(c.ACME.HTTPAddr)
+	if err != nil {
+		return fmt.Errorf("invalid HTTP address '%s': %w", c.ACME.HTTPAddr, err)
+	}
+	if port == c.Server.ListenPort() {
+		return fmt.Errorf("HTTP address '%s' must not use the HTTPS port %s", c.ACME.HTTPAddr, c.Server.ListenPort())
+	}
+
+	if c.ACME.RenewBefore < 24*time.Hour || c.ACME.RenewBefore > 60*24*time.Hour {
+		return fmt.Errorf("renew before must be between 24h and 1440h, got %v", c.ACME.RenewBefore)
+	}
+
+	return nil
+}
+
+// validateAdminConfig validates admin API configuration
+func (c *Config) validateAdminConfig() error {
+	if c.Admin.Token == "" {
+		if c.Admin.Addr != "" {
+			return fmt.Errorf("admin address is set but ADMIN_TOKEN is empty")
+		}
+		return nil
+	}
+
+	// The token is the only thing standing between the network and cache purges
+	if len(c.Admin.Token) < 16 {
+		return fmt.Errorf("admin token must be at least 16 characters, got %d", len(c.Admin.Token))
+	}
+	if c.Admin.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Admin.Addr); err != nil {
+			return fmt.Errorf("invalid admin address '%s', must be host:port", c.Admin.Addr)
+		}
+	}
+
+	return nil
+}
+
+// ValidateProxyURL checks that raw is an absolute http(s):// or socks5(h):// proxy URL
+func ValidateProxyURL(raw string) error {
+	proxy, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("invalid proxy scheme '%s', must be one of: http, https, socks5, socks5h", proxy.Scheme)
+	}
+	if proxy.Host == "" {
+		return fmt.Errorf("proxy URL must include a host")
+	}
+	return nil
+}
+
+// ParseProxyEntry parses a proxy pool entry of the form "<url> [weight]".
+// The weight defaults to 1.
+func ParseProxyEntry(entry string) (string, int, error) {
+	fields := strings.Fields(entry)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", 0, fmt.Errorf("invalid proxy entry '%s', expected '<url> [weight]'", entry)
+	}
+	if err := ValidateProxyURL(fields[0]); err != nil {
+		return "", 0, err
+	}
+
+	weight := 1
+	if len(fields) == 2 {
+		w, err := strconv.Atoi(fields[1])
+		if err != nil || w < 1 || w > 100 {
+			return "", 0, fmt.Errorf("invalid proxy weight '%s' (must be 1-100)", fields[1])
+		}
+		weight = w
+	}
+	return fields[0], weight, nil
+}
+
+// source resolves settings from command-line flags, the environment and, when one was
+// loaded, a config file. Keys are environment variable names; the file's keys are
+// translated by fileKeys.
+type source struct {
+	flags Flags
+	file  map[string]string
+}
+
+// value returns the first non-empty value for key, from the flags, the environment then
+// the file. Empty values count as unset, as they always have for scalar settings.
+func (s source) value(key string) string {
+	if value := s.flags[key]; value != "" {
+		return value
+	}
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
+	return s.file[key]
+}
+
+// lookup returns the value for key from the flags, the environment, else the file,
+// reporting whether any set it. An empty value still overrides, clearing a list.
+func (s source) lookup(key string) (string, bool) {
+	if value, ok := s.flags[key]; ok {
+		return value, true
+	}
+	if value, ok := os.LookupEnv(key); ok {
+		return value, true
+	}
+	value, ok := s.file[key]
+	return value, ok
+}
+
+// getEnv gets a setting or returns a default value
+func (s source) getEnv(key, defaultValue string) string {
+	if value := s.value(key); value != "" {
+		return value
+	}
 	return defaultValue
 }
 
-// getEnvAsBool gets an environment variable as boolean or returns a default value
-func getEnvAsBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
+// getEnvAsBool gets a setting as boolean or returns a default value
+func (s source) getEnvAsBool(key string, defaultValue bool) bool {
+	if value := s.value(key); value != "" {
 		if b, err := strconv.ParseBool(value); err == nil {
 			return b
 		}
 	}
 	return defaultValue
 }
+
+// getEnvAsDuration gets a setting as duration or returns a default value
+func (s source) getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := s.value(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsSlice gets a comma-separated setting as a trimmed slice
+func (s source) getEnvAsSlice(key, defaultValue string) []string {
+	value, ok := s.lookup(key)
+	if !ok {
+		value = defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvAsInt gets a setting as int or returns a default value
+func (s source) getEnvAsInt(key string, defaultValue int) int {
+	if value := s.value(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsFloat gets a setting as float64 or returns a default value
+func (s source) getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := s.value(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsMap gets a comma-separated list of key=value pairs as a map
+func (s source) getEnvAsMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range s.getEnvAsSlice(key, "") {
+		if k, v, ok := strings.Cut(pair, "="); ok && strings.TrimSpace(k) != "" {
+			result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return result
+}
+
+// getEnvAsHeaders gets a comma-separated list of Name=Value headers as a map. Header values
+// often contain commas themselves (Accept-Language: en-US,en;q=0.9), so a comma only starts
+// a new header when a header name and "=" follow it.
+func (s source) getEnvAsHeaders(key string) map[string]string {
+	result := make(map[string]string)
+	name := ""
+	for _, part := range strings.Split(s.value(key), ",") {
+		if k, v, ok := strings.Cut(part, "="); ok && isHeaderName(strings.TrimSpace(k)) {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(k))
+			result[name] = strings.TrimSpace(v)
+			continue
+		}
+		if name != "" {
+			result[name] += "," + part
+		}
+	}
+	return result
+}
+
+// isHeaderName reports whether s is a valid HTTP header name (an RFC 9110 token)
+func isHeaderName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateExtraHeaders rejects headers that would break requests or that have their own setting
+func validateExtraHeaders(setting string, headers map[string]string) error {
+	for name, value := range headers {
+		switch name {
+		case "Host", "Content-Length", "Transfer-Encoding", "Connection", "Upgrade", "Te", "Trailer", "Range":
+			return fmt.Errorf("%s cannot set the %s header", setting, name)
+		case "User-Agent":
+			return fmt.Errorf("%s cannot set User-Agent, use INSTAGRAM_USER_AGENT", setting)
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return fmt.Errorf("%s: header %s contains a line break", setting, name)
+		}
+	}
+	return nil
+}
+
+// getEnvAsFileMode gets an octal setting (0660) as file permissions or returns a default value
+func (s source) getEnvAsFileMode(key string, defaultValue os.FileMode) os.FileMode {
+	if value := s.value(key); value != "" {
+		if mode, err := strconv.ParseUint(value, 8, 32); err == nil {
+			return os.FileMode(mode)
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsInt64 gets a setting as int64 or returns a default value
+func (s source) getEnvAsInt64(key string, defaultValue int64) int64 {
+	if value := s.value(key); value != "" {
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	}
+	return defaultValue
+}
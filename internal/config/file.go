@@ -0,0 +1,419 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// fileKeys maps each "section.key" of a config file to the environment variable it
+// stands in for, so both sources share the same parsing, defaults and validation
+var fileKeys = map[string]string{
+	"env": "QWIKLIP_ENV",
+
+	"server.port":                 "PORT",
+	"server.listen":               "LISTEN",
+	"server.socket_mode":          "SERVER_SOCKET_MODE",
+	"server.read_timeout":         "SERVER_READ_TIMEOUT",
+	"server.write_timeout":        "SERVER_WRITE_TIMEOUT",
+	"server.idle_timeout":         "SERVER_IDLE_TIMEOUT",
+	"server.drain_timeout":        "SERVER_DRAIN_TIMEOUT",
+	"server.cors_allowed_origins": "CORS_ALLOWED_ORIGINS",
+	"server.security_headers":     "SECURITY_HEADERS",
+	"server.link_preview_agents":  "LINK_PREVIEW_AGENTS",
+	"server.alt_svc":              "ALT_SVC",
+	"server.debug_endpoints":      "DEBUG_ENDPOINTS",
+	"server.debug_addr":           "DEBUG_ADDR",
+
+	"instagram.timeout":                 "INSTAGRAM_TIMEOUT",
+	"instagram.user_agent":              "INSTAGRAM_USER_AGENT",
+	"instagram.debug":                   "DEBUG",
+	"instagram.session_id":              "INSTAGRAM_SESSION_ID",
+	"instagram.cookies_file":            "INSTAGRAM_COOKIES_FILE",
+	"instagram.session_ids":             "INSTAGRAM_SESSION_IDS",
+	"instagram.cookies_files":           "INSTAGRAM_COOKIES_FILES",
+	"instagram.account_cooldown":        "INSTAGRAM_ACCOUNT_COOLDOWN",
+	"instagram.proxy_url":               "OUTBOUND_PROXY_URL",
+	"instagram.proxy_urls":              "OUTBOUND_PROXY_URLS",
+	"instagram.proxies_file":            "OUTBOUND_PROXIES_FILE",
+	"instagram.proxy_strategy":          "OUTBOUND_PROXY_STRATEGY",
+	"instagram.proxy_cooldown":          "OUTBOUND_PROXY_COOLDOWN",
+	"instagram.max_concurrent":          "INSTAGRAM_MAX_CONCURRENT",
+	"instagram.queue_size":              "INSTAGRAM_QUEUE_SIZE",
+	"instagram.queue_timeout":           "INSTAGRAM_QUEUE_TIMEOUT",
+	"instagram.rate_limit_cooldown":     "INSTAGRAM_RATE_LIMIT_COOLDOWN",
+	"instagram.requests_per_minute":     "INSTAGRAM_REQUESTS_PER_MINUTE",
+	"instagram.request_burst":           "INSTAGRAM_REQUEST_BURST",
+	"instagram.max_retries":             "INSTAGRAM_MAX_RETRIES",
+	"instagram.retry_backoff":           "INSTAGRAM_RETRY_BACKOFF",
+	"instagram.retry_deadline":          "INSTAGRAM_RETRY_DEADLINE",
+	"instagram.max_idle_conns":          "INSTAGRAM_MAX_IDLE_CONNS",
+	"instagram.max_idle_conns_per_host": "INSTAGRAM_MAX_IDLE_CONNS_PER_HOST",
+	"instagram.idle_conn_timeout":       "INSTAGRAM_IDLE_CONN_TIMEOUT",
+	"instagram.tls_handshake_timeout":   "INSTAGRAM_TLS_HANDSHAKE_TIMEOUT",
+	"instagram.http2":                   "INSTAGRAM_HTTP2",
+	"instagram.extractors":              "INSTAGRAM_EXTRACTORS",
+	"instagram.mock_mode":               "MOCK_MODE",
+	"instagram.mock_fixtures_dir":       "MOCK_FIXTURES_DIR",
+	"instagram.record_dir":              "INSTAGRAM_RECORD_DIR",
+	"instagram.headless_fallback":       "HEADLESS_FALLBACK",
+	"instagram.headless_chrome_path":    "HEADLESS_CHROME_PATH",
+	"instagram.headless_timeout":        "HEADLESS_TIMEOUT",
+	"instagram.headless_max_procs":      "HEADLESS_MAX_PROCS",
+	"instagram.graphql_doc_id":          "INSTAGRAM_GRAPHQL_DOC_ID",
+	"instagram.adaptive_extractors":     "INSTAGRAM_ADAPTIVE_EXTRACTORS",
+	"instagram.adaptive_explore":        "INSTAGRAM_ADAPTIVE_EXPLORE",
+	"instagram.headers":                 "INSTAGRAM_EXTRA_HEADERS",
+	"instagram.cdn_headers":             "CDN_EXTRA_HEADERS",
+
+	"cache.backend":      "METADATA_CACHE_BACKEND",
+	"cache.ttl":          "METADATA_CACHE_TTL",
+	"cache.negative_ttl": "METADATA_CACHE_NEGATIVE_TTL",
+	"cache.max_entries":  "METADATA_CACHE_MAX_ENTRIES",
+	"cache.redis_url":    "REDIS_URL",
+
+	"logging.level":                  "LOG_LEVEL",
+	"logging.format":                 "LOG_FORMAT",
+	"logging.access_log_file":        "ACCESS_LOG_FILE",
+	"logging.access_log_max_size":    "ACCESS_LOG_MAX_SIZE",
+	"logging.access_log_max_age":     "ACCESS_LOG_MAX_AGE",
+	"logging.access_log_max_backups": "ACCESS_LOG_MAX_BACKUPS",
+
+	"stream.prefetch_size":       "STREAM_PREFETCH_SIZE",
+	"stream.write_idle_timeout":  "STREAM_WRITE_IDLE_TIMEOUT",
+	"stream.ffmpeg_path":         "FFMPEG_PATH",
+	"stream.metadata_tags":       "STREAM_METADATA_TAGS",
+	"stream.max_streams":         "STREAM_MAX_CONCURRENT",
+	"stream.busy_retry_after":    "STREAM_BUSY_RETRY_AFTER",
+	"stream.max_bandwidth":       "STREAM_MAX_BANDWIDTH",
+	"stream.max_conn_bandwidth":  "STREAM_MAX_CONN_BANDWIDTH",
+	"stream.gif_seconds":         "GIF_SECONDS",
+	"stream.gif_fps":             "GIF_FPS",
+	"stream.gif_width":           "GIF_WIDTH",
+	"stream.hls_dir":             "HLS_DIR",
+	"stream.hls_segment_seconds": "HLS_SEGMENT_SECONDS",
+	"stream.hls_retention":       "HLS_RETENTION",
+
+	"metrics.statsd_addr": "STATSD_ADDR",
+	"metrics.prefix":      "METRICS_PREFIX",
+	"metrics.dogstatsd":   "STATSD_DOGSTATSD",
+
+	"errors.dsn":         "SENTRY_DSN",
+	"errors.environment": "SENTRY_ENVIRONMENT",
+
+	"jobs.workers":    "JOBS_WORKERS",
+	"jobs.queue_size": "JOBS_QUEUE_SIZE",
+	"jobs.retention":  "JOBS_RETENTION",
+	"jobs.dir":        "JOBS_DIR",
+	"jobs.state_file": "JOBS_STATE_FILE",
+
+	"telegram.bot_token":       "TELEGRAM_BOT_TOKEN",
+	"telegram.api_url":         "TELEGRAM_API_URL",
+	"telegram.poll_timeout":    "TELEGRAM_POLL_TIMEOUT",
+	"telegram.max_upload_size": "TELEGRAM_MAX_UPLOAD_SIZE",
+	"telegram.public_url":      "TELEGRAM_PUBLIC_URL",
+	"telegram.allowed_chats":   "TELEGRAM_ALLOWED_CHATS",
+
+	"webhooks.urls":        "WEBHOOK_URLS",
+	"webhooks.secret":      "WEBHOOK_SECRET",
+	"webhooks.events":      "WEBHOOK_EVENTS",
+	"webhooks.timeout":     "WEBHOOK_TIMEOUT",
+	"webhooks.max_retries": "WEBHOOK_MAX_RETRIES",
+
+	"feed.cache_dir": "FEED_CACHE_DIR",
+	"feed.ttl":       "FEED_TTL",
+	"feed.items":     "FEED_ITEMS",
+
+	"branding.name":        "BRAND_NAME",
+	"branding.logo_url":    "BRAND_LOGO_URL",
+	"branding.footer":      "BRAND_FOOTER",
+	"branding.contact_url": "BRAND_CONTACT_URL",
+
+	"tracing.endpoint":     "OTEL_EXPORTER_OTLP_ENDPOINT",
+	"tracing.service_name": "OTEL_SERVICE_NAME",
+	"tracing.sample_ratio": "OTEL_TRACES_SAMPLER_ARG",
+	"tracing.headers":      "OTEL_EXPORTER_OTLP_HEADERS",
+
+	"video_cache.backend":              "VIDEO_CACHE_BACKEND",
+	"video_cache.dir":                  "VIDEO_CACHE_DIR",
+	"video_cache.max_size":             "VIDEO_CACHE_MAX_SIZE",
+	"video_cache.s3.endpoint":          "S3_ENDPOINT",
+	"video_cache.s3.region":            "S3_REGION",
+	"video_cache.s3.bucket":            "S3_BUCKET",
+	"video_cache.s3.prefix":            "S3_PREFIX",
+	"video_cache.s3.access_key_id":     "S3_ACCESS_KEY_ID",
+	"video_cache.s3.secret_access_key": "S3_SECRET_ACCESS_KEY",
+	"video_cache.s3.path_style":        "S3_PATH_STYLE",
+
+	"auth.jwks_url":     "AUTH_JWKS_URL",
+	"auth.issuer":       "AUTH_JWT_ISSUER",
+	"auth.audience":     "AUTH_JWT_AUDIENCE",
+	"auth.jwks_refresh": "AUTH_JWKS_REFRESH",
+	"auth.clock_skew":   "AUTH_JWT_CLOCK_SKEW",
+
+	"acme.domains":       "ACME_DOMAINS",
+	"acme.email":         "ACME_EMAIL",
+	"acme.cache_dir":     "ACME_CACHE_DIR",
+	"acme.directory_url": "ACME_DIRECTORY_URL",
+	"acme.http_addr":     "ACME_HTTP_ADDR",
+	"acme.renew_before":  "ACME_RENEW_BEFORE",
+
+	"admin.token": "ADMIN_TOKEN",
+	"admin.addr":  "ADMIN_ADDR",
+}
+
+// mapFileKeys are settings holding key/value pairs, written as a table in the file
+var mapFileKeys = map[string]bool{
+	"tracing.headers":       true,
+	"instagram.headers":     true,
+	"instagram.cdn_headers": true,
+}
+
+// readConfigFile parses a TOML config file into values keyed by environment variable.
+// Lists are joined with commas and tables of pairs become "k=v,k=v", matching the
+// environment syntax. Unknown keys are rejected so typos do not go unnoticed.
+func readConfigFile(path string) (map[string]string, error) {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".toml" {
+		return nil, fmt.Errorf("unsupported config file format %q (expected .toml)", ext)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	pairs := make(map[string][]string) // Tables of mapFileKeys, collected across lines
+	section := ""
+	pending, pendingLine := "", 0 // Multi-line array being accumulated
+
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line, at := strings.TrimSpace(stripComment(scanner.Text())), lineNum
+		if pending != "" {
+			pending += " " + line
+			if !balanced(pending) {
+				continue
+			}
+			line, at, pending = pending, pendingLine, ""
+		}
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("%s:%d: invalid table header %q", path, at, line)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if !knownSection(section) {
+				return nil, fmt.Errorf("%s:%d: unknown section [%s]", path, at, section)
+			}
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", path, at)
+		}
+		key, raw = strings.TrimSpace(key), strings.TrimSpace(raw)
+		if !balanced(raw) {
+			pending, pendingLine = line, at
+			continue
+		}
+
+		// Keys inside a table of pairs, e.g. [tracing.headers]
+		if mapFileKeys[section] {
+			value, err := parseScalar(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %s: %w", path, at, key, err)
+			}
+			pairs[section] = append(pairs[section], key+"="+value)
+			continue
+		}
+
+		name := key
+		if section != "" {
+			name = section + "." + key
+		}
+		envKey, known := fileKeys[name]
+		if !known {
+			return nil, fmt.Errorf("%s:%d: unknown setting %q", path, at, name)
+		}
+		value, err := parseValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %w", path, at, name, err)
+		}
+		values[envKey] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if pending != "" {
+		return nil, fmt.Errorf("%s:%d: unterminated array", path, pendingLine)
+	}
+
+	for section, entries := range pairs {
+		values[fileKeys[section]] = strings.Join(entries, ",")
+	}
+	return values, nil
+}
+
+// knownSection reports whether any setting lives in the table name
+func knownSection(name string) bool {
+	if mapFileKeys[name] {
+		return true
+	}
+	for key := range fileKeys {
+		if strings.HasPrefix(key, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseValue converts a TOML value to its environment form
+func parseValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, "["):
+		items, err := splitContainer(raw, '[', ']')
+		if err != nil {
+			return "", err
+		}
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			value, err := parseScalar(item)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, value)
+		}
+		return strings.Join(values, ","), nil
+	case strings.HasPrefix(raw, "{"):
+		items, err := splitContainer(raw, '{', '}')
+		if err != nil {
+			return "", err
+		}
+		pairs := make([]string, 0, len(items))
+		for _, item := range items {
+			key, raw, ok := strings.Cut(item, "=")
+			if !ok {
+				return "", fmt.Errorf("expected key = value in inline table")
+			}
+			value, err := parseScalar(strings.TrimSpace(raw))
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, strings.Trim(strings.TrimSpace(key), `"`)+"="+value)
+		}
+		return strings.Join(pairs, ","), nil
+	default:
+		return parseScalar(raw)
+	}
+}
+
+// parseScalar converts a string, number or boolean
+func parseScalar(raw string) (string, error) {
+	switch {
+	case raw == "":
+		return "", fmt.Errorf("missing value")
+	case strings.HasPrefix(raw, `"`):
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return value, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	case raw == "true" || raw == "false":
+		return raw, nil
+	default:
+		number := strings.ReplaceAll(raw, "_", "")
+		if _, err := strconv.ParseFloat(number, 64); err != nil {
+			return "", fmt.Errorf("invalid value %s (quote strings and durations)", raw)
+		}
+		return number, nil
+	}
+}
+
+// splitContainer splits the elements of an array or inline table on top-level commas
+func splitContainer(raw string, open, close byte) ([]string, error) {
+	if len(raw) < 2 || raw[0] != open || raw[len(raw)-1] != close {
+		return nil, fmt.Errorf("invalid value %s", raw)
+	}
+
+	var items []string
+	var quote byte
+	start := 1
+	for i := 1; i < len(raw)-1; i++ {
+		switch c := raw[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, strings.TrimSpace(raw[start:i]))
+			start = i + 1
+		case c == '[' || c == '{':
+			return nil, fmt.Errorf("nested values are not supported")
+		}
+	}
+	if last := strings.TrimSpace(raw[start : len(raw)-1]); last != "" {
+		items = append(items, last)
+	}
+	return items, nil
+}
+
+// stripComment removes a # comment that is not inside a string
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// balanced reports whether every bracket opened outside strings is closed
+func balanced(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
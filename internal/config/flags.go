@@ -0,0 +1,46 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Flags holds the settings given on the command line, keyed by environment variable name
+type Flags map[string]string
+
+// boolSettings are the settings whose flags may be given without a value (--debug)
+var boolSettings = map[string]bool{
+	"SECURITY_HEADERS": true,
+	"DEBUG_ENDPOINTS":  true,
+	"DEBUG":            true,
+	"INSTAGRAM_HTTP2":  true,
+	"STATSD_DOGSTATSD": true,
+	"S3_PATH_STYLE":    true,
+}
+
+// RegisterFlags defines a flag on fs for every setting, named after its environment
+// variable (SERVER_READ_TIMEOUT becomes --server-read-timeout), and returns the map the
+// values given are recorded in once fs is parsed. Flags accept the same values as the
+// variables and override both the environment and the config file.
+func RegisterFlags(fs *flag.FlagSet) Flags {
+	flags := make(Flags)
+	for fileKey, env := range fileKeys {
+		set := func(value string) error {
+			flags[env] = value
+			return nil
+		}
+		if boolSettings[env] {
+			fs.BoolFunc(flagName(env), fmt.Sprintf("Overrides %s (%s in the config file)", env, fileKey), set)
+		} else {
+			// The back-quoted name is shown as the flag's argument in -help
+			fs.Func(flagName(env), fmt.Sprintf("Overrides `%s` (%s in the config file)", env, fileKey), set)
+		}
+	}
+	return flags
+}
+
+// flagName returns the command-line flag that stands in for an environment variable
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
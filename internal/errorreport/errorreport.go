@@ -0,0 +1,35 @@
+// Package errorreport sends panics and unexpected failures to an error tracking
+// service. Without a DSN the no-op reporter is used.
+package errorreport
+
+import (
+	"context"
+	"log/slog"
+
+	"qwiklip/internal/config"
+)
+
+// Reporter sends unexpected failures to an error tracking service.
+// Tags are passed as alternating key/value pairs, mirroring slog attributes.
+type Reporter interface {
+	// CapturePanic reports a recovered panic; call it from the deferred function so the stack is intact
+	CapturePanic(ctx context.Context, value any, tags ...string)
+	CaptureError(ctx context.Context, err error, tags ...string)
+	// Close sends queued events, waiting at most until ctx is done
+	Close(ctx context.Context) error
+}
+
+// New creates a reporter for the configured DSN, falling back to a no-op reporter
+func New(cfg *config.ErrorReportingConfig, release string, logger *slog.Logger) (Reporter, error) {
+	if cfg.DSN == "" {
+		return Nop{}, nil
+	}
+	return NewSentry(cfg.DSN, cfg.Environment, release, logger)
+}
+
+// Nop is a reporter that discards all events
+type Nop struct{}
+
+func (Nop) CapturePanic(context.Context, any, ...string)   {}
+func (Nop) CaptureError(context.Context, error, ...string) {}
+func (Nop) Close(context.Context) error                    { return nil }
@@ -0,0 +1,319 @@
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"qwiklip/internal/models"
+)
+
+const (
+	sentryQueueSize   = 64
+	sentrySendTimeout = 10 * time.Second
+	sentryMaxFrames   = 64
+)
+
+// Sentry sends events to a Sentry-compatible envelope endpoint (Sentry, GlitchTip, Bugsink).
+// Events are queued and sent in the background; when the queue is full new events are dropped.
+type Sentry struct {
+	dsn         string
+	endpoint    string
+	authHeader  string
+	environment string
+	release     string
+	serverName  string
+	httpClient  *http.Client
+	logger      *slog.Logger
+
+	queue chan sentryEvent
+	done  chan struct{}
+	once  sync.Once
+	wg    sync.WaitGroup
+}
+
+// NewSentry creates a reporter for a DSN of the form https://<key>@<host>/<project>
+func NewSentry(dsn, environment, release string, logger *slog.Logger) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid error reporting DSN: %w", err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("error reporting DSN has no public key")
+	}
+	projectPath := strings.Trim(u.Path, "/")
+	i := strings.LastIndex(projectPath, "/")
+	pathPrefix, projectID := "", projectPath
+	if i >= 0 {
+		pathPrefix, projectID = "/"+projectPath[:i], projectPath[i+1:]
+	}
+	if projectID == "" {
+		return nil, fmt.Errorf("error reporting DSN has no project ID")
+	}
+
+	hostname, _ := os.Hostname()
+	s := &Sentry{
+		dsn:         dsn,
+		endpoint:    fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, pathPrefix, projectID),
+		authHeader:  fmt.Sprintf("Sentry sentry_version=7, sentry_client=qwiklip/%s, sentry_key=%s", release, u.User.Username()),
+		environment: environment,
+		release:     release,
+		serverName:  hostname,
+		httpClient:  &http.Client{Timeout: sentrySendTimeout},
+		logger:      logger,
+		queue:       make(chan sentryEvent, sentryQueueSize),
+		done:        make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+// sentryEvent is the subset of the Sentry event payload qwiklip fills in
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Exception   struct {
+		Values []sentryException `json:"values"`
+	} `json:"exception"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Mechanism  *sentryMechanism  `json:"mechanism,omitempty"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryMechanism struct {
+	Type    string `json:"type"`
+	Handled bool   `json:"handled"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// CapturePanic reports a recovered panic with the stack of the panicking goroutine
+func (s *Sentry) CapturePanic(ctx context.Context, value any, tags ...string) {
+	event := s.newEvent("fatal", tags)
+	event.Exception.Values = []sentryException{{
+		Type:       "panic",
+		Value:      fmt.Sprint(value),
+		Mechanism:  &sentryMechanism{Type: "recover", Handled: false},
+		Stacktrace: captureStack(3, true),
+	}}
+	s.enqueue(event)
+}
+
+// CaptureError reports err with the stack of the caller
+func (s *Sentry) CaptureError(ctx context.Context, err error, tags ...string) {
+	if err == nil {
+		return
+	}
+	event := s.newEvent("error", tags)
+	event.Exception.Values = []sentryException{{
+		Type:       errorType(err),
+		Value:      err.Error(),
+		Stacktrace: captureStack(3, false),
+	}}
+	s.enqueue(event)
+}
+
+// Close flushes queued events, waiting at most until ctx is done
+func (s *Sentry) Close(ctx context.Context) error {
+	s.once.Do(func() { close(s.done) })
+
+	finished := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Sentry) newEvent(level string, tags []string) sentryEvent {
+	var id [16]byte
+	rand.Read(id[:])
+
+	event := sentryEvent{
+		EventID:     hex.EncodeToString(id[:]),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339Nano),
+		Level:       level,
+		Platform:    "go",
+		Logger:      "qwiklip",
+		ServerName:  s.serverName,
+		Environment: s.environment,
+		Release:     s.release,
+	}
+	if len(tags) > 1 {
+		event.Tags = make(map[string]string, len(tags)/2)
+		for i := 0; i+1 < len(tags); i += 2 {
+			if tags[i+1] != "" {
+				event.Tags[tags[i]] = tags[i+1]
+			}
+		}
+	}
+	return event
+}
+
+func (s *Sentry) enqueue(event sentryEvent) {
+	select {
+	case s.queue <- event:
+	default:
+		s.logger.Warn("Error report queue full, dropping event", "event_id", event.EventID)
+	}
+}
+
+func (s *Sentry) run() {
+	defer s.wg.Done()
+	for {
+		select {
+		case event := <-s.queue:
+			s.send(event)
+		case <-s.done:
+			for {
+				select {
+				case event := <-s.queue:
+					s.send(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send posts one event as an envelope: envelope header, item header, payload
+func (s *Sentry) send(event sentryEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		s.logger.Warn("Failed to encode error report", "error", err)
+		return
+	}
+
+	var body bytes.Buffer
+	header, _ := json.Marshal(map[string]string{"event_id": event.EventID, "dsn": s.dsn, "sent_at": time.Now().UTC().Format(time.RFC3339)})
+	body.Write(header)
+	body.WriteString("\n")
+	fmt.Fprintf(&body, `{"type":"event","length":%d}`, len(payload))
+	body.WriteString("\n")
+	body.Write(payload)
+	body.WriteString("\n")
+
+	req, err := http.NewRequest("POST", s.endpoint, &body)
+	if err != nil {
+		s.logger.Warn("Failed to create error report request", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", s.authHeader)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.logger.Warn("Failed to send error report", "error", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		s.logger.Warn("Error reporting endpoint rejected event", "status", resp.StatusCode, "event_id", event.EventID)
+	}
+}
+
+// captureStack returns the caller's stack, oldest frame first as Sentry expects.
+// For panics, the recovery frames above runtime.gopanic are dropped so the stack ends where the panic happened.
+func captureStack(skip int, panicking bool) *sentryStacktrace {
+	pcs := make([]uintptr, sentryMaxFrames)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var result []sentryFrame
+	for {
+		frame, more := frames.Next()
+		if panicking && frame.Function == "runtime.gopanic" {
+			result = result[:0]
+			if !more {
+				break
+			}
+			continue
+		}
+		module, function := splitFunction(frame.Function)
+		result = append(result, sentryFrame{
+			Function: function,
+			Module:   module,
+			AbsPath:  frame.File,
+			Lineno:   frame.Line,
+			InApp:    strings.HasPrefix(module, "qwiklip"),
+		})
+		if !more {
+			break
+		}
+	}
+
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return &sentryStacktrace{Frames: result}
+}
+
+// splitFunction splits "qwiklip/internal/server.(*Server).handleReel" into package path and function
+func splitFunction(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+1+dot+1:]
+}
+
+// errorType names an error for grouping: the AppError type when there is one
+// (e.g. "extraction"), otherwise the innermost Go error type
+func errorType(err error) string {
+	var appErr *models.AppError
+	if errors.As(err, &appErr) {
+		return string(appErr.Type)
+	}
+	for {
+		unwrapped, ok := err.(interface{ Unwrap() error })
+		if !ok || unwrapped.Unwrap() == nil {
+			return reflect.TypeOf(err).String()
+		}
+		err = unwrapped.Unwrap()
+	}
+}
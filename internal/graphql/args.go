@@ -0,0 +1,79 @@
+package graphql
+
+import (
+	"fmt"
+	"math"
+)
+
+// Args are the arguments of a field with variables substituted. Numbers are int64 when
+// written in the query and float64 when they come from JSON variables.
+type Args map[string]any
+
+// String returns a required string argument
+func (a Args) String(name string) (string, error) {
+	s, err := a.OptionalString(name)
+	if err != nil {
+		return "", err
+	}
+	if s == "" {
+		return "", fmt.Errorf("argument %q is required", name)
+	}
+	return s, nil
+}
+
+// OptionalString returns a string argument, or "" when it is absent or null
+func (a Args) OptionalString(name string) (string, error) {
+	value, ok := a[name]
+	if !ok || value == nil {
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("argument %q must be a String", name)
+	}
+	return s, nil
+}
+
+// Int returns an integer argument, or fallback when it is absent or null
+func (a Args) Int(name string, fallback int) (int, error) {
+	value, ok := a[name]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+	switch n := value.(type) {
+	case int64:
+		if n >= math.MinInt32 && n <= math.MaxInt32 {
+			return int(n), nil
+		}
+	case float64:
+		if n == math.Trunc(n) && n >= math.MinInt32 && n <= math.MaxInt32 {
+			return int(n), nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q must be an Int", name)
+}
+
+// Strings returns a list of strings argument. A single string is accepted as a list of one,
+// as GraphQL input coercion allows.
+func (a Args) Strings(name string) ([]string, error) {
+	value, ok := a[name]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	if s, ok := value.(string); ok {
+		return []string{s}, nil
+	}
+	list, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("argument %q must be a list of String", name)
+	}
+	strs := make([]string, len(list))
+	for i, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("argument %q must be a list of String", name)
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
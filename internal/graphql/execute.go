@@ -0,0 +1,569 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// maxConcurrentFields caps how many root fields of one query resolve at once
+const maxConcurrentFields = 4
+
+// Request is a GraphQL request as sent in a POST body or GET query string
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is absent when the request could not be
+// executed at all (syntax or validation errors), and null fields in it have an entry in Errors.
+type Response struct {
+	Data   any      `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Error is a GraphQL error, with the path of the field it happened on, if any
+type Error struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Resolver produces the value of a root query field. The value is projected onto the
+// query's selection set through its JSON field names.
+type Resolver func(ctx context.Context, args Args) (any, error)
+
+// Schema describes what can be queried. There is no type system beyond the Go types the
+// resolvers return: object fields are the JSON fields of structs, anything else is a leaf.
+type Schema struct {
+	Query        map[string]Resolver     // Root fields of the Query type
+	TypeNames    map[reflect.Type]string // GraphQL type names for __typename and fragment conditions; defaults to the Go type name
+	PresentError func(err error) *Error  // Converts resolver errors for the response, optional; the default uses err.Error()
+}
+
+// Execute parses and runs a request against the schema
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{asError(err)}}
+	}
+	operation, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{asError(err)}}
+	}
+	variables, err := coerceVariables(operation, req.Variables)
+	if err != nil {
+		return &Response{Errors: []*Error{asError(err)}}
+	}
+
+	ex := &executor{schema: s, doc: doc, variables: variables}
+	groups, err := ex.collectFields(operation.Selections, "Query", nil)
+	if err != nil {
+		return &Response{Errors: []*Error{asError(err)}}
+	}
+
+	// Validate and bind every root field before anything is resolved
+	type rootField struct {
+		key      string
+		field    *Field
+		group    []*Field
+		resolver Resolver
+		args     Args
+	}
+	roots := make([]rootField, 0, len(groups))
+	for _, group := range groups {
+		field := group.fields[0]
+		if field.Name == "__typename" {
+			roots = append(roots, rootField{key: group.key, field: field})
+			continue
+		}
+		resolver, ok := s.Query[field.Name]
+		if !ok {
+			return &Response{Errors: []*Error{{Message: fmt.Sprintf("Cannot query field %q on type %q.", field.Name, "Query")}}}
+		}
+		args, err := ex.arguments(field.Arguments)
+		if err != nil {
+			return &Response{Errors: []*Error{asError(err)}}
+		}
+		roots = append(roots, rootField{key: group.key, field: field, group: group.fields, resolver: resolver, args: args})
+	}
+
+	data := &object{keys: make([]string, len(roots)), values: make([]any, len(roots))}
+	errs := make([][]*Error, len(roots))
+	sem := make(chan struct{}, maxConcurrentFields)
+	var wg sync.WaitGroup
+	for i, root := range roots {
+		data.keys[i] = root.key
+		if root.resolver == nil {
+			data.values[i] = "Query"
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, root rootField) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			fe := &fieldExecutor{executor: ex}
+			path := []any{root.key}
+			value, err := resolve(ctx, root.resolver, root.args)
+			if err != nil {
+				fe.fail(s.resolverError(err), path)
+			} else {
+				data.values[i] = fe.complete(value, selectionsOf(root.group), root.field.Name, path)
+			}
+			errs[i] = fe.errors
+		}(i, root)
+	}
+	wg.Wait()
+
+	resp := &Response{Data: data}
+	for _, fieldErrs := range errs {
+		resp.Errors = append(resp.Errors, fieldErrs...)
+	}
+	return resp
+}
+
+// resolve calls a resolver, turning a panic into an error so one bad field cannot take
+// down the whole response
+func resolve(ctx context.Context, resolver Resolver, args Args) (value any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			value, err = nil, fmt.Errorf("internal error: %v", recovered)
+		}
+	}()
+	return resolver(ctx, args)
+}
+
+func (s *Schema) resolverError(err error) *Error {
+	if s.PresentError != nil {
+		return s.PresentError(err)
+	}
+	return &Error{Message: err.Error()}
+}
+
+func asError(err error) *Error {
+	if gqlErr, ok := err.(*Error); ok {
+		return gqlErr
+	}
+	return &Error{Message: err.Error()}
+}
+
+func selectOperation(doc *Document, name string) (*Operation, error) {
+	var operation *Operation
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, &Error{Message: "Must provide operation name if query contains multiple operations."}
+		}
+		operation = doc.Operations[0]
+	} else {
+		for _, candidate := range doc.Operations {
+			if candidate.Name == name {
+				operation = candidate
+				break
+			}
+		}
+		if operation == nil {
+			return nil, &Error{Message: fmt.Sprintf("Unknown operation named %q.", name)}
+		}
+	}
+
+	if operation.Type != "query" {
+		return nil, &Error{Message: fmt.Sprintf("Schema is not configured for %ss; only queries are supported.", operation.Type)}
+	}
+	return operation, nil
+}
+
+// coerceVariables applies defaults and checks that non-null variables were provided
+func coerceVariables(operation *Operation, provided map[string]any) (map[string]any, error) {
+	variables := make(map[string]any, len(operation.Variables))
+	for _, definition := range operation.Variables {
+		value, ok := provided[definition.Name]
+		if !ok && definition.HasDefault {
+			value, ok = definition.Default, true
+		}
+		if definition.NonNull && (!ok || value == nil) {
+			return nil, &Error{Message: fmt.Sprintf("Variable \"$%s\" of required type %q was not provided.", definition.Name, definition.Type)}
+		}
+		variables[definition.Name] = value
+	}
+	return variables, nil
+}
+
+// executor holds the state shared by one execution
+type executor struct {
+	schema    *Schema
+	doc       *Document
+	variables map[string]any
+}
+
+// fieldGroup is the fields of a selection set that share a response key
+type fieldGroup struct {
+	key    string
+	fields []*Field
+}
+
+// collectFields flattens fragments and applies @skip/@include, grouping fields by response
+// key in the order they first appear
+func (ex *executor) collectFields(selections []Selection, typeName string, groups []fieldGroup) ([]fieldGroup, error) {
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *Field:
+			include, err := ex.included(sel.Directives)
+			if err != nil {
+				return nil, err
+			}
+			if !include {
+				continue
+			}
+			key := sel.ResponseKey()
+			found := false
+			for i := range groups {
+				if groups[i].key == key {
+					groups[i].fields = append(groups[i].fields, sel)
+					found = true
+					break
+				}
+			}
+			if !found {
+				groups = append(groups, fieldGroup{key: key, fields: []*Field{sel}})
+			}
+		case *FragmentSpread:
+			include, err := ex.included(sel.Directives)
+			if err != nil {
+				return nil, err
+			}
+			fragment, ok := ex.doc.Fragments[sel.Name]
+			if !ok {
+				return nil, &Error{Message: fmt.Sprintf("Unknown fragment %q.", sel.Name)}
+			}
+			if !include || fragment.TypeCondition != typeName {
+				continue
+			}
+			if groups, err = ex.collectFields(fragment.Selections, typeName, groups); err != nil {
+				return nil, err
+			}
+		case *InlineFragment:
+			include, err := ex.included(sel.Directives)
+			if err != nil {
+				return nil, err
+			}
+			if !include || (sel.TypeCondition != "" && sel.TypeCondition != typeName) {
+				continue
+			}
+			if groups, err = ex.collectFields(sel.Selections, typeName, groups); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return groups, nil
+}
+
+// included evaluates the @skip and @include directives
+func (ex *executor) included(directives []Directive) (bool, error) {
+	for _, directive := range directives {
+		if directive.Name != "skip" && directive.Name != "include" {
+			continue
+		}
+		condition, err := ex.value(directive.Arguments["if"])
+		if err != nil {
+			return false, err
+		}
+		b, ok := condition.(bool)
+		if !ok {
+			return false, &Error{Message: fmt.Sprintf("Directive \"@%s\" argument \"if\" must be a Boolean.", directive.Name)}
+		}
+		if b == (directive.Name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (ex *executor) arguments(literals map[string]any) (Args, error) {
+	args := make(Args, len(literals))
+	for name, literal := range literals {
+		value, err := ex.value(literal)
+		if err != nil {
+			return nil, err
+		}
+		args[name] = value
+	}
+	return args, nil
+}
+
+// value substitutes variables into a literal
+func (ex *executor) value(literal any) (any, error) {
+	switch v := literal.(type) {
+	case Variable:
+		value, ok := ex.variables[string(v)]
+		if !ok {
+			return nil, &Error{Message: fmt.Sprintf("Variable \"$%s\" is not defined.", string(v))}
+		}
+		return value, nil
+	case Enum:
+		return string(v), nil
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			value, err := ex.value(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return list, nil
+	case map[string]any:
+		object := make(map[string]any, len(v))
+		for name, item := range v {
+			value, err := ex.value(item)
+			if err != nil {
+				return nil, err
+			}
+			object[name] = value
+		}
+		return object, nil
+	}
+	return literal, nil
+}
+
+// fieldExecutor completes the value of one root field, collecting its errors
+type fieldExecutor struct {
+	*executor
+	errors []*Error
+}
+
+func (fe *fieldExecutor) fail(err *Error, path []any) {
+	err.Path = append([]any(nil), path...)
+	fe.errors = append(fe.errors, err)
+}
+
+// complete projects a resolved Go value onto a selection set: structs become objects with
+// the selected JSON fields, slices are completed item by item and anything else is a leaf
+func (fe *fieldExecutor) complete(value any, selections []Selection, fieldName string, path []any) any {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		list := make([]any, v.Len())
+		for i := range list {
+			list[i] = fe.complete(v.Index(i).Interface(), selections, fieldName, append(path, i))
+		}
+		return list
+	}
+
+	if !isObject(v.Type()) {
+		if len(selections) > 0 {
+			fe.fail(&Error{Message: fmt.Sprintf("Field %q must not have a selection since its type has no subfields.", fieldName)}, path)
+			return nil
+		}
+		return v.Interface()
+	}
+
+	typeName := fe.typeName(v.Type())
+	if len(selections) == 0 {
+		fe.fail(&Error{Message: fmt.Sprintf("Field %q of type %q must have a selection of subfields.", fieldName, typeName)}, path)
+		return nil
+	}
+	groups, err := fe.collectFields(selections, typeName, nil)
+	if err != nil {
+		fe.fail(asError(err), path)
+		return nil
+	}
+
+	fields := objectFields(v.Type())
+	result := &object{keys: make([]string, 0, len(groups)), values: make([]any, 0, len(groups))}
+	for _, group := range groups {
+		field := group.fields[0]
+		result.keys = append(result.keys, group.key)
+		if field.Name == "__typename" {
+			result.values = append(result.values, typeName)
+			continue
+		}
+
+		info, ok := fields[field.Name]
+		if !ok {
+			fe.fail(&Error{Message: fmt.Sprintf("Cannot query field %q on type %q.", field.Name, typeName)}, append(path, group.key))
+			result.values = append(result.values, nil)
+			continue
+		}
+		fieldValue, ok := fieldByIndex(v, info.index)
+		if !ok || (info.omitEmpty && isEmpty(fieldValue)) || (info.omitZero && fieldValue.IsZero()) {
+			result.values = append(result.values, nil)
+			continue
+		}
+		result.values = append(result.values, fe.complete(fieldValue.Interface(), selectionsOf(group.fields), field.Name, append(path, group.key)))
+	}
+	return result
+}
+
+func (fe *fieldExecutor) typeName(t reflect.Type) string {
+	if name, ok := fe.schema.TypeNames[t]; ok {
+		return name
+	}
+	return t.Name()
+}
+
+// selectionsOf merges the selection sets of fields sharing a response key
+func selectionsOf(fields []*Field) []Selection {
+	if len(fields) == 1 {
+		return fields[0].Selections
+	}
+	var selections []Selection
+	for _, field := range fields {
+		selections = append(selections, field.Selections...)
+	}
+	return selections
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// isObject reports whether values of t have subfields. Structs with their own JSON or
+// text encoding, like time.Time, are leaves.
+func isObject(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	return !t.Implements(jsonMarshalerType) && !reflect.PointerTo(t).Implements(jsonMarshalerType) &&
+		!t.Implements(textMarshalerType) && !reflect.PointerTo(t).Implements(textMarshalerType)
+}
+
+// fieldInfo locates a JSON field of a struct
+type fieldInfo struct {
+	index     []int
+	omitEmpty bool
+	omitZero  bool
+}
+
+var objectFieldsCache sync.Map // reflect.Type -> map[string]fieldInfo
+
+// objectFields maps the JSON names of a struct's fields to their location, flattening
+// embedded structs the way encoding/json does (shallower fields win)
+func objectFields(t reflect.Type) map[string]fieldInfo {
+	if cached, ok := objectFieldsCache.Load(t); ok {
+		return cached.(map[string]fieldInfo)
+	}
+	fields := make(map[string]fieldInfo)
+	addObjectFields(t, nil, fields, make(map[string]int))
+	objectFieldsCache.Store(t, fields)
+	return fields
+}
+
+func addObjectFields(t reflect.Type, index []int, fields map[string]fieldInfo, depths map[string]int) {
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		if sf.Anonymous && name == "" {
+			embedded := sf.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addObjectFields(embedded, fieldIndex, fields, depths)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if depth, exists := depths[name]; exists && depth <= len(fieldIndex) {
+			continue
+		}
+		depths[name] = len(fieldIndex)
+		fields[name] = fieldInfo{
+			index:     fieldIndex,
+			omitEmpty: strings.Contains(","+options+",", ",omitempty,"),
+			omitZero:  strings.Contains(","+options+",", ",omitzero,"),
+		}
+	}
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex, reporting false for a nil embedded pointer
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmpty matches encoding/json's definition of an empty value for omitempty
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// object is a result object that keeps its fields in selection order
+type object struct {
+	keys   []string
+	values []any
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
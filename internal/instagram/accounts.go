@@ -0,0 +1,270 @@
+package instagram
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/models"
+)
+
+// maxCooldownStrikes caps the exponential cool-down at cooldown * 2^(strikes-1)
+const maxCooldownStrikes = 5
+
+// account is one Instagram session in the rotation pool
+type account struct {
+	name       string
+	source     string // "file:<path>" or "session:<id>", matches accounts across reloads
+	jar        *CookieJar
+	httpClient *http.Client
+
+	// Guarded by accountPool.mu
+	coolUntil time.Time
+	strikes   int
+}
+
+// accountPool rotates extractions across configured sessions and benches accounts
+// that Instagram rate limits or challenges
+type accountPool struct {
+	mu        sync.Mutex
+	accounts  []*account
+	next      int
+	cooldown  time.Duration
+	transport http.RoundTripper
+	timeout   time.Duration
+	metrics   metrics.Recorder
+	logger    *slog.Logger
+}
+
+// newAccountPool builds one account per session ID and cookies file
+func newAccountPool(sessionIDs, cookiesFiles []string, transport http.RoundTripper, timeout, cooldown time.Duration, recorder metrics.Recorder, logger *slog.Logger) (*accountPool, error) {
+	pool := &accountPool{
+		cooldown:  cooldown,
+		transport: transport,
+		timeout:   timeout,
+		metrics:   recorder,
+		logger:    logger,
+	}
+
+	accounts, err := pool.build(sessionIDs, cookiesFiles)
+	if err != nil {
+		return nil, err
+	}
+	pool.accounts = accounts
+
+	if len(pool.accounts) > 0 {
+		logger.Info("Instagram account pool ready", "accounts", len(pool.accounts), "cooldown", cooldown)
+	}
+	return pool, nil
+}
+
+// build creates the accounts for a set of sessions and cookies files. Cookies files are
+// always read afresh; a session ID already in the pool keeps its jar, and with it the
+// cookies Instagram has handed out since.
+func (p *accountPool) build(sessionIDs, cookiesFiles []string) ([]*account, error) {
+	p.mu.Lock()
+	jars := make(map[string]*CookieJar, len(p.accounts))
+	for _, acct := range p.accounts {
+		jars[acct.source] = acct.jar
+	}
+	p.mu.Unlock()
+
+	var accounts []*account
+	for _, path := range cookiesFiles {
+		jar, err := NewCookieJar(path, p.logger)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, p.newAccount(filepath.Base(path), "file:"+path, jar))
+	}
+
+	for i, sessionID := range sessionIDs {
+		source := "session:" + sessionID
+		jar, ok := jars[source]
+		if !ok {
+			var err error
+			if jar, err = NewCookieJar("", p.logger); err != nil {
+				return nil, err
+			}
+			jar.seed(&url.URL{Scheme: "https", Host: "www.instagram.com", Path: "/"}, &http.Cookie{
+				Name:   "sessionid",
+				Value:  sessionID,
+				Domain: ".instagram.com",
+				Path:   "/",
+				Secure: true,
+			})
+		}
+		accounts = append(accounts, p.newAccount(fmt.Sprintf("session-%d", i+1), source, jar))
+	}
+	return accounts, nil
+}
+
+func (p *accountPool) newAccount(name, source string, jar *CookieJar) *account {
+	return &account{
+		name:   name,
+		source: source,
+		jar:    jar,
+		httpClient: &http.Client{
+			Transport: p.transport,
+			Timeout:   p.timeout,
+			Jar:       jar,
+		},
+	}
+}
+
+// replace swaps in accounts made by build and the cool-down applied to future strikes. Accounts that stay in the rotation keep their
+// cool-down; jars that are dropped stop writing to their cookies files, so edits made to
+// a file before the reload are not overwritten by a save that was still pending.
+func (p *accountPool) replace(accounts []*account, cooldown time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing := make(map[string]*account, len(p.accounts))
+	for _, acct := range p.accounts {
+		existing[acct.source] = acct
+	}
+	kept := make(map[*CookieJar]bool, len(accounts))
+	for _, acct := range accounts {
+		kept[acct.jar] = true
+		if old, ok := existing[acct.source]; ok {
+			acct.coolUntil = old.coolUntil
+			acct.strikes = old.strikes
+		}
+	}
+	for _, old := range p.accounts {
+		if !kept[old.jar] {
+			old.jar.detach()
+		}
+	}
+
+	if len(accounts) > 0 || len(p.accounts) > 0 {
+		p.logger.Info("Instagram account pool reloaded", "accounts", len(accounts))
+	}
+	p.accounts = accounts
+	p.next = 0
+	p.cooldown = cooldown
+}
+
+// first returns the first configured account, or nil when there are none
+func (p *accountPool) first() *account {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.accounts) == 0 {
+		return nil
+	}
+	return p.accounts[0]
+}
+
+// acquire returns the next healthy account in round-robin order, or nil when
+// none are configured or all are cooling down (callers then fetch anonymously)
+func (p *accountPool) acquire() *account {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for range p.accounts {
+		candidate := p.accounts[p.next]
+		p.next = (p.next + 1) % len(p.accounts)
+		if now.After(candidate.coolUntil) {
+			return candidate
+		}
+	}
+
+	if len(p.accounts) > 0 {
+		p.logger.Warn("All Instagram accounts are cooling down, fetching anonymously")
+	}
+	return nil
+}
+
+// report records the outcome of a request made with acct.
+// Rate limits and login challenges bench the account with exponential back-off, or
+// for as long as Instagram's Retry-After asks when that is longer; a success clears its strikes.
+func (p *accountPool) report(acct *account, err error) {
+	if acct == nil {
+		return
+	}
+
+	var appErr *models.AppError
+	burned := errors.As(err, &appErr) &&
+		(appErr.Type == models.ErrorTypeRateLimited || appErr.Type == models.ErrorTypeAuthentication)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !burned {
+		if err == nil {
+			acct.strikes = 0
+		}
+		return
+	}
+
+	acct.strikes = min(acct.strikes+1, maxCooldownStrikes)
+	cooldown := p.cooldown << (acct.strikes - 1)
+	if retryAfter := appErr.RetryAfter(); retryAfter > cooldown {
+		cooldown = retryAfter
+	}
+	acct.coolUntil = time.Now().Add(cooldown)
+
+	p.metrics.Count(metrics.AccountCooldowns, 1, "reason", string(appErr.Type))
+	p.logger.Warn("Instagram account cooling down",
+		"account", acct.name,
+		"reason", appErr.Type,
+		"strikes", acct.strikes,
+		"cooldown", cooldown)
+}
+
+// healthy returns the number of accounts not currently cooling down
+func (p *accountPool) healthy() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	count := 0
+	now := time.Now()
+	for _, acct := range p.accounts {
+		if now.After(acct.coolUntil) {
+			count++
+		}
+	}
+	return count
+}
+
+// state reports the cool-down of every account
+func (p *accountPool) state() []AccountState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	states := make([]AccountState, 0, len(p.accounts))
+	for _, acct := range p.accounts {
+		until := coolingUntil(acct.coolUntil, now)
+		states = append(states, AccountState{
+			Name:       acct.name,
+			CoolingOff: until != nil,
+			CoolUntil:  until,
+			Strikes:    acct.strikes,
+		})
+	}
+	return states
+}
+
+// save persists the cookies of every file-backed account
+func (p *accountPool) save() error {
+	p.mu.Lock()
+	accounts := p.accounts
+	p.mu.Unlock()
+
+	var errs []error
+	for _, acct := range accounts {
+		if err := acct.jar.Save(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", acct.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,138 @@
+package instagram
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// strategySmoothing is the weight of the latest attempt in the moving averages, so
+	// roughly the last 20 attempts of a strategy decide its rank
+	strategySmoothing = 0.1
+	// strategyLatencyScale halves the score of a strategy averaging this much time per attempt
+	strategyLatencyScale = 10 * time.Second
+	// strategyPriorRate is the success rate assumed for strategies not tried yet
+	strategyPriorRate = 0.5
+)
+
+// StrategyState reports the recent success and latency of one extraction strategy
+type StrategyState struct {
+	Name        string  `json:"name"`
+	Attempts    int64   `json:"attempts"`
+	Successes   int64   `json:"successes"`
+	SuccessRate float64 `json:"success_rate"` // Moving average weighted towards recent attempts
+	LatencyMs   float64 `json:"latency_ms"`   // Moving average time per attempt
+	Score       float64 `json:"score"`        // What adaptive ordering ranks by
+}
+
+// strategyStats is the moving record of one strategy
+type strategyStats struct {
+	attempts  int64
+	successes int64
+	rate      float64
+	latency   time.Duration
+}
+
+// score ranks a strategy by its success rate, discounted for slowness: a strategy that
+// takes strategyLatencyScale per attempt counts half
+func (s *strategyStats) score() float64 {
+	if s == nil {
+		return strategyPriorRate
+	}
+	return s.rate / (1 + float64(s.latency)/float64(strategyLatencyScale))
+}
+
+// strategyRanker records the outcome of every strategy attempt and, when adaptive, orders
+// strategies by recent success so the extraction self-tunes when Instagram changes its
+// pages. With probability explore another strategy goes first, so a strategy that stopped
+// working earlier is noticed when it works again.
+type strategyRanker struct {
+	mu       sync.Mutex
+	stats    map[string]*strategyStats
+	adaptive bool
+	explore  float64
+}
+
+func newStrategyRanker(adaptive bool, explore float64) *strategyRanker {
+	return &strategyRanker{stats: make(map[string]*strategyStats), adaptive: adaptive, explore: explore}
+}
+
+// record adds one attempt of the named strategy
+func (r *strategyRanker) record(name string, err error, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats[name]
+	if stats == nil {
+		stats = &strategyStats{rate: strategyPriorRate, latency: duration}
+		r.stats[name] = stats
+	}
+	success := 0.0
+	if err == nil {
+		success = 1
+		stats.successes++
+	}
+	stats.attempts++
+	stats.rate += strategySmoothing * (success - stats.rate)
+	stats.latency += time.Duration(strategySmoothing * float64(duration-stats.latency))
+}
+
+// order returns the positions of names in the order to try them: as configured unless
+// adaptive, otherwise by score, with ties keeping the configured order
+func (r *strategyRanker) order(names []string) []int {
+	positions := make([]int, len(names))
+	for i := range positions {
+		positions[i] = i
+	}
+	if !r.adaptive || len(names) < 2 {
+		return positions
+	}
+
+	r.mu.Lock()
+	scores := make([]float64, len(names))
+	for i, name := range names {
+		scores[i] = r.stats[name].score()
+	}
+	r.mu.Unlock()
+
+	slices.SortStableFunc(positions, func(a, b int) int { return cmp.Compare(scores[b], scores[a]) })
+	if rand.Float64() < r.explore {
+		// Move a random runner-up to the front; the rest keep their rank
+		pick := 1 + rand.IntN(len(positions)-1)
+		explored := positions[pick]
+		copy(positions[1:pick+1], positions[:pick])
+		positions[0] = explored
+	}
+	return positions
+}
+
+// state reports the recorded strategies, best score first
+func (r *strategyRanker) state() []StrategyState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	states := make([]StrategyState, 0, len(r.stats))
+	for name, stats := range r.stats {
+		states = append(states, StrategyState{
+			Name:        name,
+			Attempts:    stats.attempts,
+			Successes:   stats.successes,
+			SuccessRate: stats.rate,
+			LatencyMs:   float64(stats.latency) / float64(time.Millisecond),
+			Score:       stats.score(),
+		})
+	}
+	slices.SortFunc(states, func(a, b StrategyState) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Name, b.Name))
+	})
+	return states
+}
+
+// Strategies returns the recent success of every strategy tried so far, and whether
+// strategies are ordered by it (INSTAGRAM_ADAPTIVE_EXTRACTORS)
+func (c *Client) Strategies() (states []StrategyState, adaptive bool) {
+	return c.ranker.state(), c.ranker.adaptive
+}
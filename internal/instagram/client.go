@@ -1,18 +1,29 @@
 package instagram
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"qwiklip/internal/cache"
 	"qwiklip/internal/config"
+	"qwiklip/internal/errorreport"
+	"qwiklip/internal/metrics"
 	"qwiklip/internal/models"
+	"qwiklip/internal/tracing"
+	"qwiklip/internal/webhook"
 )
 
 const (
@@ -25,28 +36,177 @@ type Client struct {
 	httpClient *http.Client
 	config     *config.InstagramConfig
 	logger     *slog.Logger
+	metrics    metrics.Recorder
+	errors     errorreport.Reporter
+	notifier   webhook.Notifier
+	cache      cache.Cache
+	flights    flightGroup
+	extractors *extractorRegistry
+	ranker     *strategyRanker
+	rateWindow *rateLimitWindow // Cool-down after 429s to anonymous requests
+	accounts   *accountPool
+	proxies    *proxyPool
+	limiter    *limiter
+	scheduler  *requestScheduler
+	userAgent  atomic.Pointer[string] // INSTAGRAM_USER_AGENT, sent on CDN and headless requests
+	headless   *headlessBrowser       // nil unless the headless fallback is enabled and Chrome found
+
+	requestExtractors []requestExtractor // Tried after the page extractors
 }
 
-// NewClient creates a new Instagram client
-func NewClient(cfg *config.InstagramConfig, logger *slog.Logger) *Client {
-	return &Client{
+// NewClient creates a new Instagram client using the extraction strategies named in cfg.Extractors
+func NewClient(cfg *config.InstagramConfig, mediaCache cache.Cache, logger *slog.Logger, recorder metrics.Recorder, reporter errorreport.Reporter, notifier webhook.Notifier) (*Client, error) {
+	scheduler := newRequestScheduler(cfg.RequestsPerMinute, cfg.RequestBurst, cfg.QueueTimeout, recorder, logger)
+	transport, proxies, err := newTransport(cfg, scheduler, recorder, logger)
+	if err != nil {
+		return nil, err
+	}
+	transport = newHeaderTransport(transport, cfg)
+	if cfg.MockMode {
+		transport = fixtureTransport{dir: cfg.MockFixturesDir}
+		logger.Warn("Mock mode enabled: Instagram pages and media are served from fixtures", "dir", cfg.MockFixturesDir)
+	}
+	if cfg.RecordDir != "" {
+		sessionIDs, _ := accountSources(cfg)
+		transport = newRecordingTransport(transport, cfg.RecordDir, sessionIDs, logger)
+		logger.Warn("Recording sanitized post pages", "dir", cfg.RecordDir)
+	}
+
+	sessionIDs, cookiesFiles := accountSources(cfg)
+	accounts, err := newAccountPool(sessionIDs, cookiesFiles, transport, cfg.Timeout, cfg.AccountCooldown, recorder, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Anonymous client used when no account is configured or all are cooling down
+	anonymousJar, err := NewCookieJar("", logger)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
 		httpClient: &http.Client{
-			Timeout: cfg.Timeout,
+			Transport: transport,
+			Timeout:   cfg.Timeout,
+			Jar:       anonymousJar,
 		},
-		config: cfg,
-		logger: logger,
+		config:    cfg,
+		logger:    logger,
+		metrics:   recorder,
+		errors:    reporter,
+		notifier:  notifier,
+		cache:     mediaCache,
+		accounts:  accounts,
+		proxies:   proxies,
+		limiter:   newLimiter(cfg.MaxConcurrent, cfg.QueueSize, cfg.QueueTimeout, recorder),
+		scheduler: scheduler,
+		headless:  detectHeadlessBrowser(cfg, logger),
+		ranker:    newStrategyRanker(cfg.AdaptiveOrder, cfg.AdaptiveExplore),
+
+		rateWindow: newRateLimitWindow(cfg.RateLimitCooldown, recorder, logger),
 	}
+	userAgent := cfg.UserAgent
+	c.userAgent.Store(&userAgent)
+
+	// Request strategies make their own requests, so they are kept out of the page registry
+	pageExtractors, requestExtractors := c.splitExtractors(cfg.Extractors)
+	c.requestExtractors = requestExtractors
+	if len(pageExtractors) == 0 && len(cfg.Extractors) > 0 {
+		c.extractors = &extractorRegistry{ranker: c.ranker, metrics: recorder, logger: logger}
+		return c, nil
+	}
+
+	extractors, err := newExtractorRegistry(c.builtinExtractors(), pageExtractors, c.ranker, recorder, logger)
+	if err != nil {
+		return nil, err
+	}
+	c.extractors = extractors
+	return c, nil
+}
+
+// accountSources lists the configured sessions and cookies files; each becomes one account in the rotation
+func accountSources(cfg *config.InstagramConfig) (sessionIDs, cookiesFiles []string) {
+	sessionIDs = cfg.SessionIDs
+	if cfg.SessionID != "" {
+		sessionIDs = append([]string{cfg.SessionID}, sessionIDs...)
+	}
+	cookiesFiles = cfg.CookiesFiles
+	if cfg.CookiesFile != "" {
+		cookiesFiles = append([]string{cfg.CookiesFile}, cookiesFiles...)
+	}
+	return sessionIDs, cookiesFiles
 }
 
-// GetHTTPClient returns the underlying HTTP client
+// Reload applies the proxies, accounts and their cool-downs, the concurrency limit and
+// request budget, and the user agent from cfg without interrupting requests in flight,
+// which finish on the proxy, account and limits they started with. Nothing changes if
+// cfg fails to load. Other settings, such as timeouts, take effect on restart.
+func (c *Client) Reload(cfg *config.InstagramConfig) error {
+	proxies, err := loadProxies(cfg)
+	if err != nil {
+		return err
+	}
+	accounts, err := c.accounts.build(accountSources(cfg))
+	if err != nil {
+		return err
+	}
+
+	c.proxies.replace(proxies, cfg)
+	c.accounts.replace(accounts, cfg.AccountCooldown)
+	c.limiter.setLimits(cfg.MaxConcurrent, cfg.QueueSize, cfg.QueueTimeout)
+	c.scheduler.setLimits(cfg.RequestsPerMinute, cfg.RequestBurst, cfg.QueueTimeout)
+	userAgent := cfg.UserAgent
+	c.userAgent.Store(&userAgent)
+	return nil
+}
+
+// UserAgent returns the configured user agent, which a reload may change between requests
+func (c *Client) UserAgent() string {
+	return *c.userAgent.Load()
+}
+
+// Close persists refreshed cookies to the cookies files
+func (c *Client) Close() error {
+	return c.accounts.save()
+}
+
+// GetHTTPClient returns the HTTP client used for CDN streaming.
+// It carries the first account's cookies so CDN requests look like the session that extracted them.
 func (c *Client) GetHTTPClient() *http.Client {
+	if acct := c.accounts.first(); acct != nil {
+		return acct.httpClient
+	}
 	return c.httpClient
 }
 
-// GetMediaInfo extracts media information from an Instagram URL
-func (c *Client) GetMediaInfo(instagramURL string) (*models.InstagramMediaInfo, error) {
+// HealthyAccounts returns the number of configured accounts that are not cooling down
+func (c *Client) HealthyAccounts() int {
+	return c.accounts.healthy()
+}
+
+// clientFor returns the HTTP client of acct, or the anonymous client when acct is nil
+func (c *Client) clientFor(acct *account) *http.Client {
+	if acct == nil {
+		return c.httpClient
+	}
+	return acct.httpClient
+}
+
+// GetMediaInfo extracts media information from an Instagram URL.
+// Results are cached per shortcode and concurrent lookups of the same shortcode share one extraction.
+// Cancelling ctx aborts the Instagram fetches once no other caller is waiting on them.
+func (c *Client) GetMediaInfo(ctx context.Context, instagramURL string) (*models.InstagramMediaInfo, error) {
 	c.logger.Info("Starting Instagram media extraction", "url", instagramURL)
 
+	if parsedURL, err := url.Parse(instagramURL); err == nil && isShareLink(parsedURL) {
+		canonical, err := c.ResolveURL(ctx, instagramURL)
+		if err != nil {
+			c.logger.Error("Failed to resolve share link", "error", err, "url", instagramURL)
+			return nil, err
+		}
+		instagramURL = canonical
+	}
+
 	shortcode, err := c.ExtractShortcode(instagramURL)
 	if err != nil {
 		c.logger.Error("Failed to extract shortcode", "error", err, "url", instagramURL)
@@ -55,6 +215,300 @@ func (c *Client) GetMediaInfo(instagramURL string) (*models.InstagramMediaInfo,
 
 	c.logger.Info("Extracted shortcode", "shortcode", shortcode)
 
+	ctx, span := tracing.Start(ctx, "instagram.GetMediaInfo", tracing.KindInternal)
+	defer span.End()
+	span.SetAttributes("instagram.shortcode", shortcode)
+
+	mediaInfo, err := c.cache.Get(ctx, shortcode)
+	if err == nil {
+		c.logger.Info("Media info served from cache", "shortcode", shortcode)
+		c.metrics.Count(metrics.CacheHits, 1, "cache", "metadata")
+		span.SetAttributes("cache.hit", true)
+		observe(ctx, ExtractionEvent{Stage: StageCached, Shortcode: shortcode})
+		return mediaInfo, nil
+	}
+	if !errors.Is(err, cache.ErrMiss) {
+		c.logger.Warn("Metadata cache lookup failed", "shortcode", shortcode, "error", err)
+	}
+
+	appErr, err := c.cache.GetNegative(ctx, shortcode)
+	if err == nil {
+		c.logger.Info("Negative result served from cache", "shortcode", shortcode, "error", appErr)
+		c.metrics.Count(metrics.CacheHits, 1, "cache", "negative")
+		span.SetAttributes("cache.hit", true)
+		span.RecordError(appErr)
+		observe(ctx, ExtractionEvent{Stage: StageCached, Shortcode: shortcode, Err: appErr})
+		return nil, appErr
+	}
+	if !errors.Is(err, cache.ErrMiss) {
+		c.logger.Warn("Negative result cache lookup failed", "shortcode", shortcode, "error", err)
+	}
+	c.metrics.Count(metrics.CacheMisses, 1, "cache", "metadata")
+
+	mediaInfo, err, shared := c.flights.Do(ctx, shortcode, func(ctx context.Context) (*models.InstagramMediaInfo, error) {
+		mediaInfo, err := c.extractMediaInfo(ctx, shortcode)
+		if err == nil {
+			if cacheErr := c.cache.Set(ctx, shortcode, mediaInfo); cacheErr != nil {
+				c.logger.Warn("Failed to cache media info", "shortcode", shortcode, "error", cacheErr)
+			}
+		} else if appErr := negativeResult(err); appErr != nil {
+			if cacheErr := c.cache.SetNegative(ctx, shortcode, appErr); cacheErr != nil {
+				c.logger.Warn("Failed to cache negative result", "shortcode", shortcode, "error", cacheErr)
+			}
+		}
+		return mediaInfo, err
+	})
+	if shared {
+		c.logger.Info("Joined in-flight extraction", "shortcode", shortcode)
+		observe(ctx, ExtractionEvent{Stage: StageShared, Shortcode: shortcode, Err: err})
+	}
+	span.SetAttributes("cache.hit", false, "extraction.shared", shared)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// Callers may modify the result, so never hand out the shared pointer
+	return mediaInfo.Clone(), nil
+}
+
+// negativeResult returns the error of a failed extraction that is worth remembering: the post
+// is missing or expired. Rate limits, network trouble, shed requests and authentication errors,
+// which describe the account, session or IP rather than the post, say nothing about it.
+func negativeResult(err error) *models.AppError {
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) {
+		return nil
+	}
+	switch appErr.Type {
+	case models.ErrorTypeNotFound, models.ErrorTypeExpired:
+		return appErr
+	default:
+		return nil
+	}
+}
+
+// CachedMediaInfo returns the media info of shortcode when it is in the metadata cache,
+// without extracting it otherwise
+func (c *Client) CachedMediaInfo(ctx context.Context, shortcode string) (*models.InstagramMediaInfo, bool) {
+	mediaInfo, err := c.cache.Get(ctx, shortcode)
+	return mediaInfo, err == nil
+}
+
+// InvalidateCache removes a shortcode, and any negative result for it, from the metadata cache
+func (c *Client) InvalidateCache(ctx context.Context, shortcode string) error {
+	if err := c.cache.Delete(ctx, shortcode); err != nil {
+		c.logger.Warn("Failed to invalidate cached media info", "shortcode", shortcode, "error", err)
+		return err
+	}
+	return nil
+}
+
+// CachedShortcodes returns the keys of the metadata cache in sorted order
+func (c *Client) CachedShortcodes(ctx context.Context) ([]string, error) {
+	keys, err := c.cache.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+// PurgeCache empties the metadata cache and returns the number of entries removed
+func (c *Client) PurgeCache(ctx context.Context) (int, error) {
+	return c.cache.Purge(ctx)
+}
+
+// extractMediaInfo scrapes Instagram for the media information of a shortcode. While Instagram
+// rate limits anonymous requests it fails fast, except for background work (WithRateLimitWait),
+// which waits for the window to pass and retries after further 429s.
+func (c *Client) extractMediaInfo(ctx context.Context, shortcode string) (*models.InstagramMediaInfo, error) {
+	for retry := 0; ; retry++ {
+		if err := c.awaitRateLimit(ctx); err != nil {
+			return nil, err
+		}
+		mediaInfo, err := c.extractWithNextAccount(ctx, shortcode)
+		if retry == maxRateLimitRetries || !isRateLimited(err) || !waitsOutRateLimits(ctx) {
+			return mediaInfo, err
+		}
+		c.logger.Info("Rate limited, retrying extraction once the cool-down passes", "shortcode", shortcode, "retry", retry+1)
+	}
+}
+
+// extractWithNextAccount scrapes Instagram for the media information of a shortcode using the next pooled account
+func (c *Client) extractWithNextAccount(ctx context.Context, shortcode string) (*models.InstagramMediaInfo, error) {
+	ctx, span := tracing.Start(ctx, "instagram.extract", tracing.KindInternal)
+	defer span.End()
+
+	waitStart := time.Now()
+	release, err := c.limiter.acquire(ctx)
+	span.SetAttributes("limiter.wait_ms", time.Since(waitStart).Milliseconds())
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer release()
+
+	acct := c.accounts.acquire()
+	if acct != nil {
+		span.SetAttributes("instagram.account", acct.name)
+	}
+	if acct != nil {
+		c.logger.Debug("Extracting with account", "account", acct.name, "shortcode", shortcode)
+	}
+
+	mediaInfo, err := c.scrapeMediaInfo(withBudgetAccount(ctx, acct), c.clientFor(acct), shortcode)
+	span.RecordError(err)
+	c.accounts.report(acct, err)
+	if acct == nil {
+		c.rateWindow.record(err)
+	}
+	c.reportExtractionError(ctx, shortcode, acct, err)
+	c.notifyExtraction(ctx, shortcode, acct, mediaInfo, err, time.Since(waitStart))
+	return mediaInfo, err
+}
+
+// notifyExtraction sends the outcome of an extraction to webhooks; cancelled requests are skipped
+func (c *Client) notifyExtraction(ctx context.Context, shortcode string, acct *account, mediaInfo *models.InstagramMediaInfo, err error, duration time.Duration) {
+	if err == nil {
+		c.notifier.Notify(webhook.ExtractionSucceeded,
+			"shortcode", shortcode,
+			"username", mediaInfo.Username,
+			"image", mediaInfo.IsImage(),
+			"duration_ms", duration.Milliseconds())
+		return
+	}
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	errorType := "unknown"
+	var appErr *models.AppError
+	if errors.As(err, &appErr) {
+		errorType = string(appErr.Type)
+	}
+	c.notifier.Notify(webhook.ExtractionFailed,
+		"shortcode", shortcode,
+		"error_type", errorType,
+		"error", err.Error(),
+		"duration_ms", duration.Milliseconds())
+
+	if errorType == string(models.ErrorTypeRateLimited) {
+		accountName := ""
+		if acct != nil {
+			accountName = acct.name
+		}
+		c.notifier.Notify(webhook.RateLimited, "shortcode", shortcode, "account", accountName)
+	}
+}
+
+// reportExtractionError sends failures that point at a broken extractor to the error reporter.
+// Missing content, rate limits, login walls and cancelled requests are expected and not reported.
+func (c *Client) reportExtractionError(ctx context.Context, shortcode string, acct *account, err error) {
+	if err == nil || ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	var appErr *models.AppError
+	if errors.As(err, &appErr) && appErr.Type != models.ErrorTypeExtraction && appErr.Type != models.ErrorTypeParsing {
+		return
+	}
+
+	tags := []string{"shortcode", shortcode, "strategy", failedStrategy(err)}
+	if acct != nil {
+		tags = append(tags, "account", acct.name)
+	}
+	c.errors.CaptureError(ctx, err, tags...)
+}
+
+// scrapeMediaInfo fetches the post page with httpClient and runs the extractors over it
+func (c *Client) scrapeMediaInfo(ctx context.Context, httpClient *http.Client, shortcode string) (*models.InstagramMediaInfo, error) {
+	if c.config.MockMode {
+		mediaInfo, err := loadFixture(c.config.MockFixturesDir, shortcode)
+		if err == nil {
+			mediaInfo.Classify()
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return mediaInfo, err
+		}
+	}
+
+	// With only request strategies enabled the post page is not needed
+	var body string
+	var mediaInfo *models.InstagramMediaInfo
+	var err error = models.NewNotFoundError(fmt.Sprintf("Instagram content with shortcode '%s'", shortcode))
+	if len(c.extractors.extractors) > 0 {
+		body, err = c.fetchPostPageWithRetry(ctx, httpClient, shortcode)
+		if err == nil {
+			mediaInfo, err = c.extractors.extract(ctx, body, shortcode)
+		}
+	}
+
+	if err != nil && len(c.requestExtractors) > 0 && (len(c.extractors.extractors) == 0 || requestFallbackApplies(err, body != "")) {
+		mediaInfo, err = c.requestFallback(ctx, httpClient, shortcode, err)
+	}
+	if err != nil && body != "" && c.headless != nil {
+		mediaInfo, err = c.headlessFallback(ctx, shortcode, err)
+	}
+	if err != nil {
+		return nil, err // Return the error directly
+	}
+
+	mediaInfo.Classify()
+	c.logger.Info("Successfully completed media extraction", "media_type", mediaInfo.MediaType)
+	return mediaInfo, nil
+}
+
+// fetchPostPageWithRetry repeats fetchPostPage after transient failures, waiting an
+// exponentially growing, jittered delay between passes. No retry starts after the
+// configured deadline, so a flapping Instagram can't hold a request indefinitely.
+func (c *Client) fetchPostPageWithRetry(ctx context.Context, httpClient *http.Client, shortcode string) (string, error) {
+	deadline := time.Now().Add(c.config.RetryDeadline)
+	for attempt := 0; ; attempt++ {
+		observe(ctx, ExtractionEvent{Stage: StageFetch, Shortcode: shortcode, Attempt: attempt + 1})
+		body, retryable, err := c.fetchPostPage(ctx, httpClient, shortcode)
+		if err == nil || !retryable {
+			return body, err
+		}
+		if attempt >= c.config.MaxRetries {
+			if c.config.MaxRetries > 0 {
+				c.logger.Warn("Giving up on Instagram fetch after retries", "shortcode", shortcode, "attempts", attempt+1, "error", err)
+			}
+			return "", err
+		}
+
+		wait := retryDelay(c.config.RetryBackoff, attempt)
+		if time.Now().Add(wait).After(deadline) {
+			c.logger.Warn("Retry deadline reached, giving up on Instagram fetch",
+				"shortcode", shortcode, "attempts", attempt+1, "deadline", c.config.RetryDeadline, "error", err)
+			return "", err
+		}
+
+		c.logger.Warn("Transient Instagram fetch failure, retrying",
+			"shortcode", shortcode,
+			"attempt", attempt+1,
+			"max_retries", c.config.MaxRetries,
+			"retry_in", wait,
+			"error", err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			c.logger.Info("Extraction cancelled", "shortcode", shortcode, "error", ctx.Err())
+			return "", ctx.Err()
+		}
+	}
+}
+
+// retryDelay returns the wait before retry number attempt+1: base doubled per
+// attempt, then drawn uniformly from its upper half so concurrent retries spread out
+func retryDelay(base time.Duration, attempt int) time.Duration {
+	d := base << min(attempt, 16)
+	return d/2 + rand.N(d/2+1)
+}
+
+// fetchPostPage tries each URL format once and returns the first post page body.
+// retryable reports whether the failure was transient (network errors, 5xx responses).
+func (c *Client) fetchPostPage(ctx context.Context, httpClient *http.Client, shortcode string) (body string, retryable bool, err error) {
 	// Try different URL formats to increase success chances
 	urlFormats := []struct {
 		url       string
@@ -66,8 +520,8 @@ func (c *Client) GetMediaInfo(instagramURL string) (*models.InstagramMediaInfo,
 		{fmt.Sprintf("https://www.instagram.com/reel/%s/?__a=1&__d=dis", shortcode), MobileUserAgent},
 	}
 
-	var response *http.Response
-	var successURL string
+	// Kept when a format fails with a network error, so an unreachable Instagram is not reported as missing content
+	var networkErr error
 
 	c.logger.Info("Trying different URL formats and user agents")
 
@@ -83,7 +537,7 @@ func (c *Client) GetMediaInfo(instagramURL string) (*models.InstagramMediaInfo,
 			"url_format", format.url[:min(50, len(format.url))],
 			"user_agent", userAgentType)
 
-		req, err := http.NewRequest("GET", format.url, nil)
+		req, err := http.NewRequestWithContext(ctx, "GET", format.url, nil)
 		if err != nil {
 			c.logger.Error("Failed to create request", "error", err)
 			continue
@@ -102,42 +556,56 @@ func (c *Client) GetMediaInfo(instagramURL string) (*models.InstagramMediaInfo,
 		req.Header.Set("upgrade-insecure-requests", "1")
 
 		start := time.Now()
-		resp, err := c.httpClient.Do(req)
+		resp, err := httpClient.Do(req)
 		duration := time.Since(start)
 
 		if err != nil {
+			// The caller went away; trying the remaining formats would be wasted work
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				c.logger.Info("Extraction cancelled", "shortcode", shortcode, "error", ctxErr)
+				return "", false, ctxErr
+			}
+			if shed := shedByScheduler(err); shed != nil {
+				return "", false, shed
+			}
 			c.logger.Error("Failed to fetch", "error", err, "duration", duration)
+			networkErr = err
 			continue
 		}
 
 		c.logger.Debug("Response received", "status", resp.StatusCode, "duration", duration)
 
+		// Burned sessions are redirected to a login or challenge page instead of the post
+		if finalPath := resp.Request.URL.Path; strings.HasPrefix(finalPath, "/challenge") || strings.HasPrefix(finalPath, "/accounts/login") {
+			resp.Body.Close()
+			c.logger.Warn("Redirected to login or challenge page, stopping attempts", "path", finalPath)
+			return "", false, models.NewAuthenticationError("Instagram requested a login or challenge")
+		}
+
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			response = resp
-			successURL = format.url
-			c.logger.Info("Successfully fetched content", "url", successURL)
-			// Read and check the response body here
-			body, err := io.ReadAll(response.Body)
+			c.logger.Info("Successfully fetched content", "url", format.url)
+			c.logger.Debug("Content info",
+				"content_length", resp.Header.Get("Content-Length"),
+				"content_type", resp.Header.Get("Content-Type"))
+
+			data, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
 			if err != nil {
 				c.logger.Error("Failed to read response body", "error", err)
-				return nil, fmt.Errorf("failed to read response body: %w", err)
+				return "", ctx.Err() == nil, models.NewNetworkError("Instagram response read", err)
 			}
-			response.Body.Close() // Close the original body
 
-			c.logger.Debug("HTML content length", "length", len(body))
+			c.logger.Debug("HTML content length", "length", len(data))
 
 			// Check if this is an Instagram 404 page
-			if c.isInstagram404Page(string(body)) {
+			if c.isInstagram404Page(string(data)) {
 				c.logger.Warn("Detected Instagram 404 page", "shortcode", shortcode)
-				return nil, models.NewNotFoundError(fmt.Sprintf("Instagram content with shortcode '%s'", shortcode))
+				return "", false, models.NewNotFoundError(fmt.Sprintf("Instagram content with shortcode '%s'", shortcode))
 			}
 
 			// Save debug content if debug mode is enabled
-			c.saveDebugContent(shortcode, string(body))
-
-			// Create a new ReadCloser for the response body since we consumed it
-			response.Body = io.NopCloser(strings.NewReader(string(body)))
-			break
+			c.saveDebugContent(shortcode, string(data))
+			return string(data), false, nil
 		}
 
 		// Handle error status codes that indicate we should stop trying
@@ -145,81 +613,33 @@ func (c *Client) GetMediaInfo(instagramURL string) (*models.InstagramMediaInfo,
 			resp.Body.Close()
 			if resp.StatusCode == 404 {
 				c.logger.Warn("Content not found (404), stopping attempts", "url", format.url)
-				return nil, models.NewNotFoundError("Instagram content")
+				return "", false, models.NewNotFoundError("Instagram content")
 			} else if resp.StatusCode == 429 {
-				c.logger.Warn("Rate limited (429), stopping attempts", "url", format.url)
-				return nil, models.NewRateLimitedError("")
+				c.logger.Warn("Rate limited (429), stopping attempts", "url", format.url, "retry_after", resp.Header.Get("Retry-After"))
+				return "", false, models.NewRateLimitedError(retryAfterSeconds(resp.Header.Get("Retry-After")))
 			} else if resp.StatusCode >= 500 {
 				c.logger.Warn("Instagram server error, stopping attempts", "status", resp.StatusCode, "url", format.url)
-				return nil, models.NewNetworkError("Instagram server error", fmt.Errorf("HTTP %d", resp.StatusCode))
+				return "", true, models.NewNetworkError("Instagram server error", fmt.Errorf("HTTP %d", resp.StatusCode))
 			} else {
 				c.logger.Warn("Client error, stopping attempts", "status", resp.StatusCode, "url", format.url)
-				return nil, models.NewNetworkError("Instagram client error", fmt.Errorf("HTTP %d", resp.StatusCode))
+				return "", false, models.NewNetworkError("Instagram client error", fmt.Errorf("HTTP %d", resp.StatusCode))
 			}
 		}
 		resp.Body.Close()
 	}
 
-	if response == nil || successURL == "" {
-		c.logger.Error("All URL formats failed", "shortcode", shortcode)
-		return nil, models.NewNotFoundError(fmt.Sprintf("Instagram content with shortcode '%s'", shortcode))
-	}
-
-	defer response.Body.Close()
-
-	c.logger.Debug("Content info",
-		"content_length", response.Header.Get("Content-Length"),
-		"content_type", response.Header.Get("Content-Type"))
-
-	// Get the body content (already read and checked above)
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		c.logger.Error("Failed to read response body", "error", err)
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	// Try different patterns to extract JSON data
-	c.logger.Debug("Attempting JSON data extraction")
-	jsonData, err := c.extractJSONData(string(body), shortcode)
-	if err != nil {
-		c.logger.Warn("JSON extraction failed, trying direct video URL extraction", "error", err)
-		// Try to find direct video URLs in the HTML content
-		videoURL, err := c.extractDirectVideoURL(string(body))
-		if err != nil {
-			c.logger.Error("Direct video URL extraction also failed", "error", err)
-			// Try additional fallback patterns from TypeScript implementation
-			videoURL, err = c.extractFallbackVideoURL(string(body), shortcode)
-			if err != nil {
-				c.logger.Error("Fallback video URL extraction also failed", "error", err)
-				return nil, err // Return the error directly
-			}
-		}
-
-		c.logger.Info("Found direct video URL", "url_prefix", videoURL[:min(100, len(videoURL))])
-
-		return &models.InstagramMediaInfo{
-			VideoURL: videoURL,
-			FileName: fmt.Sprintf("%s.mp4", shortcode),
-		}, nil
-	}
-
-	c.logger.Info("Successfully extracted JSON data")
-
-	// Parse the JSON data to find the video URL
-	c.logger.Debug("Parsing JSON data for video URL")
-	mediaInfo, err := c.parseMediaInfo(jsonData, shortcode)
-	if err != nil {
-		c.logger.Error("Failed to parse media info", "error", err, "error_type", fmt.Sprintf("%T", err))
-		return nil, err // Return the error directly without wrapping
+	if networkErr != nil {
+		c.logger.Error("All URL formats failed", "shortcode", shortcode, "error", networkErr)
+		return "", true, models.NewNetworkError("Instagram post fetch", networkErr)
 	}
-
-	c.logger.Info("Successfully completed media extraction")
-	return mediaInfo, nil
+	c.logger.Error("All URL formats failed", "shortcode", shortcode)
+	return "", false, models.NewNotFoundError(fmt.Sprintf("Instagram content with shortcode '%s'", shortcode))
 }
 
-// ExtractShortcode extracts the shortcode from an Instagram URL
+// ExtractShortcode extracts the shortcode from an Instagram URL. Share links carry no
+// shortcode and have to go through ResolveURL first.
 func (c *Client) ExtractShortcode(urlStr string) (string, error) {
-	if !strings.Contains(urlStr, "instagram.com") {
+	if !strings.Contains(urlStr, "instagram.com") && !strings.Contains(urlStr, "instagr.am") {
 		return "", fmt.Errorf("not an Instagram URL: %s", urlStr)
 	}
 
@@ -227,17 +647,16 @@ func (c *Client) ExtractShortcode(urlStr string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("invalid URL: %s", urlStr)
 	}
+	if isShareLink(parsedURL) {
+		return "", fmt.Errorf("share link must be resolved first: %s", urlStr)
+	}
 
-	path := strings.TrimSuffix(parsedURL.Path, "/")
-	segments := strings.Split(path, "/")
-
-	if len(segments) >= 3 {
-		pathType := segments[len(segments)-2]
-		if pathType == "p" || pathType == "reel" || pathType == "tv" {
-			return segments[len(segments)-1], nil
+	if _, shortcode, ok := postPath(parsedURL.Path); ok {
+		if !models.ValidShortcode(shortcode) {
+			return "", models.NewInvalidShortcodeError(shortcode)
 		}
+		return shortcode, nil
 	}
-
 	return "", fmt.Errorf("could not extract shortcode from URL: %s", urlStr)
 }
 
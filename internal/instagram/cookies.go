@@ -0,0 +1,263 @@
+package instagram
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// httpOnlyPrefix marks HttpOnly cookies in Netscape cookie files (curl/yt-dlp convention)
+	httpOnlyPrefix = "#HttpOnly_"
+	// cookieSaveDelay batches bursts of Set-Cookie headers into one write
+	cookieSaveDelay = 5 * time.Second
+)
+
+// CookieJar is an http.CookieJar that can be loaded from and saved to a
+// Netscape cookies.txt file (the format exported by browsers and yt-dlp).
+// Cookies refreshed by Instagram are written back to the file so sessions survive restarts.
+type CookieJar struct {
+	mu      sync.Mutex
+	jar     *cookiejar.Jar
+	path    string                  // cookies.txt to persist to (empty keeps cookies in memory)
+	entries map[string]*http.Cookie // Full cookies by domain/path/name, kept for saving
+	timer   *time.Timer
+	logger  *slog.Logger
+}
+
+// NewCookieJar creates a jar, loading path when it is set
+func NewCookieJar(path string, logger *slog.Logger) (*CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &CookieJar{
+		jar:     jar,
+		path:    path,
+		entries: make(map[string]*http.Cookie),
+		logger:  logger,
+	}
+	if path == "" {
+		return j, nil
+	}
+
+	count, err := j.load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cookies file %s: %w", path, err)
+	}
+	logger.Info("Loaded cookies file", "path", path, "cookies", count)
+	return j, nil
+}
+
+// SetCookies implements http.CookieJar and schedules a save of the cookie file
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, cookie := range cookies {
+		stored := *cookie
+		if stored.Domain == "" {
+			stored.Domain = u.Hostname() // Host-only cookie
+		} else if !strings.HasPrefix(stored.Domain, ".") {
+			stored.Domain = "." + stored.Domain
+		}
+		if stored.Path == "" {
+			stored.Path = "/"
+		}
+		if stored.MaxAge > 0 {
+			stored.Expires = time.Now().Add(time.Duration(stored.MaxAge) * time.Second)
+		}
+
+		key := cookieKey(&stored)
+		if stored.MaxAge < 0 || (!stored.Expires.IsZero() && stored.Expires.Before(time.Now())) {
+			delete(j.entries, key)
+		} else {
+			j.entries[key] = &stored
+		}
+	}
+
+	if j.path != "" && j.timer == nil {
+		path := j.path
+		j.timer = time.AfterFunc(cookieSaveDelay, func() {
+			if err := j.Save(); err != nil {
+				j.logger.Warn("Failed to save cookies file", "path", path, "error", err)
+			}
+		})
+	}
+}
+
+// seed adds a cookie to the jar without persisting it to the cookie file
+func (j *CookieJar) seed(u *url.URL, cookie *http.Cookie) {
+	j.jar.SetCookies(u, []*http.Cookie{cookie})
+}
+
+// Cookies implements http.CookieJar
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Save writes the current cookies to the cookie file atomically
+func (j *CookieJar) Save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.timer != nil {
+		j.timer.Stop()
+		j.timer = nil
+	}
+	if j.path == "" {
+		return nil
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(j.path), ".cookies-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name()) // No-op after a successful rename
+
+	writer := bufio.NewWriter(temp)
+	fmt.Fprintln(writer, "# Netscape HTTP Cookie File")
+	fmt.Fprintln(writer, "# Written by qwiklip; edits are overwritten when Instagram refreshes cookies.")
+	for _, cookie := range j.entries {
+		writer.WriteString(formatCookieLine(cookie))
+	}
+
+	if err := writer.Flush(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Chmod(0o600); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), j.path)
+}
+
+// detach stops the jar persisting to its cookies file, which now belongs to the jar that
+// replaced it. A pending save is dropped with it.
+func (j *CookieJar) detach() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.timer != nil {
+		j.timer.Stop()
+		j.timer = nil
+	}
+	j.path = ""
+}
+
+// load reads the cookie file into the jar and returns the number of cookies loaded
+func (j *CookieJar) load() (int, error) {
+	file, err := os.Open(j.path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		cookie, err := parseCookieLine(scanner.Text())
+		if err != nil {
+			return count, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if cookie == nil || (!cookie.Expires.IsZero() && cookie.Expires.Before(time.Now())) {
+			continue
+		}
+
+		u := &url.URL{Scheme: "https", Host: strings.TrimPrefix(cookie.Domain, "."), Path: cookie.Path}
+		jarCookie := *cookie
+		if !strings.HasPrefix(cookie.Domain, ".") {
+			jarCookie.Domain = "" // Host-only cookie
+		}
+		j.jar.SetCookies(u, []*http.Cookie{&jarCookie})
+		j.entries[cookieKey(cookie)] = cookie
+		count++
+	}
+	return count, scanner.Err()
+}
+
+// parseCookieLine parses one cookies.txt line; comments and blank lines return nil
+func parseCookieLine(line string) (*http.Cookie, error) {
+	httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+	line = strings.TrimPrefix(line, httpOnlyPrefix)
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+
+	fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+	if len(fields) != 7 {
+		return nil, fmt.Errorf("expected 7 tab-separated fields, got %d", len(fields))
+	}
+
+	expiry, err := strconv.ParseInt(fields[4], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiry '%s'", fields[4])
+	}
+
+	domain := fields[0]
+	if strings.EqualFold(fields[1], "TRUE") && !strings.HasPrefix(domain, ".") {
+		domain = "." + domain
+	}
+
+	cookie := &http.Cookie{
+		Domain:   domain,
+		Path:     fields[2],
+		Secure:   strings.EqualFold(fields[3], "TRUE"),
+		Name:     fields[5],
+		Value:    fields[6],
+		HttpOnly: httpOnly,
+	}
+	if expiry > 0 {
+		cookie.Expires = time.Unix(expiry, 0)
+	}
+	return cookie, nil
+}
+
+// formatCookieLine renders a cookie in Netscape cookies.txt format
+func formatCookieLine(cookie *http.Cookie) string {
+	prefix := ""
+	if cookie.HttpOnly {
+		prefix = httpOnlyPrefix
+	}
+	var expiry int64
+	if !cookie.Expires.IsZero() {
+		expiry = cookie.Expires.Unix()
+	}
+	return fmt.Sprintf("%s%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+		prefix,
+		cookie.Domain,
+		netscapeBool(strings.HasPrefix(cookie.Domain, ".")),
+		cookie.Path,
+		netscapeBool(cookie.Secure),
+		expiry,
+		cookie.Name,
+		cookie.Value)
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// cookieKey identifies a cookie the way browsers do: by domain, path and name
+func cookieKey(cookie *http.Cookie) string {
+	return strings.ToLower(cookie.Domain) + ";" + cookie.Path + ";" + cookie.Name
+}
@@ -0,0 +1,89 @@
+package instagram
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"qwiklip/internal/models"
+)
+
+// dashManifest is the subset of an MPD (video_dash_manifest) needed to pick representations
+type dashManifest struct {
+	Periods []struct {
+		AdaptationSets []dashAdaptationSet `xml:"AdaptationSet"`
+	} `xml:"Period"`
+}
+
+type dashAdaptationSet struct {
+	ContentType     string               `xml:"contentType,attr"`
+	MimeType        string               `xml:"mimeType,attr"`
+	Representations []dashRepresentation `xml:"Representation"`
+}
+
+type dashRepresentation struct {
+	MimeType  string `xml:"mimeType,attr"`
+	Width     int    `xml:"width,attr"`
+	Height    int    `xml:"height,attr"`
+	Bandwidth int    `xml:"bandwidth,attr"`
+	BaseURL   string `xml:"BaseURL"`
+}
+
+// kind returns "video" or "audio" from the contentType or mimeType of the set or representation
+func (rep dashRepresentation) kind(set dashAdaptationSet) string {
+	for _, value := range []string{set.ContentType, set.MimeType, rep.MimeType} {
+		if kind, _, _ := strings.Cut(value, "/"); kind == "video" || kind == "audio" {
+			return kind
+		}
+	}
+	return ""
+}
+
+// parseDASHManifest picks the highest resolution video and highest bitrate audio
+// representation from an MPD. Representations without an absolute BaseURL are skipped,
+// since Instagram manifests always point straight at the CDN.
+func parseDASHManifest(manifest string) (*models.DASHStreams, error) {
+	var mpd dashManifest
+	if err := xml.Unmarshal([]byte(manifest), &mpd); err != nil {
+		return nil, fmt.Errorf("failed to parse DASH manifest: %w", err)
+	}
+
+	var video, audio *dashRepresentation
+	for _, period := range mpd.Periods {
+		for _, set := range period.AdaptationSets {
+			for i := range set.Representations {
+				rep := &set.Representations[i]
+				rep.BaseURL = strings.TrimSpace(rep.BaseURL)
+				if u, err := url.Parse(rep.BaseURL); err != nil || !u.IsAbs() {
+					continue
+				}
+				switch rep.kind(set) {
+				case "video":
+					if video == nil || rep.Height > video.Height || (rep.Height == video.Height && rep.Bandwidth > video.Bandwidth) {
+						video = rep
+					}
+				case "audio":
+					if audio == nil || rep.Bandwidth > audio.Bandwidth {
+						audio = rep
+					}
+				}
+			}
+		}
+	}
+	if video == nil {
+		return nil, fmt.Errorf("DASH manifest has no video representation")
+	}
+
+	streams := &models.DASHStreams{
+		VideoURL:  video.BaseURL,
+		Width:     video.Width,
+		Height:    video.Height,
+		Bandwidth: video.Bandwidth,
+	}
+	if audio != nil {
+		streams.AudioURL = audio.BaseURL
+		streams.Bandwidth += audio.Bandwidth
+	}
+	return streams, nil
+}
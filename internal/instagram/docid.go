@@ -0,0 +1,139 @@
+package instagram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"qwiklip/internal/models"
+)
+
+// graphQLQueryURL is the endpoint the Instagram web app posts persisted queries to
+const graphQLQueryURL = "https://www.instagram.com/graphql/query"
+
+// DefaultGraphQLDocID is the doc_id of the web app's post query (PolarisPostActionLoadPostQueryQuery)
+// when this release was made
+const DefaultGraphQLDocID = "8845758582119845"
+
+// maxGraphQLResponseBytes bounds the query response read into memory
+const maxGraphQLResponseBytes = 8 * 1024 * 1024
+
+// graphQLPostResponse is the part of a post query response that is read
+type graphQLPostResponse struct {
+	Data struct {
+		Media map[string]interface{} `json:"xdt_shortcode_media"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+	Status        string `json:"status"`
+	Message       string `json:"message"`
+	RequireLogin  bool   `json:"require_login"`
+	SpamDetection bool   `json:"spam"`
+}
+
+// extractFromGraphQL runs the web app's post query (a persisted query named by its doc_id)
+// and parses the shortcode media it returns. Meta rotates doc_ids, so it is configurable.
+func (c *Client) extractFromGraphQL(ctx context.Context, httpClient *http.Client, shortcode string) (*models.InstagramMediaInfo, error) {
+	variables, err := json.Marshal(map[string]interface{}{
+		"shortcode":               shortcode,
+		"fetch_tagged_user_count": nil,
+		"hoisted_comment_id":      nil,
+		"hoisted_reply_id":        nil,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode GraphQL variables: %w", err)
+	}
+	docID := c.config.GraphQLDocID
+	if docID == "" {
+		docID = DefaultGraphQLDocID
+	}
+	form := url.Values{"doc_id": {docID}, "variables": {string(variables)}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, graphQLQueryURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GraphQL request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+	req.Header.Set("Origin", "https://www.instagram.com")
+	req.Header.Set("Referer", fmt.Sprintf("https://www.instagram.com/p/%s/", shortcode))
+	req.Header.Set("X-IG-App-ID", webAppID)
+	req.Header.Set("X-FB-Friendly-Name", "PolarisPostActionLoadPostQueryQuery")
+	req.Header.Set("sec-fetch-dest", "empty")
+	req.Header.Set("sec-fetch-mode", "cors")
+	req.Header.Set("sec-fetch-site", "same-origin")
+	if token := csrfToken(httpClient); token != "" {
+		req.Header.Set("X-CSRFToken", token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if shed := shedByScheduler(err); shed != nil {
+			return nil, shed
+		}
+		return nil, models.NewNetworkError("Instagram GraphQL query", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, models.NewAuthenticationError("Instagram refused the GraphQL query")
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, models.NewNotFoundError("Instagram GraphQL endpoint")
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, models.NewRateLimitedError(retryAfterSeconds(resp.Header.Get("Retry-After")))
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, models.NewNetworkError("Instagram GraphQL query", fmt.Errorf("HTTP %d", resp.StatusCode))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxGraphQLResponseBytes))
+	if err != nil {
+		return nil, models.NewNetworkError("reading Instagram GraphQL response", err)
+	}
+	// Logged-out clients are sometimes sent the login page instead of JSON
+	if strings.HasPrefix(strings.TrimSpace(string(body)), "<") {
+		return nil, models.NewAuthenticationError("Instagram answered the GraphQL query with a login page")
+	}
+
+	var response graphQLPostResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, models.NewParsingError("Instagram GraphQL response", err)
+	}
+	switch {
+	case response.RequireLogin || response.SpamDetection:
+		return nil, models.NewAuthenticationError("Instagram requires a login for the GraphQL query")
+	case len(response.Errors) > 0:
+		// An outdated doc_id typically ends up here
+		return nil, models.NewExtractionError(shortcode, fmt.Errorf("GraphQL query failed (doc_id %s): %s", docID, response.Errors[0].Message))
+	case response.Status == "fail":
+		return nil, models.NewExtractionError(shortcode, fmt.Errorf("GraphQL query failed (doc_id %s): %s", docID, response.Message))
+	case response.Data.Media == nil:
+		return nil, models.NewNotFoundError(fmt.Sprintf("Instagram content with shortcode '%s'", shortcode))
+	}
+
+	// xdt_shortcode_media has the shape of the classic shortcode_media
+	return c.parseMediaInfo(map[string]interface{}{"graphql": map[string]interface{}{"shortcode_media": response.Data.Media}}, shortcode)
+}
+
+// csrfToken returns the csrftoken cookie httpClient holds for instagram.com, or ""
+func csrfToken(httpClient *http.Client) string {
+	if httpClient.Jar == nil {
+		return ""
+	}
+	for _, cookie := range httpClient.Jar.Cookies(&url.URL{Scheme: "https", Host: "www.instagram.com", Path: "/"}) {
+		if cookie.Name == "csrftoken" {
+			return cookie.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package instagram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+
+	"qwiklip/internal/models"
+)
+
+// maxEmbedPageBytes bounds the embed page read into memory
+const maxEmbedPageBytes = 4 * 1024 * 1024
+
+var (
+	// embedContextPattern matches the JSON-encoded post data of an embed page
+	embedContextPattern = regexp.MustCompile(`"contextJSON":"((?:[^"\\]|\\.)*)"`)
+	// embedImagePattern matches the image of an embedded photo
+	embedImagePattern = regexp.MustCompile(`<img[^>]+class="EmbeddedMediaImage"[^>]+src="([^"]+)"`)
+	// embedUsernamePattern matches the author shown in the embed header
+	embedUsernamePattern = regexp.MustCompile(`class="UsernameText"[^>]*>([^<]+)<`)
+	// embedVideoPattern matches the markers of an embedded video
+	embedVideoPattern = regexp.MustCompile(`"is_video":true|class="[^"]*EmbedIsVideo|GraphVideo`)
+)
+
+// extractFromEmbed reads the post from its embed page
+func (c *Client) extractFromEmbed(ctx context.Context, httpClient *http.Client, shortcode string) (*models.InstagramMediaInfo, error) {
+	page, err := c.fetchEmbedPage(ctx, httpClient, shortcode)
+	if err != nil {
+		return nil, err
+	}
+	return c.parseEmbedPage(page, shortcode)
+}
+
+// fetchEmbedPage fetches /p/{shortcode}/embed/captioned/, which Instagram serves to
+// anonymous visitors far more often than the post page
+func (c *Client) fetchEmbedPage(ctx context.Context, httpClient *http.Client, shortcode string) (string, error) {
+	embedURL := fmt.Sprintf("https://www.instagram.com/p/%s/embed/captioned/", shortcode)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, embedURL, nil)
+	if err != nil {
+		return "", models.NewInvalidURLError(embedURL, err)
+	}
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+	req.Header.Set("Referer", "https://www.instagram.com/")
+	req.Header.Set("sec-fetch-dest", "iframe")
+	req.Header.Set("sec-fetch-mode", "navigate")
+	req.Header.Set("sec-fetch-site", "cross-site")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		if shed := shedByScheduler(err); shed != nil {
+			return "", shed
+		}
+		return "", models.NewNetworkError("Instagram embed fetch", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", models.NewNotFoundError(fmt.Sprintf("embed of Instagram content with shortcode '%s'", shortcode))
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "", models.NewRateLimitedError(retryAfterSeconds(resp.Header.Get("Retry-After")))
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return "", models.NewNetworkError("Instagram embed fetch", fmt.Errorf("HTTP %d", resp.StatusCode))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxEmbedPageBytes))
+	if err != nil {
+		return "", models.NewNetworkError("Instagram embed read", err)
+	}
+	c.logger.Debug("Embed page fetched", "shortcode", shortcode, "length", len(data))
+	return string(data), nil
+}
+
+// parseEmbedPage reads the post from an embed page: the GraphQL media in its contextJSON
+// when present, otherwise the photo and author in the markup
+func (c *Client) parseEmbedPage(page, shortcode string) (*models.InstagramMediaInfo, error) {
+	if match := embedContextPattern.FindStringSubmatch(page); match != nil {
+		var contextJSON string
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(`"`+match[1]+`"`), &contextJSON); err != nil {
+			c.logger.Debug("Ignoring undecodable embed contextJSON", "error", err)
+		} else if err := json.Unmarshal([]byte(contextJSON), &data); err != nil {
+			c.logger.Debug("Ignoring malformed embed contextJSON", "error", err)
+		} else if gqlData, ok := data["gql_data"].(map[string]interface{}); ok {
+			// gql_data carries shortcode_media like the direct API response
+			if mediaInfo, err := c.parseMediaInfo(map[string]interface{}{"graphql": gqlData}, shortcode); err == nil {
+				return mediaInfo, nil
+			}
+		}
+	}
+
+	if embedVideoPattern.MatchString(page) {
+		return nil, models.NewExtractionError(shortcode, fmt.Errorf("embed page shows a video but carries no video URL"))
+	}
+	match := embedImagePattern.FindStringSubmatch(page)
+	if match == nil {
+		return nil, models.NewNotFoundError(fmt.Sprintf("media in embed of Instagram content with shortcode '%s'", shortcode))
+	}
+
+	mediaInfo := &models.InstagramMediaInfo{
+		ImageURL: html.UnescapeString(match[1]),
+		FileName: fmt.Sprintf("%s.jpg", shortcode),
+	}
+	if match := embedUsernamePattern.FindStringSubmatch(page); match != nil {
+		mediaInfo.Username = html.UnescapeString(match[1])
+	}
+	return mediaInfo, nil
+}
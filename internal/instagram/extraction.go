@@ -119,6 +119,7 @@ func (c *Client) unescapeURL(urlStr string) string {
 		`\u003F`: "?",
 		`\u003D`: "=",
 		`\u0026`: "&",
+		"&amp;":  "&", // HTML attributes such as og:video
 		`\`:      "",
 	}
 
@@ -156,15 +157,24 @@ func (c *Client) extractFallbackVideoURL(html string, shortcode string) (string,
 		}
 	}
 
+	c.logger.Error("All fallback video URL patterns failed")
+	return "", models.NewNotFoundError(fmt.Sprintf("Instagram content with shortcode '%s'", shortcode))
+}
+
+// preloaderMarkerPattern matches the key of the PolarisPostRootQueryRelayPreloader payload,
+// which is followed by the payload object
+var preloaderMarkerPattern = regexp.MustCompile(`PolarisPostRootQueryRelayPreloader_[^"]*",`)
+
+// extractPreloaderVideoURL reads the video URL from the PolarisPostRootQueryRelayPreloader payload
+func (c *Client) extractPreloaderVideoURL(html string, shortcode string) (string, error) {
 	// Try PolarisPostRootQueryRelayPreloader extraction (from TypeScript)
 	c.logger.Debug("Trying PolarisPostRootQueryRelayPreloader extraction")
-	preloaderPattern := `PolarisPostRootQueryRelayPreloader_[^"]+",(\{"__bbox":\{"complete":true,"result":\{"data":\{"xdt_api__v1__media__shortcode__web_info":\{"items":\[\{[^\}]+\}\]\}\}\}\}\})`
-	preloaderRe := regexp.MustCompile(preloaderPattern)
-	preloaderMatches := preloaderRe.FindStringSubmatch(html)
 
-	if len(preloaderMatches) > 1 {
+	// The payload nests objects in arrays, which a regular expression cannot delimit, so
+	// the object after the marker is decoded up to its closing brace
+	if loc := preloaderMarkerPattern.FindStringIndex(html); loc != nil {
 		var preloaderData map[string]interface{}
-		if err := json.Unmarshal([]byte(preloaderMatches[1]), &preloaderData); err == nil {
+		if err := json.NewDecoder(strings.NewReader(html[loc[1]:])).Decode(&preloaderData); err == nil {
 			// Navigate through the nested structure
 			if bbox, ok := preloaderData["__bbox"].(map[string]interface{}); ok {
 				if result, ok := bbox["result"].(map[string]interface{}); ok {
@@ -191,10 +201,31 @@ func (c *Client) extractFallbackVideoURL(html string, shortcode string) (string,
 		}
 	}
 
-	c.logger.Error("All fallback video URL patterns failed")
+	c.logger.Error("PolarisPostRootQueryRelayPreloader extraction failed")
 	return "", models.NewNotFoundError(fmt.Sprintf("Instagram content with shortcode '%s'", shortcode))
 }
 
+// extractOpenGraphImage returns the og:image of a photo post.
+// Video pages also carry og:image (the thumbnail), so pages announcing a video are rejected.
+func (c *Client) extractOpenGraphImage(html string, shortcode string) (string, error) {
+	c.logger.Debug("Trying Open Graph image extraction")
+
+	if regexp.MustCompile(`property="og:(video|type" content="video)`).MatchString(html) {
+		c.logger.Debug("Page announces a video, skipping Open Graph image")
+		return "", models.NewNotFoundError(fmt.Sprintf("image for Instagram content with shortcode '%s'", shortcode))
+	}
+
+	matches := regexp.MustCompile(`property="og:image" content="(https://[^"]+)"`).FindStringSubmatch(html)
+	if len(matches) < 2 {
+		c.logger.Error("Open Graph image not found")
+		return "", models.NewNotFoundError(fmt.Sprintf("image for Instagram content with shortcode '%s'", shortcode))
+	}
+
+	imageURL := strings.ReplaceAll(matches[1], "&amp;", "&")
+	c.logger.Info("Found image URL in Open Graph tags", "url_prefix", imageURL[:min(100, len(imageURL))])
+	return imageURL, nil
+}
+
 // parseMediaInfo parses the JSON data to extract media information
 func (c *Client) parseMediaInfo(jsonData map[string]interface{}, shortcode string) (*models.InstagramMediaInfo, error) {
 	c.logger.Debug("Starting JSON parsing", "shortcode", shortcode)
@@ -203,20 +234,45 @@ func (c *Client) parseMediaInfo(jsonData map[string]interface{}, shortcode strin
 		FileName: fmt.Sprintf("%s.mp4", shortcode),
 	}
 
+	// Carousel posts list their children; the first video doubles as the post's video
+	media := c.findMedia(jsonData)
+	if media != nil {
+		mediaInfo.Items = c.extractCarouselItems(media)
+		if len(mediaInfo.Items) > 0 {
+			c.logger.Info("Found carousel post", "items", len(mediaInfo.Items))
+		}
+	}
+
 	// Try different JSON structures to find the video URL
 	c.logger.Debug("Searching for video URL in JSON data")
 	videoURL := c.findVideoURL(jsonData, shortcode)
 	if videoURL == "" {
-		c.logger.Error("No video URL found in any JSON structure")
+		for _, item := range mediaInfo.Items {
+			if item.IsVideo && item.VideoURL != "" {
+				videoURL = item.VideoURL
+				break
+			}
+		}
+	}
+	if videoURL != "" {
+		c.logger.Info("Found video URL in JSON data")
+		mediaInfo.VideoURL = videoURL
+	} else if imageURL := c.findImageURL(media, mediaInfo.Items); imageURL != "" {
+		// Photo posts have no video; proxy the full-size image instead
+		c.logger.Info("Found photo post in JSON data")
+		mediaInfo.ImageURL = imageURL
+		mediaInfo.FileName = fmt.Sprintf("%s.jpg", shortcode)
+	} else {
+		c.logger.Error("No video or image URL found in any JSON structure")
 		return nil, models.NewExtractionError(shortcode, fmt.Errorf("could not find video URL in Instagram response"))
 	}
 
-	c.logger.Info("Found video URL in JSON data")
-	mediaInfo.VideoURL = videoURL
-
 	// Try to extract additional metadata
 	c.logger.Debug("Extracting additional metadata")
 	c.extractMetadata(jsonData, mediaInfo)
+	if media != nil {
+		c.extractMediaDetails(media, mediaInfo)
+	}
 
 	if mediaInfo.Username != "" || mediaInfo.Caption != "" {
 		c.logger.Debug("Metadata extracted",
@@ -0,0 +1,327 @@
+package instagram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/models"
+	"qwiklip/internal/tracing"
+)
+
+// Built-in extraction strategy names, tried in this order by default
+const (
+	ExtractorJSON      = "json"      // Embedded JSON blobs parsed for the video URL and metadata
+	ExtractorDirect    = "direct"    // Direct video URL patterns in the HTML
+	ExtractorFallback  = "fallback"  // Looser, case-insensitive video URL patterns
+	ExtractorPreloader = "preloader" // PolarisPostRootQueryRelayPreloader payload
+	ExtractorImage     = "image"     // og:image of photo posts
+	ExtractorEmbed     = "embed"     // /p/{shortcode}/embed/captioned/, often served without login
+	ExtractorGraphQL   = "graphql"   // Web GraphQL query by doc_id, as the Instagram web app sends it
+)
+
+// requestExtractor is a strategy that makes its own request instead of reading the post
+// page. Request strategies run after the page extractors, in their configured (or adaptive)
+// order, wherever they are listed.
+type requestExtractor struct {
+	name    string
+	extract func(ctx context.Context, httpClient *http.Client, shortcode string) (*models.InstagramMediaInfo, error)
+}
+
+// splitExtractors separates the request strategies from the page extractor names. Without
+// any names every strategy is enabled.
+func (c *Client) splitExtractors(names []string) (page []string, requests []requestExtractor) {
+	available := c.builtinRequestExtractors()
+	if len(names) == 0 {
+		return nil, available
+	}
+	for _, name := range names {
+		if i := slices.IndexFunc(available, func(r requestExtractor) bool { return strings.EqualFold(r.name, name) }); i >= 0 {
+			requests = append(requests, available[i])
+			continue
+		}
+		page = append(page, name)
+	}
+	return page, requests
+}
+
+// builtinRequestExtractors returns the built-in request strategies in their default order
+func (c *Client) builtinRequestExtractors() []requestExtractor {
+	return []requestExtractor{
+		{ExtractorEmbed, c.extractFromEmbed},
+		{ExtractorGraphQL, c.extractFromGraphQL},
+	}
+}
+
+// requestFallback runs the request strategies in the ranker's order after the post page failed with err and
+// returns the first result. When none finds the post, err is returned as the more telling one,
+// except for rate limits and an exhausted request budget, which the caller should act on.
+func (c *Client) requestFallback(ctx context.Context, httpClient *http.Client, shortcode string, err error) (*models.InstagramMediaInfo, error) {
+	c.logger.Info("Post page failed, trying request strategies", "shortcode", shortcode, "error", err)
+
+	names := make([]string, len(c.requestExtractors))
+	for i, extractor := range c.requestExtractors {
+		names[i] = extractor.name
+	}
+	for _, i := range c.ranker.order(names) {
+		extractor := c.requestExtractors[i]
+		mediaInfo, strategyErr := c.runStrategy(ctx, extractor.name, shortcode, func(ctx context.Context) (*models.InstagramMediaInfo, error) {
+			return extractor.extract(ctx, httpClient, shortcode)
+		})
+		if strategyErr == nil {
+			return mediaInfo, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		var appErr *models.AppError
+		if errors.As(strategyErr, &appErr) && (appErr.Type == models.ErrorTypeRateLimited || appErr.Type == models.ErrorTypeOverloaded) {
+			return nil, strategyErr
+		}
+	}
+	return nil, err
+}
+
+// requestFallbackApplies reports whether request strategies may succeed after the post page
+// failed with err: pages without usable data and login walls, but not network trouble,
+// rate limits or posts Instagram reported missing
+func requestFallbackApplies(err error, fetched bool) bool {
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) {
+		return false
+	}
+	if fetched {
+		return appErr.Type != models.ErrorTypeRateLimited && appErr.Type != models.ErrorTypeNetwork
+	}
+	return appErr.Type == models.ErrorTypeAuthentication
+}
+
+// Extractor is one strategy for finding media information in a fetched Instagram page
+type Extractor interface {
+	// Name identifies the strategy in configuration and logs
+	Name() string
+	// Extract returns the media info found in html, or an error when the strategy does not apply
+	Extract(html string, shortcode string) (*models.InstagramMediaInfo, error)
+}
+
+// extractorRegistry tries its extractors in order until one succeeds
+type extractorRegistry struct {
+	extractors []Extractor
+	ranker     *strategyRanker
+	metrics    metrics.Recorder
+	logger     *slog.Logger
+}
+
+// newExtractorRegistry selects the named extractors from available, keeping the given order.
+// An empty name list enables every available extractor in its default order.
+func newExtractorRegistry(available []Extractor, names []string, ranker *strategyRanker, recorder metrics.Recorder, logger *slog.Logger) (*extractorRegistry, error) {
+	registry := &extractorRegistry{ranker: ranker, metrics: recorder, logger: logger}
+	if len(names) == 0 {
+		registry.extractors = available
+		return registry, nil
+	}
+
+	for _, name := range names {
+		extractor := findExtractor(available, name)
+		if extractor == nil {
+			return nil, fmt.Errorf("unknown extractor '%s'", name)
+		}
+		registry.extractors = append(registry.extractors, extractor)
+	}
+	return registry, nil
+}
+
+// findExtractor returns the extractor with the given name, or nil
+func findExtractor(extractors []Extractor, name string) Extractor {
+	for _, extractor := range extractors {
+		if strings.EqualFold(extractor.Name(), name) {
+			return extractor
+		}
+	}
+	return nil
+}
+
+// register appends an extractor, replacing any existing one with the same name
+func (r *extractorRegistry) register(extractor Extractor) {
+	for i, existing := range r.extractors {
+		if strings.EqualFold(existing.Name(), extractor.Name()) {
+			r.extractors[i] = extractor
+			return
+		}
+	}
+	r.extractors = append(r.extractors, extractor)
+}
+
+// names lists the enabled extractors in order
+func (r *extractorRegistry) names() []string {
+	names := make([]string, len(r.extractors))
+	for i, extractor := range r.extractors {
+		names[i] = extractor.Name()
+	}
+	return names
+}
+
+// extract runs each extractor in order, or in the ranker's order when adaptive, and returns
+// the first result. If all fail, the most specific error wins: anything other than not-found
+// (e.g. a page that had JSON but no video URL) is reported over a plain miss.
+func (r *extractorRegistry) extract(ctx context.Context, html string, shortcode string) (*models.InstagramMediaInfo, error) {
+	var lastErr error
+	for _, i := range r.ranker.order(r.names()) {
+		extractor := r.extractors[i]
+		r.logger.Debug("Trying extractor", "extractor", extractor.Name(), "shortcode", shortcode)
+
+		_, span := tracing.Start(ctx, "instagram.extractor."+extractor.Name(), tracing.KindInternal)
+		start := time.Now()
+		mediaInfo, err := extractor.Extract(html, shortcode)
+		r.ranker.record(extractor.Name(), err, time.Since(start))
+		span.RecordError(err)
+		span.End()
+		observe(ctx, ExtractionEvent{Stage: StageStrategy, Shortcode: shortcode, Strategy: extractor.Name(), Err: err})
+		if err == nil {
+			r.metrics.Count(metrics.ExtractorAttempts, 1, "extractor", extractor.Name(), "result", "success")
+			r.logger.Info("Extractor succeeded", "extractor", extractor.Name(), "shortcode", shortcode)
+			return mediaInfo, nil
+		}
+
+		r.metrics.Count(metrics.ExtractorAttempts, 1, "extractor", extractor.Name(), "result", "failure")
+		r.logger.Warn("Extractor failed", "extractor", extractor.Name(), "error", err)
+		if lastErr == nil || isNotFound(lastErr) {
+			lastErr = &strategyError{strategy: extractor.Name(), err: err}
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = models.NewExtractionError(shortcode, errors.New("no extractors enabled"))
+	}
+	return nil, lastErr
+}
+
+// runStrategy runs a strategy that works outside the page registry, such as a fallback
+// with its own request, with the same tracing, extraction events, metrics and logs
+func (c *Client) runStrategy(ctx context.Context, name, shortcode string, run func(context.Context) (*models.InstagramMediaInfo, error)) (*models.InstagramMediaInfo, error) {
+	ctx, span := tracing.Start(ctx, "instagram.extractor."+name, tracing.KindInternal)
+	start := time.Now()
+	mediaInfo, err := run(ctx)
+	if ctx.Err() == nil {
+		c.ranker.record(name, err, time.Since(start))
+	}
+	span.RecordError(err)
+	span.End()
+	observe(ctx, ExtractionEvent{Stage: StageStrategy, Shortcode: shortcode, Strategy: name, Err: err})
+
+	if err != nil {
+		c.metrics.Count(metrics.ExtractorAttempts, 1, "extractor", name, "result", "failure")
+		c.logger.Warn("Extractor failed", "extractor", name, "error", err)
+		return nil, err
+	}
+	c.metrics.Count(metrics.ExtractorAttempts, 1, "extractor", name, "result", "success")
+	c.logger.Info("Extractor succeeded", "extractor", name, "shortcode", shortcode)
+	return mediaInfo, nil
+}
+
+// strategyError records which extractor produced the error returned by extract.
+// It is transparent to errors.As and keeps the original message.
+type strategyError struct {
+	strategy string
+	err      error
+}
+
+func (e *strategyError) Error() string { return e.err.Error() }
+func (e *strategyError) Unwrap() error { return e.err }
+
+// failedStrategy returns the extractor name recorded in err, or "" if none
+func failedStrategy(err error) string {
+	var se *strategyError
+	if errors.As(err, &se) {
+		return se.strategy
+	}
+	return ""
+}
+
+// isNotFound reports whether err is a not-found AppError
+func isNotFound(err error) bool {
+	var appErr *models.AppError
+	return errors.As(err, &appErr) && appErr.Type == models.ErrorTypeNotFound
+}
+
+// RegisterExtractor adds a custom extraction strategy after the configured ones.
+// An extractor with the same name as an existing one replaces it in place.
+func (c *Client) RegisterExtractor(extractor Extractor) {
+	c.extractors.register(extractor)
+}
+
+// Extractors returns the names of the enabled extraction strategies in their configured order,
+// which is the order they are tried unless adaptive ordering is enabled
+func (c *Client) Extractors() []string {
+	names := c.extractors.names()
+	for _, extractor := range c.requestExtractors {
+		names = append(names, extractor.name)
+	}
+	return names
+}
+
+// builtinExtractors returns the built-in strategies in their default order
+func (c *Client) builtinExtractors() []Extractor {
+	return []Extractor{
+		extractorFunc{ExtractorJSON, c.extractFromJSON},
+		extractorFunc{ExtractorDirect, c.videoURLExtractor(func(html, _ string) (string, error) {
+			return c.extractDirectVideoURL(html)
+		})},
+		extractorFunc{ExtractorFallback, c.videoURLExtractor(c.extractFallbackVideoURL)},
+		extractorFunc{ExtractorPreloader, c.videoURLExtractor(c.extractPreloaderVideoURL)},
+		extractorFunc{ExtractorImage, c.extractImage},
+	}
+}
+
+// extractFromJSON parses embedded JSON data for the video URL and metadata
+func (c *Client) extractFromJSON(html string, shortcode string) (*models.InstagramMediaInfo, error) {
+	jsonData, err := c.extractJSONData(html, shortcode)
+	if err != nil {
+		return nil, err
+	}
+	return c.parseMediaInfo(jsonData, shortcode)
+}
+
+// extractImage proxies the Open Graph image of photo posts
+func (c *Client) extractImage(html string, shortcode string) (*models.InstagramMediaInfo, error) {
+	imageURL, err := c.extractOpenGraphImage(html, shortcode)
+	if err != nil {
+		return nil, err
+	}
+	return &models.InstagramMediaInfo{
+		ImageURL: imageURL,
+		FileName: fmt.Sprintf("%s.jpg", shortcode),
+	}, nil
+}
+
+// videoURLExtractor adapts a URL-only pattern matcher into an extraction function
+func (c *Client) videoURLExtractor(find func(html, shortcode string) (string, error)) func(string, string) (*models.InstagramMediaInfo, error) {
+	return func(html, shortcode string) (*models.InstagramMediaInfo, error) {
+		videoURL, err := find(html, shortcode)
+		if err != nil {
+			return nil, err
+		}
+		return &models.InstagramMediaInfo{
+			VideoURL: videoURL,
+			FileName: fmt.Sprintf("%s.mp4", shortcode),
+		}, nil
+	}
+}
+
+// extractorFunc adapts a function into an Extractor
+type extractorFunc struct {
+	name string
+	fn   func(html string, shortcode string) (*models.InstagramMediaInfo, error)
+}
+
+func (e extractorFunc) Name() string { return e.name }
+
+func (e extractorFunc) Extract(html string, shortcode string) (*models.InstagramMediaInfo, error) {
+	return e.fn(html, shortcode)
+}
@@ -0,0 +1,62 @@
+package instagram
+
+import (
+	"net/http"
+	"strings"
+
+	"qwiklip/internal/config"
+)
+
+// headerTransport sets the operator's extra headers (INSTAGRAM_EXTRA_HEADERS and
+// CDN_EXTRA_HEADERS) on requests to Instagram and its media CDN, replacing the headers
+// the client sends. A Cookie entry is added to the cookies of the jar instead.
+type headerTransport struct {
+	next      http.RoundTripper
+	instagram http.Header
+	cdn       http.Header
+}
+
+// newHeaderTransport wraps next with the extra headers of cfg, or returns next when none are set
+func newHeaderTransport(next http.RoundTripper, cfg *config.InstagramConfig) http.RoundTripper {
+	if len(cfg.ExtraHeaders) == 0 && len(cfg.CDNHeaders) == 0 {
+		return next
+	}
+	return &headerTransport{next: next, instagram: toHeader(cfg.ExtraHeaders), cdn: toHeader(cfg.CDNHeaders)}
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	extra := t.cdn
+	if isInstagramHost(req) {
+		extra = t.instagram
+	} else if !isCDNHost(req.URL.Hostname()) {
+		return t.next.RoundTrip(req)
+	}
+	if len(extra) == 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+	for name, values := range extra {
+		if name == "Cookie" && req.Header.Get("Cookie") != "" {
+			req.Header.Set("Cookie", req.Header.Get("Cookie")+"; "+values[0])
+			continue
+		}
+		req.Header[name] = values
+	}
+	return t.next.RoundTrip(req)
+}
+
+// toHeader converts configured headers to canonical names
+func toHeader(headers map[string]string) http.Header {
+	header := make(http.Header, len(headers))
+	for name, value := range headers {
+		header[http.CanonicalHeaderKey(name)] = []string{value}
+	}
+	return header
+}
+
+// isCDNHost reports whether host serves Instagram media
+func isCDNHost(host string) bool {
+	return strings.HasSuffix(host, ".cdninstagram.com") || strings.HasSuffix(host, ".fbcdn.net")
+}
@@ -0,0 +1,220 @@
+package instagram
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"qwiklip/internal/config"
+	"qwiklip/internal/models"
+)
+
+// ExtractorHeadless names the headless browser fallback in logs, metrics and extraction events
+const ExtractorHeadless = "headless"
+
+// chromeNames are tried on PATH when HEADLESS_CHROME_PATH is empty
+var chromeNames = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"}
+
+// netLogURLPattern matches the (JSON-escaped) URLs of requests in a Chrome NetLog
+var netLogURLPattern = regexp.MustCompile(`"url":"((?:[^"\\]|\\.)*)"`)
+
+// headlessBrowser renders post pages in headless Chrome and sniffs the video request
+// from its network log. It runs Instagram's JavaScript, so it still works when the
+// served HTML carries no media data, at the cost of a browser process per extraction.
+type headlessBrowser struct {
+	path    string
+	proxy   string
+	timeout time.Duration
+	slots   chan struct{} // Bounds concurrent browser processes
+	logger  *slog.Logger
+}
+
+// detectHeadlessBrowser finds Chrome for the headless fallback. It returns nil when the
+// fallback is disabled, or when no browser is installed, which is logged.
+func detectHeadlessBrowser(cfg *config.InstagramConfig, logger *slog.Logger) *headlessBrowser {
+	if !cfg.HeadlessFallback {
+		return nil
+	}
+
+	names := chromeNames
+	if cfg.HeadlessChromePath != "" {
+		names = []string{cfg.HeadlessChromePath}
+	}
+	for _, name := range names {
+		chromePath, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		logger.Info("Headless browser fallback enabled", "path", chromePath, "max_procs", cfg.HeadlessMaxProcs)
+		return &headlessBrowser{
+			path:    chromePath,
+			proxy:   chromeProxy(cfg.ProxyURL),
+			timeout: cfg.HeadlessTimeout,
+			slots:   make(chan struct{}, cfg.HeadlessMaxProcs),
+			logger:  logger,
+		}
+	}
+	logger.Warn("Chrome not found, headless browser fallback disabled", "tried", strings.Join(names, ", "))
+	return nil
+}
+
+// chromeProxy converts OUTBOUND_PROXY_URL for --proxy-server, which does not know socks5h
+// (Chrome resolves names through SOCKS proxies anyway) and takes no credentials
+func chromeProxy(proxyURL string) string {
+	u, err := url.Parse(proxyURL)
+	if proxyURL == "" || err != nil {
+		return ""
+	}
+	if u.Scheme == "socks5h" {
+		u.Scheme = "socks5"
+	}
+	u.User = nil
+	return u.String()
+}
+
+// render loads the reel page of shortcode as userAgent and returns the first video the page requested
+func (b *headlessBrowser) render(ctx context.Context, shortcode, userAgent string) (*models.InstagramMediaInfo, error) {
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-b.slots }()
+
+	dir, err := os.MkdirTemp("", "qwiklip-headless-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create browser profile: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	netLog := filepath.Join(dir, "netlog.json")
+	args := []string{
+		"--headless=new",
+		"--disable-gpu",
+		"--mute-audio",
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--disable-extensions",
+		"--autoplay-policy=no-user-gesture-required",
+		"--user-data-dir=" + filepath.Join(dir, "profile"),
+		"--user-agent=" + userAgent,
+		"--log-net-log=" + netLog,
+		// Let the page run its scripts for most of the timeout, then dump the DOM and exit
+		fmt.Sprintf("--virtual-time-budget=%d", (b.timeout * 2 / 3).Milliseconds()),
+		"--dump-dom",
+	}
+	if os.Geteuid() == 0 {
+		args = append(args, "--no-sandbox") // Chrome refuses to sandbox as root, as in most containers
+	}
+	if b.proxy != "" {
+		args = append(args, "--proxy-server="+b.proxy)
+	}
+	args = append(args, fmt.Sprintf("https://www.instagram.com/reel/%s/", shortcode))
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, b.path, args...)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = 5 * time.Second
+
+	start := time.Now()
+	runErr := cmd.Run()
+	b.logger.Debug("Headless render finished", "shortcode", shortcode, "duration", time.Since(start), "error", runErr)
+
+	// Chrome writes the log as it goes, so even a render cut off by the timeout may have seen the video
+	data, err := os.ReadFile(netLog)
+	if err != nil && runErr == nil {
+		return nil, fmt.Errorf("failed to read browser network log: %w", err)
+	}
+	if videoURL := sniffVideoURL(data); videoURL != "" {
+		return &models.InstagramMediaInfo{VideoURL: videoURL, FileName: fmt.Sprintf("%s.mp4", shortcode)}, nil
+	}
+
+	switch {
+	case ctx.Err() != nil && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return nil, models.NewExtractionError(shortcode, fmt.Errorf("headless render timed out after %v", b.timeout))
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case runErr != nil:
+		return nil, fmt.Errorf("headless browser failed: %w: %s", runErr, lastLine(stderr.String()))
+	}
+	return nil, models.NewNotFoundError(fmt.Sprintf("video request while rendering '%s'", shortcode))
+}
+
+// sniffVideoURL returns the first MP4 video fetched from the Instagram CDN in a NetLog,
+// without the byte range parameters players add to it. Audio-only DASH renditions are skipped.
+func sniffVideoURL(netLog []byte) string {
+	for _, match := range netLogURLPattern.FindAllSubmatch(netLog, -1) {
+		raw, err := strconv.Unquote(`"` + string(match[1]) + `"`)
+		if err != nil {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme != "https" || path.Ext(u.Path) != ".mp4" {
+			continue
+		}
+		if !isCDNHost(u.Hostname()) {
+			continue
+		}
+
+		query := u.Query()
+		if isAudioRendition(query.Get("efg")) {
+			continue
+		}
+		query.Del("bytestart")
+		query.Del("byteend")
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return ""
+}
+
+// isAudioRendition decodes the efg parameter of a CDN URL, a base64 JSON tag naming the
+// rendition, e.g. {"vencode_tag":"dash_ln_heaac_vbr3_audio"}
+func isAudioRendition(efg string) bool {
+	for _, encoding := range []*base64.Encoding{base64.RawURLEncoding, base64.URLEncoding, base64.StdEncoding, base64.RawStdEncoding} {
+		if tag, err := encoding.DecodeString(efg); err == nil {
+			return strings.Contains(string(tag), "audio")
+		}
+	}
+	return false
+}
+
+// lastLine returns the last non-empty line of s, enough to say why a process failed
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return lines[len(lines)-1]
+}
+
+// headlessFallback renders the post in the headless browser after every extractor failed
+// with err. When the render finds nothing either, err is returned as the more telling one.
+func (c *Client) headlessFallback(ctx context.Context, shortcode string, err error) (*models.InstagramMediaInfo, error) {
+	c.logger.Info("Extractors failed, rendering page in headless browser", "shortcode", shortcode, "error", err)
+
+	mediaInfo, renderErr := c.runStrategy(ctx, ExtractorHeadless, shortcode, func(ctx context.Context) (*models.InstagramMediaInfo, error) {
+		return c.headless.render(ctx, shortcode, c.UserAgent())
+	})
+	if renderErr != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(ctxErr, context.DeadlineExceeded) {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	return mediaInfo, nil
+}
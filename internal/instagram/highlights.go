@@ -0,0 +1,80 @@
+package instagram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"qwiklip/internal/cache"
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/models"
+)
+
+var highlightIDPattern = regexp.MustCompile(`^(highlight:)?[0-9]{1,30}$`)
+
+// GetHighlight extracts the items of a highlight reel.
+// The result lists every item in Items (1-based, like carousels); VideoURL is the first video.
+// Like stories, highlights require a logged-in session.
+func (c *Client) GetHighlight(ctx context.Context, highlightID string) (*models.InstagramMediaInfo, error) {
+	if !highlightIDPattern.MatchString(highlightID) {
+		return nil, models.NewInvalidURLError(highlightID, fmt.Errorf("highlight id must be numeric"))
+	}
+	if highlightID[0] != 'h' {
+		highlightID = "highlight:" + highlightID
+	}
+
+	c.logger.Info("Starting Instagram highlight extraction", "highlight_id", highlightID)
+
+	if mediaInfo, err := c.cache.Get(ctx, highlightID); err == nil {
+		c.logger.Info("Highlight served from cache", "highlight_id", highlightID)
+		c.metrics.Count(metrics.CacheHits, 1, "cache", "metadata")
+		return mediaInfo, nil
+	} else if !errors.Is(err, cache.ErrMiss) {
+		c.logger.Warn("Metadata cache lookup failed", "highlight_id", highlightID, "error", err)
+	}
+	c.metrics.Count(metrics.CacheMisses, 1, "cache", "metadata")
+
+	mediaInfo, err, _ := c.flights.Do(ctx, highlightID, func(ctx context.Context) (*models.InstagramMediaInfo, error) {
+		reel, err := c.fetchReel(ctx, highlightID)
+		if err != nil {
+			return nil, err
+		}
+
+		mediaInfo := &models.InstagramMediaInfo{
+			Username: reel.User.Username,
+			Caption:  reel.Title,
+			FileName: fmt.Sprintf("%s.mp4", highlightID[len("highlight:"):]),
+		}
+		for _, item := range reel.Items {
+			story, err := c.parseStoryItem(item, storyItemID(item))
+			if err != nil {
+				c.logger.Warn("Skipping unreadable highlight item", "highlight_id", highlightID, "error", err)
+				continue
+			}
+			mediaInfo.Items = append(mediaInfo.Items, models.MediaItem{
+				Index:    len(mediaInfo.Items) + 1,
+				IsVideo:  story.VideoURL != "",
+				VideoURL: story.VideoURL,
+				ImageURL: story.ThumbnailURL,
+				AltText:  story.AltText,
+			})
+			if mediaInfo.VideoURL == "" {
+				mediaInfo.VideoURL = story.VideoURL
+			}
+		}
+		if len(mediaInfo.Items) == 0 {
+			return nil, models.NewExtractionError(highlightID, fmt.Errorf("highlight has no readable items"))
+		}
+		mediaInfo.Classify()
+
+		if cacheErr := c.cache.Set(ctx, highlightID, mediaInfo); cacheErr != nil {
+			c.logger.Warn("Failed to cache highlight info", "highlight_id", highlightID, "error", cacheErr)
+		}
+		return mediaInfo, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mediaInfo.Clone(), nil
+}
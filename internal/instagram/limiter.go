@@ -0,0 +1,108 @@
+package instagram
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/models"
+)
+
+// limiter caps how many requests hit Instagram at once.
+// Callers beyond the cap wait in a bounded queue; a full queue or a wait longer
+// than the timeout is rejected so a spike does not pile up on Instagram.
+type limiter struct {
+	metrics metrics.Recorder
+
+	mu       sync.Mutex
+	slots    chan struct{} // nil without a limit
+	timeout  time.Duration
+	maxQueue int
+	queued   int
+}
+
+// newLimiter returns a limiter allowing maxConcurrent requests; 0 means no limit
+func newLimiter(maxConcurrent, maxQueue int, timeout time.Duration, recorder metrics.Recorder) *limiter {
+	l := &limiter{metrics: recorder}
+	l.setLimits(maxConcurrent, maxQueue, timeout)
+	return l
+}
+
+// setLimits changes the limits for requests that start from now on. A new cap gets fresh
+// slots, so requests in flight finish under the old one and may briefly exceed the new one.
+func (l *limiter) setLimits(maxConcurrent, maxQueue int, timeout time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch {
+	case maxConcurrent <= 0:
+		l.slots = nil
+	case l.slots == nil || cap(l.slots) != maxConcurrent:
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	l.timeout = timeout
+	l.maxQueue = maxQueue
+}
+
+// acquire blocks until a slot is free and returns the function that releases it
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	l.mu.Lock()
+	slots, timeout := l.slots, l.timeout
+	if slots == nil {
+		l.mu.Unlock()
+		return func() {}, nil
+	}
+	release := func() { <-slots }
+
+	select {
+	case slots <- struct{}{}:
+		l.mu.Unlock()
+		return release, nil
+	default:
+	}
+
+	if l.queued >= l.maxQueue {
+		l.mu.Unlock()
+		l.metrics.Count(metrics.LimiterRejections, 1, "reason", "queue_full")
+		return nil, models.NewOverloadedError("too many Instagram requests in progress")
+	}
+	l.queued++
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		l.queued--
+		l.mu.Unlock()
+	}()
+
+	start := time.Now()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case slots <- struct{}{}:
+		l.metrics.Timing(metrics.LimiterWait, time.Since(start))
+		return release, nil
+	case <-timer.C:
+		l.metrics.Count(metrics.LimiterRejections, 1, "reason", "timeout")
+		return nil, models.NewOverloadedError("timed out waiting for an Instagram request slot")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// state reports the slots in use and the callers waiting, or nil without a limit
+func (l *limiter) state() *LimiterState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.slots == nil {
+		return nil
+	}
+	return &LimiterState{
+		InFlight:      len(l.slots),
+		MaxConcurrent: cap(l.slots),
+		Queued:        l.queued,
+		MaxQueue:      l.maxQueue,
+	}
+}
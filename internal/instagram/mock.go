@@ -0,0 +1,230 @@
+package instagram
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"qwiklip/internal/models"
+)
+
+// fixtureHost is the host of the media URLs generated for JSON fixtures in mock mode
+const fixtureHost = "fixtures.qwiklip.invalid"
+
+// fixtureTransport answers every outbound request from files in a directory instead of
+// the network (MOCK_MODE):
+//
+//	instagram.com/p/{shortcode}/ and /reel/{shortcode}/  ->  {shortcode}.html
+//	instagram.com/p/{shortcode}/embed/captioned/         ->  {shortcode}.embed.html
+//	the GraphQL post query for {shortcode}               ->  {shortcode}.graphql.json
+//	any other URL                                        ->  the file named like its last path segment
+//
+// Missing files get a 404, just like posts that do not exist. Single byte ranges are
+// honoured so players can seek.
+type fixtureTransport struct {
+	dir string
+}
+
+func (t fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	name := path.Base(req.URL.Path)
+	if isInstagramHost(req) {
+		fixture, ok := instagramFixtureName(req)
+		if !ok {
+			return fixtureResponse(req, http.StatusNotFound, nil, nil), nil
+		}
+		name = fixture
+	}
+	if name == "." || name == "/" || strings.HasPrefix(name, ".") {
+		return fixtureResponse(req, http.StatusNotFound, nil, nil), nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(t.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return fixtureResponse(req, http.StatusNotFound, nil, nil), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read fixture %s: %w", name, err)
+	}
+
+	header := http.Header{}
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("Accept-Ranges", "bytes")
+
+	status := http.StatusOK
+	if start, end, ok := parseFixtureRange(req.Header.Get("Range"), int64(len(data))); ok {
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		data, status = data[start:end+1], http.StatusPartialContent
+	}
+	return fixtureResponse(req, status, header, data), nil
+}
+
+// instagramFixtureName returns the fixture file of a request to instagram.com that fixtures
+// replay and recordings capture: post pages, embed pages and the GraphQL post query
+func instagramFixtureName(req *http.Request) (string, bool) {
+	if shortcode, ok := postPageShortcode(req); ok {
+		return shortcode + ".html", true
+	}
+	if shortcode, ok := embedPageShortcode(req); ok {
+		return shortcode + ".embed.html", true
+	}
+	if shortcode, ok := graphQLQueryShortcode(req); ok {
+		return shortcode + ".graphql.json", true
+	}
+	return "", false
+}
+
+// embedPageShortcode returns the shortcode of a www.instagram.com/p/{shortcode}/embed/ or
+// /embed/captioned/ request
+func embedPageShortcode(req *http.Request) (string, bool) {
+	if !isInstagramHost(req) {
+		return "", false
+	}
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) < 3 || len(segments) > 4 || (segments[0] != "p" && segments[0] != "reel") ||
+		segments[2] != "embed" || (len(segments) == 4 && segments[3] != "captioned") ||
+		!pageShortcodePattern.MatchString(segments[1]) {
+		return "", false
+	}
+	return segments[1], true
+}
+
+// graphQLQueryShortcode returns the shortcode in the variables of a GraphQL post query.
+// The form is read through GetBody, so the request body is left for the transport.
+func graphQLQueryShortcode(req *http.Request) (string, bool) {
+	if !isInstagramHost(req) || req.Method != http.MethodPost || strings.Trim(req.URL.Path, "/") != "graphql/query" || req.GetBody == nil {
+		return "", false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return "", false
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, 64*1024))
+	if err != nil {
+		return "", false
+	}
+	form, err := url.ParseQuery(string(data))
+	if err != nil {
+		return "", false
+	}
+	var variables struct {
+		Shortcode string `json:"shortcode"`
+	}
+	if err := json.Unmarshal([]byte(form.Get("variables")), &variables); err != nil || !pageShortcodePattern.MatchString(variables.Shortcode) {
+		return "", false
+	}
+	return variables.Shortcode, true
+}
+
+// parseFixtureRange parses a single "bytes=start-end", "bytes=start-" or "bytes=-suffix" range
+func parseFixtureRange(value string, size int64) (start, end int64, ok bool) {
+	spec, found := strings.CutPrefix(value, "bytes=")
+	if !found || strings.Contains(spec, ",") || size == 0 {
+		return 0, 0, false
+	}
+	first, last, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	var err error
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix <= 0 {
+			return 0, 0, false
+		}
+		return max(size-suffix, 0), size - 1, true
+	}
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil || start >= size {
+		return 0, 0, false
+	}
+	end = size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, true
+}
+
+func fixtureResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	if req.Method == http.MethodHead {
+		resp.Body = http.NoBody
+	}
+	return resp
+}
+
+// loadFixture reads {shortcode}.json from the fixtures directory: media info in the
+// format of /api/media/{shortcode}, used as the extraction result without running the
+// extractors. Without a video or image URL, {shortcode}.mp4 (or .jpg) beside it is used.
+// It returns an fs.ErrNotExist error when there is no JSON fixture.
+func loadFixture(dir, shortcode string) (*models.InstagramMediaInfo, error) {
+	data, err := os.ReadFile(filepath.Join(dir, shortcode+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var mediaInfo models.InstagramMediaInfo
+	if err := json.Unmarshal(data, &mediaInfo); err != nil {
+		return nil, models.NewParsingError("fixture "+shortcode+".json", err)
+	}
+
+	if mediaInfo.VideoURL == "" && mediaInfo.ImageURL == "" {
+		switch {
+		case fixtureExists(dir, shortcode+".mp4"):
+			mediaInfo.VideoURL = fmt.Sprintf("https://%s/%s.mp4", fixtureHost, shortcode)
+		case fixtureExists(dir, shortcode+".jpg"):
+			mediaInfo.ImageURL = fmt.Sprintf("https://%s/%s.jpg", fixtureHost, shortcode)
+		}
+	}
+	if mediaInfo.FileName == "" {
+		ext := ".mp4"
+		if mediaInfo.IsImage() {
+			ext = ".jpg"
+		}
+		mediaInfo.FileName = shortcode + ext
+	}
+	return &mediaInfo, nil
+}
+
+func fixtureExists(dir, name string) bool {
+	info, err := os.Stat(filepath.Join(dir, name))
+	return err == nil && !info.IsDir()
+}
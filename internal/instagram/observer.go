@@ -0,0 +1,39 @@
+package instagram
+
+import "context"
+
+// Extraction stages reported to an ExtractionObserver
+const (
+	StageCached   = "cached"   // Media info, or the error of a negative result (Err), served from the metadata cache
+	StageShared   = "shared"   // Joined an extraction of the same shortcode already in flight
+	StageFetch    = "fetch"    // Fetching the post page; Attempt counts retries from 1
+	StageStrategy = "strategy" // An extractor ran over the page; Err is set when it failed
+)
+
+// ExtractionEvent reports one step of a media extraction
+type ExtractionEvent struct {
+	Stage     string
+	Shortcode string
+	Strategy  string // Extractor name for StageStrategy
+	Attempt   int    // Fetch attempt for StageFetch
+	Err       error
+}
+
+// ExtractionObserver is told about the steps of extractions started with its context.
+// Extractions shared between callers only report to the caller that started them, and
+// may report after that caller has stopped waiting, so observers must not block.
+type ExtractionObserver func(event ExtractionEvent)
+
+type extractionObserverKey struct{}
+
+// WithExtractionObserver returns a context whose extractions report to observer
+func WithExtractionObserver(ctx context.Context, observer ExtractionObserver) context.Context {
+	return context.WithValue(ctx, extractionObserverKey{}, observer)
+}
+
+// observe reports event to the observer of ctx, if any
+func observe(ctx context.Context, event ExtractionEvent) {
+	if observer, ok := ctx.Value(extractionObserverKey{}).(ExtractionObserver); ok {
+		observer(event)
+	}
+}
@@ -2,7 +2,10 @@ package instagram
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"qwiklip/internal/models"
 )
@@ -42,7 +45,12 @@ func (c *Client) findVideoURL(jsonData map[string]interface{}, shortcode string)
 	c.logger.Debug("Checking SharedData format")
 	if entryData, ok := jsonData["entry_data"].(map[string]interface{}); ok {
 		if postPage, ok := entryData["PostPage"].([]interface{}); ok && len(postPage) > 0 {
-			if media := c.getShortcodeMedia(postPage[0]); media != nil {
+			// shortcode_media sits under "graphql" or directly in the page entry, as for findMedia
+			media := c.getShortcodeMedia(postPage[0])
+			if page, ok := postPage[0].(map[string]interface{}); ok && media == nil {
+				media = c.getShortcodeMedia(page["graphql"])
+			}
+			if media != nil {
 				if videoURL := c.extractVideoURLFromMedia(media); videoURL != "" {
 					c.logger.Info("Found video URL in SharedData entry_data structure")
 					return videoURL
@@ -148,6 +156,43 @@ func (c *Client) extractVideoURLFromMedia(media map[string]interface{}) string {
 	return ""
 }
 
+// extractVideoVersions lists every video_versions rendition, highest resolution first.
+// Renditions sharing a URL (Instagram repeats them with different type IDs) are listed once.
+func extractVideoVersions(media map[string]interface{}) []models.VideoVersion {
+	entries, _ := media["video_versions"].([]interface{})
+	seen := make(map[string]bool)
+	var versions []models.VideoVersion
+	for _, entry := range entries {
+		entryMap, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		url, _ := entryMap["url"].(string)
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+
+		width, _ := entryMap["width"].(float64)
+		height, _ := entryMap["height"].(float64)
+		bandwidth, _ := entryMap["bandwidth"].(float64)
+		versions = append(versions, models.VideoVersion{
+			URL:       url,
+			Width:     int(width),
+			Height:    int(height),
+			Bandwidth: int(bandwidth),
+		})
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].Height != versions[j].Height {
+			return versions[i].Height > versions[j].Height
+		}
+		return versions[i].Bandwidth > versions[j].Bandwidth
+	})
+	return versions
+}
+
 // extractMetadata tries to extract additional metadata like username, caption, etc.
 func (c *Client) extractMetadata(jsonData map[string]interface{}, mediaInfo *models.InstagramMediaInfo) {
 	// Try to find username and caption from various structures
@@ -179,6 +224,325 @@ func (c *Client) extractMetadata(jsonData map[string]interface{}, mediaInfo *mod
 	}
 }
 
+// extractMediaDetails fills thumbnail, alt text, duration, date, dimensions, media type, counts, location, co-authors,
+// tagged users, renditions and DASH streams from the post's media object.
+// It understands both the GraphQL (dimensions, owner) and API (original_width, user) shapes
+// and only sets username and caption when extractMetadata found none.
+func (c *Client) extractMediaDetails(media map[string]interface{}, mediaInfo *models.InstagramMediaInfo) {
+	if displayURL, ok := media["display_url"].(string); ok && displayURL != "" {
+		mediaInfo.ThumbnailURL = displayURL
+	} else if imageURL := firstImageCandidate(media); imageURL != "" {
+		mediaInfo.ThumbnailURL = imageURL
+	}
+
+	mediaInfo.AltText, _ = media["accessibility_caption"].(string)
+	if duration, ok := media["video_duration"].(float64); ok {
+		mediaInfo.Duration = duration
+	}
+	for _, key := range []string{"taken_at_timestamp", "taken_at"} {
+		if takenAt, ok := media[key].(float64); ok && takenAt > 0 {
+			mediaInfo.TakenAt = time.Unix(int64(takenAt), 0).UTC()
+			break
+		}
+	}
+	if mediaInfo.VideoURL != "" {
+		mediaInfo.Versions = extractVideoVersions(media)
+		if manifest, ok := media["video_dash_manifest"].(string); ok && manifest != "" {
+			if streams, err := parseDASHManifest(manifest); err != nil {
+				c.logger.Debug("Ignoring DASH manifest", "error", err)
+			} else {
+				mediaInfo.DASH = streams
+			}
+		}
+	}
+
+	mediaInfo.MediaType = parseMediaType(media)
+	mediaInfo.LikeCount = mediaCount(media, "edge_media_preview_like", "edge_liked_by", "like_count")
+	mediaInfo.CommentCount = mediaCount(media, "edge_media_to_parent_comment", "edge_media_to_comment", "edge_media_preview_comment", "comment_count")
+	mediaInfo.Location = parseLocation(media)
+	mediaInfo.Coauthors = parseCoauthors(media)
+	mediaInfo.TaggedUsers = parseTaggedUsers(media)
+
+	if dimensions, ok := media["dimensions"].(map[string]interface{}); ok {
+		width, _ := dimensions["width"].(float64)
+		height, _ := dimensions["height"].(float64)
+		mediaInfo.Width, mediaInfo.Height = int(width), int(height)
+	} else {
+		width, _ := media["original_width"].(float64)
+		height, _ := media["original_height"].(float64)
+		mediaInfo.Width, mediaInfo.Height = int(width), int(height)
+	}
+
+	if mediaInfo.Username == "" {
+		for _, key := range []string{"owner", "user"} {
+			if owner, ok := media[key].(map[string]interface{}); ok {
+				if username, ok := owner["username"].(string); ok && username != "" {
+					mediaInfo.Username = username
+					break
+				}
+			}
+		}
+	}
+
+	if mediaInfo.Caption == "" {
+		if caption, ok := media["caption"].(map[string]interface{}); ok {
+			mediaInfo.Caption, _ = caption["text"].(string)
+		} else if caption, ok := media["edge_media_to_caption"].(map[string]interface{}); ok {
+			if edges, ok := caption["edges"].([]interface{}); ok && len(edges) > 0 {
+				if edge, ok := edges[0].(map[string]interface{}); ok {
+					if node, ok := edge["node"].(map[string]interface{}); ok {
+						mediaInfo.Caption, _ = node["text"].(string)
+					}
+				}
+			}
+		}
+	}
+}
+
+// parseMediaType maps the GraphQL __typename or the API media_type of a media object to a
+// models.PostType* value, or "" when it has neither
+func parseMediaType(media map[string]interface{}) string {
+	typeName, _ := media["__typename"].(string)
+	switch strings.TrimPrefix(typeName, "XDT") {
+	case "GraphVideo":
+		return models.PostTypeVideo
+	case "GraphImage":
+		return models.PostTypeImage
+	case "GraphSidecar":
+		return models.PostTypeCarousel
+	}
+
+	mediaType, _ := media["media_type"].(float64)
+	switch int(mediaType) {
+	case apiMediaTypeVideo:
+		return models.PostTypeVideo
+	case apiMediaTypeImage:
+		return models.PostTypeImage
+	case apiMediaTypeCarousel:
+		return models.PostTypeCarousel
+	}
+	return ""
+}
+
+// mediaCount returns the first count found under keys of a media object: a number in the
+// API shape (like_count) or the count of an edge in the GraphQL shape (edge_liked_by)
+func mediaCount(media map[string]interface{}, keys ...string) int {
+	for _, key := range keys {
+		switch value := media[key].(type) {
+		case float64:
+			return max(int(value), 0)
+		case map[string]interface{}:
+			if count, ok := value["count"].(float64); ok {
+				return max(int(count), 0)
+			}
+		}
+	}
+	return 0
+}
+
+// parseLocation returns the location tag of a media object, or nil when it has none. The
+// GraphQL shape carries id and slug, the API shape pk and coordinates.
+func parseLocation(media map[string]interface{}) *models.Location {
+	location, ok := media["location"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	name, _ := location["name"].(string)
+	if name == "" {
+		return nil
+	}
+	result := &models.Location{Name: name}
+	result.Slug, _ = location["slug"].(string)
+	result.Latitude, _ = location["lat"].(float64)
+	result.Longitude, _ = location["lng"].(float64)
+	for _, key := range []string{"id", "pk"} {
+		switch id := location[key].(type) {
+		case string:
+			result.ID = id
+		case float64:
+			result.ID = strconv.FormatInt(int64(id), 10)
+		}
+		if result.ID != "" {
+			break
+		}
+	}
+	return result
+}
+
+// parseCoauthors returns the usernames of a collab post's co-authors, leaving out the owner
+func parseCoauthors(media map[string]interface{}) []string {
+	producers, _ := media["coauthor_producers"].([]interface{})
+	owner := ""
+	for _, key := range []string{"owner", "user"} {
+		if user, ok := media[key].(map[string]interface{}); ok {
+			owner, _ = user["username"].(string)
+			break
+		}
+	}
+
+	var usernames []string
+	for _, producer := range producers {
+		if user, ok := producer.(map[string]interface{}); ok {
+			if username, _ := user["username"].(string); username != "" && username != owner {
+				usernames = append(usernames, username)
+			}
+		}
+	}
+	return usernames
+}
+
+// parseTaggedUsers returns the usernames tagged in a media object, from the GraphQL
+// edge_media_to_tagged_user edges or the API usertags list, without duplicates
+func parseTaggedUsers(media map[string]interface{}) []string {
+	var users []interface{}
+	if tagged, ok := media["edge_media_to_tagged_user"].(map[string]interface{}); ok {
+		edges, _ := tagged["edges"].([]interface{})
+		for _, edge := range edges {
+			if edge, ok := edge.(map[string]interface{}); ok {
+				if node, ok := edge["node"].(map[string]interface{}); ok {
+					users = append(users, node["user"])
+				}
+			}
+		}
+	} else if usertags, ok := media["usertags"].(map[string]interface{}); ok {
+		tags, _ := usertags["in"].([]interface{})
+		for _, tag := range tags {
+			if tag, ok := tag.(map[string]interface{}); ok {
+				users = append(users, tag["user"])
+			}
+		}
+	}
+
+	var usernames []string
+	seen := make(map[string]bool, len(users))
+	for _, user := range users {
+		if user, ok := user.(map[string]interface{}); ok {
+			if username, _ := user["username"].(string); username != "" && !seen[username] {
+				seen[username] = true
+				usernames = append(usernames, username)
+			}
+		}
+	}
+	return usernames
+}
+
+// findMedia returns the post's media object from the known JSON structures, or nil
+func (c *Client) findMedia(jsonData map[string]interface{}) map[string]interface{} {
+	// PostPage format
+	if require, ok := jsonData["require"].([]interface{}); ok {
+		for _, item := range require {
+			if itemMap, ok := item.(map[string]interface{}); ok && itemMap["0"] == "PostPage" {
+				if page, ok := itemMap["1"].(map[string]interface{}); ok {
+					if media := c.getShortcodeMedia(page["graphql"]); media != nil {
+						return media
+					}
+				}
+			}
+		}
+	}
+
+	// SharedData format
+	if entryData, ok := jsonData["entry_data"].(map[string]interface{}); ok {
+		if postPage, ok := entryData["PostPage"].([]interface{}); ok && len(postPage) > 0 {
+			if graphql, ok := postPage[0].(map[string]interface{}); ok {
+				if media := c.getShortcodeMedia(graphql["graphql"]); media != nil {
+					return media
+				}
+			}
+			if media := c.getShortcodeMedia(postPage[0]); media != nil {
+				return media
+			}
+		}
+	}
+
+	// Direct items format
+	if items, ok := jsonData["items"].([]interface{}); ok && len(items) > 0 {
+		if media, ok := items[0].(map[string]interface{}); ok {
+			return media
+		}
+	}
+
+	// Direct API response format
+	return c.getShortcodeMedia(jsonData["graphql"])
+}
+
+// extractCarouselItems lists the children of a carousel (sidecar) post.
+// It understands both the GraphQL edge_sidecar_to_children and the API carousel_media shapes.
+func (c *Client) extractCarouselItems(media map[string]interface{}) []models.MediaItem {
+	var items []models.MediaItem
+
+	if sidecar, ok := media["edge_sidecar_to_children"].(map[string]interface{}); ok {
+		edges, _ := sidecar["edges"].([]interface{})
+		for _, edge := range edges {
+			edgeMap, ok := edge.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			node, ok := edgeMap["node"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			item := models.MediaItem{Index: len(items) + 1}
+			item.IsVideo, _ = node["is_video"].(bool)
+			item.ImageURL, _ = node["display_url"].(string)
+			item.AltText, _ = node["accessibility_caption"].(string)
+			if item.IsVideo {
+				item.VideoURL, _ = node["video_url"].(string)
+			}
+			items = append(items, item)
+		}
+		return items
+	}
+
+	carousel, _ := media["carousel_media"].([]interface{})
+	for _, child := range carousel {
+		childMap, ok := child.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		item := models.MediaItem{Index: len(items) + 1}
+		item.VideoURL = c.extractVideoURLFromMedia(childMap)
+		item.IsVideo = item.VideoURL != ""
+		item.ImageURL = firstImageCandidate(childMap)
+		item.AltText, _ = childMap["accessibility_caption"].(string)
+		items = append(items, item)
+	}
+	return items
+}
+
+// findImageURL returns the full-size image of a photo post, falling back to the first carousel image
+func (c *Client) findImageURL(media map[string]interface{}, items []models.MediaItem) string {
+	if media != nil {
+		if displayURL, ok := media["display_url"].(string); ok && displayURL != "" {
+			return displayURL
+		}
+		if imageURL := firstImageCandidate(media); imageURL != "" {
+			return imageURL
+		}
+	}
+
+	for _, item := range items {
+		if item.ImageURL != "" {
+			return item.ImageURL
+		}
+	}
+	return ""
+}
+
+// firstImageCandidate returns the largest image_versions2 candidate URL of an API media object
+func firstImageCandidate(media map[string]interface{}) string {
+	if versions, ok := media["image_versions2"].(map[string]interface{}); ok {
+		if candidates, ok := versions["candidates"].([]interface{}); ok && len(candidates) > 0 {
+			if candidate, ok := candidates[0].(map[string]interface{}); ok {
+				if url, ok := candidate["url"].(string); ok {
+					return url
+				}
+			}
+		}
+	}
+	return ""
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -0,0 +1,120 @@
+package instagram
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"qwiklip/internal/models"
+)
+
+// Profile feed page size limits
+const (
+	DefaultFeedPageSize = 12
+	MaxFeedPageSize     = 50
+)
+
+// API media_type values
+const (
+	apiMediaTypeImage    = 1
+	apiMediaTypeVideo    = 2
+	apiMediaTypeCarousel = 8
+)
+
+// ValidUsername reports whether username is a syntactically valid Instagram username
+func ValidUsername(username string) bool {
+	return usernamePattern.MatchString(username)
+}
+
+// GetUserPosts lists a page of a user's recent posts, newest first.
+// cursor is empty for the first page and PostPage.NextCursor afterwards.
+func (c *Client) GetUserPosts(ctx context.Context, username, cursor string, count int) (*models.PostPage, error) {
+	if !usernamePattern.MatchString(username) {
+		return nil, models.NewInvalidURLError(username, fmt.Errorf("invalid username"))
+	}
+	if count <= 0 {
+		count = DefaultFeedPageSize
+	}
+	count = min(count, MaxFeedPageSize)
+
+	c.logger.Info("Fetching profile feed", "username", username, "cursor", cursor, "count", count)
+
+	userID, err := c.lookupUserID(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"count": {fmt.Sprint(count)}}
+	if cursor != "" {
+		query.Set("max_id", cursor)
+	}
+
+	var feed struct {
+		Items         []map[string]interface{} `json:"items"`
+		MoreAvailable bool                     `json:"more_available"`
+		NextMaxID     string                   `json:"next_max_id"`
+	}
+	endpoint := fmt.Sprintf("%s/feed/user/%s/?%s", mobileAPIBase, url.PathEscape(userID), query.Encode())
+	if err := c.getAPIJSON(ctx, endpoint, &feed); err != nil {
+		return nil, err
+	}
+
+	page := &models.PostPage{
+		Username: username,
+		Posts:    make([]models.PostSummary, 0, len(feed.Items)),
+	}
+	if feed.MoreAvailable {
+		page.NextCursor = feed.NextMaxID
+	}
+
+	for _, item := range feed.Items {
+		if post, ok := c.parsePostSummary(item); ok {
+			page.Posts = append(page.Posts, post)
+		}
+	}
+
+	c.logger.Info("Fetched profile feed", "username", username, "posts", len(page.Posts), "more", page.NextCursor != "")
+	return page, nil
+}
+
+// parsePostSummary converts a feed item into a post summary; items without a shortcode are skipped
+func (c *Client) parsePostSummary(item map[string]interface{}) (models.PostSummary, bool) {
+	shortcode, _ := item["code"].(string)
+	if shortcode == "" {
+		return models.PostSummary{}, false
+	}
+
+	post := models.PostSummary{
+		Shortcode:    shortcode,
+		Type:         models.PostTypeImage,
+		ThumbnailURL: firstImageCandidate(item),
+	}
+
+	mediaType, _ := item["media_type"].(float64)
+	switch int(mediaType) {
+	case apiMediaTypeVideo:
+		post.Type = models.PostTypeVideo
+	case apiMediaTypeCarousel:
+		post.Type = models.PostTypeCarousel
+		// Carousels carry their thumbnail on the first child
+		if post.ThumbnailURL == "" {
+			if children, ok := item["carousel_media"].([]interface{}); ok && len(children) > 0 {
+				if child, ok := children[0].(map[string]interface{}); ok {
+					post.ThumbnailURL = firstImageCandidate(child)
+				}
+			}
+		}
+	}
+
+	if caption, ok := item["caption"].(map[string]interface{}); ok {
+		post.Caption, _ = caption["text"].(string)
+	}
+	post.Duration, _ = item["video_duration"].(float64)
+	post.LikeCount = mediaCount(item, "like_count")
+	post.CommentCount = mediaCount(item, "comment_count")
+	if takenAt, ok := item["taken_at"].(float64); ok && takenAt > 0 {
+		post.TakenAt = time.Unix(int64(takenAt), 0).UTC()
+	}
+	return post, true
+}
@@ -0,0 +1,331 @@
+package instagram
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"qwiklip/internal/config"
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/tracing"
+)
+
+// maxProxyFailures is the number of consecutive failures after which a proxy is benched
+const maxProxyFailures = 3
+
+// outboundProxy is one upstream proxy in the pool
+type outboundProxy struct {
+	url    *url.URL
+	weight int
+
+	// Guarded by proxyPool.mu
+	current   int // Smooth weighted round-robin state
+	failures  int
+	coolUntil time.Time
+	requests  int64
+	errors    int64
+}
+
+// proxyPool selects an upstream proxy for every outbound request and benches
+// proxies that fail or get rate limited
+type proxyPool struct {
+	mu       sync.Mutex
+	proxies  []*outboundProxy
+	next     int
+	weighted bool
+	cooldown time.Duration
+	metrics  metrics.Recorder
+	logger   *slog.Logger
+}
+
+// proxyContextKey carries the proxy chosen for a request to the transport's Proxy func
+type proxyContextKey struct{}
+
+// newTransport returns the transport shared by page fetches, API calls and CDN streaming,
+// together with the proxy pool it routes through. Configured proxies override the
+// HTTP_PROXY/HTTPS_PROXY environment; with more than one, requests rotate across them.
+// Every request waits for its turn in scheduler first.
+func newTransport(cfg *config.InstagramConfig, scheduler *requestScheduler, recorder metrics.Recorder, logger *slog.Logger) (http.RoundTripper, *proxyPool, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = cfg.MaxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
+	transport.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
+	if !cfg.HTTP2 {
+		// A non-nil empty map is how net/http is told not to negotiate h2
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	proxies, err := loadProxies(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool := &proxyPool{metrics: recorder, logger: logger}
+	pool.replace(proxies, cfg)
+
+	// The pool is installed even when empty so that proxies added by a reload take effect
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		if proxy, ok := req.Context().Value(proxyContextKey{}).(*outboundProxy); ok {
+			return proxy.url, nil
+		}
+		return http.ProxyFromEnvironment(req)
+	}
+	return tracing.Transport(&proxyTransport{base: transport, pool: pool, scheduler: scheduler}), pool, nil
+}
+
+// loadProxies parses the proxies configured by PROXY_URL, PROXY_URLS and the proxies file, in that order
+func loadProxies(cfg *config.InstagramConfig) ([]*outboundProxy, error) {
+	var entries []string
+	if cfg.ProxyURL != "" {
+		entries = append(entries, cfg.ProxyURL)
+	}
+	entries = append(entries, cfg.ProxyURLs...)
+	if cfg.ProxiesFile != "" {
+		fileEntries, err := readProxiesFile(cfg.ProxiesFile)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	proxies := make([]*outboundProxy, 0, len(entries))
+	for _, entry := range entries {
+		raw, weight, err := config.ParseProxyEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		proxyURL, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		proxies = append(proxies, &outboundProxy{url: proxyURL, weight: weight})
+	}
+	return proxies, nil
+}
+
+// readProxiesFile reads one "<url> [weight]" entry per line, skipping blanks and # comments
+func readProxiesFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open proxies file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read proxies file: %w", err)
+	}
+	return entries, nil
+}
+
+// proxyTransport routes each request through a proxy from the pool, holds requests to
+// Instagram to the request budget of their account and proxy, and reports the outcome
+type proxyTransport struct {
+	base      *http.Transport
+	pool      *proxyPool
+	scheduler *requestScheduler
+}
+
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	proxy := t.pool.acquire()
+	if isInstagramHost(req) {
+		if err := t.scheduler.wait(req.Context(), budgetKeys(req.Context(), proxy)...); err != nil {
+			return nil, err
+		}
+	}
+	if proxy != nil {
+		req = req.WithContext(context.WithValue(req.Context(), proxyContextKey{}, proxy))
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	t.pool.report(req.Context(), proxy, resp, err)
+	return resp, err
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach the pooled connections
+func (t *proxyTransport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+}
+
+// replace swaps in a new proxy list and the strategy settings from cfg. Proxies that stay
+// in the list keep their health and round-robin state.
+func (p *proxyPool) replace(proxies []*outboundProxy, cfg *config.InstagramConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing := make(map[string]*outboundProxy, len(p.proxies))
+	for _, proxy := range p.proxies {
+		existing[proxy.url.String()] = proxy
+	}
+	for i, proxy := range proxies {
+		if old, ok := existing[proxy.url.String()]; ok {
+			old.weight = proxy.weight
+			proxies[i] = old
+			delete(existing, proxy.url.String())
+		}
+	}
+
+	p.proxies = proxies
+	p.next = 0
+	p.weighted = cfg.ProxyStrategy == "weighted"
+	p.cooldown = cfg.ProxyCooldown
+
+	if len(proxies) > 0 {
+		p.logger.Info("Routing Instagram traffic through proxies",
+			"proxies", len(proxies),
+			"strategy", cfg.ProxyStrategy,
+			"cooldown", cfg.ProxyCooldown)
+	}
+}
+
+// acquire returns the next healthy proxy by the configured strategy, or nil when no proxies
+// are configured. When every proxy is benched, the one that recovers soonest is used rather
+// than falling back to a direct connection.
+func (p *proxyPool) acquire() *outboundProxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.proxies) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var chosen *outboundProxy
+	if p.weighted {
+		chosen = p.nextWeighted(now)
+	} else {
+		for range p.proxies {
+			candidate := p.proxies[p.next]
+			p.next = (p.next + 1) % len(p.proxies)
+			if now.After(candidate.coolUntil) {
+				chosen = candidate
+				break
+			}
+		}
+	}
+
+	if chosen == nil {
+		chosen = p.proxies[0]
+		for _, proxy := range p.proxies[1:] {
+			if proxy.coolUntil.Before(chosen.coolUntil) {
+				chosen = proxy
+			}
+		}
+		p.logger.Warn("All outbound proxies are cooling down", "using", chosen.url.Redacted())
+	}
+
+	chosen.requests++
+	return chosen
+}
+
+// nextWeighted picks among healthy proxies with smooth weighted round-robin,
+// which spreads a proxy's share evenly instead of sending its requests in bursts
+func (p *proxyPool) nextWeighted(now time.Time) *outboundProxy {
+	var best *outboundProxy
+	total := 0
+	for _, proxy := range p.proxies {
+		if !now.After(proxy.coolUntil) {
+			continue
+		}
+		proxy.current += proxy.weight
+		total += proxy.weight
+		if best == nil || proxy.current > best.current {
+			best = proxy
+		}
+	}
+	if best != nil {
+		best.current -= total
+	}
+	return best
+}
+
+// report records the outcome of a request sent through proxy.
+// A 429, a 407 or repeated connection failures bench the proxy; any other response clears its failures.
+func (p *proxyPool) report(ctx context.Context, proxy *outboundProxy, resp *http.Response, err error) {
+	if proxy == nil {
+		return
+	}
+	host := proxy.url.Host
+	p.metrics.Count(metrics.ProxyRequests, 1, "proxy", host)
+
+	// The caller giving up says nothing about the proxy
+	if err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled)) {
+		return
+	}
+
+	var reason string
+	switch {
+	case err != nil:
+		reason = "error"
+	case resp.StatusCode == http.StatusTooManyRequests:
+		reason = "rate_limited"
+	case resp.StatusCode == http.StatusProxyAuthRequired:
+		reason = "proxy_auth"
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if reason == "" {
+		proxy.failures = 0
+		return
+	}
+
+	proxy.errors++
+	proxy.failures++
+	p.metrics.Count(metrics.ProxyFailures, 1, "proxy", host, "reason", reason)
+
+	if reason == "error" && proxy.failures < maxProxyFailures {
+		p.logger.Debug("Outbound proxy request failed", "proxy", proxy.url.Redacted(), "failures", proxy.failures, "error", err)
+		return
+	}
+
+	proxy.coolUntil = time.Now().Add(p.cooldown)
+	proxy.failures = 0
+	p.logger.Warn("Outbound proxy cooling down",
+		"proxy", proxy.url.Redacted(),
+		"reason", reason,
+		"requests", proxy.requests,
+		"errors", proxy.errors,
+		"cooldown", p.cooldown)
+}
+
+// state reports the health and traffic of every proxy
+func (p *proxyPool) state() []ProxyState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	states := make([]ProxyState, 0, len(p.proxies))
+	for _, proxy := range p.proxies {
+		until := coolingUntil(proxy.coolUntil, now)
+		states = append(states, ProxyState{
+			URL:        proxy.url.Redacted(),
+			Weight:     proxy.weight,
+			CoolingOff: until != nil,
+			CoolUntil:  until,
+			Failures:   proxy.failures,
+			Requests:   proxy.requests,
+			Errors:     proxy.errors,
+		})
+	}
+	return states
+}
@@ -0,0 +1,82 @@
+package instagram
+
+import (
+	"slices"
+	"time"
+)
+
+// RateLimitState is a snapshot of everything that throttles or benches requests to Instagram
+type RateLimitState struct {
+	Limiter  *LimiterState  `json:"limiter,omitempty"` // Nil when INSTAGRAM_MAX_CONCURRENT is 0
+	Accounts []AccountState `json:"accounts"`
+	Proxies  []ProxyState   `json:"proxies"`
+
+	// Until when anonymous requests pause after a 429, nil when they do not
+	AnonymousCoolUntil *time.Time `json:"anonymous_cool_until,omitempty"`
+}
+
+// LimiterState reports the slots of the concurrent request limiter
+type LimiterState struct {
+	InFlight      int `json:"in_flight"`
+	MaxConcurrent int `json:"max_concurrent"`
+	Queued        int `json:"queued"`
+	MaxQueue      int `json:"max_queue"`
+}
+
+// AccountState reports one account in the rotation pool
+type AccountState struct {
+	Name       string     `json:"name"`
+	CoolingOff bool       `json:"cooling_off"`
+	CoolUntil  *time.Time `json:"cool_until,omitempty"`
+	Strikes    int        `json:"strikes"`
+}
+
+// ProxyState reports one outbound proxy; credentials are redacted from the URL
+type ProxyState struct {
+	URL        string     `json:"url"`
+	Weight     int        `json:"weight"`
+	CoolingOff bool       `json:"cooling_off"`
+	CoolUntil  *time.Time `json:"cool_until,omitempty"`
+	Failures   int        `json:"failures"`
+	Requests   int64      `json:"requests"`
+	Errors     int64      `json:"errors"`
+}
+
+// RateLimits returns the current limiter, account and proxy state
+func (c *Client) RateLimits() RateLimitState {
+	return RateLimitState{
+		Limiter:  c.limiter.state(),
+		Accounts: c.accounts.state(),
+		Proxies:  c.proxies.state(),
+
+		AnonymousCoolUntil: c.rateWindow.state(),
+	}
+}
+
+// Unavailable returns why requests to Instagram are expected to fail, or "" when they can
+// go out. That is the case while every configured outbound proxy is cooling off, or while
+// anonymous requests wait out a 429 and every account is benched; benched accounts alone
+// are not, since extraction then continues anonymously.
+func (s RateLimitState) Unavailable() string {
+	if s.AnonymousCoolUntil != nil && !slices.ContainsFunc(s.Accounts, func(a AccountState) bool { return !a.CoolingOff }) {
+		return "rate limited by Instagram until " + s.AnonymousCoolUntil.Format(time.RFC3339)
+	}
+	if len(s.Proxies) == 0 {
+		return ""
+	}
+	for _, proxy := range s.Proxies {
+		if !proxy.CoolingOff {
+			return ""
+		}
+	}
+	return "all outbound proxies are cooling off"
+}
+
+// coolingUntil returns until when a cool-down lasts, or nil once it has passed
+func coolingUntil(until, now time.Time) *time.Time {
+	if !now.Before(until) {
+		return nil
+	}
+	until = until.UTC()
+	return &until
+}
@@ -0,0 +1,133 @@
+package instagram
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// redactedValue replaces secrets in recorded pages
+const redactedValue = "REDACTED"
+
+// secretFieldPattern matches JSON string fields of post pages that identify the viewer or
+// their session: CSRF and DTSG tokens, device and machine IDs, viewer IDs and claims
+var secretFieldPattern = regexp.MustCompile(`"(csrf_token|token|fb_dtsg|dtsg|lsd|device_id|machine_id|viewer_id|viewerId|viewerID|actorID|claim|hmac|encrypted_[a-z_]+|sessionid|ds_user_id)":"[^"]*"`)
+
+// pageShortcodePattern matches the shortcodes accepted in recorded and replayed page paths
+var pageShortcodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// nonceAttrPattern matches the CSP nonces of script tags, which differ per response
+var nonceAttrPattern = regexp.MustCompile(`nonce="[^"]*"`)
+
+// recordingTransport saves a sanitized copy of every post page, embed page and GraphQL
+// post query Instagram answers, under the fixture names replayed by MOCK_MODE ({shortcode}.html,
+// {shortcode}.embed.html, {shortcode}.graphql.json) in dir. Snapshots of real responses let
+// the extractors be checked against them when Instagram changes its markup.
+type recordingTransport struct {
+	next    http.RoundTripper
+	dir     string
+	secrets []string // Session IDs of configured accounts, removed wherever they appear
+	logger  *slog.Logger
+}
+
+func newRecordingTransport(next http.RoundTripper, dir string, secrets []string, logger *slog.Logger) *recordingTransport {
+	return &recordingTransport{next: next, dir: dir, secrets: secrets, logger: logger}
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name, ok := instagramFixtureName(req)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if !ok || resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	// Pages count only as HTML; a login page instead of a post is not worth keeping
+	if strings.HasSuffix(name, ".html") && !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		// Hand the caller what arrived along with the error it would have seen reading it
+		resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errorReader{err}))
+		return resp, nil
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := t.save(name, body); err != nil {
+		t.logger.Warn("Failed to record Instagram response", "file", name, "error", err)
+	} else {
+		t.logger.Info("Recorded Instagram response", "file", name, "dir", t.dir, "bytes", len(body))
+	}
+	return resp, nil
+}
+
+// save writes the sanitized response through a temporary file, so a replay never sees half of it
+func (t *recordingTransport) save(name string, body []byte) error {
+	tmp, err := os.CreateTemp(t.dir, "."+name+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(t.sanitize(string(body))); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(t.dir, name)); err != nil {
+		return fmt.Errorf("rename recording: %w", err)
+	}
+	return nil
+}
+
+// sanitize removes session secrets and per-response tokens from a page, keeping
+// everything the extractors read
+func (t *recordingTransport) sanitize(page string) string {
+	for _, secret := range t.secrets {
+		if secret != "" {
+			page = strings.ReplaceAll(page, secret, redactedValue)
+		}
+	}
+	page = secretFieldPattern.ReplaceAllString(page, `"$1":"`+redactedValue+`"`)
+	return nonceAttrPattern.ReplaceAllString(page, `nonce="`+redactedValue+`"`)
+}
+
+// isInstagramHost reports whether a request goes to instagram.com or one of its subdomains
+func isInstagramHost(req *http.Request) bool {
+	host := req.URL.Hostname()
+	return host == "instagram.com" || strings.HasSuffix(host, ".instagram.com")
+}
+
+// postPageShortcode returns the shortcode of a www.instagram.com/p/{shortcode}/ or
+// /reel/{shortcode}/ request
+func postPageShortcode(req *http.Request) (string, bool) {
+	if !isInstagramHost(req) {
+		return "", false
+	}
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) != 2 || (segments[0] != "p" && segments[0] != "reel") || !pageShortcodePattern.MatchString(segments[1]) {
+		return "", false
+	}
+	return segments[1], true
+}
+
+// errorReader fails every read with err
+type errorReader struct{ err error }
+
+func (r errorReader) Read([]byte) (int, error) { return 0, r.err }
@@ -0,0 +1,149 @@
+package instagram
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"qwiklip/internal/config"
+	"qwiklip/internal/errorreport"
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/models"
+	"qwiklip/internal/webhook"
+)
+
+// newReplayClient returns a client that runs only extractor, answering every request
+// from the fixtures in testdata through the replay transport
+func newReplayClient(t *testing.T, extractor string) *Client {
+	t.Helper()
+	cfg := &config.InstagramConfig{
+		Timeout:         5 * time.Second,
+		Extractors:      []string{extractor},
+		MockMode:        true,
+		MockFixturesDir: "testdata",
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client, err := NewClient(cfg, nil, logger, metrics.Nop{}, errorreport.Nop{}, webhook.Nop{})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client
+}
+
+func TestExtractorsReplayFixtures(t *testing.T) {
+	tests := []struct {
+		name      string
+		extractor string
+		shortcode string
+		want      models.InstagramMediaInfo // Only VideoURL, ImageURL, Username and Caption are compared
+		wantErr   models.ErrorType
+	}{
+		{
+			name:      "embedded JSON",
+			extractor: ExtractorJSON,
+			shortcode: "CxJSONreel1",
+			want: models.InstagramMediaInfo{
+				VideoURL: "https://scontent.cdninstagram.com/o1/v/t16/f2/m86/CxJSONreel1.mp4?efg=eyJ2ZW5jb2RlX3RhZyI6ImNsaXBzIn0&_nc_ht=scontent.cdninstagram.com&oh=REDACTED",
+				Username: "qwiklip.demo",
+				Caption:  "Sunset over the harbour",
+			},
+		},
+		{
+			name:      "direct video URL",
+			extractor: ExtractorDirect,
+			shortcode: "CxDIRECTrl1",
+			want: models.InstagramMediaInfo{
+				VideoURL: "https://scontent.cdninstagram.com/o1/v/t16/f2/m86/CxDIRECTrl1.mp4?efg=eyJ2ZW5jb2RlX3RhZyI6ImNsaXBzIn0&oh=REDACTED",
+			},
+		},
+		{
+			name:      "case-insensitive fallback",
+			extractor: ExtractorFallback,
+			shortcode: "CxFALLBK001",
+			want: models.InstagramMediaInfo{
+				VideoURL: "https://scontent.cdninstagram.com/o1/v/t16/f2/m86/CxFALLBK001.mp4?efg=eyJ2ZW5jb2RlX3RhZyI6ImNsaXBzIn0&oh=REDACTED",
+			},
+		},
+		{
+			name:      "relay preloader",
+			extractor: ExtractorPreloader,
+			shortcode: "CxPRELOAD01",
+			want: models.InstagramMediaInfo{
+				VideoURL: "https://scontent.cdninstagram.com/o1/v/t16/f2/m86/CxPRELOAD01.mp4?efg=eyJ2ZW5jb2RlX3RhZyI6ImNsaXBzIn0&oh=REDACTED",
+			},
+		},
+		{
+			name:      "Open Graph image",
+			extractor: ExtractorImage,
+			shortcode: "CxPHOTOpost",
+			want: models.InstagramMediaInfo{
+				ImageURL: "https://scontent.cdninstagram.com/v/t51.29350-15/CxPHOTOpost.jpg?stp=dst-jpg_e35&_nc_ht=scontent.cdninstagram.com&oh=REDACTED",
+			},
+		},
+		{
+			name:      "image extractor skips video pages",
+			extractor: ExtractorImage,
+			shortcode: "CxDIRECTrl1",
+			wantErr:   models.ErrorTypeNotFound,
+		},
+		{
+			name:      "embed page",
+			extractor: ExtractorEmbed,
+			shortcode: "CxEMBEDrl01",
+			want: models.InstagramMediaInfo{
+				VideoURL: "https://scontent.cdninstagram.com/o1/v/t16/f2/m86/CxEMBEDrl01.mp4?oh=REDACTED",
+				Username: "qwiklip.demo",
+				Caption:  "Harbour timelapse",
+			},
+		},
+		{
+			name:      "GraphQL post query",
+			extractor: ExtractorGraphQL,
+			shortcode: "CxGRAPHQL01",
+			want: models.InstagramMediaInfo{
+				VideoURL: "https://scontent.cdninstagram.com/o1/v/t16/f2/m86/CxGRAPHQL01.mp4?efg=eyJ2ZW5jb2RlX3RhZyI6ImNsaXBzIn0&oh=REDACTED",
+				Username: "qwiklip.demo",
+				Caption:  "Night ferry",
+			},
+		},
+		{
+			name:      "missing post",
+			extractor: ExtractorJSON,
+			shortcode: "CxMISSING01",
+			wantErr:   models.ErrorTypeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newReplayClient(t, tt.extractor)
+			got, err := client.scrapeMediaInfo(context.Background(), client.httpClient, tt.shortcode)
+
+			if tt.wantErr != "" {
+				var appErr *models.AppError
+				if !errors.As(err, &appErr) || appErr.Type != tt.wantErr {
+					t.Fatalf("error = %v, want type %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.VideoURL != tt.want.VideoURL {
+				t.Errorf("VideoURL = %q, want %q", got.VideoURL, tt.want.VideoURL)
+			}
+			if got.ImageURL != tt.want.ImageURL {
+				t.Errorf("ImageURL = %q, want %q", got.ImageURL, tt.want.ImageURL)
+			}
+			if got.Username != tt.want.Username {
+				t.Errorf("Username = %q, want %q", got.Username, tt.want.Username)
+			}
+			if got.Caption != tt.want.Caption {
+				t.Errorf("Caption = %q, want %q", got.Caption, tt.want.Caption)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package instagram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"qwiklip/internal/models"
+)
+
+// maxShareRedirects bounds the redirects followed to resolve a share link
+const maxShareRedirects = 5
+
+// ResolveURL returns the canonical https://www.instagram.com/{p,reel,tv}/{shortcode}/ URL
+// of a post URL. Share links (instagram.com/share/...) and instagr.am short links that
+// carry no shortcode are resolved by following their redirects, which must stay on
+// Instagram's domains.
+func (c *Client) ResolveURL(ctx context.Context, rawURL string) (string, error) {
+	parsedURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || !isInstagramDomain(parsedURL.Hostname()) {
+		return "", models.NewInvalidURLError(rawURL, fmt.Errorf("not an Instagram URL"))
+	}
+	if canonical, ok := canonicalPostURL(parsedURL); ok {
+		return canonical, nil
+	}
+	if !isShareLink(parsedURL) {
+		return "", models.NewInvalidURLError(rawURL, fmt.Errorf("could not extract shortcode from URL"))
+	}
+	return c.followShareLink(ctx, parsedURL)
+}
+
+// followShareLink requests link and its redirects one at a time until one points at a post
+func (c *Client) followShareLink(ctx context.Context, link *url.URL) (string, error) {
+	client := *c.httpClient
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	next := link
+	for range maxShareRedirects {
+		if next.Scheme != "https" && next.Scheme != "http" {
+			return "", models.NewInvalidURLError(link.String(), fmt.Errorf("unsupported scheme %q", next.Scheme))
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next.String(), nil)
+		if err != nil {
+			return "", models.NewInvalidURLError(link.String(), err)
+		}
+		req.Header.Set("User-Agent", DefaultUserAgent)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if shed := shedByScheduler(err); shed != nil {
+				return "", shed
+			}
+			return "", models.NewNetworkError("share link resolution", err)
+		}
+		resp.Body.Close()
+
+		location, err := resp.Location()
+		if errors.Is(err, http.ErrNoLocation) {
+			c.logger.Debug("Share link did not redirect to a post", "url", link.String(), "status", resp.StatusCode)
+			return "", models.NewNotFoundError("Instagram post behind share link")
+		}
+		if err != nil {
+			return "", models.NewParsingError("share link redirect", err)
+		}
+		if !isInstagramDomain(location.Hostname()) {
+			return "", models.NewInvalidURLError(link.String(), fmt.Errorf("redirects off Instagram to %s", location.Host))
+		}
+		if canonical, ok := canonicalPostURL(location); ok {
+			c.logger.Debug("Resolved share link", "url", link.String(), "canonical", canonical)
+			return canonical, nil
+		}
+		// Logged-out visitors are sent to the login page with the post in ?next=
+		if target, err := location.Parse(location.Query().Get("next")); err == nil && isInstagramDomain(target.Hostname()) {
+			if canonical, ok := canonicalPostURL(target); ok {
+				return canonical, nil
+			}
+		}
+		next = location
+	}
+	return "", models.NewNotFoundError("Instagram post behind share link")
+}
+
+// postPath returns the content type segment (p, reel or tv) and shortcode of a post URL path.
+// Anything after the shortcode (e.g. a carousel index) is ignored.
+func postPath(path string) (pathType, shortcode string, ok bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i < len(segments)-1; i++ {
+		switch segments[i] {
+		case "p", "reel", "tv":
+			if segments[i+1] == "" {
+				return "", "", false
+			}
+			return segments[i], segments[i+1], true
+		}
+	}
+	return "", "", false
+}
+
+// canonicalPostURL returns the www.instagram.com URL of the post u points at, without
+// tracking parameters, if u is a post URL rather than a share link
+func canonicalPostURL(u *url.URL) (string, bool) {
+	if isShareLink(u) {
+		return "", false
+	}
+	pathType, shortcode, ok := postPath(u.Path)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("https://www.instagram.com/%s/%s/", pathType, shortcode), true
+}
+
+// isShareLink reports whether u is a share link that only redirects to a post:
+// instagram.com/share/... or an instagr.am link
+func isShareLink(u *url.URL) bool {
+	host := u.Hostname()
+	if host == "instagr.am" || host == "www.instagr.am" {
+		_, _, ok := postPath(u.Path)
+		return !ok
+	}
+	return isInstagramDomain(host) && strings.HasPrefix(u.Path, "/share/")
+}
+
+// isInstagramDomain reports whether host belongs to instagram.com or instagr.am
+func isInstagramDomain(host string) bool {
+	host = strings.ToLower(host)
+	return host == "instagram.com" || strings.HasSuffix(host, ".instagram.com") ||
+		host == "instagr.am" || host == "www.instagr.am"
+}
@@ -0,0 +1,135 @@
+package instagram
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/models"
+)
+
+const (
+	// maxRetryAfter caps the cool-down a Retry-After header can impose
+	maxRetryAfter = time.Hour
+	// maxRateLimitRetries bounds how often a waiting extraction retries after 429s
+	maxRateLimitRetries = 2
+)
+
+// retryAfterSeconds converts a Retry-After header, in seconds or as an HTTP date, to whole
+// seconds for models.NewRateLimitedError, or "" when it is missing or invalid
+func retryAfterSeconds(header string) string {
+	if header == "" {
+		return ""
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(header); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		wait = time.Until(date)
+	}
+	if wait <= 0 {
+		return ""
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	return strconv.Itoa(int((wait + time.Second - 1) / time.Second))
+}
+
+// rateLimitWindow is the cool-down Instagram asked for with its last 429 to an anonymous
+// request. Accounts cool down in the account pool instead; while the window lasts and no
+// account is available, extractions fail fast with the remaining time, or wait it out for
+// background work, rather than hitting Instagram again.
+type rateLimitWindow struct {
+	mu       sync.Mutex
+	until    time.Time
+	fallback time.Duration // Used when the 429 carries no Retry-After
+	metrics  metrics.Recorder
+	logger   *slog.Logger
+}
+
+func newRateLimitWindow(fallback time.Duration, recorder metrics.Recorder, logger *slog.Logger) *rateLimitWindow {
+	return &rateLimitWindow{fallback: fallback, metrics: recorder, logger: logger}
+}
+
+// record opens or extends the window after an anonymous request failed with err
+func (w *rateLimitWindow) record(err error) {
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) || appErr.Type != models.ErrorTypeRateLimited {
+		return
+	}
+	wait := appErr.RetryAfter()
+	if wait == 0 {
+		wait = w.fallback
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if until := time.Now().Add(wait); until.After(w.until) {
+		w.until = until
+		w.logger.Warn("Rate limited by Instagram, pausing anonymous requests", "retry_after", wait, "until", until)
+	}
+}
+
+// remaining returns how long the window still lasts
+func (w *rateLimitWindow) remaining() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return max(time.Until(w.until), 0)
+}
+
+// state returns until when the window lasts, or nil once it has passed
+func (w *rateLimitWindow) state() *time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return coolingUntil(w.until, time.Now())
+}
+
+// rateLimitWaitKey marks contexts of background work that waits for rate limits to pass
+type rateLimitWaitKey struct{}
+
+// WithRateLimitWait marks ctx as background work: its extractions wait for a rate limit
+// window to pass and retry after 429s, instead of failing fast as requests of clients do
+func WithRateLimitWait(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rateLimitWaitKey{}, true)
+}
+
+// waitsOutRateLimits reports whether ctx was marked by WithRateLimitWait
+func waitsOutRateLimits(ctx context.Context) bool {
+	wait, _ := ctx.Value(rateLimitWaitKey{}).(bool)
+	return wait
+}
+
+// awaitRateLimit returns a rate limited error carrying the remaining time while the window
+// lasts and every account is cooling down, or sleeps through it for background work
+func (c *Client) awaitRateLimit(ctx context.Context) error {
+	remaining := c.rateWindow.remaining()
+	if remaining == 0 || c.accounts.healthy() > 0 {
+		return nil
+	}
+	if !waitsOutRateLimits(ctx) {
+		c.metrics.Count(metrics.LimiterRejections, 1, "reason", "rate_limited")
+		return models.NewRateLimitedError(strconv.Itoa(int((remaining + time.Second - 1) / time.Second)))
+	}
+
+	c.logger.Info("Waiting for Instagram rate limit to pass", "remaining", remaining)
+	timer := time.NewTimer(remaining)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isRateLimited reports whether err is a rate limited AppError
+func isRateLimited(err error) bool {
+	var appErr *models.AppError
+	return errors.As(err, &appErr) && appErr.Type == models.ErrorTypeRateLimited
+}
@@ -0,0 +1,138 @@
+package instagram
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/models"
+)
+
+// requestScheduler spaces requests to Instagram to a budget per account and per outbound
+// proxy (or the server's own address without proxies), so a busy hour is spread out
+// instead of getting the deployment blocked. Each budget allows perMinute requests,
+// evenly spaced, with bursts of up to burst. A request over budget waits for its turn
+// when that comes within maxWait and is shed with an overloaded error otherwise.
+type requestScheduler struct {
+	mu       sync.Mutex
+	due      map[string]time.Time // Theoretical arrival time of the next request per budget
+	interval time.Duration        // Spacing of requests within a budget, 0 without a budget
+	burst    int
+	maxWait  time.Duration
+	metrics  metrics.Recorder
+	logger   *slog.Logger
+}
+
+// newRequestScheduler returns a scheduler allowing perMinute requests per budget; 0
+// means no budget
+func newRequestScheduler(perMinute, burst int, maxWait time.Duration, recorder metrics.Recorder, logger *slog.Logger) *requestScheduler {
+	s := &requestScheduler{
+		due:     make(map[string]time.Time),
+		metrics: recorder,
+		logger:  logger,
+	}
+	s.setLimits(perMinute, burst, maxWait)
+	return s
+}
+
+// setLimits changes the budget. Slots already charged keep their place, so a lower
+// budget spaces out the requests after them.
+func (s *requestScheduler) setLimits(perMinute, burst int, maxWait time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.interval = 0
+	if perMinute > 0 {
+		s.interval = time.Minute / time.Duration(perMinute)
+		s.logger.Info("Instagram request budget enabled", "per_minute", perMinute, "burst", burst)
+	}
+	s.burst = burst
+	s.maxWait = maxWait
+}
+
+// wait blocks until every budget in keys has room for one more request, then charges it
+func (s *requestScheduler) wait(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	if s.interval == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	tolerance := time.Duration(s.burst-1) * s.interval
+	start := now
+	for _, key := range keys {
+		if at := s.due[key].Add(-tolerance); at.After(start) {
+			start = at
+		}
+	}
+	delay := start.Sub(now)
+	if delay > s.maxWait {
+		s.mu.Unlock()
+		s.metrics.Count(metrics.LimiterRejections, 1, "reason", "budget")
+		s.logger.Warn("Instagram request budget exhausted, shedding request", "budgets", keys, "next_slot_in", delay)
+		return models.NewOverloadedError("Instagram request budget exhausted")
+	}
+	// The slot is charged now, so concurrent requests line up behind it
+	for _, key := range keys {
+		due := s.due[key]
+		if due.Before(now) {
+			due = now
+		}
+		s.due[key] = due.Add(s.interval)
+	}
+	s.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	s.metrics.Timing(metrics.SchedulerDelay, delay)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// budgetAccountKey carries the name of the account a request is made with
+type budgetAccountKey struct{}
+
+// withBudgetAccount records on ctx which account requests are made with, for the scheduler
+func withBudgetAccount(ctx context.Context, acct *account) context.Context {
+	if acct == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, budgetAccountKey{}, acct.name)
+}
+
+// budgetKeys names the budgets a request is charged to: its account, if any, and the
+// proxy it goes through, or the server's own address when it has neither proxy nor account
+func budgetKeys(ctx context.Context, proxy *outboundProxy) []string {
+	var keys []string
+	accountName, hasAccount := ctx.Value(budgetAccountKey{}).(string)
+	if hasAccount {
+		keys = append(keys, "account:"+accountName)
+	}
+	switch {
+	case proxy != nil:
+		keys = append(keys, "proxy:"+proxy.url.Redacted())
+	case !hasAccount:
+		keys = append(keys, "direct")
+	}
+	return keys
+}
+
+// shedByScheduler returns the error of a request the scheduler shed, which http.Client
+// wraps in a *url.Error, or nil for any other failure
+func shedByScheduler(err error) error {
+	var appErr *models.AppError
+	if errors.As(err, &appErr) && appErr.Type == models.ErrorTypeOverloaded {
+		return appErr
+	}
+	return nil
+}
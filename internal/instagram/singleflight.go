@@ -0,0 +1,71 @@
+package instagram
+
+import (
+	"context"
+	"sync"
+
+	"qwiklip/internal/models"
+)
+
+// flightGroup coalesces concurrent extractions of the same shortcode into one call
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is an in-progress or completed extraction shared by waiting callers
+type flightCall struct {
+	done      chan struct{}
+	cancel    context.CancelFunc
+	waiters   int
+	mediaInfo *models.InstagramMediaInfo
+	err       error
+}
+
+// Do runs fn once per key at a time; concurrent callers wait for and share the result.
+// The shared extraction is cancelled only once every waiting caller's context is done,
+// so one disconnecting client does not fail the others.
+func (g *flightGroup) Do(ctx context.Context, key string, fn func(context.Context) (*models.InstagramMediaInfo, error)) (*models.InstagramMediaInfo, error, bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	call, shared := g.calls[key]
+	if !shared {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &flightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = call
+
+		go func() {
+			call.mediaInfo, call.err = fn(flightCtx)
+
+			g.mu.Lock()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+
+			cancel()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.mediaInfo, call.err, shared
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// Nobody is waiting anymore; abort the extraction and let the next caller start afresh
+			call.cancel()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err(), shared
+	}
+}
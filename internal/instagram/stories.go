@@ -0,0 +1,273 @@
+package instagram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"qwiklip/internal/cache"
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/models"
+	"qwiklip/internal/tracing"
+)
+
+const (
+	// mobileAPIBase is the private API used by the Instagram apps; stories are only served there
+	mobileAPIBase = "https://i.instagram.com/api/v1"
+	// webAppID identifies the Instagram web app to the private API
+	webAppID = "936619743392459"
+	// APIUserAgent mimics the Instagram Android app for mobile API requests
+	APIUserAgent = "Instagram 319.0.0.43.110 Android (33/13; 420dpi; 1080x2400; samsung; SM-G991B; o1s; exynos2100; en_US; 567067343)"
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._]{1,30}$`)
+	storyIDPattern  = regexp.MustCompile(`^[0-9]{1,30}$`)
+)
+
+// GetStory extracts a single story item of a user.
+// Stories require a logged-in session (INSTAGRAM_SESSION_ID); the result carries ExpiresAt.
+func (c *Client) GetStory(ctx context.Context, username, storyID string) (*models.InstagramMediaInfo, error) {
+	if !usernamePattern.MatchString(username) {
+		return nil, models.NewInvalidURLError(username, fmt.Errorf("invalid username"))
+	}
+	if !storyIDPattern.MatchString(storyID) {
+		return nil, models.NewInvalidURLError(storyID, fmt.Errorf("story id must be numeric"))
+	}
+
+	c.logger.Info("Starting Instagram story extraction", "username", username, "story_id", storyID)
+
+	cacheKey := "story:" + storyID
+	if mediaInfo, err := c.cache.Get(ctx, cacheKey); err == nil && !mediaInfo.Expired() {
+		c.logger.Info("Story served from cache", "story_id", storyID)
+		c.metrics.Count(metrics.CacheHits, 1, "cache", "metadata")
+		return mediaInfo, nil
+	} else if err != nil && !errors.Is(err, cache.ErrMiss) {
+		c.logger.Warn("Metadata cache lookup failed", "story_id", storyID, "error", err)
+	}
+	c.metrics.Count(metrics.CacheMisses, 1, "cache", "metadata")
+
+	mediaInfo, err, _ := c.flights.Do(ctx, cacheKey, func(ctx context.Context) (*models.InstagramMediaInfo, error) {
+		userID, err := c.lookupUserID(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+
+		reel, err := c.fetchReel(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range reel.Items {
+			if storyItemID(item) != storyID {
+				continue
+			}
+			mediaInfo, err := c.parseStoryItem(item, storyID)
+			if err == nil {
+				if cacheErr := c.cache.Set(ctx, cacheKey, mediaInfo); cacheErr != nil {
+					c.logger.Warn("Failed to cache story info", "story_id", storyID, "error", cacheErr)
+				}
+			}
+			return mediaInfo, err
+		}
+
+		// Stories vanish from the reel after 24 hours
+		return nil, models.NewNotFoundError(fmt.Sprintf("story '%s' of '%s'", storyID, username))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mediaInfo.Clone(), nil
+}
+
+// lookupUserID resolves a username to the numeric user ID the API expects
+func (c *Client) lookupUserID(ctx context.Context, username string) (string, error) {
+	var profile struct {
+		Data struct {
+			User *struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		} `json:"data"`
+	}
+
+	endpoint := fmt.Sprintf("%s/users/web_profile_info/?username=%s", mobileAPIBase, url.QueryEscape(username))
+	if err := c.getAPIJSON(ctx, endpoint, &profile); err != nil {
+		return "", err
+	}
+	if profile.Data.User == nil || profile.Data.User.ID == "" {
+		return "", models.NewNotFoundError(fmt.Sprintf("Instagram user '%s'", username))
+	}
+	return profile.Data.User.ID, nil
+}
+
+// reelMedia is a reel as returned by the reels_media API: a user's stories or a highlight
+type reelMedia struct {
+	Title string `json:"title"` // Highlight title; empty for stories
+	User  struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Items []map[string]interface{} `json:"items"`
+}
+
+// fetchReel returns a reel with its media items. reelID is a user ID for stories
+// or "highlight:<id>" for highlights.
+func (c *Client) fetchReel(ctx context.Context, reelID string) (*reelMedia, error) {
+	var response struct {
+		Reels map[string]reelMedia `json:"reels"`
+	}
+
+	endpoint := fmt.Sprintf("%s/feed/reels_media/?reel_ids=%s", mobileAPIBase, url.QueryEscape(reelID))
+	if err := c.getAPIJSON(ctx, endpoint, &response); err != nil {
+		return nil, err
+	}
+
+	reel, ok := response.Reels[reelID]
+	if !ok || len(reel.Items) == 0 {
+		return nil, models.NewNotFoundError(fmt.Sprintf("reel '%s'", reelID))
+	}
+	return &reel, nil
+}
+
+// getAPIJSON performs a mobile API GET with the next pooled account and decodes the JSON response into v
+func (c *Client) getAPIJSON(ctx context.Context, endpoint string, v interface{}) error {
+	if err := c.awaitRateLimit(ctx); err != nil {
+		return err
+	}
+	release, err := c.limiter.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ctx, span := tracing.Start(ctx, "instagram.api", tracing.KindInternal)
+	defer span.End()
+	if u, parseErr := url.Parse(endpoint); parseErr == nil {
+		span.SetAttributes("url.path", u.Path)
+	}
+
+	acct := c.accounts.acquire()
+	err = c.doAPIJSON(withBudgetAccount(ctx, acct), c.clientFor(acct), endpoint, v)
+	span.RecordError(err)
+	c.accounts.report(acct, err)
+	if acct == nil {
+		c.rateWindow.record(err)
+	}
+	return err
+}
+
+// doAPIJSON performs a mobile API GET with httpClient and decodes the JSON response into v
+func (c *Client) doAPIJSON(ctx context.Context, httpClient *http.Client, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create API request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", APIUserAgent)
+	req.Header.Set("X-IG-App-ID", webAppID)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if shed := shedByScheduler(err); shed != nil {
+			return shed
+		}
+		return models.NewNetworkError("Instagram API request", err)
+	}
+	defer resp.Body.Close()
+
+	c.logger.Debug("API response received", "endpoint", endpoint, "status", resp.StatusCode, "duration", time.Since(start))
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return models.NewAuthenticationError("a valid INSTAGRAM_SESSION_ID is required for this content")
+	case resp.StatusCode == http.StatusNotFound:
+		return models.NewNotFoundError("Instagram content")
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return models.NewRateLimitedError(retryAfterSeconds(resp.Header.Get("Retry-After")))
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return models.NewNetworkError("Instagram API request", fmt.Errorf("HTTP %d", resp.StatusCode))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16*1024*1024))
+	if err != nil {
+		return models.NewNetworkError("reading Instagram API response", err)
+	}
+
+	// Logged-out sessions get a 200 with a login redirect instead of JSON
+	if strings.Contains(string(body), `"login_required"`) || strings.HasPrefix(strings.TrimSpace(string(body)), "<") {
+		return models.NewAuthenticationError("a valid INSTAGRAM_SESSION_ID is required for this content")
+	}
+	if strings.Contains(string(body), `"challenge_required"`) || strings.Contains(string(body), `"checkpoint_required"`) {
+		return models.NewAuthenticationError("Instagram requested a login challenge for this session")
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return models.NewParsingError("Instagram API response", err)
+	}
+	return nil
+}
+
+// storyItemID returns the numeric media ID of a reel item ("<pk>" or the pk part of "<pk>_<userid>")
+func storyItemID(item map[string]interface{}) string {
+	if pk, ok := item["pk"].(string); ok && pk != "" {
+		return pk
+	}
+	if pk, ok := item["pk"].(float64); ok {
+		return fmt.Sprintf("%.0f", pk)
+	}
+	if id, ok := item["id"].(string); ok {
+		pk, _, _ := strings.Cut(id, "_")
+		return pk
+	}
+	return ""
+}
+
+// parseStoryItem converts a story (or highlight) item into media info including its expiry
+func (c *Client) parseStoryItem(item map[string]interface{}, mediaID string) (*models.InstagramMediaInfo, error) {
+	mediaInfo := &models.InstagramMediaInfo{
+		VideoURL:     c.extractVideoURLFromMedia(item),
+		ThumbnailURL: firstImageCandidate(item),
+		FileName:     fmt.Sprintf("%s.mp4", mediaID),
+	}
+
+	if mediaInfo.VideoURL == "" {
+		if mediaInfo.ThumbnailURL == "" {
+			return nil, models.NewExtractionError(mediaID, fmt.Errorf("story item has no video or image"))
+		}
+		mediaInfo.ImageURL = mediaInfo.ThumbnailURL
+		mediaInfo.FileName = fmt.Sprintf("%s.jpg", mediaID)
+	}
+
+	if mediaInfo.VideoURL != "" {
+		mediaInfo.Versions = extractVideoVersions(item)
+		mediaInfo.Duration, _ = item["video_duration"].(float64)
+	}
+	width, _ := item["original_width"].(float64)
+	height, _ := item["original_height"].(float64)
+	mediaInfo.Width, mediaInfo.Height = int(width), int(height)
+	if user, ok := item["user"].(map[string]interface{}); ok {
+		mediaInfo.Username, _ = user["username"].(string)
+	}
+	mediaInfo.AltText, _ = item["accessibility_caption"].(string)
+	if takenAt, ok := item["taken_at"].(float64); ok && takenAt > 0 {
+		mediaInfo.TakenAt = time.Unix(int64(takenAt), 0).UTC()
+	}
+	if expiringAt, ok := item["expiring_at"].(float64); ok && expiringAt > 0 {
+		mediaInfo.ExpiresAt = time.Unix(int64(expiringAt), 0).UTC()
+	}
+	mediaInfo.Classify()
+
+	return mediaInfo, nil
+}
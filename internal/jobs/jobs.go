@@ -0,0 +1,430 @@
+// Package jobs runs long operations (bulk exports, batch extractions) in the
+// background on a bounded worker pool and keeps their status for polling.
+//
+// Jobs live in memory; when a state file is configured every state change is
+// also written to it so finished jobs and their results survive a restart.
+// Jobs that were queued or running when the process stopped are marked failed
+// on the next start, since their work cannot be resumed.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"qwiklip/internal/config"
+)
+
+// Status is the lifecycle state of a job
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Finished reports whether the job can no longer change
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+var (
+	// ErrNotFound is returned for unknown or expired job IDs
+	ErrNotFound = errors.New("job not found")
+	// ErrQueueFull is returned when no more jobs can be queued
+	ErrQueueFull = errors.New("job queue is full")
+)
+
+// Progress counts completed items of a job and, for jobs that download media, the bytes so far
+type Progress struct {
+	Done  int   `json:"done"`
+	Total int   `json:"total"`
+	Bytes int64 `json:"bytes,omitempty"`
+}
+
+// Job is a snapshot of one job's state
+type Job struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Status     Status          `json:"status"`
+	Progress   Progress        `json:"progress"`
+	Error      string          `json:"error,omitempty"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	StartedAt  time.Time       `json:"startedAt,omitzero"`
+	FinishedAt time.Time       `json:"finishedAt,omitzero"`
+}
+
+// Task is the work of one job. It calls progress as items complete (or bytes arrive) and
+// returns a JSON-encodable result. Files it produces belong in Manager.FilePath(jobID, ext).
+type Task func(ctx context.Context, jobID string, progress func(Progress)) (any, error)
+
+// entry is a job plus the state only the manager needs
+type entry struct {
+	job     Job
+	task    Task
+	cancel  context.CancelFunc
+	changed chan struct{} // Closed at the next change of job; nil until someone watches
+}
+
+// Manager queues jobs and runs them on a fixed number of workers
+type Manager struct {
+	dir       string
+	stateFile string
+	retention time.Duration
+	logger    *slog.Logger
+
+	mu   sync.Mutex
+	jobs map[string]*entry
+
+	queue chan *entry
+	ctx   context.Context
+	stop  context.CancelFunc
+	wg    sync.WaitGroup
+}
+
+// New creates a manager, restores persisted jobs and starts the workers.
+// It returns nil (jobs disabled) when no workers are configured.
+func New(cfg *config.JobsConfig, logger *slog.Logger) (*Manager, error) {
+	if cfg.Workers == 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create jobs directory: %w", err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	m := &Manager{
+		dir:       cfg.Dir,
+		stateFile: cfg.StateFile,
+		retention: cfg.Retention,
+		logger:    logger,
+		jobs:      make(map[string]*entry),
+		queue:     make(chan *entry, cfg.QueueSize),
+		ctx:       ctx,
+		stop:      stop,
+	}
+
+	if err := m.load(); err != nil {
+		stop()
+		return nil, err
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	m.wg.Add(1)
+	go m.expireLoop()
+
+	return m, nil
+}
+
+// Submit queues a task and returns the new job
+func (m *Manager) Submit(jobType string, task Task) (Job, error) {
+	var id [8]byte
+	rand.Read(id[:])
+
+	e := &entry{
+		job: Job{
+			ID:        hex.EncodeToString(id[:]),
+			Type:      jobType,
+			Status:    StatusQueued,
+			CreatedAt: time.Now().UTC(),
+		},
+		task: task,
+	}
+
+	m.mu.Lock()
+	select {
+	case m.queue <- e:
+	default:
+		m.mu.Unlock()
+		return Job{}, ErrQueueFull
+	}
+	m.jobs[e.job.ID] = e
+	job := e.job
+	m.saveLocked()
+	m.mu.Unlock()
+
+	m.logger.Info("Job queued", "job_id", job.ID, "type", jobType)
+	return job, nil
+}
+
+// Get returns a snapshot of the job
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return e.job, nil
+}
+
+// Watch returns a snapshot of the job and a channel that is closed at its next change:
+// progress, a new status, or removal once the job expires
+func (m *Manager) Watch(id string) (Job, <-chan struct{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, nil, ErrNotFound
+	}
+	if e.changed == nil {
+		e.changed = make(chan struct{})
+	}
+	return e.job, e.changed, nil
+}
+
+// notifyLocked wakes the watchers of a job; m.mu must be held
+func (m *Manager) notifyLocked(e *entry) {
+	if e.changed != nil {
+		close(e.changed)
+		e.changed = nil
+	}
+}
+
+// Cancel stops a queued or running job. Cancelling a finished job is a no-op.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	switch e.job.Status {
+	case StatusQueued:
+		// The worker skips it when it comes off the queue
+		m.finishLocked(e, StatusCanceled, nil, context.Canceled)
+	case StatusRunning:
+		e.cancel()
+	}
+	return e.job, nil
+}
+
+// FilePath returns where a job stores a file with the given extension (e.g. ".zip")
+func (m *Manager) FilePath(id, ext string) string {
+	return filepath.Join(m.dir, id+ext)
+}
+
+// Close stops the workers, cancelling running jobs, and waits until ctx is done
+func (m *Manager) Close(ctx context.Context) error {
+	m.stop()
+
+	finished := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Manager) worker() {
+	defer m.wg.Done()
+	for {
+		select {
+		case e := <-m.queue:
+			m.run(e)
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// run executes one job and records its outcome
+func (m *Manager) run(e *entry) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+
+	m.mu.Lock()
+	if e.job.Status != StatusQueued {
+		m.mu.Unlock()
+		return
+	}
+	task := e.task
+	e.cancel = cancel
+	e.job.Status = StatusRunning
+	e.job.StartedAt = time.Now().UTC()
+	m.saveLocked()
+	m.notifyLocked(e)
+	m.mu.Unlock()
+
+	m.logger.Info("Job started", "job_id", e.job.ID, "type", e.job.Type)
+
+	progress := func(p Progress) {
+		m.mu.Lock()
+		e.job.Progress = p
+		m.notifyLocked(e)
+		m.mu.Unlock()
+	}
+	result, err := task(ctx, e.job.ID, progress)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := StatusSucceeded
+	switch {
+	case err != nil && m.ctx.Err() != nil:
+		status, err = StatusFailed, errors.New("interrupted by server shutdown")
+	case err != nil && ctx.Err() != nil:
+		status = StatusCanceled
+	case err != nil:
+		status = StatusFailed
+	}
+	m.finishLocked(e, status, result, err)
+
+	m.logger.Info("Job finished",
+		"job_id", e.job.ID,
+		"type", e.job.Type,
+		"status", e.job.Status,
+		"duration", e.job.FinishedAt.Sub(e.job.StartedAt))
+}
+
+// finishLocked moves a job to a final state; m.mu must be held
+func (m *Manager) finishLocked(e *entry, status Status, result any, err error) {
+	e.job.Status = status
+	e.job.FinishedAt = time.Now().UTC()
+	e.task = nil
+	if err != nil {
+		e.job.Error = err.Error()
+	}
+	if result != nil {
+		encoded, encodeErr := json.Marshal(result)
+		if encodeErr != nil {
+			e.job.Status = StatusFailed
+			e.job.Error = fmt.Sprintf("failed to encode result: %v", encodeErr)
+		} else {
+			e.job.Result = encoded
+		}
+	}
+	m.saveLocked()
+	m.notifyLocked(e)
+}
+
+// expireLoop drops finished jobs and their files once the retention period has passed
+func (m *Manager) expireLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(min(m.retention, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.expire(time.Now())
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *Manager) expire(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for id, e := range m.jobs {
+		if !e.job.Status.Finished() || now.Sub(e.job.FinishedAt) < m.retention {
+			continue
+		}
+		delete(m.jobs, id)
+		m.notifyLocked(e)
+		m.removeFiles(id)
+		removed++
+	}
+	if removed > 0 {
+		m.logger.Debug("Expired finished jobs", "count", removed)
+		m.saveLocked()
+	}
+}
+
+// removeFiles deletes every file a job stored via FilePath
+func (m *Manager) removeFiles(id string) {
+	matches, _ := filepath.Glob(filepath.Join(m.dir, id+".*"))
+	for _, match := range matches {
+		if err := os.Remove(match); err != nil && !os.IsNotExist(err) {
+			m.logger.Warn("Failed to remove job file", "path", match, "error", err)
+		}
+	}
+}
+
+// load restores jobs from the state file; work that was interrupted is marked failed
+func (m *Manager) load() error {
+	if m.stateFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(m.stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read jobs state file: %w", err)
+	}
+
+	var jobs []Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return fmt.Errorf("failed to parse jobs state file: %w", err)
+	}
+
+	interrupted := 0
+	for _, job := range jobs {
+		if !job.Status.Finished() {
+			job.Status = StatusFailed
+			job.Error = "interrupted by server restart"
+			job.FinishedAt = time.Now().UTC()
+			interrupted++
+		}
+		m.jobs[job.ID] = &entry{job: job}
+	}
+	m.logger.Info("Restored jobs", "jobs", len(jobs), "interrupted", interrupted)
+	return nil
+}
+
+// saveLocked writes all jobs to the state file atomically; m.mu must be held.
+// Progress updates are not saved on their own, only state changes.
+func (m *Manager) saveLocked() {
+	if m.stateFile == "" {
+		return
+	}
+
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, e := range m.jobs {
+		jobs = append(jobs, e.job)
+	}
+	data, err := json.Marshal(jobs)
+	if err != nil {
+		m.logger.Warn("Failed to encode jobs state", "error", err)
+		return
+	}
+
+	tmp := m.stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		m.logger.Warn("Failed to write jobs state file", "error", err)
+		return
+	}
+	if err := os.Rename(tmp, m.stateFile); err != nil {
+		m.logger.Warn("Failed to replace jobs state file", "error", err)
+	}
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"time"
+
+	"qwiklip/internal/config"
+)
+
+// Metric names emitted by the application
+const (
+	HTTPRequests      = "http.requests"
+	HTTPDuration      = "http.request.duration"
+	StreamBytes       = "stream.bytes"
+	StreamActive      = "stream.active"
+	StreamLimit       = "stream.limit"
+	StreamRejections  = "stream.rejections"
+	StreamCancelled   = "stream.client_cancelled"
+	ExtractionLatency = "extraction.latency"
+	ExtractorAttempts = "extraction.extractor.attempts"
+	CacheHits         = "cache.hits"
+	CacheMisses       = "cache.misses"
+	CacheEvictions    = "cache.evictions"
+	AccountCooldowns  = "instagram.account.cooldowns"
+	ProxyRequests     = "proxy.requests"
+	ProxyFailures     = "proxy.failures"
+	LimiterWait       = "instagram.limiter.wait"
+	LimiterRejections = "instagram.limiter.rejections"
+	SchedulerDelay    = "instagram.scheduler.delay"
+)
+
+// Recorder defines the interface for emitting application metrics.
+// Tags are passed as alternating key/value pairs, mirroring slog attributes.
+type Recorder interface {
+	Count(name string, value int64, tags ...string)
+	Timing(name string, d time.Duration, tags ...string)
+	Gauge(name string, value float64, tags ...string)
+}
+
+// New creates a recorder for the configured sink, falling back to a no-op recorder
+func New(cfg *config.MetricsConfig) (Recorder, error) {
+	if cfg.StatsDAddr == "" {
+		return Nop{}, nil
+	}
+	return NewStatsD(cfg.StatsDAddr, cfg.Prefix, cfg.DogStatsD)
+}
+
+// Nop is a recorder that discards all metrics
+type Nop struct{}
+
+func (Nop) Count(string, int64, ...string)          {}
+func (Nop) Timing(string, time.Duration, ...string) {}
+func (Nop) Gauge(string, float64, ...string)        {}
+
+// Multi sends every metric to each of recorders
+func Multi(recorders ...Recorder) Recorder {
+	return multi(recorders)
+}
+
+type multi []Recorder
+
+func (m multi) Count(name string, value int64, tags ...string) {
+	for _, r := range m {
+		r.Count(name, value, tags...)
+	}
+}
+
+func (m multi) Timing(name string, d time.Duration, tags ...string) {
+	for _, r := range m {
+		r.Timing(name, d, tags...)
+	}
+}
+
+func (m multi) Gauge(name string, value float64, tags ...string) {
+	for _, r := range m {
+		r.Gauge(name, value, tags...)
+	}
+}
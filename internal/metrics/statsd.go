@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatsD emits metrics over UDP using the StatsD line protocol
+type StatsD struct {
+	conn      net.Conn
+	prefix    string
+	dogStatsD bool // Append tags in DogStatsD "|#key:value" form
+}
+
+// NewStatsD creates a StatsD recorder sending to the given host:port
+func NewStatsD(addr, prefix string, dogStatsD bool) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to statsd at %s: %w", addr, err)
+	}
+
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+
+	return &StatsD{
+		conn:      conn,
+		prefix:    prefix,
+		dogStatsD: dogStatsD,
+	}, nil
+}
+
+// Count increments a counter by value
+func (s *StatsD) Count(name string, value int64, tags ...string) {
+	s.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// Timing records a duration in milliseconds
+func (s *StatsD) Timing(name string, d time.Duration, tags ...string) {
+	ms := float64(d) / float64(time.Millisecond)
+	s.send(name, strconv.FormatFloat(ms, 'f', 3, 64), "ms", tags)
+}
+
+// Gauge sets a gauge to value
+func (s *StatsD) Gauge(name string, value float64, tags ...string) {
+	s.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+// Close closes the underlying UDP connection
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
+
+// send writes a single metric line; delivery is best-effort like StatsD itself
+func (s *StatsD) send(name, value, metricType string, tags []string) {
+	var line strings.Builder
+	line.WriteString(s.prefix)
+	line.WriteString(name)
+	line.WriteByte(':')
+	line.WriteString(value)
+	line.WriteByte('|')
+	line.WriteString(metricType)
+
+	if s.dogStatsD && len(tags) >= 2 {
+		line.WriteString("|#")
+		for i := 0; i+1 < len(tags); i += 2 {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			line.WriteString(tags[i])
+			line.WriteByte(':')
+			line.WriteString(tags[i+1])
+		}
+	}
+
+	s.conn.Write([]byte(line.String()))
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"qwiklip/internal/accesslog"
+)
+
+// AccessLogMiddleware writes an access log entry with the status, body size and duration of each request
+func AccessLogMiddleware(log *accesslog.Logger) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrapper := &countingWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next(wrapper, r)
+
+			log.Log(accesslog.Entry{
+				Time:       start.UTC(),
+				RequestID:  RequestIDFromContext(r.Context()),
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				Proto:      r.Proto,
+				Status:     wrapper.statusCode,
+				Bytes:      wrapper.written,
+				DurationMS: time.Since(start).Milliseconds(),
+				ClientIP:   getClientIP(r),
+				UserAgent:  r.UserAgent(),
+				Referer:    r.Referer(),
+			})
+		}
+	}
+}
+
+// countingWriter wraps http.ResponseWriter to capture the status code and body size
+type countingWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	written     int64
+	wroteHeader bool
+}
+
+func (cw *countingWriter) WriteHeader(code int) {
+	if !cw.wroteHeader {
+		cw.statusCode = code
+		cw.wroteHeader = code >= 200 // 1xx responses are followed by the real one
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	cw.wroteHeader = true
+	n, err := cw.ResponseWriter.Write(p)
+	cw.written += int64(n)
+	return n, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController (flushing, deadlines)
+func (cw *countingWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,446 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"qwiklip/internal/config"
+)
+
+// minJWKSRefetch bounds how often an unknown key ID can trigger a JWKS fetch
+const minJWKSRefetch = time.Minute
+
+// Claims holds the registered claims of a verified token plus the raw claim set
+type Claims struct {
+	Subject string
+	Issuer  string
+	Raw     map[string]interface{}
+}
+
+type claimsContextKey struct{}
+
+// ClaimsFromContext returns the claims of the authenticated request, if any
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims, ok
+}
+
+// JWTVerifier validates RS256/384/512 and ES256/384 bearer tokens against an identity provider's JWKS
+type JWTVerifier struct {
+	config     *config.AuthConfig
+	httpClient *http.Client
+	logger     *slog.Logger
+
+	mu        sync.RWMutex
+	keys      map[string]signingKey
+	fetchedAt time.Time
+}
+
+// signingKey is a JWKS key with the algorithm it is restricted to, if the JWKS names one
+type signingKey struct {
+	key crypto.PublicKey
+	alg string
+}
+
+// NewJWTVerifier creates a verifier and loads the signing keys.
+// A failed initial fetch is logged and retried on the first request.
+func NewJWTVerifier(cfg *config.AuthConfig, logger *slog.Logger) *JWTVerifier {
+	v := &JWTVerifier{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+	}
+	if err := v.refresh(context.Background()); err != nil {
+		logger.Warn("Failed to load JWKS, will retry on demand", "url", cfg.JWKSURL, "error", err)
+	}
+	return v
+}
+
+// JWTAuthMiddleware rejects requests without a valid bearer token.
+// CORS preflight requests pass through so browsers can discover the allowed headers.
+func JWTAuthMiddleware(verifier *JWTVerifier, logger *slog.Logger) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				next(w, r)
+				return
+			}
+
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				writeUnauthorized(w, "", "missing bearer token")
+				return
+			}
+
+			claims, err := verifier.Verify(r.Context(), strings.TrimSpace(token))
+			if err != nil {
+				LoggerFromContext(r.Context(), logger).Warn("Rejected bearer token",
+					"path", r.URL.Path,
+					"client_ip", getClientIP(r),
+					"error", err)
+				writeUnauthorized(w, "invalid_token", err.Error())
+				return
+			}
+
+			next(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey{}, claims)))
+		}
+	}
+}
+
+// StaticTokenMiddleware rejects requests whose bearer token is not token. Browsers may
+// send the token as the password of HTTP Basic credentials instead, with any user name.
+// The comparison runs in constant time so the token cannot be guessed byte by byte.
+func StaticTokenMiddleware(token string, logger *slog.Logger) func(http.HandlerFunc) http.HandlerFunc {
+	expected := sha256.Sum256([]byte(token))
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok {
+				_, presented, ok = r.BasicAuth()
+			}
+			if !ok || presented == "" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="qwiklip admin"`)
+				writeUnauthorized(w, "", "missing bearer token")
+				return
+			}
+
+			// Hashing first keeps the comparison constant-time regardless of length
+			actual := sha256.Sum256([]byte(strings.TrimSpace(presented)))
+			if subtle.ConstantTimeCompare(actual[:], expected[:]) != 1 {
+				LoggerFromContext(r.Context(), logger).Warn("Rejected static bearer token",
+					"path", r.URL.Path,
+					"client_ip", getClientIP(r))
+				w.Header().Set("WWW-Authenticate", `Basic realm="qwiklip admin"`)
+				writeUnauthorized(w, "invalid_token", "invalid token")
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// writeUnauthorized sends a 401 JSON error with an RFC 6750 challenge, in addition to any
+// challenge already set
+func writeUnauthorized(w http.ResponseWriter, code, message string) {
+	challenge := `Bearer realm="qwiklip"`
+	if code != "" {
+		challenge += fmt.Sprintf(`, error="%s"`, code)
+	}
+	w.Header().Add("WWW-Authenticate", challenge)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+
+	response := map[string]interface{}{
+		"error":  message,
+		"status": http.StatusText(http.StatusUnauthorized),
+		"code":   http.StatusUnauthorized,
+	}
+	if id := w.Header().Get(RequestIDHeader); id != "" {
+		response["request_id"] = id
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// Verify checks the token signature, expiry, issuer and audience and returns its claims
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature encoding: %w", err)
+	}
+	if key.alg != "" && header.Alg != key.alg {
+		return nil, fmt.Errorf("algorithm '%s' does not match key algorithm '%s'", header.Alg, key.alg)
+	}
+	if err := verifySignature(header.Alg, key.key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+	if err := v.validateClaims(raw); err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{Raw: raw}
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Issuer, _ = raw["iss"].(string)
+	return claims, nil
+}
+
+// validateClaims enforces exp, nbf, iss and aud
+func (v *JWTVerifier) validateClaims(raw map[string]interface{}) error {
+	now := time.Now()
+	skew := v.config.ClockSkew
+
+	exp, ok := raw["exp"].(float64)
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(skew)) {
+		return errors.New("token has expired")
+	}
+	if nbf, ok := raw["nbf"].(float64); ok && now.Add(skew).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token is not valid yet")
+	}
+
+	if v.config.Issuer != "" {
+		if iss, _ := raw["iss"].(string); iss != v.config.Issuer {
+			return fmt.Errorf("unexpected issuer '%s'", iss)
+		}
+	}
+
+	if v.config.Audience != "" {
+		// "aud" may be a single string or a list
+		matched := false
+		switch aud := raw["aud"].(type) {
+		case string:
+			matched = aud == v.config.Audience
+		case []interface{}:
+			for _, entry := range aud {
+				if entry == v.config.Audience {
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			return errors.New("token is not intended for this audience")
+		}
+	}
+
+	return nil
+}
+
+// key returns the signing key for kid, re-fetching the JWKS when it is stale or the key is unknown
+func (v *JWTVerifier) key(ctx context.Context, kid string) (signingKey, error) {
+	v.mu.RLock()
+	key, found := v.keys[kid]
+	age := time.Since(v.fetchedAt)
+	v.mu.RUnlock()
+
+	stale := age > v.config.JWKSRefresh
+	if (found && !stale) || (!found && age < minJWKSRefetch) {
+		if !found {
+			return signingKey{}, fmt.Errorf("unknown signing key '%s'", kid)
+		}
+		return key, nil
+	}
+
+	if err := v.refresh(ctx); err != nil {
+		if found {
+			// Keep serving the cached key while the identity provider is unreachable
+			v.logger.Warn("Failed to refresh JWKS", "url", v.config.JWKSURL, "error", err)
+			return key, nil
+		}
+		return signingKey{}, err
+	}
+
+	v.mu.RLock()
+	key, found = v.keys[kid]
+	v.mu.RUnlock()
+	if !found {
+		return signingKey{}, fmt.Errorf("unknown signing key '%s'", kid)
+	}
+	return key, nil
+}
+
+// refresh downloads the JWKS and replaces the cached keys
+func (v *JWTVerifier) refresh(ctx context.Context) error {
+	// Record the attempt up front so a failing IdP is not hammered by every request
+	v.mu.Lock()
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", v.config.JWKSURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS endpoint returned status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]signingKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			v.logger.Warn("Skipping unusable JWKS key", "kid", jwk.Kid, "error", err)
+			continue
+		}
+		keys[jwk.Kid] = signingKey{key: key, alg: jwk.Alg}
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS contains no usable signing keys")
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.mu.Unlock()
+
+	v.logger.Debug("Loaded JWKS", "url", v.config.JWKSURL, "keys", len(keys))
+	return nil
+}
+
+// jsonWebKey is an RSA or EC public key from a JWKS document
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", k.Kty)
+	}
+}
+
+// ecdsaCurves is the one curve each ES algorithm is defined for (RFC 7518 section 3.4)
+var ecdsaCurves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+}
+
+// verifySignature checks signature over signed with key using the JWS algorithm alg
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	var h hash.Hash
+	var hashID crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		h, hashID = sha256.New(), crypto.SHA256
+	case "RS384", "ES384":
+		h, hashID = sha512.New384(), crypto.SHA384
+	case "RS512":
+		h, hashID = sha512.New(), crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported signing algorithm '%s'", alg)
+	}
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algorithm '%s' does not match RSA key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, hashID, digest, signature); err != nil {
+			return errors.New("invalid token signature")
+		}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if ecdsaCurves[alg] != pub.Curve || len(signature) != 2*size {
+			return fmt.Errorf("algorithm '%s' does not match %s key", alg, pub.Curve.Params().Name)
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid token signature")
+		}
+	default:
+		return errors.New("unsupported key type")
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+
+	"qwiklip/internal/errorreport"
 )
 
 // LoggingMiddleware logs HTTP requests with structured logging
@@ -13,6 +15,7 @@ func LoggingMiddleware(logger *slog.Logger) func(http.HandlerFunc) http.HandlerF
 		return func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 			clientIP := getClientIP(r)
+			logger := LoggerFromContext(r.Context(), logger)
 
 			logger.Info("Request started",
 				"method", r.Method,
@@ -48,6 +51,11 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController (flushing, deadlines)
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // getClientIP extracts the real client IP from the request
 func getClientIP(r *http.Request) string {
 	// Check X-Forwarded-For header (most common with proxies/load balancers)
@@ -64,33 +72,85 @@ func getClientIP(r *http.Request) string {
 	return r.RemoteAddr
 }
 
-// CORSMiddleware adds CORS headers for cross-origin requests
-func CORSMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+// CORSMiddleware adds CORS headers for requests from allowed origins ("*" allows any)
+func CORSMiddleware(allowedOrigins []string) func(http.HandlerFunc) http.HandlerFunc {
+	allowAll := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			switch {
+			case allowAll:
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			case origin != "" && allowed[origin]:
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Add("Vary", "Origin")
+			default:
+				// Origin not allowed - serve the request without CORS headers
+				next(w, r)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+			w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-Qwiklip-Shortcode, X-Qwiklip-Username, X-Qwiklip-Caption, X-Qwiklip-Duration, X-Qwiklip-API-Version")
+
+			if r.Method == "OPTIONS" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// SecurityHeadersMiddleware adds hardening headers to every response
+func SecurityHeadersMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		if r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
 		}
 
 		next(w, r)
 	}
 }
 
-// RecoveryMiddleware recovers from panics and logs them
-func RecoveryMiddleware(logger *slog.Logger) func(http.HandlerFunc) http.HandlerFunc {
+// AltSvcMiddleware advertises an alternative service, such as the HTTP/3 endpoint of a
+// front proxy, on every response; clients remember it for the whole origin
+func AltSvcMiddleware(value string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Alt-Svc", value)
+			next(w, r)
+		}
+	}
+}
+
+// RecoveryMiddleware recovers from panics, logs them and sends them to the error reporter
+func RecoveryMiddleware(logger *slog.Logger, reporter errorreport.Reporter) func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
-					logger.Error("Panic recovered",
+					LoggerFromContext(r.Context(), logger).Error("Panic recovered",
 						"panic", err,
 						"method", r.Method,
 						"path", r.URL.Path,
 						"client_ip", getClientIP(r))
+					reporter.CapturePanic(r.Context(), err,
+						"method", r.Method,
+						"route", r.Pattern,
+						"request_id", RequestIDFromContext(r.Context()))
 
 					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 				}
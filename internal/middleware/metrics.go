@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"qwiklip/internal/metrics"
+)
+
+// MetricsMiddleware records request counts and latency per route and status
+func MetricsMiddleware(recorder metrics.Recorder) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next(wrapper, r)
+
+			// Use the matched mux pattern rather than the raw path to keep tag cardinality low
+			tags := []string{
+				"method", r.Method,
+				"route", r.Pattern,
+				"status", strconv.Itoa(wrapper.statusCode),
+			}
+			recorder.Count(metrics.HTTPRequests, 1, tags...)
+			recorder.Timing(metrics.HTTPDuration, time.Since(start), tags...)
+		}
+	}
+}
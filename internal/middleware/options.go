@@ -8,6 +8,10 @@ type MiddlewareConfig struct {
 	EnableRecovery bool
 	EnableLogging  bool
 	EnableCORS     bool
+	EnableMetrics  bool
+	EnableAuth     bool // Require a bearer token when authentication is configured
+	EnableTracing  bool // Record a span per request when tracing is configured
+	EnableThrottle bool // Pace response bodies when bandwidth limits are configured
 }
 
 // WithRecovery enables error recovery middleware
@@ -31,21 +35,65 @@ func WithCORS() MiddlewareOption {
 	}
 }
 
-// DefaultConfig returns a middleware configuration with common defaults
+// WithMetrics enables request metrics middleware
+func WithMetrics() MiddlewareOption {
+	return func(c *MiddlewareConfig) {
+		c.EnableMetrics = true
+	}
+}
+
+// WithAuth enables bearer token authentication middleware
+func WithAuth() MiddlewareOption {
+	return func(c *MiddlewareConfig) {
+		c.EnableAuth = true
+	}
+}
+
+// WithTracing enables request tracing middleware
+func WithTracing() MiddlewareOption {
+	return func(c *MiddlewareConfig) {
+		c.EnableTracing = true
+	}
+}
+
+// WithThrottle enables response bandwidth limiting middleware
+func WithThrottle() MiddlewareOption {
+	return func(c *MiddlewareConfig) {
+		c.EnableThrottle = true
+	}
+}
+
+// DefaultConfig returns a middleware configuration with common defaults. Bearer tokens
+// are not required, since browsers cannot attach them to <video> or <iframe> requests.
 func DefaultConfig() *MiddlewareConfig {
 	return &MiddlewareConfig{
 		EnableRecovery: true,
 		EnableLogging:  true,
 		EnableCORS:     true,
+		EnableMetrics:  true,
+		EnableAuth:     false,
+		EnableTracing:  true,
+		EnableThrottle: true,
 	}
 }
 
+// APIConfig returns DefaultConfig with bearer token authentication, for JSON API routes
+func APIConfig() *MiddlewareConfig {
+	config := DefaultConfig()
+	config.EnableAuth = true
+	return config
+}
+
 // MinimalConfig returns a middleware configuration with minimal features
 func MinimalConfig() *MiddlewareConfig {
 	return &MiddlewareConfig{
 		EnableRecovery: false,
 		EnableLogging:  false,
 		EnableCORS:     false,
+		EnableMetrics:  false,
+		EnableAuth:     false,
+		EnableTracing:  false,
+		EnableThrottle: false,
 	}
 }
 
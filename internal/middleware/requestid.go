@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds incoming IDs so they cannot bloat logs
+const maxRequestIDLength = 128
+
+type requestIDContextKey struct{}
+type loggerContextKey struct{}
+
+// RequestIDMiddleware honors a well-formed incoming X-Request-ID or generates one,
+// echoes it in the response and stores it, plus a logger tagged with it, in the request context
+func RequestIDMiddleware(logger *slog.Logger) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			ctx := context.WithValue(r.Context(), requestIDContextKey{}, id)
+			ctx = context.WithValue(ctx, loggerContextKey{}, logger.With("request_id", id))
+			next(w, r.WithContext(ctx))
+		}
+	}
+}
+
+// RequestIDFromContext returns the ID of the current request, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// LoggerFromContext returns the request-scoped logger, or fallback outside a request
+func LoggerFromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// validRequestID accepts non-empty IDs of visible ASCII characters up to maxRequestIDLength
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
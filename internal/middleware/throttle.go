@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"qwiklip/internal/config"
+)
+
+// minThrottleBurst is the smallest chunk a throttled response is written in, so that
+// low limits do not turn every write into a syscall of a few bytes
+const minThrottleBurst = 16 * 1024
+
+// Throttle limits response bandwidth across the whole server and per client connection.
+// Limits can be changed while it runs; responses in progress pick them up on their next write.
+type Throttle struct {
+	global  tokenBucket
+	rate    atomic.Int64 // Bytes per second for the whole server (0 = unlimited)
+	perConn atomic.Int64 // Bytes per second per connection (0 = unlimited)
+}
+
+type connBucketContextKey struct{}
+
+// NewThrottle creates a bandwidth limiter with the limits from cfg
+func NewThrottle(cfg *config.StreamConfig) *Throttle {
+	t := &Throttle{}
+	t.SetLimits(cfg.MaxBandwidth, cfg.MaxConnBandwidth)
+	return t
+}
+
+// SetLimits replaces the global and per-connection limits in bytes per second; 0 lifts a limit
+func (t *Throttle) SetLimits(global, perConn int64) {
+	t.rate.Store(max(global, 0))
+	t.perConn.Store(max(perConn, 0))
+}
+
+// Enabled reports whether any limit is set
+func (t *Throttle) Enabled() bool {
+	return t.rate.Load() > 0 || t.perConn.Load() > 0
+}
+
+// ConnContext gives every client connection its own bucket; install it as
+// http.Server.ConnContext so HTTP/2 streams and keep-alive requests share it
+func (t *Throttle) ConnContext(ctx context.Context, _ net.Conn) context.Context {
+	return context.WithValue(ctx, connBucketContextKey{}, &tokenBucket{})
+}
+
+// ThrottleMiddleware paces response bodies to the configured bandwidth limits
+func ThrottleMiddleware(t *Throttle) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !t.Enabled() {
+				next(w, r)
+				return
+			}
+			conn, _ := r.Context().Value(connBucketContextKey{}).(*tokenBucket)
+			if conn == nil {
+				conn = &tokenBucket{} // Server started without ConnContext
+			}
+			next(&throttledWriter{ResponseWriter: w, ctx: r.Context(), conn: conn, throttle: t}, r)
+		}
+	}
+}
+
+// throttledWriter waits for both buckets before writing each chunk of the body
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	conn     *tokenBucket
+	throttle *Throttle
+}
+
+func (tw *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		rate, perConn := tw.throttle.rate.Load(), tw.throttle.perConn.Load()
+		chunk := p[:min(len(p), chunkSize(rate, perConn, len(p)))]
+
+		wait := max(tw.conn.reserve(len(chunk), perConn), tw.throttle.global.reserve(len(chunk), rate))
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-tw.ctx.Done():
+				timer.Stop()
+				return written, tw.ctx.Err()
+			}
+		}
+
+		n, err := tw.ResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// chunkSize is the burst of the tightest limit, or n when nothing is limited
+func chunkSize(rate, perConn int64, n int) int {
+	for _, r := range []int64{rate, perConn} {
+		if r > 0 {
+			n = min(n, burstSize(r))
+		}
+	}
+	return n
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController (flushing, deadlines)
+func (tw *throttledWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// tokenBucket refills at the rate it is given up to a tenth of a second's worth.
+// Reservations may drive it negative; the caller then waits until it is paid back,
+// which queues concurrent writers in the order they arrived. The zero value starts full.
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// burstSize is the capacity of a bucket refilling at bytesPerSecond
+func burstSize(bytesPerSecond int64) int {
+	return max(int(bytesPerSecond/10), minThrottleBurst)
+}
+
+// reserve takes n bytes from the bucket at rate bytes per second and returns how long to
+// wait before sending them. A rate of 0 never waits.
+func (b *tokenBucket) reserve(n int, rate int64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	burst := float64(burstSize(rate))
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*float64(rate), burst)
+	}
+	b.last = now
+
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / float64(rate) * float64(time.Second))
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"qwiklip/internal/tracing"
+)
+
+// TracingMiddleware starts a server span per request, continuing an incoming W3C traceparent
+func TracingMiddleware(tracer *tracing.Tracer) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := tracer.StartRoot(r.Context(), r.Method+" "+r.Pattern, r.Header.Get("traceparent"))
+			if span == nil {
+				next(w, r)
+				return
+			}
+			defer span.End()
+
+			wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next(wrapper, r.WithContext(ctx))
+
+			span.SetAttributes(
+				"http.request.method", r.Method,
+				"http.route", r.Pattern,
+				"url.path", r.URL.Path,
+				"http.response.status_code", wrapper.statusCode)
+			if wrapper.statusCode >= 500 {
+				span.RecordError(fmt.Errorf("%s", http.StatusText(wrapper.statusCode)))
+			}
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
 
 // Error types for better error handling
 type ErrorType string
@@ -14,6 +18,10 @@ const (
 	ErrorTypeUnsupported    ErrorType = "unsupported"
 	ErrorTypeAuthentication ErrorType = "authentication"
 	ErrorTypeRateLimited    ErrorType = "rate_limited"
+	ErrorTypeExpired        ErrorType = "expired"
+	ErrorTypeOverloaded     ErrorType = "overloaded"
+	ErrorTypeUnavailable    ErrorType = "unavailable"
+	ErrorTypeRange          ErrorType = "range_not_satisfiable"
 )
 
 // AppError represents a custom application error
@@ -35,6 +43,16 @@ func (e *AppError) Unwrap() error {
 	return e.Cause
 }
 
+// RetryAfter returns the wait a rate limited error asks for, or 0 when it is unknown
+func (e *AppError) RetryAfter() time.Duration {
+	retryAfter, _ := e.Details["retry_after"].(string)
+	seconds, err := strconv.Atoi(retryAfter)
+	if e.Type != ErrorTypeRateLimited || err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
 // HTTPStatusCode returns the appropriate HTTP status code for the error
 func (e *AppError) HTTPStatusCode() int {
 	switch e.Type {
@@ -48,6 +66,14 @@ func (e *AppError) HTTPStatusCode() int {
 		return 401
 	case ErrorTypeRateLimited:
 		return 429
+	case ErrorTypeExpired:
+		return 410
+	case ErrorTypeOverloaded:
+		return 503
+	case ErrorTypeUnavailable:
+		return 501
+	case ErrorTypeRange:
+		return 416
 	default:
 		return 500
 	}
@@ -63,6 +89,15 @@ func NewInvalidURLError(url string, cause error) *AppError {
 	}
 }
 
+// NewInvalidShortcodeError creates an invalid URL error for a malformed shortcode
+func NewInvalidShortcodeError(shortcode string) *AppError {
+	return &AppError{
+		Type:    ErrorTypeInvalidURL,
+		Message: fmt.Sprintf("invalid shortcode %.80q: expected 1 to %d letters, digits, '-' or '_'", shortcode, MaxShortcodeLength),
+		Details: map[string]interface{}{"shortcode": shortcode},
+	}
+}
+
 // NewNetworkError creates a new network error
 func NewNetworkError(operation string, cause error) *AppError {
 	return &AppError{
@@ -111,7 +146,7 @@ func NewUnsupportedError(contentType string) *AppError {
 	}
 }
 
-// NewRateLimitedError creates a new rate limited error
+// NewRateLimitedError creates a new rate limited error; retryAfter is in seconds, or empty when unknown
 func NewRateLimitedError(retryAfter string) *AppError {
 	return &AppError{
 		Type:    ErrorTypeRateLimited,
@@ -119,3 +154,49 @@ func NewRateLimitedError(retryAfter string) *AppError {
 		Details: map[string]interface{}{"retry_after": retryAfter},
 	}
 }
+
+// NewAuthenticationError creates a new authentication error
+func NewAuthenticationError(reason string) *AppError {
+	return &AppError{
+		Type:    ErrorTypeAuthentication,
+		Message: fmt.Sprintf("Instagram authentication required: %s", reason),
+		Details: map[string]interface{}{"reason": reason},
+	}
+}
+
+// NewExpiredError creates a new error for media that is no longer available, such as an old story
+func NewExpiredError(resource string) *AppError {
+	return &AppError{
+		Type:    ErrorTypeExpired,
+		Message: fmt.Sprintf("%s has expired", resource),
+		Details: map[string]interface{}{"resource": resource},
+	}
+}
+
+// NewOverloadedError creates a new error for requests shed because too many are already in flight
+func NewOverloadedError(reason string) *AppError {
+	return &AppError{
+		Type:    ErrorTypeOverloaded,
+		Message: fmt.Sprintf("server busy: %s", reason),
+		Details: map[string]interface{}{"reason": reason},
+	}
+}
+
+// NewUnavailableError creates a new error for features this server cannot provide, e.g. a missing dependency
+func NewUnavailableError(feature, reason string) *AppError {
+	return &AppError{
+		Type:    ErrorTypeUnavailable,
+		Message: fmt.Sprintf("%s is not available: %s", feature, reason),
+		Details: map[string]interface{}{"feature": feature, "reason": reason},
+	}
+}
+
+// NewRangeError creates a new error for Range headers that cannot be served
+func NewRangeError(rangeHeader string, cause error) *AppError {
+	return &AppError{
+		Type:    ErrorTypeRange,
+		Message: fmt.Sprintf("range not satisfiable: %s", rangeHeader),
+		Cause:   cause,
+		Details: map[string]interface{}{"range": rangeHeader},
+	}
+}
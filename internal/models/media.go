@@ -1,10 +1,229 @@
 package models
 
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // InstagramMediaInfo represents the extracted media information from Instagram
 type InstagramMediaInfo struct {
-	VideoURL     string `json:"videoUrl"`
-	FileName     string `json:"fileName"`
-	ThumbnailURL string `json:"thumbnailUrl,omitempty"`
-	Caption      string `json:"caption,omitempty"`
-	Username     string `json:"username,omitempty"`
+	VideoURL     string         `json:"videoUrl"`
+	ImageURL     string         `json:"imageUrl,omitempty"` // Set instead of VideoURL for photo posts
+	FileName     string         `json:"fileName"`
+	ThumbnailURL string         `json:"thumbnailUrl,omitempty"`
+	Caption      string         `json:"caption,omitempty"`
+	AltText      string         `json:"altText,omitempty"` // Accessibility caption, written by the owner or generated by Instagram
+	Username     string         `json:"username,omitempty"`
+	Duration     float64        `json:"duration,omitempty"` // Video length in seconds
+	Width        int            `json:"width,omitempty"`
+	Height       int            `json:"height,omitempty"`
+	IsVideo      bool           `json:"isVideo"`                // The post itself is a video (carousels are not)
+	MediaType    string         `json:"mediaType,omitempty"`    // video, image or carousel
+	LikeCount    int            `json:"likeCount,omitempty"`    // Zero when hidden or unknown
+	CommentCount int            `json:"commentCount,omitempty"` // Zero when disabled or unknown
+	TakenAt      time.Time      `json:"takenAt,omitzero"`       // When the post was published
+	Location     *Location      `json:"location,omitempty"`     // Place the post is tagged with
+	Coauthors    []string       `json:"coauthors,omitempty"`    // Usernames of collab co-authors besides Username
+	TaggedUsers  []string       `json:"taggedUsers,omitempty"`  // Usernames tagged in the media
+	Items        []MediaItem    `json:"items,omitempty"`        // Carousel (sidecar) children in post order
+	Versions     []VideoVersion `json:"versions,omitempty"`     // Available renditions, highest resolution first
+	DASH         *DASHStreams   `json:"dash,omitempty"`         // Best separate video/audio streams from video_dash_manifest
+	ExpiresAt    time.Time      `json:"expiresAt,omitzero"`     // When ephemeral media (stories) stops being available
+}
+
+// Location is the place a post is tagged with
+type Location struct {
+	ID        string  `json:"id,omitempty"`
+	Name      string  `json:"name"`
+	Slug      string  `json:"slug,omitempty"`
+	Latitude  float64 `json:"lat,omitempty"` // Only returned by the API
+	Longitude float64 `json:"lng,omitempty"`
+}
+
+// URL returns the location's page on Instagram, or "" when its ID is unknown
+func (l *Location) URL() string {
+	if l.ID == "" {
+		return ""
+	}
+	if l.Slug == "" {
+		return fmt.Sprintf("https://www.instagram.com/explore/locations/%s/", l.ID)
+	}
+	return fmt.Sprintf("https://www.instagram.com/explore/locations/%s/%s/", l.ID, l.Slug)
+}
+
+// VideoVersion is one rendition of a video from the API's video_versions list
+type VideoVersion struct {
+	URL       string `json:"url"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	Bandwidth int    `json:"bandwidth,omitempty"` // Bits per second, when Instagram reports it
+}
+
+// DASHStreams are the representations picked from a DASH manifest. Instagram serves
+// video and audio separately there, usually at a higher quality than video_versions.
+type DASHStreams struct {
+	VideoURL  string `json:"videoUrl"`
+	AudioURL  string `json:"audioUrl,omitempty"` // Empty for videos without sound
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	Bandwidth int    `json:"bandwidth,omitempty"` // Combined video and audio bits per second
+}
+
+// Quality presets accepted by SelectVersion besides an explicit height.
+// QualityBest prefers the DASH streams when they can be muxed and otherwise behaves like QualityHigh.
+const (
+	QualityLow    = "low"
+	QualityMedium = "medium"
+	QualityHigh   = "high"
+	QualityBest   = "best"
+)
+
+// MediaItem is one entry of a carousel post
+type MediaItem struct {
+	Index    int    `json:"index"` // 1-based position in the carousel
+	IsVideo  bool   `json:"isVideo"`
+	VideoURL string `json:"videoUrl,omitempty"`
+	ImageURL string `json:"imageUrl,omitempty"`
+	AltText  string `json:"altText,omitempty"`
+}
+
+// IsImage reports whether the media is a photo rather than a video
+func (m *InstagramMediaInfo) IsImage() bool {
+	return m.VideoURL == "" && m.ImageURL != ""
+}
+
+// Classify sets MediaType from the media found, unless the parser took it from Instagram's data, and IsVideo from MediaType
+func (m *InstagramMediaInfo) Classify() {
+	if m.MediaType == "" {
+		switch {
+		case len(m.Items) > 0:
+			m.MediaType = PostTypeCarousel
+		case m.VideoURL != "":
+			m.MediaType = PostTypeVideo
+		default:
+			m.MediaType = PostTypeImage
+		}
+	}
+	m.IsVideo = m.MediaType == PostTypeVideo
+}
+
+// MediaURL returns the CDN URL of the video, or of the image for photo posts
+func (m *InstagramMediaInfo) MediaURL() string {
+	if m.IsImage() {
+		return m.ImageURL
+	}
+	return m.VideoURL
+}
+
+// SelectVersion returns the video URL for a quality: low, medium, high (or best), or a height in pixels
+// (the closest rendition wins). An empty quality, or no known renditions, returns VideoURL.
+func (m *InstagramMediaInfo) SelectVersion(quality string) (string, error) {
+	height := 0
+	switch quality {
+	case "", QualityLow, QualityMedium, QualityHigh, QualityBest:
+	default:
+		parsed, err := strconv.Atoi(strings.TrimSuffix(quality, "p"))
+		if err != nil || parsed <= 0 {
+			return "", fmt.Errorf("quality must be low, medium, high, best or a height in pixels, got '%s'", quality)
+		}
+		height = parsed
+	}
+
+	if quality == "" || len(m.Versions) == 0 {
+		return m.VideoURL, nil
+	}
+
+	switch {
+	case quality == QualityHigh, quality == QualityBest:
+		return m.Versions[0].URL, nil
+	case quality == QualityLow:
+		return m.Versions[len(m.Versions)-1].URL, nil
+	case quality == QualityMedium:
+		return m.Versions[len(m.Versions)/2].URL, nil
+	}
+
+	best := m.Versions[0]
+	for _, version := range m.Versions[1:] {
+		if abs(version.Height-height) < abs(best.Height-height) {
+			best = version
+		}
+	}
+	return best.URL, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Expired reports whether ephemeral media has passed its expiry time
+func (m *InstagramMediaInfo) Expired() bool {
+	return !m.ExpiresAt.IsZero() && time.Now().After(m.ExpiresAt)
+}
+
+// Clone returns a deep copy so cached values are never shared between callers
+func (m *InstagramMediaInfo) Clone() *InstagramMediaInfo {
+	clone := *m
+	if m.Items != nil {
+		clone.Items = append([]MediaItem(nil), m.Items...)
+	}
+	if m.Versions != nil {
+		clone.Versions = append([]VideoVersion(nil), m.Versions...)
+	}
+	if m.DASH != nil {
+		dash := *m.DASH
+		clone.DASH = &dash
+	}
+	return &clone
+}
+
+// Item returns the carousel item at the 1-based index, or nil if out of range
+func (m *InstagramMediaInfo) Item(index int) *MediaItem {
+	if index < 1 || index > len(m.Items) {
+		return nil
+	}
+	return &m.Items[index-1]
+}
+
+// MaxShortcodeLength bounds accepted shortcodes; public posts use 11 characters, private share codes more
+const MaxShortcodeLength = 64
+
+// shortcodePattern is the alphabet of Instagram shortcodes, base64 in its URL-safe form
+var shortcodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidShortcode reports whether s can be an Instagram shortcode: 1 to MaxShortcodeLength
+// letters, digits, '-' or '_'. Anything else would be spliced into the upstream URL.
+func ValidShortcode(s string) bool {
+	return len(s) <= MaxShortcodeLength && shortcodePattern.MatchString(s)
+}
+
+// Post types reported in PostSummary.Type and InstagramMediaInfo.MediaType
+const (
+	PostTypeVideo    = "video"
+	PostTypeImage    = "image"
+	PostTypeCarousel = "carousel"
+)
+
+// PostSummary is one post of a profile feed listing
+type PostSummary struct {
+	Shortcode    string    `json:"shortcode"`
+	Type         string    `json:"type"` // video, image or carousel
+	ThumbnailURL string    `json:"thumbnailUrl,omitempty"`
+	Caption      string    `json:"caption,omitempty"`
+	Duration     float64   `json:"duration,omitempty"` // Video length in seconds
+	LikeCount    int       `json:"likeCount,omitempty"`
+	CommentCount int       `json:"commentCount,omitempty"`
+	TakenAt      time.Time `json:"takenAt,omitzero"`
+}
+
+// PostPage is one page of a user's recent posts
+type PostPage struct {
+	Username   string        `json:"username"`
+	Posts      []PostSummary `json:"posts"`
+	NextCursor string        `json:"nextCursor,omitempty"` // Pass back to fetch the next page; empty on the last page
 }
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"qwiklip/internal/instagram"
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+	"qwiklip/web/static"
+)
+
+// maxAdminBodyBytes bounds the JSON body of admin requests
+const maxAdminBodyBytes = 4 * 1024
+
+// adminRateLimits is the response of GET /admin/ratelimits
+type adminRateLimits struct {
+	instagram.RateLimitState
+	Streams adminStreams `json:"streams"`
+}
+
+// adminStreams reports the video stream cap
+type adminStreams struct {
+	Active int64 `json:"active"`
+	Limit  int64 `json:"limit"` // 0 when streams are not capped
+}
+
+// adminExtractors is the response of GET /admin/extractors
+type adminExtractors struct {
+	Configured []string                  `json:"configured"`
+	Adaptive   bool                      `json:"adaptive"`
+	Strategies []instagram.StrategyState `json:"strategies"`
+}
+
+// logLevelRequest is the JSON body accepted by PUT /admin/log-level
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// registerAdminRoutes adds the admin API to mux. Every route requires ADMIN_TOKEN.
+func (s *Server) registerAdminRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/cache", s.withAdminMiddleware(s.handleAdminCache))
+	mux.HandleFunc("/admin/cache/", s.withAdminMiddleware(s.handleAdminCacheEntry))
+	mux.HandleFunc("/admin/ratelimits", s.withAdminMiddleware(s.handleAdminRateLimits))
+	mux.HandleFunc("/admin/extractors", s.withAdminMiddleware(s.handleAdminExtractors))
+	mux.HandleFunc("/admin/log-level", s.withAdminMiddleware(s.handleAdminLogLevel))
+	mux.HandleFunc("/admin/dashboard", s.withAdminMiddleware(s.handleDashboard))
+}
+
+// withAdminMiddleware checks the admin token inside the logging middleware, so rejected attempts are logged too
+func (s *Server) withAdminMiddleware(handler http.HandlerFunc) http.HandlerFunc {
+	authenticated := middleware.StaticTokenMiddleware(s.config.Admin.Token, s.logger)(handler)
+	return s.applyMiddleware(authenticated, ApplyMiddlewareOptions(middleware.WithRecovery(), middleware.WithLogging()))
+}
+
+// startAdminServer serves the admin API on its own listener (ADMIN_ADDR)
+func (s *Server) startAdminServer() {
+	mux := http.NewServeMux()
+	s.registerAdminRoutes(mux)
+	// The dashboard's stylesheet and icons
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(static.GetStaticFS())))
+
+	s.adminServer = &http.Server{
+		Addr:              s.config.Admin.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      s.config.Server.WriteTimeout,
+	}
+
+	go func() {
+		s.logger.Info("Admin server starting", "addr", s.adminServer.Addr)
+		if err := s.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Admin server failed to start", "error", err)
+		}
+	}()
+}
+
+// handleAdminCache handles GET /admin/cache, listing the cached shortcodes, and
+// DELETE /admin/cache, purging the whole metadata cache
+func (s *Server) handleAdminCache(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	switch r.Method {
+	case http.MethodGet:
+		keys, err := s.client.CachedShortcodes(r.Context())
+		if err != nil {
+			s.sendErrorResponse(w, models.NewNetworkError("list cached media", err))
+			return
+		}
+		if keys == nil {
+			keys = []string{}
+		}
+		s.writeAdminJSON(w, logger, map[string]interface{}{"count": len(keys), "shortcodes": keys})
+	case http.MethodDelete:
+		purged, err := s.client.PurgeCache(r.Context())
+		if err != nil {
+			s.sendErrorResponse(w, models.NewNetworkError("purge cache", err))
+			return
+		}
+		logger.Info("Metadata cache purged", "entries", purged)
+		s.writeAdminJSON(w, logger, map[string]interface{}{"purged": purged})
+	default:
+		allowed := http.MethodGet + ", " + http.MethodDelete
+		w.Header().Set("Allow", allowed)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use "+allowed)
+	}
+}
+
+// handleAdminCacheEntry handles DELETE /admin/cache/{shortcode}, dropping the cached
+// metadata and video so the next request extracts the post again
+func (s *Server) handleAdminCacheEntry(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	shortcode := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/cache/"), "/")
+	if shortcode == "" || strings.Contains(shortcode, "/") {
+		s.sendJSONError(w, http.StatusNotFound, "Expected /admin/cache/{shortcode}")
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", http.MethodDelete)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use DELETE")
+		return
+	}
+
+	if err := s.client.InvalidateCache(r.Context(), shortcode); err != nil {
+		s.sendErrorResponse(w, models.NewNetworkError("invalidate cached media", err))
+		return
+	}
+	if s.videoCache != nil {
+		s.videoCache.Purge(shortcode)
+	}
+
+	logger.Info("Cache entry invalidated", "shortcode", shortcode)
+	s.writeAdminJSON(w, logger, map[string]interface{}{"invalidated": shortcode})
+}
+
+// handleAdminRateLimits handles GET /admin/ratelimits: the Instagram request limiter,
+// account and proxy cool-downs, and the video stream cap
+func (s *Server) handleAdminRateLimits(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use GET")
+		return
+	}
+
+	s.writeAdminJSON(w, logger, adminRateLimits{
+		RateLimitState: s.client.RateLimits(),
+		Streams: adminStreams{
+			Active: s.streams.inFlight(),
+			Limit:  s.streams.limit.Load(),
+		},
+	})
+}
+
+// handleAdminExtractors handles GET /admin/extractors: the recent success rate and latency
+// of each extraction strategy, best first, which adaptive ordering tries them in
+func (s *Server) handleAdminExtractors(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use GET")
+		return
+	}
+
+	strategies, adaptive := s.client.Strategies()
+	s.writeAdminJSON(w, logger, adminExtractors{
+		Configured: s.client.Extractors(),
+		Adaptive:   adaptive,
+		Strategies: strategies,
+	})
+}
+
+// handleAdminLogLevel handles GET and PUT /admin/log-level. A level set here lasts
+// until the next SIGHUP reload, which applies LOG_LEVEL again.
+func (s *Server) handleAdminLogLevel(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req logLevelRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBodyBytes)).Decode(&req); err != nil {
+			s.sendErrorResponse(w, models.NewParsingError("log level request body", err))
+			return
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+			s.sendJSONError(w, http.StatusBadRequest,
+				fmt.Sprintf("invalid log level '%s', must be one of: debug, info, warn, error", req.Level))
+			return
+		}
+
+		// Logged before the change so that raising the level does not hide the record
+		logger.Info("Log level changed", "from", s.level.Level(), "to", level)
+		s.level.Set(level)
+	default:
+		allowed := http.MethodGet + ", " + http.MethodPut
+		w.Header().Set("Allow", allowed)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use "+allowed)
+		return
+	}
+
+	s.writeAdminJSON(w, logger, map[string]interface{}{"level": strings.ToLower(s.level.Level().String())})
+}
+
+// writeAdminJSON sends an admin response; admin state must never be cached by a proxy
+func (s *Server) writeAdminJSON(w http.ResponseWriter, logger *slog.Logger, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("Failed to encode admin response", "error", err)
+	}
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const (
+	// apiVersion is the current version of the JSON API
+	apiVersion = "1"
+	// apiV1Prefix is the versioned namespace of the JSON API
+	apiV1Prefix = "/api/v1"
+	// apiVersionHeader names the version on responses of versioned routes
+	apiVersionHeader = "X-Qwiklip-API-Version"
+)
+
+// apiV1Aliases are the JSON routes outside /api/ that are also served under /api/v1/
+var apiV1Aliases = map[string]bool{
+	"/resolve": true,
+	"/graphql": true,
+	"/version": true,
+}
+
+// apiBaseKey marks contexts of requests that came in through /api/v1/
+type apiBaseKey struct{}
+
+// serveAPIv1 handles /api/v1/...: the path is mapped onto the unversioned route and
+// served through mux, so both share handlers and middleware. The unversioned paths stay
+// as aliases of v1; a v2 would get its own prefix while v1 keeps its response shapes.
+func serveAPIv1(mux *http.ServeMux) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, apiV1Prefix)
+		target := "/api" + rest
+		if apiV1Aliases[rest] {
+			target = rest
+		}
+
+		w.Header().Set(apiVersionHeader, apiVersion)
+		versioned := r.Clone(context.WithValue(r.Context(), apiBaseKey{}, apiV1Prefix))
+		versioned.URL.Path = target
+		versioned.URL.RawPath = ""
+		mux.ServeHTTP(w, versioned)
+	}
+}
+
+// apiBase returns the prefix of the JSON API the request used, /api/v1 or /api, for
+// links in responses that should stay in the same namespace
+func apiBase(ctx context.Context) string {
+	if base, ok := ctx.Value(apiBaseKey{}).(string); ok {
+		return base
+	}
+	return "/api"
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+)
+
+const (
+	maxBatchItems     = 50        // Upper bound on URLs per batch request
+	maxBatchBodyBytes = 64 * 1024 // Upper bound on the JSON request body
+	batchWorkers      = 4         // Extractions run concurrently per batch request
+)
+
+// batchRequest is the JSON body accepted by /api/batch
+type batchRequest struct {
+	URLs []string `json:"urls"`
+}
+
+// batchResponse is the JSON body returned by /api/batch; results are in request order
+type batchResponse struct {
+	Results   []batchResult `json:"results"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+}
+
+// batchResult is the outcome for one requested URL: either media or error is set
+type batchResult struct {
+	URL   string         `json:"url"`
+	Media *mediaResponse `json:"media,omitempty"`
+	Error *batchError    `json:"error,omitempty"`
+}
+
+// batchError mirrors the JSON error body of the single-item endpoints
+type batchError struct {
+	Message string `json:"error"`
+	Type    string `json:"type,omitempty"`
+	Code    int    `json:"code"`
+}
+
+// handleBatch handles POST /api/batch, extracting several Instagram URLs in one call.
+// Individual failures are reported per URL; the response is 200 as long as the request itself is valid.
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use POST")
+		return
+	}
+
+	var req batchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)).Decode(&req); err != nil {
+		s.sendErrorResponse(w, models.NewParsingError("batch request body", err))
+		return
+	}
+
+	urls := trimURLList(req.URLs)
+	if len(urls) == 0 {
+		s.sendJSONError(w, http.StatusBadRequest, "urls list is required")
+		return
+	}
+	if len(urls) > maxBatchItems {
+		s.sendJSONError(w, http.StatusBadRequest,
+			fmt.Sprintf("too many urls (max %d), got %d", maxBatchItems, len(urls)))
+		return
+	}
+
+	logger.Info("Starting batch extraction", "items", len(urls))
+	start := time.Now()
+
+	response := s.runBatch(r.Context(), requestBaseURL(r), urls, nil)
+
+	logger.Info("Batch extraction completed",
+		"items", len(urls),
+		"succeeded", response.Succeeded,
+		"failed", response.Failed,
+		"duration", time.Since(start))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("Failed to encode batch response", "error", err)
+	}
+}
+
+// runBatch extracts each URL with bounded concurrency, keeping request order.
+// progress, when set, is called as URLs complete.
+func (s *Server) runBatch(ctx context.Context, baseURL string, urls []string, progress func(done, total int)) batchResponse {
+	results := make([]batchResult, len(urls))
+	sem := make(chan struct{}, batchWorkers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+
+	for i, rawURL := range urls {
+		wg.Add(1)
+		go func(i int, rawURL string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = s.resolveBatchItem(ctx, baseURL, rawURL)
+			if progress != nil {
+				mu.Lock()
+				done++
+				progress(done, len(urls))
+				mu.Unlock()
+			}
+		}(i, rawURL)
+	}
+	wg.Wait()
+
+	response := batchResponse{Results: results}
+	for _, result := range results {
+		if result.Error != nil {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+	return response
+}
+
+// resolveBatchItem extracts one URL and converts the outcome into a batch result
+func (s *Server) resolveBatchItem(ctx context.Context, baseURL, rawURL string) batchResult {
+	result := batchResult{URL: rawURL}
+
+	canonical, err := s.client.ResolveURL(ctx, rawURL)
+	if err != nil {
+		result.Error = newBatchError(err)
+		return result
+	}
+	shortcode, err := s.client.ExtractShortcode(canonical)
+	if err != nil {
+		result.Error = newBatchError(models.NewInvalidURLError(rawURL, err))
+		return result
+	}
+
+	mediaInfo, err := s.fetchMediaInfo(ctx, canonical)
+	if err != nil {
+		result.Error = newBatchError(err)
+		return result
+	}
+
+	// The proxy URL mirrors the Instagram path on this server, that of the post for share links
+	path := proxyPath(canonical)
+	if _, err := s.client.ExtractShortcode(rawURL); err == nil {
+		path = proxyPath(rawURL)
+	}
+	result.Media = &mediaResponse{
+		Shortcode:          shortcode,
+		InstagramMediaInfo: mediaInfo,
+		URL:                baseURL + path,
+	}
+	return result
+}
+
+// trimURLList trims each URL and drops blanks
+func trimURLList(rawURLs []string) []string {
+	urls := make([]string, 0, len(rawURLs))
+	for _, rawURL := range rawURLs {
+		if rawURL = strings.TrimSpace(rawURL); rawURL != "" {
+			urls = append(urls, rawURL)
+		}
+	}
+	return urls
+}
+
+// newBatchError describes a failed batch item like sendErrorResponse would
+func newBatchError(err error) *batchError {
+	var appErr *models.AppError
+	if errors.As(err, &appErr) {
+		return &batchError{
+			Message: appErr.Message,
+			Type:    string(appErr.Type),
+			Code:    appErr.HTTPStatusCode(),
+		}
+	}
+	return &batchError{Message: "Internal server error", Code: http.StatusInternalServerError}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+	"qwiklip/internal/stats"
+)
+
+const (
+	// dashboardRefresh is how often the dashboard page reloads itself
+	dashboardRefresh = 5 * time.Second
+
+	// Request chart geometry in SVG user units, one bar per minute
+	chartWidth    = 600
+	chartHeight   = 100
+	chartBarWidth = chartWidth / 60
+	chartBarGap   = 2
+)
+
+// chartBar is one minute of the request chart, failures drawn over the bottom of the bar
+type chartBar struct {
+	X, Y, Width, Height     int
+	FailureY, FailureHeight int
+	Label                   string
+}
+
+// handleDashboard handles GET /admin/dashboard: live request, stream, extraction and cache
+// statistics with the most recent errors, refreshed every few seconds
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use GET")
+		return
+	}
+	if !s.templatesEnabled {
+		s.handleError(w, r, models.NewUnavailableError("HTML pages", "templates failed to load"))
+		return
+	}
+
+	snapshot := s.stats.Snapshot()
+
+	var peak int64 = 1
+	for _, minute := range snapshot.History {
+		if minute.Requests > peak {
+			peak = minute.Requests
+		}
+	}
+	bars := make([]chartBar, len(snapshot.History))
+	for i, minute := range snapshot.History {
+		height := int(minute.Requests * chartHeight / peak)
+		failureHeight := int(minute.Failures * chartHeight / peak)
+		bars[i] = chartBar{
+			X:             i * chartBarWidth,
+			Y:             chartHeight - height,
+			Width:         chartBarWidth - chartBarGap,
+			Height:        height,
+			FailureY:      chartHeight - failureHeight,
+			FailureHeight: failureHeight,
+			Label:         fmt.Sprintf("%s UTC: %d requests, %d failed", minute.Start.Format("15:04"), minute.Requests, minute.Failures),
+		}
+	}
+
+	data := struct {
+		RefreshSeconds int
+		Uptime         string
+		UpdatedAt      string
+		Stats          stats.Snapshot
+		Bars           []chartBar
+		ChartWidth     int
+		ChartHeight    int
+		Version        string
+		Commit         string
+	}{
+		RefreshSeconds: int(dashboardRefresh.Seconds()),
+		Uptime:         snapshot.Uptime.String(),
+		UpdatedAt:      time.Now().UTC().Format("15:04:05 UTC"),
+		Stats:          snapshot,
+		Bars:           bars,
+		ChartWidth:     chartWidth,
+		ChartHeight:    chartHeight,
+		Version:        s.versionInfo.Version,
+		Commit:         s.versionInfo.Commit,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := s.templateSet.Dashboard.Execute(w, data); err != nil {
+		logger.Error("Failed to execute dashboard template", "error", err)
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// publishDebugVars guards expvar.Publish, which panics on duplicate names
+var publishDebugVars sync.Once
+
+// processStart is reported as the uptime_seconds expvar
+var processStart = time.Now()
+
+// registerDebugRoutes adds the pprof and expvar handlers to mux, wrapping each with wrap
+func (s *Server) registerDebugRoutes(mux *http.ServeMux, wrap func(http.HandlerFunc) http.HandlerFunc) {
+	publishDebugVars.Do(func() {
+		expvar.Publish("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+		expvar.Publish("uptime_seconds", expvar.Func(func() any { return int64(time.Since(processStart).Seconds()) }))
+	})
+
+	mux.HandleFunc("/debug/pprof/", wrap(pprof.Index))
+	mux.HandleFunc("/debug/pprof/cmdline", wrap(pprof.Cmdline))
+	mux.HandleFunc("/debug/pprof/profile", wrap(pprof.Profile))
+	mux.HandleFunc("/debug/pprof/symbol", wrap(pprof.Symbol))
+	mux.HandleFunc("/debug/pprof/trace", wrap(pprof.Trace))
+	mux.HandleFunc("/debug/vars", wrap(expvar.Handler().ServeHTTP))
+}
+
+// startDebugServer serves the debug endpoints on their own listener (DEBUG_ADDR).
+// It has no write timeout so CPU profiles and execution traces can run as long as requested.
+func (s *Server) startDebugServer() {
+	mux := http.NewServeMux()
+	s.registerDebugRoutes(mux, s.withMinimalMiddleware)
+
+	s.debugServer = &http.Server{
+		Addr:              s.config.Server.DebugAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		s.logger.Info("Debug server starting", "addr", s.debugServer.Addr)
+		if err := s.debugServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Debug server failed to start", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+)
+
+// isDownloadPath reports whether the path is /reel/{shortcode}/download
+func isDownloadPath(requestPath string) bool {
+	segments := strings.Split(strings.Trim(requestPath, "/"), "/")
+	return len(segments) == 3 && segments[2] == "download"
+}
+
+// handleReelDownload handles /reel/{shortcode}/download. It streams the same file as
+// /reel/{shortcode} but as an attachment named after the author, date and shortcode,
+// so browsers save it instead of playing it.
+func (s *Server) handleReelDownload(w http.ResponseWriter, r *http.Request, instagramURL string) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	instagramURL = strings.TrimSuffix(strings.TrimSuffix(instagramURL, "/"), "/download")
+	shortcode, err := s.client.ExtractShortcode(instagramURL)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	// The file name needs the metadata even when the video itself is cached
+	mediaInfo, err := s.fetchMediaInfo(r.Context(), instagramURL)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	setMetadataHeaders(w, shortcode, mediaInfo)
+	fileName := downloadFileName(mediaInfo, shortcode)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	logger.Info("Starting download", "file", fileName)
+
+	if mediaInfo.IsImage() {
+		s.streamDownload(w, r, instagramURL, mediaInfo, imageURL, fileName, "")
+		return
+	}
+
+	if s.wantsTags(r) {
+		s.streamTagged(w, r, instagramURL, mediaInfo, fileName)
+		return
+	}
+	quality := r.URL.Query().Get("quality")
+	if quality == "" && s.serveCachedVideo(w, r, shortcode) {
+		return
+	}
+	videoURL, err := s.selectVideoURL(r, mediaInfo)
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		s.handleError(w, r, err)
+		return
+	}
+	cacheKey := shortcode
+	if videoURL != mediaInfo.VideoURL {
+		cacheKey = ""
+	}
+	s.streamDownload(w, r, instagramURL, mediaInfo, versionURL(quality), fileName, cacheKey)
+}
+
+// streamDownload is streamExtracted for attachments; error responses are sent without Content-Disposition
+func (s *Server) streamDownload(w http.ResponseWriter, r *http.Request, instagramURL string, mediaInfo *models.InstagramMediaInfo, pick mediaURLPicker, fileName, cacheKey string) {
+	if err := s.streamExtracted(w, r, instagramURL, mediaInfo, pick, fileName, cacheKey); err != nil {
+		w.Header().Del("Content-Disposition")
+		s.handleError(w, r, err)
+	}
+}
+
+// downloadFileName builds "{username}_{yyyy-mm-dd}_{shortcode}.{ext}", leaving out unknown parts
+func downloadFileName(mediaInfo *models.InstagramMediaInfo, shortcode string) string {
+	var parts []string
+	if username := sanitizeFileNamePart(mediaInfo.Username); username != "" {
+		parts = append(parts, username)
+	}
+	if !mediaInfo.TakenAt.IsZero() {
+		parts = append(parts, mediaInfo.TakenAt.Format("2006-01-02"))
+	}
+	parts = append(parts, shortcode)
+
+	ext := path.Ext(mediaInfo.FileName)
+	if ext == "" {
+		ext = ".mp4"
+	}
+	return strings.Join(parts, "_") + ext
+}
+
+// sanitizeFileNamePart keeps the characters Instagram allows in usernames, which are also safe in headers and file names
+func sanitizeFileNamePart(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_':
+			return r
+		}
+		return -1
+	}, value)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"qwiklip/internal/config"
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+)
+
+// handleEmbed handles GET /embed/{shortcode}: a minimal player page meant for iframes.
+// ?autoplay=1 starts muted playback in a loop and ?caption=0 hides the caption overlay.
+func (s *Server) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	shortcode, mediaInfo, ok := s.fetchPageMedia(w, r, "/embed/")
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	data := struct {
+		VideoURL    string
+		ImageURL    string
+		PosterURL   string
+		Username    string
+		Caption     string
+		AltText     string
+		ShowCaption bool
+		Autoplay    bool
+		Brand       config.BrandingConfig
+	}{
+		Brand:       s.config.Branding,
+		PosterURL:   mediaInfo.ThumbnailURL,
+		Username:    mediaInfo.Username,
+		Caption:     mediaInfo.Caption,
+		AltText:     mediaInfo.AltText,
+		ShowCaption: query.Get("caption") != "0",
+		Autoplay:    query.Get("autoplay") == "1",
+	}
+	if mediaInfo.IsImage() {
+		data.ImageURL = fmt.Sprintf("/p/%s/", shortcode)
+	} else {
+		data.VideoURL = fmt.Sprintf("/reel/%s/", shortcode)
+	}
+
+	// Embeds exist to be framed by other sites, so the security headers' frame policy is lifted
+	w.Header().Del("X-Frame-Options")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors *")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templateSet.Embed.Execute(w, data); err != nil {
+		logger.Error("Failed to execute embed template", "error", err)
+	}
+}
+
+// fetchPageMedia validates a GET /{prefix}{shortcode} request for an HTML page and fetches the media.
+// It sends the error response itself and returns false when the page cannot be rendered.
+func (s *Server) fetchPageMedia(w http.ResponseWriter, r *http.Request, prefix string) (string, *models.InstagramMediaInfo, bool) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use GET")
+		return "", nil, false
+	}
+	if s.redirectToNormalized(w, r) {
+		return "", nil, false
+	}
+	if !s.templatesEnabled {
+		s.handleError(w, r, models.NewUnavailableError("HTML pages", "templates failed to load"))
+		return "", nil, false
+	}
+
+	shortcode := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if shortcode == "" || strings.Contains(shortcode, "/") {
+		s.sendJSONError(w, http.StatusNotFound, fmt.Sprintf("Expected %s{shortcode}", prefix))
+		return "", nil, false
+	}
+	if !models.ValidShortcode(shortcode) {
+		s.handleError(w, r, models.NewInvalidShortcodeError(shortcode))
+		return "", nil, false
+	}
+
+	mediaInfo, err := s.fetchMediaInfo(r.Context(), fmt.Sprintf("https://www.instagram.com/p/%s/", shortcode))
+	if err != nil {
+		s.handleError(w, r, err)
+		return "", nil, false
+	}
+	return shortcode, mediaInfo, true
+}
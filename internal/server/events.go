@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"qwiklip/internal/middleware"
+)
+
+const (
+	sseMinInterval  = 250 * time.Millisecond // Bursts of progress are coalesced to at most 4 events per second
+	sseKeepalive    = 15 * time.Second       // Comment lines keep proxies from closing quiet streams
+	sseWriteTimeout = 30 * time.Second       // Write deadline per event; stalled clients are cut off
+	sseRetry        = 3 * time.Second        // Reconnect delay suggested to EventSource clients
+)
+
+// serveJobEvents streams a job's state as Server-Sent Events: a progress event with the
+// job whenever it changes and a done event once it has finished, after which the stream
+// ends. Reconnecting clients start again from the current state.
+func (s *Server) serveJobEvents(w http.ResponseWriter, r *http.Request, id string) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+
+	write := func(format string, args ...any) error {
+		rc.SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	if err := write("retry: %d\n\n", sseRetry.Milliseconds()); err != nil {
+		return
+	}
+
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
+
+	for seq := 1; ; seq++ {
+		job, changed, err := s.jobs.Watch(id)
+		if err != nil {
+			write("event: expired\ndata: {\"id\":%q}\n\n", id)
+			return
+		}
+
+		event := "progress"
+		if job.Status.Finished() {
+			event = "done"
+		}
+		data, err := json.Marshal(job)
+		if err != nil {
+			logger.Error("Failed to encode job event", "job_id", id, "error", err)
+			return
+		}
+		if err := write("id: %d\nevent: %s\ndata: %s\n\n", seq, event, data); err != nil {
+			logger.Debug("Job event stream closed", "job_id", id, "error", err)
+			return
+		}
+		if job.Status.Finished() {
+			return
+		}
+
+		sent := time.Now()
+	wait:
+		for {
+			select {
+			case <-changed:
+				break wait
+			case <-keepalive.C:
+				if err := write(": keepalive\n\n"); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			case <-s.closing:
+				return
+			}
+		}
+
+		// Let more progress accumulate before the next snapshot
+		select {
+		case <-time.After(sseMinInterval - time.Since(sent)):
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		}
+	}
+}
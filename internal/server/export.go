@@ -0,0 +1,261 @@
+package server
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"qwiklip/internal/jobs"
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+)
+
+const (
+	maxExportItems     = 20        // Upper bound on shortcodes per export request
+	maxExportBodyBytes = 64 * 1024 // Upper bound on the JSON request body
+	exportWorkers      = 3         // Items resolved and opened ahead of the zip writer
+)
+
+// exportRequest is the JSON body accepted by /api/export.zip
+type exportRequest struct {
+	Shortcodes []string `json:"shortcodes"`
+}
+
+// exportItem is a resolved export entry waiting to be written to the archive
+type exportItem struct {
+	shortcode string
+	mediaInfo *models.InstagramMediaInfo
+	video     *http.Response
+	err       error
+	release   func() // Frees the item's concurrency slot once written
+}
+
+// close releases the item's CDN body and concurrency slot
+func (item exportItem) close() {
+	if item.video != nil {
+		item.video.Body.Close()
+	}
+	if item.release != nil {
+		item.release()
+	}
+}
+
+// handleExport handles POST /api/export.zip, streaming a ZIP with each video and its metadata
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use POST")
+		return
+	}
+
+	var req exportRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxExportBodyBytes)).Decode(&req); err != nil {
+		s.sendErrorResponse(w, models.NewParsingError("export request body", err))
+		return
+	}
+
+	// Shortcodes name the archive entries; malformed ones such as ".." are dropped
+	// before anything is fetched or written
+	shortcodes, invalid := normalizeShortcodes(req.Shortcodes)
+	if len(invalid) > 0 {
+		logger.Warn("Dropping malformed shortcodes from export", "count", len(invalid))
+	}
+	if len(shortcodes) == 0 {
+		if len(invalid) > 0 {
+			s.sendErrorResponse(w, models.NewInvalidShortcodeError(invalid[0]))
+			return
+		}
+		s.sendJSONError(w, http.StatusBadRequest, "shortcodes list is required")
+		return
+	}
+	if len(shortcodes) > maxExportItems {
+		s.sendJSONError(w, http.StatusBadRequest,
+			fmt.Sprintf("too many shortcodes (max %d), got %d", maxExportItems, len(shortcodes)))
+		return
+	}
+
+	logger.Info("Starting bulk export", "items", len(shortcodes))
+	start := time.Now()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="qwiklip-export.zip"`)
+	w.WriteHeader(http.StatusOK)
+
+	streamer := s.newVideoStreamer()
+	rc := http.NewResponseController(w)
+	streamer.extendWriteDeadline(rc)
+
+	written, failed, err := s.writeExportArchive(r.Context(), streamer, &deadlineWriter{w: w, streamer: streamer, rc: rc}, shortcodes, nil)
+	if err != nil {
+		logger.Warn("Bulk export aborted", "error", err)
+		return
+	}
+
+	logger.Info("Bulk export completed",
+		"items", len(shortcodes),
+		"written", written,
+		"failed", failed,
+		"duration", time.Since(start))
+}
+
+// writeExportArchive writes a ZIP with each shortcode's video and metadata to dst, in request order.
+// Failed items get an error.json entry; progress, when set, is called after every item
+// and, while videos are copied, as their bytes arrive.
+func (s *Server) writeExportArchive(ctx context.Context, streamer *VideoStreamer, dst io.Writer, shortcodes []string, progress func(jobs.Progress)) (written, failed int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	items := s.resolveExportItems(ctx, streamer, shortcodes)
+	archive := zip.NewWriter(dst)
+
+	var exported int64 // Video bytes of the items written so far
+	for i, itemCh := range items {
+		item := <-itemCh
+		var onBytes func(int64)
+		if progress != nil {
+			onBytes = func(copied int64) {
+				progress(jobs.Progress{Done: i, Total: len(items), Bytes: exported + copied})
+			}
+		}
+		n, err := s.writeExportItem(archive, item, onBytes)
+		item.close()
+		if err != nil {
+			// The archive is unrecoverable once an entry fails mid-write
+			cancel()
+			drainExportItems(items[i+1:])
+			return written, failed, fmt.Errorf("failed to write '%s': %w", item.shortcode, err)
+		}
+		if item.err != nil {
+			failed++
+		} else {
+			written++
+		}
+		exported += n
+		if progress != nil {
+			progress(jobs.Progress{Done: i + 1, Total: len(items), Bytes: exported})
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return written, failed, fmt.Errorf("failed to finalize export archive: %w", err)
+	}
+	return written, failed, nil
+}
+
+// resolveExportItems extracts and opens each video with bounded concurrency.
+// Each item gets its own channel so the archive is written in request order.
+func (s *Server) resolveExportItems(ctx context.Context, streamer *VideoStreamer, shortcodes []string) []chan exportItem {
+	items := make([]chan exportItem, len(shortcodes))
+	for i := range items {
+		items[i] = make(chan exportItem, 1)
+	}
+
+	// Slots are released by the writer once an item's body is consumed,
+	// bounding the number of open CDN connections
+	sem := make(chan struct{}, exportWorkers)
+	release := func() { <-sem }
+	go func() {
+		for i, shortcode := range shortcodes {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				items[i] <- exportItem{shortcode: shortcode, err: ctx.Err()}
+				continue
+			}
+
+			go func(i int, shortcode string) {
+				item := exportItem{shortcode: shortcode, release: release}
+				item.mediaInfo, item.err = s.fetchMediaInfo(ctx, fmt.Sprintf("https://www.instagram.com/reel/%s/", shortcode))
+				if item.err == nil {
+					item.video, item.err = streamer.OpenVideo(ctx, item.mediaInfo.MediaURL())
+				}
+				items[i] <- item
+			}(i, shortcode)
+		}
+	}()
+
+	return items
+}
+
+// writeExportItem writes the video and metadata.json (or error.json) for one item and
+// returns the video bytes written. onBytes, when set, hears about the copy as it goes.
+func (s *Server) writeExportItem(archive *zip.Writer, item exportItem, onBytes func(copied int64)) (int64, error) {
+	if item.err != nil {
+		s.logger.Warn("Export item failed", "shortcode", item.shortcode, "error", item.err)
+		return 0, writeZipJSON(archive, path.Join(item.shortcode, "error.json"), exportErrorBody(item.err))
+	}
+
+	metadata := struct {
+		Shortcode string `json:"shortcode"`
+		*models.InstagramMediaInfo
+	}{item.shortcode, item.mediaInfo}
+	if err := writeZipJSON(archive, path.Join(item.shortcode, "metadata.json"), metadata); err != nil {
+		return 0, err
+	}
+
+	// Videos are already compressed, so store them as-is
+	entry, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     path.Join(item.shortcode, path.Base(item.mediaInfo.FileName)),
+		Method:   zip.Store,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	dst := entry
+	if onBytes != nil {
+		observer := ProgressFunc(func(p Progress) { onBytes(p.Written) })
+		dst = newProgressWriter(entry, []ProgressObserver{observer}, item.mediaInfo.FileName, item.video.ContentLength)
+	}
+	n, err := io.Copy(dst, item.video.Body)
+	if err != nil {
+		return n, err
+	}
+	s.logger.Debug("Exported video", "shortcode", item.shortcode, "bytes", n)
+	return n, nil
+}
+
+// drainExportItems releases items resolved ahead of an aborted export.
+// Every channel receives exactly one item, so this terminates once in-flight work stops.
+func drainExportItems(items []chan exportItem) {
+	go func() {
+		for _, itemCh := range items {
+			(<-itemCh).close()
+		}
+	}()
+}
+
+// writeZipJSON writes v as an indented JSON file entry
+func writeZipJSON(archive *zip.Writer, name string, v interface{}) error {
+	entry, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// exportErrorBody describes a failed export item
+func exportErrorBody(err error) map[string]interface{} {
+	body := map[string]interface{}{"error": err.Error()}
+	var appErr *models.AppError
+	if errors.As(err, &appErr) {
+		body["error"] = appErr.Message
+		body["type"] = string(appErr.Type)
+	}
+	return body
+}
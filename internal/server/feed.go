@@ -0,0 +1,269 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"qwiklip/internal/instagram"
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+	"qwiklip/internal/transcode"
+)
+
+// maxFeedTitle bounds item titles taken from captions, in characters
+const maxFeedTitle = 80
+
+// rssFeed is an RSS 2.0 document with the Atom self link and Media RSS thumbnails.
+// Audio feeds add the iTunes podcast tags podcast apps expect.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Media   string     `xml:"xmlns:media,attr"`
+	Itunes  string     `xml:"xmlns:itunes,attr,omitempty"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title          string       `xml:"title"`
+	Link           string       `xml:"link"`
+	Description    string       `xml:"description"`
+	Self           rssLink      `xml:"atom:link"`
+	LastBuildDate  string       `xml:"lastBuildDate"`
+	TTL            int          `xml:"ttl"` // Minutes
+	ItunesAuthor   string       `xml:"itunes:author,omitempty"`
+	ItunesImage    *itunesImage `xml:"itunes:image,omitempty"`
+	ItunesExplicit string       `xml:"itunes:explicit,omitempty"`
+	Items          []rssItem    `xml:"item"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate,omitempty"`
+	Description string        `xml:"description,omitempty"`
+	Enclosure   rssEnclosure  `xml:"enclosure"`
+	Thumbnail   *rssThumbnail `xml:"media:thumbnail,omitempty"`
+	Duration    string        `xml:"itunes:duration,omitempty"` // Audio feeds only
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int    `xml:"length,attr"` // Unknown, so 0 as the spec allows
+}
+
+type rssThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+// handleFeed handles GET /feed/{username}.xml, an RSS feed of the user's recent reels
+// that links to the proxy so creators can be followed from a feed reader, and
+// GET /feed/{username}/audio.xml?format=m4a|mp3, the same reels as a podcast of their soundtracks
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use GET")
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/feed/")
+	username, audio := strings.CutSuffix(name, "/audio.xml")
+	if !audio {
+		username, _ = strings.CutSuffix(name, ".xml")
+	}
+	if username == name || !instagram.ValidUsername(username) {
+		s.sendJSONError(w, http.StatusNotFound, "Expected /feed/{username}.xml or /feed/{username}/audio.xml")
+		return
+	}
+
+	var audioFormat transcode.AudioFormat
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if audio {
+		if s.ffmpeg == nil {
+			s.sendErrorResponse(w, models.NewUnavailableError("audio feeds", "ffmpeg is not installed on this server"))
+			return
+		}
+		if format == "" {
+			format = defaultAudioFormat
+		}
+		var ok bool
+		if audioFormat, ok = transcode.AudioFormats[format]; !ok {
+			s.sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("audio format must be m4a or mp3, got '%s'", format))
+			return
+		}
+	}
+
+	page, fetchedAt, err := s.loadFeedPage(r.Context(), username)
+	if err != nil {
+		s.sendErrorResponse(w, err)
+		return
+	}
+
+	baseURL := requestBaseURL(r)
+	feed := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Media:   "http://search.yahoo.com/mrss/",
+		Channel: rssChannel{
+			Title:         fmt.Sprintf("@%s reels", username),
+			Link:          fmt.Sprintf("https://www.instagram.com/%s/", username),
+			Description:   fmt.Sprintf("Recent reels by @%s via Qwiklip", username),
+			Self:          rssLink{Href: baseURL + r.URL.RequestURI(), Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: fetchedAt.UTC().Format(time.RFC1123Z),
+			TTL:           int(s.config.Feed.TTL.Minutes()),
+		},
+	}
+	if audio {
+		feed.Itunes = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+		feed.Channel.Title = fmt.Sprintf("@%s reels (audio)", username)
+		feed.Channel.Description = fmt.Sprintf("Soundtracks of recent reels by @%s via Qwiklip", username)
+		feed.Channel.ItunesAuthor = "@" + username
+		feed.Channel.ItunesExplicit = "false"
+	}
+
+	for _, post := range page.Posts {
+		if post.Type != models.PostTypeVideo {
+			continue
+		}
+		item := newRSSItem(baseURL, username, post)
+		if audio {
+			item.Enclosure = rssEnclosure{
+				URL:  fmt.Sprintf("%s/reel/%s/audio?format=%s", baseURL, post.Shortcode, format),
+				Type: audioFormat.ContentType,
+			}
+			if post.Duration > 0 {
+				item.Duration = formatDuration(post.Duration)
+			}
+			// Podcast apps want channel artwork; the newest reel's thumbnail stands in for it
+			if feed.Channel.ItunesImage == nil && post.ThumbnailURL != "" {
+				feed.Channel.ItunesImage = &itunesImage{Href: post.ThumbnailURL}
+			}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.config.Feed.TTL.Seconds())))
+	w.Write([]byte(xml.Header))
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		logger.Error("Failed to encode feed", "error", err)
+	}
+}
+
+// newRSSItem builds the feed entry of one reel; the link opens the watch page and the enclosure streams the video
+func newRSSItem(baseURL, username string, post models.PostSummary) rssItem {
+	title := strings.TrimSpace(strings.SplitN(post.Caption, "\n", 2)[0])
+	if title == "" {
+		title = fmt.Sprintf("Reel by @%s", username)
+	}
+
+	item := rssItem{
+		Title:       truncateRunes(title, maxFeedTitle),
+		Link:        fmt.Sprintf("%s/watch/%s", baseURL, post.Shortcode),
+		GUID:        rssGUID{Value: "instagram:" + post.Shortcode},
+		Description: post.Caption,
+		Enclosure:   rssEnclosure{URL: fmt.Sprintf("%s/reel/%s/", baseURL, post.Shortcode), Type: "video/mp4"},
+	}
+	if !post.TakenAt.IsZero() {
+		item.PubDate = post.TakenAt.Format(time.RFC1123Z)
+	}
+	if post.ThumbnailURL != "" {
+		item.Thumbnail = &rssThumbnail{URL: post.ThumbnailURL}
+	}
+	return item
+}
+
+// loadFeedPage returns the user's first feed page from the disk cache while it is younger
+// than FEED_TTL, refreshing it otherwise. A stale copy is served when the refresh fails.
+func (s *Server) loadFeedPage(ctx context.Context, username string) (*models.PostPage, time.Time, error) {
+	logger := middleware.LoggerFromContext(ctx, s.logger)
+	path := filepath.Join(s.config.Feed.CacheDir, username+".json")
+
+	cached, cachedAt, cacheErr := readFeedCache(path)
+	if cacheErr == nil && time.Since(cachedAt) < s.config.Feed.TTL {
+		return cached, cachedAt, nil
+	}
+
+	page, err := s.client.GetUserPosts(ctx, username, "", s.config.Feed.Items)
+	if err != nil {
+		if cacheErr == nil {
+			logger.Warn("Feed refresh failed, serving stale copy", "username", username, "age", time.Since(cachedAt), "error", err)
+			return cached, cachedAt, nil
+		}
+		return nil, time.Time{}, err
+	}
+
+	if err := writeFeedCache(path, page); err != nil {
+		logger.Warn("Failed to cache feed", "username", username, "error", err)
+	}
+	return page, time.Now(), nil
+}
+
+// readFeedCache loads a cached feed page and the time it was written
+func readFeedCache(path string) (*models.PostPage, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var page models.PostPage
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to parse cached feed: %w", err)
+	}
+	return &page, info.ModTime(), nil
+}
+
+// writeFeedCache stores a feed page atomically, so readers and concurrent refreshes never see a partial file
+func writeFeedCache(path string, page *models.PostPage) error {
+	data, err := json.Marshal(page)
+	if err != nil {
+		return fmt.Errorf("failed to encode feed: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create feed cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write feed cache: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write feed cache: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
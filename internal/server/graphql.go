@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"qwiklip/internal/graphql"
+	"qwiklip/internal/instagram"
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+)
+
+// graphQLTypeNames names the response types for __typename and fragment type conditions
+var graphQLTypeNames = map[reflect.Type]string{
+	reflect.TypeFor[mediaResponse]():             "Media",
+	reflect.TypeFor[models.InstagramMediaInfo](): "Media",
+	reflect.TypeFor[models.MediaItem]():          "MediaItem",
+	reflect.TypeFor[models.VideoVersion]():       "VideoVersion",
+	reflect.TypeFor[models.DASHStreams]():        "DASHStreams",
+	reflect.TypeFor[models.Location]():           "Location",
+	reflect.TypeFor[userPostsResponse]():         "UserPosts",
+	reflect.TypeFor[userPost]():                  "Post",
+	reflect.TypeFor[batchResponse]():             "BatchResponse",
+	reflect.TypeFor[batchResult]():               "BatchResult",
+	reflect.TypeFor[batchError]():                "Error",
+}
+
+// handleGraphQL handles GET and POST /graphql. Queries can select any JSON field of the
+// REST responses:
+//
+//	media(shortcode: String!): Media
+//	user(username: String!, count: Int, cursor: String): UserPosts
+//	batch(urls: [String!]!): BatchResponse
+//
+// Requests that fail to parse or validate get 400; field errors come back with a 200,
+// null data for the field and an entry in errors.
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	var req graphql.Request
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if raw := query.Get("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+				s.sendErrorResponse(w, models.NewParsingError("GraphQL variables", err))
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)).Decode(&req); err != nil {
+			s.sendErrorResponse(w, models.NewParsingError("GraphQL request body", err))
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use GET or POST")
+		return
+	}
+	if req.Query == "" {
+		s.sendJSONError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	start := time.Now()
+	response := s.graphQLSchema(requestBaseURL(r)).Execute(r.Context(), req)
+
+	statusCode := http.StatusOK
+	if response.Data == nil {
+		statusCode = http.StatusBadRequest
+	}
+	logger.Info("GraphQL query executed",
+		"operation", req.OperationName,
+		"errors", len(response.Errors),
+		"status", statusCode,
+		"duration", time.Since(start))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("Failed to encode GraphQL response", "error", err)
+	}
+}
+
+// graphQLSchema returns the query fields, with proxy URLs under baseURL
+func (s *Server) graphQLSchema(baseURL string) *graphql.Schema {
+	return &graphql.Schema{
+		Query: map[string]graphql.Resolver{
+			"media": func(ctx context.Context, args graphql.Args) (any, error) {
+				shortcode, err := args.String("shortcode")
+				if err != nil {
+					return nil, err
+				}
+				return s.lookupMedia(ctx, baseURL, shortcode)
+			},
+			"user": func(ctx context.Context, args graphql.Args) (any, error) {
+				username, err := args.String("username")
+				if err != nil {
+					return nil, err
+				}
+				count, err := args.Int("count", instagram.DefaultFeedPageSize)
+				if err != nil {
+					return nil, err
+				}
+				if count < 1 || count > instagram.MaxFeedPageSize {
+					return nil, fmt.Errorf("count must be between 1 and %d", instagram.MaxFeedPageSize)
+				}
+				cursor, err := args.OptionalString("cursor")
+				if err != nil {
+					return nil, err
+				}
+				return s.userPosts(ctx, baseURL, username, cursor, count)
+			},
+			"batch": func(ctx context.Context, args graphql.Args) (any, error) {
+				rawURLs, err := args.Strings("urls")
+				if err != nil {
+					return nil, err
+				}
+				urls := trimURLList(rawURLs)
+				if len(urls) == 0 {
+					return nil, errors.New("urls list is required")
+				}
+				if len(urls) > maxBatchItems {
+					return nil, fmt.Errorf("too many urls (max %d), got %d", maxBatchItems, len(urls))
+				}
+				return s.runBatch(ctx, baseURL, urls, nil), nil
+			},
+		},
+		TypeNames:    graphQLTypeNames,
+		PresentError: presentGraphQLError,
+	}
+}
+
+// presentGraphQLError reports application errors with the message, type and status code
+// the REST endpoints would have used
+func presentGraphQLError(err error) *graphql.Error {
+	var appErr *models.AppError
+	if errors.As(err, &appErr) {
+		return &graphql.Error{
+			Message: appErr.Message,
+			Extensions: map[string]any{
+				"type": string(appErr.Type),
+				"code": appErr.HTTPStatusCode(),
+			},
+		}
+	}
+	return &graphql.Error{Message: err.Error()}
+}
@@ -1,32 +1,230 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"qwiklip/internal/config"
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/middleware"
 	"qwiklip/internal/models"
 )
 
 // handleReel handles requests to /reel/{shortcode}
 func (s *Server) handleReel(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if s.redirectToNormalized(w, r) {
+		return
+	}
 	instagramURL := s.parseReelURL(r.URL.Path)
-	s.logger.Info("Processing Instagram URL", "url", instagramURL, "original_path", r.URL.Path)
+	logger.Info("Processing Instagram URL", "url", instagramURL, "original_path", r.URL.Path)
+
+	// Malformed shortcodes are rejected before any of the routes below goes to Instagram
+	shortcode, err := s.client.ExtractShortcode(strings.TrimSuffix(strings.TrimSuffix(instagramURL, "/"), ".gif"))
+	if err != nil {
+		s.handleError(w, r, invalidPathError(r.URL.Path, err))
+		return
+	}
+
+	if isDownloadPath(r.URL.Path) {
+		s.handleReelDownload(w, r, instagramURL)
+		return
+	}
+	if isHLSPath(r.URL.Path) {
+		s.handleReelHLS(w, r, instagramURL)
+		return
+	}
+	if isAudioPath(r.URL.Path) {
+		s.handleReelAudio(w, r, instagramURL)
+		return
+	}
+	if isGIFPath(r.URL.Path) {
+		s.handleReelGIF(w, r, strings.TrimSuffix(strings.TrimSuffix(instagramURL, "/"), ".gif"))
+		return
+	}
+	if isClipRequest(r) {
+		s.handleReelClip(w, r, instagramURL)
+		return
+	}
+
+	// Programmatic clients can ask the stream URL for the metadata instead of the bytes
+	w.Header().Add("Vary", "Accept")
+	if wantsMetadata(r) {
+		s.serveReelMetadata(w, r, instagramURL)
+		return
+	}
 
-	mediaInfo, err := s.fetchMediaInfo(instagramURL)
+	// Bots and browsers get different responses for the same URL
+	if len(s.config.Server.LinkPreviewAgents) > 0 {
+		w.Header().Add("Vary", "User-Agent")
+	}
+	if s.wantsPreview(r) {
+		s.handlePreview(w, r, instagramURL)
+		return
+	}
+
+	// Serve straight from the disk cache when the video was streamed before.
+	// The cache only holds the default rendition, so ?quality= always goes to the CDN.
+	quality := r.URL.Query().Get("quality")
+	tagged := s.wantsTags(r) // The video cache holds untagged files
+	if quality == "" && !tagged {
+		s.setCachedMetadataHeaders(w, r, shortcode)
+		if s.serveCachedVideo(w, r, shortcode) {
+			s.stats.RecordShortcode(shortcode)
+			return
+		}
+	}
+
+	mediaInfo, err := s.fetchMediaInfo(r.Context(), instagramURL)
 	if err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
 	s.logMediaMetadata(mediaInfo)
+	setMetadataHeaders(w, shortcode, mediaInfo)
+
+	if tagged && !mediaInfo.IsImage() {
+		logger.Info("Starting tagged video streaming", "quality", quality)
+		s.streamTagged(w, r, instagramURL, mediaInfo, mediaInfo.FileName)
+		return
+	}
+
+	// Photo posts are proxied as-is and never written to the video cache
+	if mediaInfo.IsImage() {
+		logger.Info("Starting image streaming")
+		s.streamExtractedOrError(w, r, instagramURL, mediaInfo, imageURL, mediaInfo.FileName, "")
+		return
+	}
+
+	// ?quality=best muxes the separate DASH streams when there are any, otherwise it acts like high
+	if quality == models.QualityBest && mediaInfo.DASH != nil {
+		if s.ffmpeg != nil {
+			logger.Info("Starting DASH muxing", "height", mediaInfo.DASH.Height, "bandwidth", mediaInfo.DASH.Bandwidth)
+			s.muxDASH(w, r, mediaInfo)
+			return
+		}
+		logger.Debug("ffmpeg not installed, streaming the best progressive rendition instead of DASH")
+	}
+
+	videoURL, err := s.selectVideoURL(r, mediaInfo)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	cacheKey := shortcode
+	if videoURL != mediaInfo.VideoURL {
+		cacheKey = ""
+	}
 
 	// Stream the video content
-	s.logger.Info("Starting video streaming")
-	s.streamVideo(w, r, mediaInfo.VideoURL, mediaInfo.FileName)
+	logger.Info("Starting video streaming", "quality", quality)
+	s.streamExtractedOrError(w, r, instagramURL, mediaInfo, versionURL(quality), mediaInfo.FileName, cacheKey)
+}
+
+// selectVideoURL picks the rendition requested with ?quality=low|medium|high|best|{height}
+func (s *Server) selectVideoURL(r *http.Request, mediaInfo *models.InstagramMediaInfo) (string, error) {
+	videoURL, err := mediaInfo.SelectVersion(r.URL.Query().Get("quality"))
+	if err != nil {
+		return "", models.NewInvalidURLError(r.URL.String(), err)
+	}
+	return videoURL, nil
+}
+
+// handlePost handles requests to /p/{shortcode}/ and /p/{shortcode}/{index}.
+// Without an index it streams the post's video; with a 1-based index it streams that carousel item.
+func (s *Server) handlePost(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if s.redirectToNormalized(w, r) {
+		return
+	}
+	shortcode, index, err := parsePostPath(r.URL.Path)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	if index == 0 {
+		s.handleReel(w, r)
+		return
+	}
+
+	logger.Info("Processing carousel item", "shortcode", shortcode, "index", index)
+
+	cacheKey := fmt.Sprintf("%s_%d", shortcode, index)
+	s.setCachedMetadataHeaders(w, r, shortcode)
+	w.Header().Del("X-Qwiklip-Duration") // It is the post's, not the item's
+	if s.serveCachedVideo(w, r, cacheKey) {
+		s.stats.RecordShortcode(shortcode)
+		return
+	}
+
+	instagramURL := fmt.Sprintf("https://www.instagram.com/p/%s/", shortcode)
+	mediaInfo, err := s.fetchMediaInfo(r.Context(), instagramURL)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	item := mediaInfo.Item(index)
+	if item == nil {
+		s.handleError(w, r, models.NewNotFoundError(fmt.Sprintf("carousel item %d of '%s'", index, shortcode)))
+		return
+	}
+	s.logMediaMetadata(mediaInfo)
+	setMetadataHeaders(w, shortcode, mediaInfo)
+	w.Header().Del("X-Qwiklip-Duration")
+	if item.IsVideo && item.VideoURL != "" {
+		s.streamExtractedOrError(w, r, instagramURL, mediaInfo, itemURL(index, true), cacheKey+".mp4", cacheKey)
+		return
+	}
+	if item.ImageURL == "" {
+		s.handleError(w, r, models.NewNotFoundError(fmt.Sprintf("media for carousel item %d of '%s'", index, shortcode)))
+		return
+	}
+	s.streamExtractedOrError(w, r, instagramURL, mediaInfo, itemURL(index, false), cacheKey+".jpg", "")
+}
+
+// parsePostPath splits /p/{shortcode}/{index} into its parts; index is 0 when absent
+func parsePostPath(requestPath string) (string, int, error) {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(requestPath, "/p/"), "/"), "/")
+	if segments[0] == "" || len(segments) > 2 {
+		return "", 0, models.NewInvalidURLError(requestPath, fmt.Errorf("expected /p/{shortcode}/{index}"))
+	}
+	if !models.ValidShortcode(segments[0]) {
+		return "", 0, models.NewInvalidShortcodeError(segments[0])
+	}
+	if len(segments) == 1 {
+		return segments[0], 0, nil
+	}
+
+	index, err := strconv.Atoi(segments[1])
+	if err != nil || index < 1 {
+		return "", 0, models.NewInvalidURLError(requestPath, fmt.Errorf("carousel index must be a positive integer, got '%s'", segments[1]))
+	}
+	return segments[0], index, nil
+}
+
+// serveCachedVideo serves a video from the video cache with full range support
+func (s *Server) serveCachedVideo(w http.ResponseWriter, r *http.Request, shortcode string) bool {
+	if s.videoCache == nil || shortcode == "" {
+		return false
+	}
+
+	release, err := s.streams.acquire(w)
+	if err != nil {
+		s.handleError(w, r, err)
+		return true
+	}
+	defer release()
+	return s.videoCache.Serve(s.newVideoStreamer().withSlidingDeadline(w), r, shortcode)
 }
 
 // parseReelURL extracts and builds the Instagram URL from the request path
@@ -36,19 +234,24 @@ func (s *Server) parseReelURL(requestPath string) string {
 }
 
 // fetchMediaInfo retrieves media information with timing and error handling
-func (s *Server) fetchMediaInfo(instagramURL string) (*models.InstagramMediaInfo, error) {
+func (s *Server) fetchMediaInfo(ctx context.Context, instagramURL string) (*models.InstagramMediaInfo, error) {
 	start := time.Now()
-	mediaInfo, err := s.client.GetMediaInfo(instagramURL)
+	mediaInfo, err := s.client.GetMediaInfo(ctx, instagramURL)
 	duration := time.Since(start)
 
 	if err != nil {
+		s.metrics.Timing(metrics.ExtractionLatency, duration, "result", "error")
 		s.logger.Error("Failed to extract media info", "error", err, "duration", duration)
 		return nil, err
 	}
+	s.metrics.Timing(metrics.ExtractionLatency, duration, "result", "success")
+	if shortcode, err := s.client.ExtractShortcode(instagramURL); err == nil {
+		s.stats.RecordShortcode(shortcode)
+	}
 
 	s.logger.Info("Successfully extracted media info",
 		"duration", duration,
-		"video_url_prefix", mediaInfo.VideoURL[:min(100, len(mediaInfo.VideoURL))],
+		"media_url_prefix", mediaInfo.MediaURL()[:min(100, len(mediaInfo.MediaURL()))],
 		"filename", mediaInfo.FileName)
 
 	return mediaInfo, nil
@@ -69,27 +272,129 @@ func (s *Server) logMediaMetadata(mediaInfo *models.InstagramMediaInfo) {
 	}
 }
 
+// newVideoStreamer creates a video streamer using the server's configuration
+func (s *Server) newVideoStreamer() *VideoStreamer {
+	return NewVideoStreamer(s.client, &s.config.Stream, s.videoCache, s.metrics, s.logger)
+}
+
 // streamVideo streams the video content from Instagram to the client
-func (s *Server) streamVideo(w http.ResponseWriter, r *http.Request, videoURL, fileName string) {
-	streamer := NewVideoStreamer(s.client, s.config.Instagram.UserAgent, s.logger)
-	if err := streamer.StreamVideo(w, r, videoURL, fileName); err != nil {
+func (s *Server) streamVideo(w http.ResponseWriter, r *http.Request, videoURL, fileName, cacheKey string) {
+	release, err := s.streams.acquire(w)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	defer release()
+
+	r = s.webSockets.attach(r)
+	streamer := s.newVideoStreamer()
+	if err := streamer.StreamVideo(w, r, videoURL, fileName, cacheKey); err != nil {
+		s.handleError(w, r, err)
+	}
+}
+
+// mediaURLPicker selects the URL to stream from extracted media info, or "" when there is none
+type mediaURLPicker func(*models.InstagramMediaInfo) string
+
+// imageURL picks the photo of an image post
+func imageURL(mediaInfo *models.InstagramMediaInfo) string {
+	return mediaInfo.ImageURL
+}
+
+// versionURL picks the rendition for a ?quality= value
+func versionURL(quality string) mediaURLPicker {
+	return func(mediaInfo *models.InstagramMediaInfo) string {
+		videoURL, _ := mediaInfo.SelectVersion(quality)
+		return videoURL
+	}
+}
+
+// itemURL picks the video or image of a 1-based carousel item
+func itemURL(index int, video bool) mediaURLPicker {
+	return func(mediaInfo *models.InstagramMediaInfo) string {
+		item := mediaInfo.Item(index)
+		switch {
+		case item == nil:
+			return ""
+		case video:
+			return item.VideoURL
+		default:
+			return item.ImageURL
+		}
+	}
+}
+
+// streamExtracted streams the URL pick selects from mediaInfo, or only its headers for HEAD. CDN URLs are signed and
+// expire, so when the CDN rejects one the cached media info is dropped, the post is
+// extracted again and the stream is retried once with the fresh URL.
+func (s *Server) streamExtracted(w http.ResponseWriter, r *http.Request, instagramURL string, mediaInfo *models.InstagramMediaInfo, pick mediaURLPicker, fileName, cacheKey string) error {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+	streamer := s.newVideoStreamer()
+
+	// HEAD probes send no body, so only real streams count against the cap
+	if r.Method != http.MethodHead {
+		release, err := s.streams.acquire(w)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	r = s.webSockets.attach(r)
+
+	// HEAD requests only probe the CDN for the size
+	send := func(mediaURL string) error {
+		if r.Method == http.MethodHead {
+			return streamer.ProbeVideo(w, r, mediaURL, fileName)
+		}
+		return streamer.StreamVideo(w, r, mediaURL, fileName, cacheKey)
+	}
+
+	err := send(pick(mediaInfo))
+	if !errors.Is(err, ErrExpiredURL) || r.Context().Err() != nil {
+		return err
+	}
+
+	logger.Warn("CDN URL expired, extracting media info again", "url", instagramURL)
+	if shortcode, scErr := s.client.ExtractShortcode(instagramURL); scErr == nil {
+		s.client.InvalidateCache(r.Context(), shortcode)
+	}
+	fresh, err := s.fetchMediaInfo(r.Context(), instagramURL)
+	if err != nil {
+		return err
+	}
+	mediaURL := pick(fresh)
+	if mediaURL == "" {
+		return models.NewNotFoundError(fmt.Sprintf("media for '%s'", instagramURL))
+	}
+	return send(mediaURL)
+}
+
+// streamExtractedOrError is streamExtracted with errors sent to the client
+func (s *Server) streamExtractedOrError(w http.ResponseWriter, r *http.Request, instagramURL string, mediaInfo *models.InstagramMediaInfo, pick mediaURLPicker, fileName, cacheKey string) {
+	if err := s.streamExtracted(w, r, instagramURL, mediaInfo, pick, fileName, cacheKey); err != nil {
 		s.handleError(w, r, err)
 	}
 }
 
 // handleError provides structured error handling with custom error types
 func (s *Server) handleError(w http.ResponseWriter, r *http.Request, err error) {
-	s.logger.Error("Handling request error", "error", err, "error_type", fmt.Sprintf("%T", err), "path", r.URL.Path)
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	logger.Error("Handling request error", "error", err, "error_type", fmt.Sprintf("%T", err), "path", r.URL.Path)
+	s.stats.RecordError(r.URL.Path, w.Header().Get(middleware.RequestIDHeader), err)
+	// Downloads ask for an attachment up front; an error page must not be saved as the file
+	w.Header().Del("Content-Disposition")
 
 	// Check if client accepts JSON (API-style responses)
 	if s.shouldReturnJSON(r) {
-		s.sendErrorResponse(w, err)
+		s.writeErrorResponse(w, err)
 		return
 	}
 
 	// Default to HTML error page for web browsers
 	var appErr *models.AppError
 	if errors.As(err, &appErr) {
+		setRetryAfter(w, appErr)
 		httpCode := appErr.HTTPStatusCode()
 		s.renderError(w, httpCode, appErr.Message,
 			fmt.Sprintf("Error type: %s", string(appErr.Type)),
@@ -105,25 +410,39 @@ func (s *Server) handleError(w http.ResponseWriter, r *http.Request, err error)
 		})
 }
 
+// invalidPathError turns a failure to read a shortcode from path into a 400, keeping its
+// own message when it is already an AppError (such as a malformed shortcode)
+func invalidPathError(path string, err error) error {
+	var appErr *models.AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return models.NewInvalidURLError(path, err)
+}
+
 // sendErrorResponse sends structured JSON error responses
 func (s *Server) sendErrorResponse(w http.ResponseWriter, err error) {
+	s.stats.RecordError("", w.Header().Get(middleware.RequestIDHeader), err)
+	s.writeErrorResponse(w, err)
+}
+
+// writeErrorResponse writes the JSON body of an error already recorded by the caller
+func (s *Server) writeErrorResponse(w http.ResponseWriter, err error) {
 	var appErr *models.AppError
 	if errors.As(err, &appErr) {
+		retryAfter := setRetryAfter(w, appErr)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appErr.HTTPStatusCode())
 
-		response := map[string]interface{}{
-			"error":  appErr.Message,
-			"status": http.StatusText(appErr.HTTPStatusCode()),
-			"code":   appErr.HTTPStatusCode(),
-			"type":   string(appErr.Type),
-		}
-
+		response := newErrorBody(w, appErr.HTTPStatusCode(), appErr.Message)
+		response.Type = string(appErr.Type)
+		response.RetryAfter = retryAfter
 		json.NewEncoder(w).Encode(response)
 		s.logger.Error("Request failed",
 			"error", appErr.Message,
 			"type", string(appErr.Type),
-			"status", appErr.HTTPStatusCode())
+			"status", appErr.HTTPStatusCode(),
+			"request_id", w.Header().Get(middleware.RequestIDHeader))
 		return
 	}
 
@@ -131,46 +450,62 @@ func (s *Server) sendErrorResponse(w http.ResponseWriter, err error) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusInternalServerError)
 
-	response := map[string]interface{}{
-		"error":  "Internal server error",
-		"status": http.StatusText(http.StatusInternalServerError),
-		"code":   http.StatusInternalServerError,
-	}
-
-	json.NewEncoder(w).Encode(response)
-	s.logger.Error("Unexpected error", "error", err)
+	json.NewEncoder(w).Encode(newErrorBody(w, http.StatusInternalServerError, "Internal server error"))
+	s.logger.Error("Unexpected error", "error", err, "request_id", w.Header().Get(middleware.RequestIDHeader))
 }
 
-// shouldReturnJSON determines if the client expects JSON response
-func (s *Server) shouldReturnJSON(r *http.Request) bool {
-	accept := r.Header.Get("Accept")
+// sendJSONError sends a plain JSON error for requests rejected before extraction
+func (s *Server) sendJSONError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
 
-	// If no Accept header, default to HTML for browsers
-	if accept == "" {
-		return false
-	}
+	json.NewEncoder(w).Encode(newErrorBody(w, statusCode, message))
+	s.logger.Warn("Request rejected",
+		"error", message,
+		"status", statusCode,
+		"request_id", w.Header().Get(middleware.RequestIDHeader))
+}
 
-	// Check if JSON is explicitly requested
-	if strings.Contains(accept, "application/json") {
-		return true
+// setRetryAfter passes on the wait Instagram asked for in the Retry-After header of a rate
+// limited response and returns it in seconds, or 0 when unknown
+func setRetryAfter(w http.ResponseWriter, appErr *models.AppError) int {
+	seconds := int(appErr.RetryAfter() / time.Second)
+	if seconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
 	}
+	return seconds
+}
 
-	// Check if HTML is explicitly preferred (browsers typically send text/html)
-	if strings.Contains(accept, "text/html") {
-		return false
-	}
+// errorBody is the JSON body of every error response
+type errorBody struct {
+	Code      int    `json:"code"`
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"` // Set by the request ID middleware
+	Status    string `json:"status"`
+	Type      string `json:"type,omitempty"` // models.ErrorType, when known
+
+	RetryAfter int `json:"retry_after,omitempty"` // Seconds until Instagram accepts requests again, when rate limited
+}
 
-	// For generic accept headers like */*, prefer HTML for better user experience
-	// Only return JSON if it's the only or most specific type
-	if strings.Contains(accept, "*/*") {
-		// If */* is the only type or HTML is not mentioned, check for JSON preference
-		// Most browsers send "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
-		// which should prefer HTML
-		return !strings.Contains(accept, "text/html") && !strings.Contains(accept, "application/xhtml+xml")
+// newErrorBody builds an error body carrying the request ID of w
+func newErrorBody(w http.ResponseWriter, statusCode int, message string) errorBody {
+	return errorBody{
+		Code:      statusCode,
+		Error:     message,
+		RequestID: w.Header().Get(middleware.RequestIDHeader),
+		Status:    http.StatusText(statusCode),
 	}
+}
 
-	// Default to HTML for web browsers
-	return false
+// shouldReturnJSON reports whether an error should be sent as JSON rather than an HTML
+// page. The Accept header decides by its quality values; API routes default to JSON and
+// pages and streams to HTML, so browsers, players and curl sending */* get the page.
+func (s *Server) shouldReturnJSON(r *http.Request) bool {
+	fallback := contentTypeHTML
+	if isAPIPath(r.URL.Path) {
+		fallback = contentTypeJSON
+	}
+	return negotiate(r, fallback, contentTypeHTML, contentTypeJSON) == contentTypeJSON
 }
 
 // getErrorSuggestions provides contextual error suggestions based on error type
@@ -186,6 +521,7 @@ func (s *Server) getErrorSuggestions(errorType string) []string {
 		return []string{
 			"Verify the Instagram URL is correct",
 			"Ensure the URL format is /reel/{shortcode}",
+			"Shortcodes only contain letters, digits, '-' and '_'",
 			"Check that the content still exists",
 		}
 	case "not_found":
@@ -200,6 +536,32 @@ func (s *Server) getErrorSuggestions(errorType string) []string {
 			"Reduce the frequency of requests",
 			"Consider upgrading your plan for higher limits",
 		}
+	case "authentication":
+		return []string{
+			"This content is only available to logged-in accounts",
+			"Set INSTAGRAM_SESSION_ID to the sessionid cookie of an Instagram account",
+			"The configured session may have expired; log in again and update it",
+		}
+	case "expired":
+		return []string{
+			"Stories are only available for 24 hours",
+			"Ask the author to add it to a highlight",
+		}
+	case "overloaded":
+		return []string{
+			"The server is handling too many requests right now",
+			"Try again in a few seconds",
+		}
+	case "range_not_satisfiable":
+		return []string{
+			"Request a single byte range, e.g. Range: bytes=0-1023",
+			"Make sure the range starts before the end of the file",
+		}
+	case "unavailable":
+		return []string{
+			"This feature needs an optional dependency the server does not have",
+			"Ask the operator to install it, or use the plain video URL",
+		}
 	case "extraction", "parsing":
 		return []string{
 			"The Instagram content format may have changed",
@@ -216,25 +578,68 @@ func (s *Server) getErrorSuggestions(errorType string) []string {
 
 // handleHealthCheck provides a simple health check endpoint
 func (s *Server) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
+	ffmpeg := map[string]interface{}{"available": s.ffmpeg != nil}
+	if s.ffmpeg != nil {
+		ffmpeg["version"] = s.ffmpeg.Version()
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{"status":"healthy","timestamp":"%s"}`, time.Now().Format(time.RFC3339))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "healthy",
+		"timestamp": time.Now().Format(time.RFC3339),
+		"ffmpeg":    ffmpeg,
+	})
 }
 
 // serveAPIInfo provides API information when templates are not available
 func (s *Server) serveAPIInfo(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
 	apiInfo := map[string]interface{}{
 		"service": "Qwiklip",
-		"version": "1.0.0",
+		"version": s.versionInfo.Version,
 		"status":  "running",
 		"mode":    "api-only",
 		"endpoints": map[string]string{
-			"GET /":          "API information",
-			"GET /health":    "Health check",
-			"GET /reel/{id}": "Download Instagram reel",
+			"GET /":                        "API information",
+			"GET /health":                  "Health check",
+			"GET /livez":                   "Liveness probe",
+			"GET /readyz":                  "Readiness probe, 503 while degraded",
+			"GET /api/stats":               "Counters since startup as JSON",
+			"GET /version":                 "Build information",
+			"GET /api/openapi.json":        "OpenAPI 3 description of the JSON API",
+			"/api/v1/...":                  "Versioned JSON API; unversioned /api/ paths are aliases of v1",
+			"GET /reel/{id}":               "Download Instagram reel",
+			"GET /reel/{id}/download":      "Reel as an attachment named {username}_{date}_{id}.mp4",
+			"GET /reel/{id}?tags=1":        "Reel with caption, author, URL and date as MP4 tags (requires ffmpeg)",
+			"GET /reel/{id}/audio":         "Reel soundtrack as m4a or mp3 (?format=, requires ffmpeg)",
+			"GET /reel/{id}.gif":           "First seconds of a reel as an animated GIF (requires ffmpeg)",
+			"GET /reel/{id}?start=&end=":   "Segment of a reel (requires ffmpeg)",
+			"GET /reel/{id}/index.m3u8":    "Reel repackaged as HLS for players that require it (requires ffmpeg)",
+			"GET /reel/{id}?quality=":      "Reel in a given rendition: low, medium, high, best (DASH) or a height",
+			"GET /feed/{user}.xml":         "RSS feed of a user's recent reels",
+			"GET /feed/{user}/audio.xml":   "Podcast feed of a user's reel soundtracks (?format=, requires ffmpeg)",
+			"GET /watch/{id}":              "Watch page with player, caption, post date and download button",
+			"GET /embed/{id}":              "Iframe-friendly HTML5 player page (?autoplay=1, ?caption=0)",
+			"GET /p/{id}/{index}":          "Download a post video or carousel item",
+			"GET /stories/{user}/{id}":     "Download a story item (requires session)",
+			"GET /highlights/{id}/{index}": "List or download highlight items (requires session)",
+			"GET /playlist.m3u8?ids=a,b,c": "M3U playlist of several reels",
+			"POST /api/export.zip":         "ZIP archive of several reels with metadata",
+			"GET /api/media/{id}":          "Extracted media info as JSON",
+			"POST /api/batch":              "Extracted media info for several URLs",
+			"GET /resolve?url=":            "Shortcode and proxy URL of a post, share or instagr.am link",
+			"GET /fetch?url=":              "Redirect to the proxy path of a pasted Instagram URL",
+			"POST /api/jobs":               "Queue a background batch or export job",
+			"GET /api/jobs/{id}":           "Status and result of a background job",
+			"GET /api/jobs/{id}/events":    "Server-Sent Events with background job progress",
+			"GET /api/user/{user}/posts":   "Paginated list of a user's recent posts",
+			"POST /graphql":                "GraphQL queries for media, user posts and batches",
+			"GET /ws":                      "WebSocket extraction with live progress events",
 		},
 		"server": map[string]interface{}{
 			"port": s.config.Server.Port,
@@ -247,17 +652,19 @@ func (s *Server) serveAPIInfo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewEncoder(w).Encode(apiInfo); err != nil {
-		s.logger.Error("Failed to encode API info", "error", err)
+		logger.Error("Failed to encode API info", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
 
 // handleRoot provides information about the service
 func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
 	if !s.templatesEnabled {
-		s.logger.Info("Templates not available, serving API information in JSON format")
+		logger.Info("Templates not available, serving API information in JSON format")
 		s.serveAPIInfo(w, r)
 		return
 	}
@@ -267,7 +674,9 @@ func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 		Version   string
 		Commit    string
 		BuildTime string
+		Brand     config.BrandingConfig
 	}{
+		Brand:     s.config.Branding,
 		Port:      s.config.Server.Port,
 		Version:   s.versionInfo.Version,
 		Commit:    s.versionInfo.Commit,
@@ -276,7 +685,7 @@ func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 
 	// Execute template
 	if err := s.templateSet.Index.Execute(w, data); err != nil {
-		s.logger.Error("Failed to execute template", "error", err)
+		logger.Error("Failed to execute template", "error", err)
 		s.renderError(w, http.StatusInternalServerError, "Service temporarily unavailable",
 			"Template rendering failed", nil)
 		return
@@ -309,16 +718,20 @@ func (s *Server) renderError(w http.ResponseWriter, statusCode int, message stri
 		Message     string
 		Details     string
 		Suggestions []string
+		RequestID   string
 		Timestamp   string
 		Version     string
 		Commit      string
 		BuildTime   string
+		Brand       config.BrandingConfig
 	}{
+		Brand:       s.config.Branding,
 		StatusCode:  statusCode,
 		StatusText:  http.StatusText(statusCode),
 		Message:     message,
 		Details:     details,
 		Suggestions: suggestions,
+		RequestID:   w.Header().Get(middleware.RequestIDHeader),
 		Timestamp:   time.Now().UTC().Format(time.RFC3339),
 		Version:     s.versionInfo.Version,
 		Commit:      s.versionInfo.Commit,
@@ -340,7 +753,8 @@ func (s *Server) renderError(w http.ResponseWriter, statusCode int, message stri
 	s.logger.Warn("Rendered error page",
 		"status_code", statusCode,
 		"message", message,
-		"suggestion_count", len(suggestions))
+		"suggestion_count", len(suggestions),
+		"request_id", errorData.RequestID)
 }
 
 // getDefaultSuggestions provides default error suggestions based on HTTP status code
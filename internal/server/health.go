@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// readinessResponse is the JSON body of GET /readyz
+type readinessResponse struct {
+	Status    string            `json:"status"` // ready or not_ready
+	Timestamp string            `json:"timestamp"`
+	Checks    map[string]string `json:"checks"` // "ok" or why the check failed
+}
+
+// handleLivez handles GET /livez: the process is up and serving HTTP. It never checks
+// dependencies, so a degraded upstream does not get the pod restarted.
+func (s *Server) handleLivez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "alive",
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// handleReadyz handles GET /readyz: 200 while the instance should receive traffic and
+// 503 while it is degraded, so a load balancer routes around it without a restart
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := s.readinessChecks()
+	response := readinessResponse{
+		Status:    "ready",
+		Timestamp: time.Now().Format(time.RFC3339),
+		Checks:    checks,
+	}
+	status := http.StatusOK
+	for _, result := range checks {
+		if result != "ok" {
+			response.Status = "not_ready"
+			status = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// readinessChecks runs every readiness check and returns "ok" or the reason it failed, by name
+func (s *Server) readinessChecks() map[string]string {
+	checks := map[string]string{
+		"templates": "ok",
+		"config":    "ok",
+		"draining":  "ok",
+		"upstream":  "ok",
+	}
+	if !s.templatesEnabled {
+		checks["templates"] = "HTML templates failed to load"
+	}
+	if reloadErr := s.reloadError.Load(); reloadErr != nil {
+		checks["config"] = "configuration reload failed: " + *reloadErr
+	}
+	if s.streams.draining.Load() {
+		checks["draining"] = "server is shutting down"
+	}
+	if reason := s.client.RateLimits().Unavailable(); reason != "" {
+		checks["upstream"] = reason
+	}
+	return checks
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+)
+
+// highlightListing is the JSON body of GET /highlights/{id}
+type highlightListing struct {
+	ID       string          `json:"id"`
+	Title    string          `json:"title,omitempty"`
+	Username string          `json:"username,omitempty"`
+	Items    []highlightItem `json:"items"`
+}
+
+// highlightItem is one entry of a highlight listing with its proxy URL
+type highlightItem struct {
+	Index   int    `json:"index"`
+	IsVideo bool   `json:"isVideo"`
+	URL     string `json:"url"`
+}
+
+// handleHighlight handles /highlights/{id} (JSON listing) and /highlights/{id}/{index} (stream one item)
+func (s *Server) handleHighlight(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/highlights/"), "/"), "/")
+	if segments[0] == "" || len(segments) > 2 {
+		s.handleError(w, r, models.NewInvalidURLError(r.URL.Path, fmt.Errorf("expected /highlights/{id}/{index}")))
+		return
+	}
+	highlightID := strings.TrimPrefix(segments[0], "highlight:")
+
+	index := 0
+	if len(segments) == 2 {
+		var err error
+		if index, err = strconv.Atoi(segments[1]); err != nil || index < 1 {
+			s.handleError(w, r, models.NewInvalidURLError(r.URL.Path, fmt.Errorf("item index must be a positive integer, got '%s'", segments[1])))
+			return
+		}
+	}
+
+	logger.Info("Processing Instagram highlight", "highlight_id", highlightID, "index", index)
+
+	mediaInfo, err := s.client.GetHighlight(r.Context(), highlightID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if index == 0 {
+		s.writeHighlightListing(w, r, highlightID, mediaInfo)
+		return
+	}
+
+	item := mediaInfo.Item(index)
+	if item == nil {
+		s.handleError(w, r, models.NewNotFoundError(fmt.Sprintf("highlight item %d of '%s'", index, highlightID)))
+		return
+	}
+
+	// Highlight items reuse story CDN URLs, which rotate, so they bypass the video cache
+	fileName := fmt.Sprintf("%s_%d.mp4", highlightID, index)
+	mediaURL := item.VideoURL
+	if !item.IsVideo {
+		fileName = fmt.Sprintf("%s_%d.jpg", highlightID, index)
+		mediaURL = item.ImageURL
+	}
+	s.streamVideo(w, r, mediaURL, fileName, "")
+}
+
+// writeHighlightListing lists the highlight's items with their proxy URLs
+func (s *Server) writeHighlightListing(w http.ResponseWriter, r *http.Request, highlightID string, mediaInfo *models.InstagramMediaInfo) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	listing := highlightListing{
+		ID:       highlightID,
+		Title:    mediaInfo.Caption,
+		Username: mediaInfo.Username,
+		Items:    make([]highlightItem, len(mediaInfo.Items)),
+	}
+
+	baseURL := requestBaseURL(r)
+	for i, item := range mediaInfo.Items {
+		listing.Items[i] = highlightItem{
+			Index:   item.Index,
+			IsVideo: item.IsVideo,
+			URL:     fmt.Sprintf("%s/highlights/%s/%d/", baseURL, highlightID, item.Index),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(listing); err != nil {
+		logger.Error("Failed to encode highlight listing", "error", err)
+	}
+}
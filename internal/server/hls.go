@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"qwiklip/internal/config"
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+	"qwiklip/internal/transcode"
+)
+
+// hlsPackageTimeout bounds one packaging run, independent of the requests waiting for it
+const hlsPackageTimeout = 2 * time.Minute
+
+// hlsSegmentName matches the segment files written by transcode.SegmentHLS
+var hlsSegmentName = regexp.MustCompile(`^segment[0-9]{3,}\.ts$`)
+
+// isHLSPath reports whether the path is /reel/{shortcode}/index.m3u8 or one of its segments
+func isHLSPath(requestPath string) bool {
+	segments := strings.Split(strings.Trim(requestPath, "/"), "/")
+	return len(segments) == 3 && segments[0] == "reel" &&
+		(segments[2] == transcode.HLSPlaylist || hlsSegmentName.MatchString(segments[2]))
+}
+
+// hlsPackager repackages reels into HLS on first request and keeps the files for a while,
+// so the playlist and all its segments are served from one ffmpeg run
+type hlsPackager struct {
+	dir            string
+	segmentSeconds int
+	retention      time.Duration
+	logger         *slog.Logger
+
+	mu       sync.Mutex
+	packages map[string]*hlsPackage
+}
+
+// hlsPackage is an in-progress or finished packaging run for one shortcode
+type hlsPackage struct {
+	done     chan struct{}
+	err      error
+	finished time.Time
+}
+
+// newHLSPackager creates a packager, removing segments left over from a previous run
+func newHLSPackager(cfg *config.StreamConfig, logger *slog.Logger) *hlsPackager {
+	if err := os.RemoveAll(cfg.HLSDir); err != nil {
+		logger.Warn("Failed to clear HLS directory", "dir", cfg.HLSDir, "error", err)
+	}
+	return &hlsPackager{
+		dir:            cfg.HLSDir,
+		segmentSeconds: cfg.HLSSegmentSeconds,
+		retention:      cfg.HLSRetention,
+		logger:         logger,
+		packages:       make(map[string]*hlsPackage),
+	}
+}
+
+// get returns the directory holding the shortcode's HLS files, running package first if needed.
+// Concurrent callers share one run; failed runs are not kept so the next request retries.
+func (p *hlsPackager) get(ctx context.Context, shortcode string, pack func(ctx context.Context, dir string) error) (string, error) {
+	dir := filepath.Join(p.dir, shortcode)
+
+	p.mu.Lock()
+	p.expireLocked(time.Now())
+	pkg, ok := p.packages[shortcode]
+	if !ok {
+		pkg = &hlsPackage{done: make(chan struct{})}
+		p.packages[shortcode] = pkg
+
+		go func() {
+			packCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), hlsPackageTimeout)
+			defer cancel()
+
+			err := os.MkdirAll(dir, 0o755)
+			if err == nil {
+				err = pack(packCtx, dir)
+			}
+
+			p.mu.Lock()
+			pkg.err, pkg.finished = err, time.Now()
+			if err != nil {
+				delete(p.packages, shortcode)
+				os.RemoveAll(dir)
+			}
+			p.mu.Unlock()
+			close(pkg.done)
+		}()
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-pkg.done:
+		return dir, pkg.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// expireLocked removes packages finished longer than the retention period ago; p.mu must be held
+func (p *hlsPackager) expireLocked(now time.Time) {
+	for shortcode, pkg := range p.packages {
+		if pkg.finished.IsZero() || now.Sub(pkg.finished) < p.retention {
+			continue
+		}
+		delete(p.packages, shortcode)
+		if err := os.RemoveAll(filepath.Join(p.dir, shortcode)); err != nil {
+			p.logger.Warn("Failed to remove HLS package", "shortcode", shortcode, "error", err)
+		}
+	}
+}
+
+// handleReelHLS handles /reel/{shortcode}/index.m3u8 and its segments. The reel is
+// repackaged without re-encoding on the first request and then served from disk.
+func (s *Server) handleReelHLS(w http.ResponseWriter, r *http.Request, instagramURL string) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if s.ffmpeg == nil {
+		s.handleError(w, r, models.NewUnavailableError("HLS output", "ffmpeg is not installed on this server"))
+		return
+	}
+
+	name := filepath.Base(r.URL.Path)
+	instagramURL = strings.TrimSuffix(instagramURL, "/"+name)
+	shortcode, err := s.client.ExtractShortcode(instagramURL)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	start := time.Now()
+	dir, err := s.hls.get(r.Context(), shortcode, func(ctx context.Context, dir string) error {
+		return s.packageHLS(ctx, instagramURL, dir)
+	})
+	if err != nil {
+		if r.Context().Err() == nil {
+			s.handleError(w, r, err)
+		}
+		return
+	}
+	if name == transcode.HLSPlaylist {
+		logger.Info("Serving HLS playlist", "shortcode", shortcode, "duration", time.Since(start))
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	} else {
+		w.Header().Set("Content-Type", "video/mp2t")
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.config.Stream.HLSRetention.Seconds())))
+
+	file := filepath.Join(dir, name)
+	if _, err := os.Stat(file); err != nil {
+		s.sendJSONError(w, http.StatusNotFound, "HLS segment not found")
+		return
+	}
+	http.ServeFile(s.newVideoStreamer().withSlidingDeadline(w), r, file)
+}
+
+// packageHLS fetches the reel's default video and writes its HLS files to dir
+func (s *Server) packageHLS(ctx context.Context, instagramURL, dir string) error {
+	mediaInfo, err := s.fetchMediaInfo(ctx, instagramURL)
+	if err != nil {
+		return err
+	}
+	if mediaInfo.VideoURL == "" {
+		return models.NewUnsupportedError("image (photo posts cannot be converted)")
+	}
+
+	streamer := s.newVideoStreamer()
+	video, err := streamer.OpenVideo(ctx, mediaInfo.VideoURL)
+	if err != nil {
+		return models.NewNetworkError("fetching video from CDN", err)
+	}
+	defer video.Body.Close()
+
+	s.logger.Info("Packaging reel as HLS", "dir", dir)
+	if err := s.ffmpeg.SegmentHLS(ctx, video.Body, dir, s.config.Stream.HLSSegmentSeconds); err != nil {
+		return fmt.Errorf("failed to package HLS: %w", err)
+	}
+	return nil
+}
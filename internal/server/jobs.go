@@ -0,0 +1,236 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"qwiklip/internal/instagram"
+	"qwiklip/internal/jobs"
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+)
+
+const (
+	maxJobItems     = 200        // Upper bound on URLs or shortcodes per job
+	maxJobBodyBytes = 256 * 1024 // Upper bound on the JSON request body
+)
+
+// Job types accepted by POST /api/jobs
+const (
+	jobTypeBatch  = "batch"  // Extract URLs; the result is the /api/batch response
+	jobTypeExport = "export" // Build an export ZIP; fetched from /api/jobs/{id}/result
+)
+
+// jobRequest is the JSON body accepted by POST /api/jobs
+type jobRequest struct {
+	Type       string   `json:"type"`
+	URLs       []string `json:"urls"`       // batch jobs
+	Shortcodes []string `json:"shortcodes"` // export jobs
+}
+
+// exportJobResult is the result of an export job
+type exportJobResult struct {
+	Download string `json:"download"` // URL of the finished ZIP
+	Size     int64  `json:"size"`
+	Written  int    `json:"written"`
+	Failed   int    `json:"failed"`
+}
+
+// handleJobs handles POST /api/jobs, queueing a batch or export job.
+// It responds 202 with the job and a Location header to poll.
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use POST")
+		return
+	}
+
+	var req jobRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJobBodyBytes)).Decode(&req); err != nil {
+		s.sendErrorResponse(w, models.NewParsingError("job request body", err))
+		return
+	}
+
+	baseURL := requestBaseURL(r)
+	var task jobs.Task
+	switch req.Type {
+	case jobTypeBatch:
+		urls := trimURLList(req.URLs)
+		if !s.checkJobItems(w, "urls", len(urls)) {
+			return
+		}
+		task = func(ctx context.Context, jobID string, progress func(jobs.Progress)) (any, error) {
+			response := s.runBatch(instagram.WithRateLimitWait(ctx), baseURL, urls, func(done, total int) {
+				progress(jobs.Progress{Done: done, Total: total})
+			})
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return response, nil
+		}
+	case jobTypeExport:
+		// As for /api/export.zip, malformed shortcodes are dropped before the job runs
+		shortcodes, invalid := normalizeShortcodes(req.Shortcodes)
+		if len(shortcodes) == 0 && len(invalid) > 0 {
+			s.sendErrorResponse(w, models.NewInvalidShortcodeError(invalid[0]))
+			return
+		}
+		if !s.checkJobItems(w, "shortcodes", len(shortcodes)) {
+			return
+		}
+		task = func(ctx context.Context, jobID string, progress func(jobs.Progress)) (any, error) {
+			return s.runExportJob(instagram.WithRateLimitWait(ctx), baseURL, jobID, shortcodes, progress)
+		}
+	default:
+		s.sendJSONError(w, http.StatusBadRequest,
+			fmt.Sprintf("invalid job type '%s', must be one of: %s, %s", req.Type, jobTypeBatch, jobTypeExport))
+		return
+	}
+
+	job, err := s.jobs.Submit(req.Type, task)
+	if errors.Is(err, jobs.ErrQueueFull) {
+		s.sendErrorResponse(w, models.NewOverloadedError(err.Error()))
+		return
+	}
+	if err != nil {
+		s.sendErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Location", apiBase(r.Context())+"/jobs/"+job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		logger.Error("Failed to encode job", "error", err)
+	}
+}
+
+// checkJobItems rejects jobs with no items or too many
+func (s *Server) checkJobItems(w http.ResponseWriter, field string, count int) bool {
+	if count == 0 {
+		s.sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("%s list is required", field))
+		return false
+	}
+	if count > maxJobItems {
+		s.sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("too many %s (max %d), got %d", field, maxJobItems, count))
+		return false
+	}
+	return true
+}
+
+// handleJob handles GET and DELETE /api/jobs/{id}, GET /api/jobs/{id}/result and
+// GET /api/jobs/{id}/events
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/jobs/"), "/"), "/")
+	if segments[0] == "" || len(segments) > 2 || (len(segments) == 2 && segments[1] != "result" && segments[1] != "events") {
+		s.sendJSONError(w, http.StatusNotFound, "Expected /api/jobs/{id}, /api/jobs/{id}/result or /api/jobs/{id}/events")
+		return
+	}
+	id := segments[0]
+
+	allowed := http.MethodGet + ", " + http.MethodDelete
+	if len(segments) == 2 {
+		allowed = http.MethodGet
+	}
+	if r.Method != http.MethodGet && (len(segments) == 2 || r.Method != http.MethodDelete) {
+		w.Header().Set("Allow", allowed)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use "+allowed)
+		return
+	}
+
+	var job jobs.Job
+	var err error
+	if r.Method == http.MethodDelete {
+		job, err = s.jobs.Cancel(id)
+	} else {
+		job, err = s.jobs.Get(id)
+	}
+	if err != nil {
+		s.sendJSONError(w, http.StatusNotFound, fmt.Sprintf("job '%s' not found", id))
+		return
+	}
+
+	if len(segments) == 2 && segments[1] == "events" {
+		s.serveJobEvents(w, r, job.ID)
+		return
+	}
+	if len(segments) == 2 {
+		s.serveJobResult(w, r, job)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		logger.Error("Failed to encode job", "error", err)
+	}
+}
+
+// serveJobResult sends the ZIP of a finished export job, or the JSON result of other jobs
+func (s *Server) serveJobResult(w http.ResponseWriter, r *http.Request, job jobs.Job) {
+	if job.Status != jobs.StatusSucceeded {
+		s.sendJSONError(w, http.StatusConflict, fmt.Sprintf("job '%s' has no result (status %s)", job.ID, job.Status))
+		return
+	}
+
+	if job.Type != jobTypeExport {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(job.Result)
+		return
+	}
+
+	file, err := os.Open(s.jobs.FilePath(job.ID, ".zip"))
+	if err != nil {
+		s.sendJSONError(w, http.StatusNotFound, fmt.Sprintf("result of job '%s' is no longer available", job.ID))
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		s.sendErrorResponse(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="qwiklip-export-%s.zip"`, job.ID))
+	http.ServeContent(s.newVideoStreamer().withSlidingDeadline(w), r, "", info.ModTime(), file)
+}
+
+// runExportJob writes the export ZIP to the job's result file
+func (s *Server) runExportJob(ctx context.Context, baseURL, jobID string, shortcodes []string, progress func(jobs.Progress)) (any, error) {
+	progress(jobs.Progress{Total: len(shortcodes)})
+
+	path := s.jobs.FilePath(jobID, ".zip")
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	written, failed, err := s.writeExportArchive(ctx, s.newVideoStreamer(), file, shortcodes, progress)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write export file: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat export file: %w", err)
+	}
+	return exportJobResult{
+		Download: fmt.Sprintf("%s/api/jobs/%s/result", baseURL, jobID),
+		Size:     info.Size(),
+		Written:  written,
+		Failed:   failed,
+	}, nil
+}
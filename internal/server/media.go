@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+)
+
+// mediaResponse is the JSON body of GET /api/media/{shortcode}
+type mediaResponse struct {
+	Shortcode string `json:"shortcode"`
+	*models.InstagramMediaInfo
+	URL string `json:"url"` // Proxy URL that streams the media
+}
+
+// handleMedia handles GET /api/media/{shortcode}: it runs extraction and returns the
+// media info as JSON instead of streaming, for clients that only need the metadata.
+func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use GET")
+		return
+	}
+
+	shortcode := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/media/"), "/")
+	if shortcode == "" || strings.Contains(shortcode, "/") {
+		s.sendJSONError(w, http.StatusNotFound, "Expected /api/media/{shortcode}")
+		return
+	}
+	if !models.ValidShortcode(shortcode) {
+		s.sendErrorResponse(w, models.NewInvalidShortcodeError(shortcode))
+		return
+	}
+
+	response, err := s.lookupMedia(r.Context(), requestBaseURL(r), shortcode)
+	if err != nil {
+		s.sendErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("Failed to encode media info", "error", err)
+	}
+}
+
+// wantsMetadata reports whether a stream request asks for the media info JSON instead
+// of the media, with ?meta=1 or an Accept header listing application/json. Wildcards
+// such as */*, which players send, do not count.
+func wantsMetadata(r *http.Request) bool {
+	if meta, err := strconv.ParseBool(r.URL.Query().Get("meta")); err == nil {
+		return meta
+	}
+	// application/json;q=0 explicitly refuses JSON
+	for _, accepted := range parseAccept(r.Header.Values("Accept")) {
+		if accepted.mediaType == contentTypeJSON && accepted.q > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// serveReelMetadata answers a stream URL with the /api/media/{shortcode} body
+func (s *Server) serveReelMetadata(w http.ResponseWriter, r *http.Request, instagramURL string) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	shortcode, err := s.client.ExtractShortcode(instagramURL)
+	if err != nil {
+		s.sendErrorResponse(w, err)
+		return
+	}
+
+	response, err := s.lookupMedia(r.Context(), requestBaseURL(r), shortcode)
+	if err != nil {
+		s.sendErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("Failed to encode media info", "error", err)
+	}
+}
+
+// lookupMedia extracts a post by shortcode and pairs it with its proxy URL under baseURL
+func (s *Server) lookupMedia(ctx context.Context, baseURL, shortcode string) (*mediaResponse, error) {
+	mediaInfo, err := s.fetchMediaInfo(ctx, fmt.Sprintf("https://www.instagram.com/p/%s/", shortcode))
+	if err != nil {
+		return nil, err
+	}
+
+	pathType := "reel"
+	if mediaInfo.IsImage() || len(mediaInfo.Items) > 0 {
+		pathType = "p"
+	}
+	return &mediaResponse{
+		Shortcode:          shortcode,
+		InstagramMediaInfo: mediaInfo,
+		URL:                fmt.Sprintf("%s/%s/%s/", baseURL, pathType, shortcode),
+	}, nil
+}
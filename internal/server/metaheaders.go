@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"qwiklip/internal/models"
+)
+
+// maxCaptionHeader is the caption length, in characters, sent in X-Qwiklip-Caption
+const maxCaptionHeader = 200
+
+// setMetadataHeaders describes the streamed post in X-Qwiklip-* headers, so download tools
+// get the metadata without a second API call. Header values must be ASCII, so the caption
+// is percent-encoded UTF-8; unknown fields are left out.
+func setMetadataHeaders(w http.ResponseWriter, shortcode string, mediaInfo *models.InstagramMediaInfo) {
+	header := w.Header()
+	header.Set("X-Qwiklip-Shortcode", shortcode)
+	if mediaInfo.Username != "" {
+		header.Set("X-Qwiklip-Username", url.PathEscape(mediaInfo.Username))
+	}
+	if mediaInfo.Caption != "" {
+		header.Set("X-Qwiklip-Caption", url.PathEscape(truncateRunes(mediaInfo.Caption, maxCaptionHeader)))
+	}
+	if mediaInfo.Duration > 0 {
+		header.Set("X-Qwiklip-Duration", strconv.FormatFloat(mediaInfo.Duration, 'f', -1, 64))
+	}
+}
+
+// setCachedMetadataHeaders sets the metadata headers for a video served from the video
+// cache while its media info is still in the metadata cache
+func (s *Server) setCachedMetadataHeaders(w http.ResponseWriter, r *http.Request, shortcode string) {
+	if mediaInfo, ok := s.client.CachedMediaInfo(r.Context(), shortcode); ok {
+		setMetadataHeaders(w, shortcode, mediaInfo)
+	}
+}
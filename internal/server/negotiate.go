@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	contentTypeHTML = "text/html"
+	contentTypeJSON = "application/json"
+)
+
+// acceptRange is one media range of an Accept header
+type acceptRange struct {
+	mediaType string  // Lower case, e.g. "text/html", "text/*" or "*/*"
+	q         float64 // Quality value between 0 and 1
+}
+
+// parseAccept parses Accept header values into their media ranges. A range without a
+// valid q parameter weighs 1.
+func parseAccept(values []string) []acceptRange {
+	var ranges []acceptRange
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, _ := strings.Cut(part, ";")
+			mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+			if mediaType == "" {
+				continue
+			}
+			// Some clients send a bare * for */*
+			if mediaType == "*" {
+				mediaType = "*/*"
+			}
+
+			accepted := acceptRange{mediaType: mediaType, q: 1}
+			for _, param := range strings.Split(params, ";") {
+				name, value, _ := strings.Cut(param, "=")
+				if !strings.EqualFold(strings.TrimSpace(name), "q") {
+					continue
+				}
+				if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && q >= 0 && q <= 1 {
+					accepted.q = q
+				}
+			}
+			ranges = append(ranges, accepted)
+		}
+	}
+	return ranges
+}
+
+// quality returns the weight ranges give mediaType, taken from the most specific range
+// that matches it: the exact type, then type/*, then */*. It is 0 when none matches.
+func quality(ranges []acceptRange, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	best, specificity := 0.0, -1
+	for _, accepted := range ranges {
+		var level int
+		switch accepted.mediaType {
+		case mediaType:
+			level = 2
+		case mainType + "/*":
+			level = 1
+		case "*/*":
+			level = 0
+		default:
+			continue
+		}
+		if level > specificity {
+			best, specificity = accepted.q, level
+		}
+	}
+	return best
+}
+
+// negotiate returns the offer the request's Accept header weighs highest. fallback, the
+// route's default, wins ties and is returned when the header is missing or accepts none
+// of the offers, so Accept: */* gets the route's usual format.
+func negotiate(r *http.Request, fallback string, offers ...string) string {
+	ranges := parseAccept(r.Header.Values("Accept"))
+	if len(ranges) == 0 {
+		return fallback
+	}
+
+	best, bestQ := fallback, quality(ranges, fallback)
+	for _, offer := range offers {
+		if q := quality(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// isAPIPath reports whether path is a JSON API route, whose errors default to JSON.
+// Requests under /api/v1/ have been mapped onto these paths by then.
+func isAPIPath(path string) bool {
+	return strings.HasPrefix(path, "/api/") || apiV1Aliases[path]
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/transcode"
+)
+
+// trackingParams are the query parameters Instagram and ad networks append to shared links.
+// utm_* parameters are matched by prefix.
+var trackingParams = map[string]bool{
+	"igsh":   true,
+	"igshid": true,
+	"ig_rid": true,
+	"ig_mid": true,
+	"fbclid": true,
+	"gclid":  true,
+}
+
+// redirectToNormalized answers a GET or HEAD request for a post whose path has empty or
+// trailing segments, or whose query has tracking parameters, with a permanent redirect to
+// the normalized URL, so one post has one URL in caches and logs. It reports whether it
+// redirected.
+func (s *Server) redirectToNormalized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	path := normalizedPath(r.URL.Path)
+	query := stripTrackingParams(r.URL.RawQuery)
+	if path == r.URL.Path && query == r.URL.RawQuery {
+		return false
+	}
+
+	target := path
+	if query != "" {
+		target += "?" + query
+	}
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+	logger.Debug("Redirecting to normalized URL", "from", r.URL.RequestURI(), "to", target)
+	http.Redirect(w, r, target, http.StatusMovedPermanently)
+	return true
+}
+
+// normalizedPath drops empty segments and anything after the shortcode and the sub-path
+// the route knows (a carousel index, download, audio or the HLS files) from a post path.
+// A path that is already normal is returned as is, with or without its trailing slash.
+func normalizedPath(requestPath string) string {
+	var segments []string
+	for _, segment := range strings.Split(requestPath, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) < 2 {
+		return requestPath
+	}
+
+	keep := 2
+	if len(segments) > 2 && isKnownSubPath(segments[0], segments[2]) {
+		keep = 3
+	}
+	if len(segments) == keep && !strings.Contains(requestPath, "//") {
+		return requestPath
+	}
+	normalized := "/" + strings.Join(segments[:keep], "/")
+	if keep == 2 && !strings.HasSuffix(segments[1], ".gif") {
+		normalized += "/"
+	}
+	return normalized
+}
+
+// isKnownSubPath reports whether segment is a sub-path of a shortcode on the route named by kind
+func isKnownSubPath(kind, segment string) bool {
+	switch kind {
+	case "reel":
+		return segment == "download" || segment == "audio" ||
+			segment == transcode.HLSPlaylist || hlsSegmentName.MatchString(segment)
+	case "p":
+		// Invalid indexes such as 0 are kept, for parsePostPath to reject
+		_, err := strconv.Atoi(segment)
+		return err == nil
+	}
+	return false
+}
+
+// stripTrackingParams removes tracking parameters from a raw query, keeping the others in order
+func stripTrackingParams(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	var kept []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && isTrackingParam(name) {
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	return strings.Join(kept, "&")
+}
+
+// isTrackingParam reports whether a query parameter only tracks where a link was shared
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	return trackingParams[name] || strings.HasPrefix(name, "utm_")
+}
@@ -0,0 +1,399 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"qwiklip/internal/graphql"
+	"qwiklip/internal/jobs"
+	"qwiklip/internal/middleware"
+)
+
+// apiRoute documents one endpoint in /api/openapi.json. Request and response bodies
+// are given as values of the types the handlers encode, so the schemas follow the code.
+type apiRoute struct {
+	Method      string
+	Path        string // With {name} placeholders for path parameters
+	Summary     string
+	Tag         string
+	Params      []apiParam
+	Body        any    // Request body (nil without one)
+	Response    any    // Success body (nil when it is not JSON)
+	Status      int    // Success status, 200 when zero
+	ContentType string // Success content type when Response is nil
+	Errors      []int  // Error statuses, all with the errorBody schema
+}
+
+// apiParam is a path or query parameter of an apiRoute
+type apiParam struct {
+	Name        string
+	In          string // path or query
+	Type        string // string or integer
+	Description string
+}
+
+// streamErrors are the error statuses of the streaming endpoints
+var streamErrors = []int{http.StatusBadRequest, http.StatusNotFound, http.StatusGone, http.StatusRequestedRangeNotSatisfiable, http.StatusTooManyRequests, http.StatusServiceUnavailable}
+
+var shortcodeParam = apiParam{Name: "shortcode", In: "path", Type: "string", Description: "Instagram shortcode, e.g. ABC123"}
+
+// apiRoutes are the documented endpoints. The admin API and HTML pages are left out.
+var apiRoutes = []apiRoute{
+	{
+		Method: http.MethodGet, Path: "/reel/{shortcode}/", Tag: "streaming",
+		Summary: "Stream a reel",
+		Params: []apiParam{shortcodeParam,
+			{Name: "quality", In: "query", Type: "string", Description: "low, medium, high, best or a height in pixels"},
+			{Name: "meta", In: "query", Type: "boolean", Description: "Return the /api/media/{shortcode} JSON instead of the media, as does Accept: application/json"}},
+		ContentType: "video/mp4",
+		Errors:      streamErrors,
+	},
+	{
+		Method: http.MethodGet, Path: "/reel/{shortcode}/download", Tag: "streaming",
+		Summary:     "Download a reel as an attachment",
+		Params:      []apiParam{shortcodeParam},
+		ContentType: "video/mp4",
+		Errors:      streamErrors,
+	},
+	{
+		Method: http.MethodGet, Path: "/p/{shortcode}/{index}/", Tag: "streaming",
+		Summary:     "Stream one item of a carousel post",
+		Params:      []apiParam{shortcodeParam, {Name: "index", In: "path", Type: "integer", Description: "1-based position in the carousel"}},
+		ContentType: "video/mp4",
+		Errors:      streamErrors,
+	},
+	{
+		Method: http.MethodGet, Path: "/api/media/{shortcode}", Tag: "metadata",
+		Summary:  "Extract a post without streaming it",
+		Params:   []apiParam{shortcodeParam},
+		Response: mediaResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	},
+	{
+		Method: http.MethodGet, Path: "/resolve", Tag: "metadata",
+		Summary:  "Resolve a post, share or short link to its shortcode and proxy URL",
+		Params:   []apiParam{{Name: "url", In: "query", Type: "string", Description: "Instagram URL, e.g. https://www.instagram.com/share/reel/BAabc123/"}},
+		Response: resolveResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable},
+	},
+	{
+		Method: http.MethodGet, Path: "/fetch", Tag: "streaming",
+		Summary:     "Redirect a pasted Instagram URL to its proxy path",
+		Params:      []apiParam{{Name: "url", In: "query", Type: "string", Description: "Instagram URL, e.g. https://www.instagram.com/reel/ABC123/"}},
+		Status:      http.StatusFound,
+		ContentType: "text/html",
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/batch", Tag: "metadata",
+		Summary:  "Extract up to 50 Instagram URLs",
+		Body:     batchRequest{},
+		Response: batchResponse{},
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		Method: http.MethodPost, Path: "/graphql", Tag: "metadata",
+		Summary:  "Run a GraphQL query for media, user posts or batches",
+		Body:     graphql.Request{},
+		Response: graphql.Response{},
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/user/{username}/posts", Tag: "metadata",
+		Summary: "List a user's recent posts",
+		Params: []apiParam{
+			{Name: "username", In: "path", Type: "string", Description: "Instagram username"},
+			{Name: "count", In: "query", Type: "integer", Description: "Posts per page"},
+			{Name: "cursor", In: "query", Type: "string", Description: "nextCursor of the previous page"},
+		},
+		Response: userPostsResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/export.zip", Tag: "export",
+		Summary:     "Download several videos and their metadata as a ZIP",
+		Body:        exportRequest{},
+		ContentType: "application/zip",
+		Errors:      []int{http.StatusBadRequest},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/jobs", Tag: "jobs",
+		Summary:  "Queue a batch or export job",
+		Body:     jobRequest{},
+		Response: jobs.Job{},
+		Status:   http.StatusAccepted,
+		Errors:   []int{http.StatusBadRequest, http.StatusServiceUnavailable},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/jobs/{id}", Tag: "jobs",
+		Summary:  "Poll a job",
+		Params:   []apiParam{{Name: "id", In: "path", Type: "string", Description: "Job ID"}},
+		Response: jobs.Job{},
+		Errors:   []int{http.StatusNotFound},
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/jobs/{id}", Tag: "jobs",
+		Summary:  "Cancel a job",
+		Params:   []apiParam{{Name: "id", In: "path", Type: "string", Description: "Job ID"}},
+		Response: jobs.Job{},
+		Errors:   []int{http.StatusNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/jobs/{id}/events", Tag: "jobs",
+		Summary:     "Server-Sent Events with the job on every change until it finishes",
+		Params:      []apiParam{{Name: "id", In: "path", Type: "string", Description: "Job ID"}},
+		ContentType: "text/event-stream",
+		Errors:      []int{http.StatusNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/stats", Tag: "service",
+		Summary:  "Counters since startup",
+		Response: statsResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/version", Tag: "service",
+		Summary:  "Build information",
+		Response: versionResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/readyz", Tag: "service",
+		Summary:  "Readiness probe; answers 503 with the same body while degraded",
+		Response: readinessResponse{},
+	},
+}
+
+// handleOpenAPI handles GET /api/openapi.json, describing apiRoutes as OpenAPI 3.0
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use GET")
+		return
+	}
+
+	document := openAPIDocument(apiRoutes, s.versionInfo.Version, requestBaseURL(r))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(document); err != nil {
+		logger.Error("Failed to encode OpenAPI document", "error", err)
+	}
+}
+
+// openAPIDocument builds the OpenAPI document for routes
+func openAPIDocument(routes []apiRoute, version, serverURL string) map[string]any {
+	schemas := newSchemaRegistry()
+	errorRef := schemas.schemaFor(reflect.TypeOf(errorBody{}))
+
+	paths := make(map[string]map[string]any)
+	for _, route := range routes {
+		operation := map[string]any{
+			"summary":     route.Summary,
+			"operationId": operationID(route),
+			"tags":        []string{route.Tag},
+		}
+
+		if len(route.Params) > 0 {
+			params := make([]map[string]any, 0, len(route.Params))
+			for _, param := range route.Params {
+				params = append(params, map[string]any{
+					"name":        param.Name,
+					"in":          param.In,
+					"required":    param.In == "path",
+					"description": param.Description,
+					"schema":      map[string]any{"type": param.Type},
+				})
+			}
+			operation["parameters"] = params
+		}
+
+		if route.Body != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemas.schemaFor(reflect.TypeOf(route.Body))),
+			}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]any{"description": http.StatusText(status)}
+		switch {
+		case route.Response != nil:
+			success["content"] = jsonContent(schemas.schemaFor(reflect.TypeOf(route.Response)))
+		case route.ContentType != "":
+			success["content"] = map[string]any{
+				route.ContentType: map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}},
+			}
+		}
+		responses := map[string]any{strconv.Itoa(status): success}
+		for _, code := range route.Errors {
+			responses[strconv.Itoa(code)] = map[string]any{
+				"description": http.StatusText(code),
+				"content":     jsonContent(errorRef),
+			}
+		}
+		operation["responses"] = responses
+
+		if paths[route.Path] == nil {
+			paths[route.Path] = make(map[string]any)
+		}
+		paths[route.Path][strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title": "Qwiklip",
+			"description": "Instagram video proxy: streams reels and posts and returns their metadata as JSON. " +
+				"Every /api/ path, and /resolve, /graphql and /version, is also served under /api/v1/.",
+			"version": version,
+		},
+		"servers":    []map[string]any{{"url": serverURL}},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas.schemas},
+	}
+}
+
+// operationID derives a stable operation name, e.g. getApiMediaShortcode
+func operationID(route apiRoute) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.Method))
+	for _, word := range strings.FieldsFunc(route.Path, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaRegistry turns Go types into JSON schemas the way encoding/json encodes them.
+// Named structs become components referenced by $ref.
+type schemaRegistry struct {
+	schemas map[string]any
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: make(map[string]any), names: make(map[reflect.Type]string)}
+}
+
+// schemaFor returns the schema of t, registering the structs it refers to
+func (sr *schemaRegistry) schemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]any{} // Any JSON value
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": sr.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": sr.schemaFor(t.Elem())}
+	case reflect.Struct:
+		return sr.structRef(t)
+	}
+	return map[string]any{}
+}
+
+// structRef registers struct t under a component name and returns a reference to it
+func (sr *schemaRegistry) structRef(t reflect.Type) map[string]any {
+	name, ok := sr.names[t]
+	if !ok {
+		name = sr.componentName(t)
+		sr.names[t] = name
+		sr.schemas[name] = map[string]any{} // Placeholder for recursive types
+		properties, required := make(map[string]any), []string{}
+		sr.addFields(t, properties, &required)
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+		sr.schemas[name] = schema
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// addFields adds the JSON fields of struct t, flattening embedded structs as encoding/json does
+func (sr *schemaRegistry) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			sr.addFields(fieldType, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = sr.schemaFor(field.Type)
+		optional := strings.Contains(options, "omitempty") || strings.Contains(options, "omitzero")
+		if !optional && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// componentName names the schema of t after the type, adding the package when two types share a name
+func (sr *schemaRegistry) componentName(t reflect.Type) string {
+	name := t.Name()
+	if name == "" {
+		name = "Object"
+	}
+	name = strings.ToUpper(name[:1]) + name[1:]
+	for _, taken := range sr.names {
+		if taken == name {
+			pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+			return strings.ToUpper(pkg[:1]) + pkg[1:] + name
+		}
+	}
+	return name
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+)
+
+const (
+	maxPlaylistItems       = 50 // Upper bound on shortcodes per playlist request
+	playlistFetchWorkers   = 4  // Concurrent extractions used to resolve titles
+	playlistTitleMaxLength = 80
+)
+
+// playlistEntry is a single item in a generated M3U playlist
+type playlistEntry struct {
+	shortcode string
+	title     string
+}
+
+// handlePlaylist handles requests to /playlist.m3u8?ids=a,b,c
+func (s *Server) handlePlaylist(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	shortcodes, invalid := parseShortcodeList(r.URL.Query().Get("ids"))
+	// Ids end up in #EXTINF titles and entry URLs, so a malformed one rejects the playlist
+	if len(invalid) > 0 {
+		s.handleError(w, r, models.NewInvalidShortcodeError(invalid[0]))
+		return
+	}
+	if len(shortcodes) == 0 {
+		s.handleError(w, r, models.NewInvalidURLError(r.URL.String(), fmt.Errorf("ids parameter is required")))
+		return
+	}
+	if len(shortcodes) > maxPlaylistItems {
+		s.handleError(w, r, models.NewInvalidURLError(r.URL.String(),
+			fmt.Errorf("too many ids (max %d), got %d", maxPlaylistItems, len(shortcodes))))
+		return
+	}
+
+	logger.Info("Building playlist", "items", len(shortcodes))
+	entries := s.resolvePlaylistEntries(r.Context(), shortcodes)
+
+	baseURL := requestBaseURL(r)
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n")
+	for _, entry := range entries {
+		fmt.Fprintf(&playlist, "#EXTINF:-1,%s\n", entry.title)
+		fmt.Fprintf(&playlist, "%s/reel/%s/\n", baseURL, entry.shortcode)
+	}
+
+	w.Header().Set("Content-Type", "audio/x-mpegurl; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="qwiklip.m3u8"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(playlist.String()))
+}
+
+// resolvePlaylistEntries extracts titles for each shortcode with bounded concurrency.
+// Extraction failures fall back to the shortcode as title so the playlist stays complete.
+func (s *Server) resolvePlaylistEntries(ctx context.Context, shortcodes []string) []playlistEntry {
+	entries := make([]playlistEntry, len(shortcodes))
+	sem := make(chan struct{}, playlistFetchWorkers)
+	var wg sync.WaitGroup
+
+	for i, shortcode := range shortcodes {
+		wg.Add(1)
+		go func(i int, shortcode string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			entries[i] = playlistEntry{shortcode: shortcode, title: shortcode}
+			mediaInfo, err := s.fetchMediaInfo(ctx, fmt.Sprintf("https://www.instagram.com/reel/%s/", shortcode))
+			if err != nil {
+				s.logger.Warn("Playlist item extraction failed, using shortcode as title",
+					"shortcode", shortcode, "error", err)
+				return
+			}
+			entries[i].title = playlistTitle(mediaInfo, shortcode)
+		}(i, shortcode)
+	}
+
+	wg.Wait()
+	return entries
+}
+
+// playlistTitle builds a single-line title from the username and caption
+func playlistTitle(mediaInfo *models.InstagramMediaInfo, shortcode string) string {
+	caption := strings.TrimSpace(mediaInfo.Caption)
+	if idx := strings.IndexAny(caption, "\r\n"); idx >= 0 {
+		caption = caption[:idx]
+	}
+	if runes := []rune(caption); len(runes) > playlistTitleMaxLength {
+		caption = string(runes[:playlistTitleMaxLength]) + "..."
+	}
+
+	switch {
+	case mediaInfo.Username != "" && caption != "":
+		return fmt.Sprintf("%s - %s", mediaInfo.Username, caption)
+	case caption != "":
+		return caption
+	case mediaInfo.Username != "":
+		return fmt.Sprintf("%s - %s", mediaInfo.Username, shortcode)
+	default:
+		return shortcode
+	}
+}
+
+// parseShortcodeList splits a comma-separated id list as normalizeShortcodes does
+func parseShortcodeList(ids string) (shortcodes, invalid []string) {
+	return normalizeShortcodes(strings.Split(ids, ","))
+}
+
+// normalizeShortcodes trims a list of shortcodes, dropping blanks and duplicates.
+// Ids that fail models.ValidShortcode are returned apart in invalid, for the caller to
+// reject or report; they must not reach extraction or the response.
+func normalizeShortcodes(ids []string) (shortcodes, invalid []string) {
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		id = strings.Trim(strings.TrimSpace(id), "/")
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		if !models.ValidShortcode(id) {
+			invalid = append(invalid, id)
+			continue
+		}
+		shortcodes = append(shortcodes, id)
+	}
+	return shortcodes, invalid
+}
+
+// requestBaseURL reconstructs the externally visible scheme and host of the request
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
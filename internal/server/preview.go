@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"qwiklip/internal/config"
+	"qwiklip/internal/middleware"
+)
+
+// maxPreviewDescription bounds the caption shown in link previews, in characters
+const maxPreviewDescription = 200
+
+// wantsPreview reports whether the request comes from a link unfurler (LINK_PREVIEW_AGENTS)
+// that should get an Open Graph page instead of the media. ?raw=1 always gets the media,
+// which is how the page's own og:video and og:image links fetch it.
+func (s *Server) wantsPreview(r *http.Request) bool {
+	if !s.templatesEnabled || r.Method != http.MethodGet || r.URL.Query().Has("raw") {
+		return false
+	}
+	userAgent := strings.ToLower(r.UserAgent())
+	for _, agent := range s.config.Server.LinkPreviewAgents {
+		if strings.Contains(userAgent, strings.ToLower(agent)) {
+			return true
+		}
+	}
+	return false
+}
+
+// handlePreview renders an HTML page whose Open Graph tags point at the proxy stream,
+// so links shared in chat apps unfurl into playable embeds
+func (s *Server) handlePreview(w http.ResponseWriter, r *http.Request, instagramURL string) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	mediaInfo, err := s.fetchMediaInfo(r.Context(), instagramURL)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	pageURL := requestBaseURL(r) + r.URL.Path
+	mediaURL := pageURL + "?raw=1"
+
+	data := struct {
+		Title       string
+		Description string
+		PageURL     string
+		VideoURL    string
+		ImageURL    string
+		Width       int
+		Height      int
+		Brand       config.BrandingConfig
+	}{
+		Brand:       s.config.Branding,
+		Title:       "Instagram post",
+		Description: truncateRunes(mediaInfo.Caption, maxPreviewDescription),
+		PageURL:     pageURL,
+		Width:       mediaInfo.Width,
+		Height:      mediaInfo.Height,
+	}
+	if mediaInfo.Username != "" {
+		data.Title = "@" + mediaInfo.Username + " on Instagram"
+	}
+	if mediaInfo.IsImage() {
+		data.ImageURL = mediaURL
+	} else {
+		data.VideoURL = mediaURL
+		data.ImageURL = mediaInfo.ThumbnailURL
+	}
+
+	logger.Info("Serving link preview", "user_agent", r.UserAgent())
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templateSet.Preview.Execute(w, data); err != nil {
+		logger.Error("Failed to execute preview template", "error", err)
+	}
+}
+
+// truncateRunes shortens s to at most n characters, marking the cut with an ellipsis
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
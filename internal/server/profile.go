@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"qwiklip/internal/instagram"
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+)
+
+// userPostsResponse is the JSON body of GET /api/user/{username}/posts
+type userPostsResponse struct {
+	Username   string     `json:"username"`
+	Posts      []userPost `json:"posts"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+	Next       string     `json:"next,omitempty"` // Ready-made URL of the next page
+}
+
+// userPost is a post summary plus the proxy URL that streams it
+type userPost struct {
+	models.PostSummary
+	URL string `json:"url"`
+}
+
+// handleUserPosts handles GET /api/user/{username}/posts?cursor=&count=
+func (s *Server) handleUserPosts(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use GET")
+		return
+	}
+
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/user/"), "/"), "/")
+	if len(segments) != 2 || segments[0] == "" || segments[1] != "posts" {
+		s.sendJSONError(w, http.StatusNotFound, "Expected /api/user/{username}/posts")
+		return
+	}
+	username := segments[0]
+
+	count := instagram.DefaultFeedPageSize
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > instagram.MaxFeedPageSize {
+			s.sendJSONError(w, http.StatusBadRequest, fmt.Sprintf("count must be between 1 and %d", instagram.MaxFeedPageSize))
+			return
+		}
+		count = parsed
+	}
+	cursor := r.URL.Query().Get("cursor")
+
+	response, err := s.userPosts(r.Context(), requestBaseURL(r), username, cursor, count)
+	if err != nil {
+		s.sendErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("Failed to encode user posts", "error", err)
+	}
+}
+
+// userPosts fetches one page of a user's posts with proxy URLs under baseURL
+func (s *Server) userPosts(ctx context.Context, baseURL, username, cursor string, count int) (*userPostsResponse, error) {
+	page, err := s.client.GetUserPosts(ctx, username, cursor, count)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &userPostsResponse{
+		Username:   page.Username,
+		Posts:      make([]userPost, len(page.Posts)),
+		NextCursor: page.NextCursor,
+	}
+	for i, post := range page.Posts {
+		pathType := "p"
+		if post.Type == models.PostTypeVideo {
+			pathType = "reel"
+		}
+		response.Posts[i] = userPost{
+			PostSummary: post,
+			URL:         fmt.Sprintf("%s/%s/%s/", baseURL, pathType, post.Shortcode),
+		}
+	}
+	if page.NextCursor != "" {
+		response.Next = fmt.Sprintf("%s%s/user/%s/posts?count=%d&cursor=%s", baseURL, apiBase(ctx), username, count, url.QueryEscape(page.NextCursor))
+	}
+	return response, nil
+}
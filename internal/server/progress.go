@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"qwiklip/internal/metrics"
+)
+
+const (
+	// progressBytes is how many bytes a stream sends between progress reports
+	progressBytes = 1024 * 1024
+	// progressInterval is the longest gap between progress reports of a slow stream
+	progressInterval = 2 * time.Second
+)
+
+// Stream results reported in the final Progress of a stream
+const (
+	StreamComplete     = "complete"
+	StreamDisconnected = "disconnected"     // A write to the client failed, e.g. it stalled past the write deadline
+	StreamCancelled    = "client_cancelled" // The client went away and the CDN fetch was torn down
+	StreamFailed       = "error"
+)
+
+// Progress is a report on a video stream to a client
+type Progress struct {
+	FileName string
+	Written  int64 // Bytes sent to the client so far
+	Total    int64 // Bytes in the response, -1 when the CDN did not say
+	Elapsed  time.Duration
+	Result   string // Empty while streaming; StreamComplete, StreamDisconnected, StreamCancelled or StreamFailed at the end
+	Err      error  // Why the stream ended early
+}
+
+// Done reports whether this is the last report of the stream
+func (p Progress) Done() bool {
+	return p.Result != ""
+}
+
+// Rate returns the average throughput in bytes per second
+func (p Progress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Written) / p.Elapsed.Seconds()
+}
+
+// Percent returns how much of the response has been sent, or -1 when the size is unknown
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	return float64(p.Written) * 100 / float64(p.Total)
+}
+
+// ProgressObserver is told about the progress of video streams: every progressBytes or
+// progressInterval while data flows, and once when the stream ends. It is called on the
+// streaming goroutine, so it must not block.
+type ProgressObserver interface {
+	StreamProgress(p Progress)
+}
+
+// ProgressFunc adapts a function to a ProgressObserver
+type ProgressFunc func(p Progress)
+
+// StreamProgress implements ProgressObserver
+func (f ProgressFunc) StreamProgress(p Progress) {
+	f(p)
+}
+
+type progressObserversKey struct{}
+
+// WithProgressObserver returns a context whose video streams also report to observer
+func WithProgressObserver(ctx context.Context, observer ProgressObserver) context.Context {
+	existing := progressObserversFromContext(ctx)
+	observers := make([]ProgressObserver, len(existing), len(existing)+1)
+	copy(observers, existing)
+	return context.WithValue(ctx, progressObserversKey{}, append(observers, observer))
+}
+
+func progressObserversFromContext(ctx context.Context) []ProgressObserver {
+	observers, _ := ctx.Value(progressObserversKey{}).([]ProgressObserver)
+	return observers
+}
+
+// logProgress writes stream progress to the application log
+type logProgress struct {
+	logger *slog.Logger
+}
+
+func (o logProgress) StreamProgress(p Progress) {
+	rate := fmt.Sprintf("%.2f", p.Rate()/1024/1024) // MB/s
+	switch p.Result {
+	case "":
+		o.logger.Info("Stream progress",
+			"filename", p.FileName,
+			"streamed_mb", p.Written/(1024*1024),
+			"rate_mbs", rate)
+	case StreamDisconnected:
+		o.logger.Warn("Client disconnected during streaming", "filename", p.FileName, "total_bytes", p.Written, "error", p.Err)
+	case StreamCancelled:
+		o.logger.Info("Client cancelled stream", "filename", p.FileName, "total_bytes", p.Written)
+	case StreamFailed:
+		o.logger.Error("Error streaming video", "filename", p.FileName, "total_bytes", p.Written, "error", p.Err)
+	default:
+		o.logger.Info("Successfully streamed video",
+			"filename", p.FileName,
+			"total_bytes", p.Written,
+			"rate_mbs", rate,
+			"duration", p.Elapsed)
+	}
+}
+
+// recordProgress counts the bytes of finished streams, and the streams clients cancelled
+type recordProgress struct {
+	metrics metrics.Recorder
+}
+
+func (o recordProgress) StreamProgress(p Progress) {
+	if p.Done() {
+		o.metrics.Count(metrics.StreamBytes, p.Written, "result", p.Result)
+	}
+	if p.Result == StreamCancelled {
+		o.metrics.Count(metrics.StreamCancelled, 1, "phase", "body")
+	}
+}
+
+// progressWriter counts streamed bytes, reports them to observers and keeps the first
+// write error, which tells client disconnects apart from upstream read errors
+type progressWriter struct {
+	w         io.Writer
+	observers []ProgressObserver
+	progress  Progress
+	start     time.Time
+	reported  time.Time // When observers last heard about the stream
+	lastBytes int64     // Progress.Written at that time
+	err       error
+}
+
+func newProgressWriter(w io.Writer, observers []ProgressObserver, fileName string, total int64) *progressWriter {
+	now := time.Now()
+	return &progressWriter{
+		w:         w,
+		observers: observers,
+		progress:  Progress{FileName: fileName, Total: total},
+		start:     now,
+		reported:  now,
+	}
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	if err != nil && pw.err == nil {
+		pw.err = err
+	}
+
+	pw.progress.Written += int64(n)
+	if n > 0 && (pw.progress.Written-pw.lastBytes >= progressBytes || time.Since(pw.reported) >= progressInterval) {
+		pw.report()
+	}
+	return n, err
+}
+
+// finish sends the final report with the stream's result
+func (pw *progressWriter) finish(result string, err error) {
+	pw.progress.Result, pw.progress.Err = result, err
+	pw.report()
+}
+
+func (pw *progressWriter) report() {
+	now := time.Now()
+	pw.progress.Elapsed = now.Sub(pw.start)
+	pw.reported, pw.lastBytes = now, pw.progress.Written
+	for _, observer := range pw.observers {
+		observer.StreamProgress(pw.progress)
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// errMultipleRanges is returned for Range headers that still ask for several parts after
+// overlapping ones are merged; the CDN has no multipart/byteranges support to proxy
+var errMultipleRanges = errors.New("multiple byte ranges are not supported")
+
+// byteRange is one range-spec of a Range header: bytes=start-end, bytes=start- (end -1)
+// or the suffix form bytes=-length (start -1, end holding the length)
+type byteRange struct {
+	start, end int64
+}
+
+func (br byteRange) String() string {
+	switch {
+	case br.start < 0:
+		return fmt.Sprintf("-%d", br.end)
+	case br.end < 0:
+		return fmt.Sprintf("%d-", br.start)
+	default:
+		return fmt.Sprintf("%d-%d", br.start, br.end)
+	}
+}
+
+// resolve returns the first and last byte offsets of the range in a file of size bytes.
+// ok is false when the range lies entirely past the end of the file.
+func (br byteRange) resolve(size int64) (start, end int64, ok bool) {
+	switch {
+	case br.start < 0:
+		if size == 0 {
+			return 0, 0, false
+		}
+		if br.end >= size {
+			return 0, size - 1, true
+		}
+		return size - br.end, size - 1, true
+	case br.start >= size:
+		return 0, 0, false
+	case br.end < 0:
+		return br.start, size - 1, true
+	case br.end >= size:
+		return br.start, size - 1, true
+	default:
+		return br.start, br.end, true
+	}
+}
+
+// parseRange parses a Range header into a single range to forward to the CDN.
+// ok is false when the header should be ignored (absent, not bytes, or malformed), in
+// which case the whole file is served as RFC 9110 allows. Overlapping and adjacent
+// ranges are merged; ranges that stay disjoint fail with errMultipleRanges.
+func parseRange(header string) (br byteRange, ok bool, err error) {
+	specs, found := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !found {
+		return byteRange{}, false, nil
+	}
+
+	var ranges []byteRange
+	for _, spec := range strings.Split(specs, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue // Empty list elements are allowed
+		}
+		r, valid := parseRangeSpec(spec)
+		if !valid {
+			return byteRange{}, false, nil
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		return byteRange{}, false, nil
+	}
+
+	merged, err := mergeRanges(ranges)
+	if err != nil {
+		return byteRange{}, false, err
+	}
+	return merged, true, nil
+}
+
+// parseRangeSpec parses "first-last", "first-" or "-suffix"
+func parseRangeSpec(spec string) (byteRange, bool) {
+	first, last, found := strings.Cut(spec, "-")
+	if !found {
+		return byteRange{}, false
+	}
+	first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+	if first == "" {
+		length, err := parseRangeNumber(last)
+		if err != nil || length == 0 {
+			return byteRange{}, false
+		}
+		return byteRange{start: -1, end: length}, true
+	}
+
+	start, err := parseRangeNumber(first)
+	if err != nil {
+		return byteRange{}, false
+	}
+	if last == "" {
+		return byteRange{start: start, end: -1}, true
+	}
+	end, err := parseRangeNumber(last)
+	if err != nil || end < start {
+		return byteRange{}, false
+	}
+	return byteRange{start: start, end: end}, true
+}
+
+// parseRangeNumber parses the digits of a range bound; signs and spaces are not allowed
+func parseRangeNumber(s string) (int64, error) {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return 0, fmt.Errorf("invalid range bound %q", s)
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// mergeRanges collapses ranges into one when they overlap or touch. Without the file
+// size suffix ranges can only be merged with each other (the longest wins), and an open
+// range absorbs every range starting after it.
+func mergeRanges(ranges []byteRange) (byteRange, error) {
+	if len(ranges) == 1 {
+		return ranges[0], nil
+	}
+
+	var suffix, offsets []byteRange
+	for _, r := range ranges {
+		if r.start < 0 {
+			suffix = append(suffix, r)
+		} else {
+			offsets = append(offsets, r)
+		}
+	}
+	if len(suffix) > 0 && len(offsets) > 0 {
+		return byteRange{}, errMultipleRanges
+	}
+	if len(suffix) > 0 {
+		longest := slices.MaxFunc(suffix, func(a, b byteRange) int { return cmp.Compare(a.end, b.end) })
+		return longest, nil
+	}
+
+	slices.SortFunc(offsets, func(a, b byteRange) int { return cmp.Compare(a.start, b.start) })
+	merged := offsets[0]
+	for _, r := range offsets[1:] {
+		if merged.end >= 0 && r.start > merged.end+1 {
+			return byteRange{}, errMultipleRanges
+		}
+		if merged.end >= 0 && (r.end < 0 || r.end > merged.end) {
+			merged.end = r.end
+		}
+	}
+	return merged, nil
+}
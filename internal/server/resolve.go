@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"qwiklip/internal/middleware"
+)
+
+// maxFetchFormBytes bounds the form body of POST /fetch
+const maxFetchFormBytes = 8 * 1024
+
+// resolveResponse is the JSON body of GET /resolve
+type resolveResponse struct {
+	URL          string `json:"url"` // The URL as given
+	Shortcode    string `json:"shortcode"`
+	InstagramURL string `json:"instagramUrl"` // Canonical post URL, without tracking parameters
+	ProxyURL     string `json:"proxyUrl"`     // URL that streams the post through this server
+}
+
+// handleResolve handles GET /resolve?url=...: it turns any Instagram post URL, including
+// share links and instagr.am short links, into its shortcode and proxy URL without
+// extracting the post. Share links are resolved by following their redirects on Instagram.
+func (s *Server) handleResolve(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use GET")
+		return
+	}
+
+	rawURL := r.URL.Query().Get("url")
+	if rawURL == "" {
+		s.sendJSONError(w, http.StatusBadRequest, "Expected /resolve?url={instagram url}")
+		return
+	}
+
+	canonical, err := s.client.ResolveURL(r.Context(), rawURL)
+	if err != nil {
+		s.sendErrorResponse(w, err)
+		return
+	}
+	shortcode, err := s.client.ExtractShortcode(canonical)
+	if err != nil {
+		s.sendErrorResponse(w, err)
+		return
+	}
+
+	response := resolveResponse{
+		URL:          rawURL,
+		Shortcode:    shortcode,
+		InstagramURL: canonical,
+		ProxyURL:     requestBaseURL(r) + proxyPath(canonical),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("Failed to encode resolved URL", "error", err)
+	}
+}
+
+// handleFetch handles GET /fetch?url=... and the URL form of the index page (POST): it
+// resolves a pasted Instagram URL and redirects to the matching proxy path, so users
+// do not have to edit the hostname by hand. A carousel's ?img_index= selects the item.
+func (s *Server) handleFetch(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use GET or POST")
+		return
+	}
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxFetchFormBytes)
+	}
+
+	rawURL := strings.TrimSpace(r.FormValue("url"))
+	if rawURL == "" {
+		if s.shouldReturnJSON(r) {
+			s.sendJSONError(w, http.StatusBadRequest, "Expected /fetch?url={instagram url}")
+			return
+		}
+		s.renderError(w, http.StatusBadRequest, "Missing URL",
+			"Paste the URL of an Instagram post or reel", []string{
+				"Copy the link from Instagram's share menu",
+				"Use the format https://www.instagram.com/reel/{shortcode}/",
+			})
+		return
+	}
+
+	canonical, err := s.client.ResolveURL(r.Context(), rawURL)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	target := proxyPath(canonical)
+	if parsed, err := url.Parse(rawURL); err == nil && strings.HasPrefix(target, "/p/") {
+		if index, err := strconv.Atoi(parsed.Query().Get("img_index")); err == nil && index > 0 {
+			target += strconv.Itoa(index) + "/"
+		}
+	}
+	logger.Info("Redirecting pasted URL to proxy path", "url", rawURL, "target", target)
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// proxyPath returns the path on this server that mirrors a canonical Instagram post URL.
+// IGTV videos (/tv/) are served under /reel/.
+func proxyPath(canonical string) string {
+	parsed, err := url.Parse(canonical)
+	if err != nil {
+		return "/"
+	}
+	if rest, ok := strings.CutPrefix(parsed.Path, "/tv/"); ok {
+		return "/reel/" + rest
+	}
+	return parsed.Path
+}
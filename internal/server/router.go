@@ -28,10 +28,92 @@ func (r *Router) SetupRoutes() http.Handler {
 	// Health check endpoint - Minimal middleware for performance
 	r.mux.HandleFunc("/health", r.server.withMinimalMiddleware(r.server.handleHealthCheck))
 
+	// Kubernetes probes - liveness never checks dependencies, readiness reports degradation
+	r.mux.HandleFunc("/livez", r.server.withMinimalMiddleware(r.server.handleLivez))
+	r.mux.HandleFunc("/readyz", r.server.withMinimalMiddleware(r.server.handleReadyz))
+
 	// Instagram reel endpoint - Full middleware stack
 	// Can also be written as: r.server.applyMiddleware(r.server.handleReel, ApplyMiddlewareOptions(middleware.WithRecovery(), middleware.WithLogging(), middleware.WithCORS()))
 	r.mux.HandleFunc("/reel/", r.server.applyMiddleware(r.server.handleReel, middleware.DefaultConfig()))
 
+	// Instagram post endpoint - /p/{shortcode}/ or /p/{shortcode}/{index} for carousel items
+	r.mux.HandleFunc("/p/", r.server.applyMiddleware(r.server.handlePost, middleware.DefaultConfig()))
+
+	// Instagram story endpoint - /stories/{username}/{id}, needs a logged-in session
+	r.mux.HandleFunc("/stories/", r.server.applyMiddleware(r.server.handleStory, middleware.DefaultConfig()))
+
+	// Instagram highlight endpoint - /highlights/{id} lists items, /highlights/{id}/{index} streams one
+	r.mux.HandleFunc("/highlights/", r.server.applyMiddleware(r.server.handleHighlight, middleware.DefaultConfig()))
+
+	// Embed player endpoint - iframe-friendly HTML5 player page for /embed/{shortcode}
+	r.mux.HandleFunc("/embed/", r.server.withStandardMiddleware(r.server.handleEmbed))
+
+	// Watch page endpoint - player with author, caption, date and download button
+	r.mux.HandleFunc("/watch/", r.server.withStandardMiddleware(r.server.handleWatch))
+
+	// JSON API routes use withAPIMiddleware, which requires a bearer token when authentication
+	// is configured. HTML pages, media streams, feeds and playlists stay open, since browsers
+	// and players cannot attach the token to <video>, <iframe> or feed requests.
+
+	// Media metadata endpoint - extraction result as JSON, without streaming
+	r.mux.HandleFunc("/api/media/", r.server.withAPIMiddleware(r.server.handleMedia))
+
+	// URL resolution endpoint - shortcode and proxy URL of share links and short links
+	r.mux.HandleFunc("/resolve", r.server.withAPIMiddleware(r.server.handleResolve))
+
+	// Fetch endpoint - redirects a pasted Instagram URL to its proxy path
+	r.mux.HandleFunc("/fetch", r.server.withStandardMiddleware(r.server.handleFetch))
+
+	// Batch extraction endpoint - JSON results for several Instagram URLs
+	r.mux.HandleFunc("/api/batch", r.server.withAPIMiddleware(r.server.handleBatch))
+
+	// GraphQL endpoint - media, user and batch queries over the same extraction as the JSON API
+	r.mux.HandleFunc("/graphql", r.server.withAPIMiddleware(r.server.handleGraphQL))
+
+	// WebSocket endpoint - extraction and stream progress events for interactive clients
+	r.mux.HandleFunc("/ws", r.server.withAPIMiddleware(r.server.handleWebSocket))
+
+	// OpenAPI document - describes the JSON API for clients and gateways
+	r.mux.HandleFunc("/api/openapi.json", r.server.withAPIMiddleware(r.server.handleOpenAPI))
+
+	// Version endpoint - build information and start time of this instance
+	r.mux.HandleFunc("/version", r.server.withAPIMiddleware(r.server.handleVersion))
+
+	// Statistics endpoint - JSON counters since startup
+	r.mux.HandleFunc("/api/stats", r.server.withAPIMiddleware(r.server.handleStats))
+
+	// Background job endpoints - queue batch/export jobs and poll their status
+	if r.server.jobs != nil {
+		r.mux.HandleFunc("/api/jobs", r.server.withAPIMiddleware(r.server.handleJobs))
+		r.mux.HandleFunc("/api/jobs/", r.server.withAPIMiddleware(r.server.handleJob))
+	}
+
+	// Profile feed endpoint - paginated JSON list of a user's recent posts
+	r.mux.HandleFunc("/api/user/", r.server.withAPIMiddleware(r.server.handleUserPosts))
+
+	// RSS feed endpoint - /feed/{username}.xml lists a user's recent reels
+	r.mux.HandleFunc("/feed/", r.server.withStandardMiddleware(r.server.handleFeed))
+
+	// Playlist endpoint - M3U of proxy stream URLs for several shortcodes
+	r.mux.HandleFunc("/playlist.m3u8", r.server.withStandardMiddleware(r.server.handlePlaylist))
+
+	// Bulk export endpoint - ZIP of videos plus metadata
+	r.mux.HandleFunc("/api/export.zip", r.server.withAPIMiddleware(r.server.handleExport))
+
+	// Debug endpoints - pprof and expvar on the main port unless DEBUG_ADDR moves them to their own listener.
+	// They sit behind bearer auth when it is configured.
+	if r.server.config.Server.DebugEndpoints && r.server.config.Server.DebugAddr == "" {
+		r.server.registerDebugRoutes(r.mux, r.server.withDebugMiddleware)
+	}
+
+	// Admin API - cache and runtime management on the main port unless ADMIN_ADDR moves it to its own listener
+	if r.server.config.Admin.Token != "" && r.server.config.Admin.Addr == "" {
+		r.server.registerAdminRoutes(r.mux)
+	}
+
+	// Versioned JSON API - /api/v1/... serves the routes above, which stay as aliases of v1
+	r.mux.HandleFunc(apiV1Prefix+"/", serveAPIv1(r.mux))
+
 	// Catch-all route for 404 handling
 	r.mux.HandleFunc("/", r.server.withStandardMiddleware(r.server.handleNotFound))
 
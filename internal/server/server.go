@@ -3,13 +3,26 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"qwiklip/internal/accesslog"
+	"qwiklip/internal/acme"
 	"qwiklip/internal/config"
+	"qwiklip/internal/errorreport"
 	"qwiklip/internal/instagram"
+	"qwiklip/internal/jobs"
+	"qwiklip/internal/metrics"
 	"qwiklip/internal/middleware"
+	"qwiklip/internal/stats"
+	"qwiklip/internal/tracing"
+	"qwiklip/internal/transcode"
+	"qwiklip/internal/videocache"
+	"qwiklip/internal/webhook"
 	"qwiklip/web/templates"
 )
 
@@ -32,14 +45,35 @@ type Server struct {
 	config           *config.Config
 	client           *instagram.Client
 	logger           *slog.Logger
+	level            *slog.LevelVar // Log level, adjustable through the admin API
+	metrics          metrics.Recorder
+	stats            *stats.Collector // In-process aggregates for the admin dashboard and /api/stats
+	errors           errorreport.Reporter
+	videoCache       videocache.Store // Cache of streamed videos (nil when disabled)
 	httpServer       *http.Server
-	templateSet      *templates.TemplateSet // Parsed HTML templates (optional)
-	templatesEnabled bool                   // Whether templates are available for use
-	versionInfo      *VersionInfo           // Version information for templates
+	debugServer      *http.Server            // Separate pprof/expvar listener (nil unless DEBUG_ADDR is set)
+	adminServer      *http.Server            // Separate admin API listener (nil unless ADMIN_ADDR is set)
+	templateSet      *templates.TemplateSet  // Parsed HTML templates (optional)
+	templatesEnabled bool                    // Whether templates are available for use
+	versionInfo      *VersionInfo            // Version information for templates and /version
+	started          time.Time               // Reported by /version
+	auth             *middleware.JWTVerifier // Bearer token verifier (nil when auth is disabled)
+	tracer           *tracing.Tracer         // OTLP span exporter (nil when tracing is disabled)
+	jobs             *jobs.Manager           // Background job runner (nil when JOBS_WORKERS=0)
+	ffmpeg           *transcode.FFmpeg       // Audio extraction (nil when ffmpeg is not installed)
+	hls              *hlsPackager            // HLS repackaging (nil when ffmpeg is not installed)
+	throttle         *middleware.Throttle    // Response bandwidth limits
+	streams          *streamLimiter          // Tracks in-flight streams, capped by STREAM_MAX_CONCURRENT
+	certs            *acme.Manager           // Automatic HTTPS certificates (nil unless ACME_DOMAINS is set)
+	challengeServer  *http.Server            // HTTP-01 challenges and HTTPS redirects (nil without ACME)
+	accessLog        *accesslog.Logger       // JSON request log file (nil unless ACCESS_LOG_FILE is set)
+	reloadError      atomic.Pointer[string]  // Why the last SIGHUP reload failed (nil after a good one)
+	webSockets       *wsSessions             // Open /ws connections and their progress tokens
+	closing          chan struct{}           // Closed on shutdown to end event streams, which would hold up draining
 }
 
 // New creates a new server instance
-func New(cfg *config.Config, client *instagram.Client, logger *slog.Logger, versionInfo *VersionInfo) (*Server, error) {
+func New(cfg *config.Config, client *instagram.Client, logger *slog.Logger, level *slog.LevelVar, recorder metrics.Recorder, collector *stats.Collector, reporter errorreport.Reporter, notifier webhook.Notifier, versionInfo *VersionInfo) (*Server, error) {
 	if cfg == nil {
 		return nil, errors.New("config cannot be nil")
 	}
@@ -52,6 +86,21 @@ func New(cfg *config.Config, client *instagram.Client, logger *slog.Logger, vers
 	if logger == nil {
 		return nil, errors.New("logger cannot be nil")
 	}
+	if level == nil {
+		return nil, errors.New("log level cannot be nil")
+	}
+	if recorder == nil {
+		return nil, errors.New("metrics recorder cannot be nil")
+	}
+	if collector == nil {
+		return nil, errors.New("stats collector cannot be nil")
+	}
+	if reporter == nil {
+		return nil, errors.New("error reporter cannot be nil")
+	}
+	if notifier == nil {
+		return nil, errors.New("webhook notifier cannot be nil")
+	}
 	if versionInfo == nil {
 		return nil, errors.New("version info cannot be nil")
 	}
@@ -60,7 +109,76 @@ func New(cfg *config.Config, client *instagram.Client, logger *slog.Logger, vers
 		config:      cfg,
 		client:      client,
 		logger:      logger,
+		level:       level,
+		metrics:     recorder,
+		stats:       collector,
+		errors:      reporter,
 		versionInfo: versionInfo,
+		started:     time.Now().UTC().Truncate(time.Second),
+		webSockets:  newWSSessions(),
+		closing:     make(chan struct{}),
+	}
+
+	// Open the video cache (disabled unless a directory is configured)
+	videoCache, err := videocache.New(&cfg.VideoCache, recorder, notifier, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize video cache: %w", err)
+	}
+	s.videoCache = videoCache
+
+	// Start the background job workers
+	s.jobs, err = jobs.New(&cfg.Jobs, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize jobs: %w", err)
+	}
+
+	// Audio extraction needs ffmpeg; the rest of the server works without it
+	detectCtx, cancelDetect := context.WithTimeout(context.Background(), 5*time.Second)
+	s.ffmpeg = transcode.Detect(detectCtx, cfg.Stream.FFmpegPath, logger)
+	cancelDetect()
+	if s.ffmpeg != nil {
+		s.hls = newHLSPackager(&cfg.Stream, logger)
+	}
+
+	// Export request traces when a collector is configured
+	s.tracer = tracing.New(&cfg.Tracing, logger)
+	if s.tracer != nil {
+		s.logger.Info("Tracing enabled", "endpoint", cfg.Tracing.Endpoint, "sample_ratio", cfg.Tracing.SampleRatio)
+	}
+
+	// Require bearer tokens when an identity provider is configured
+	if cfg.Auth.JWKSURL != "" {
+		s.auth = middleware.NewJWTVerifier(&cfg.Auth, logger)
+		s.logger.Info("JWT authentication enabled", "issuer", cfg.Auth.Issuer, "audience", cfg.Auth.Audience)
+	}
+
+	// Write an access log file next to the application log when one is configured
+	s.accessLog, err = accesslog.New(&cfg.Logging, logger)
+	if err != nil {
+		return nil, err
+	}
+	if s.accessLog != nil {
+		s.logger.Info("Access log enabled", "path", cfg.Logging.AccessLogFile)
+	}
+
+	// Track video streams for the concurrency cap and shutdown draining
+	s.streams = newStreamLimiter(cfg.Stream.MaxStreams, cfg.Stream.BusyRetryAfter, recorder)
+
+	// Pace response bodies when bandwidth limits are configured; a reload can set them later
+	s.throttle = middleware.NewThrottle(&cfg.Stream)
+	if s.throttle.Enabled() {
+		s.logger.Info("Bandwidth limits enabled",
+			"max_bytes_per_sec", cfg.Stream.MaxBandwidth,
+			"max_conn_bytes_per_sec", cfg.Stream.MaxConnBandwidth)
+	}
+
+	// Serve HTTPS with certificates from an ACME CA when domains are configured
+	if len(cfg.ACME.Domains) > 0 {
+		s.certs, err = acme.New(&cfg.ACME, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize ACME: %w", err)
+		}
+		s.logger.Info("Automatic HTTPS enabled", "domains", cfg.ACME.Domains, "directory", cfg.ACME.DirectoryURL)
 	}
 
 	// Load templates (optional - server can run in API-only mode)
@@ -82,19 +200,44 @@ func (s *Server) Start(ctx context.Context) error {
 	router := NewRouter(s)
 	handler := router.SetupRoutes()
 
+	// Listen up front so that a taken port or socket fails startup
+	listener, err := s.listen()
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
 	// Create HTTP server
 	s.httpServer = &http.Server{
-		Addr:         ":" + s.config.Server.Port,
 		Handler:      handler,
 		ReadTimeout:  s.config.Server.ReadTimeout,
 		WriteTimeout: s.config.Server.WriteTimeout,
 		IdleTimeout:  s.config.Server.IdleTimeout,
+		ConnContext:  s.throttle.ConnContext,
+	}
+	s.httpServer.RegisterOnShutdown(s.webSockets.closeAll)
+	s.httpServer.RegisterOnShutdown(sync.OnceFunc(func() { close(s.closing) }))
+
+	if s.config.Server.DebugEndpoints && s.config.Server.DebugAddr != "" {
+		s.startDebugServer()
+	}
+	if s.config.Admin.Token != "" && s.config.Admin.Addr != "" {
+		s.startAdminServer()
+	}
+	if s.certs != nil {
+		s.httpServer.TLSConfig = s.certs.TLSConfig()
+		s.startChallengeServer(ctx)
 	}
 
 	// Start server in background
 	go func() {
-		s.logger.Info("Server starting", "addr", s.httpServer.Addr)
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		s.logger.Info("Server starting", "network", listener.Addr().Network(), "addr", listener.Addr().String(), "tls", s.certs != nil)
+		var err error
+		if s.certs != nil {
+			err = s.httpServer.ServeTLS(listener, "", "") // Certificates come from TLSConfig
+		} else {
+			err = s.httpServer.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			s.logger.Error("Server failed to start", "error", err)
 		}
 	}()
@@ -120,47 +263,172 @@ func (s *Server) withStandardMiddleware(handler http.HandlerFunc) http.HandlerFu
 	return s.applyMiddleware(handler, middleware.DefaultConfig())
 }
 
+func (s *Server) withAPIMiddleware(handler http.HandlerFunc) http.HandlerFunc {
+	return s.applyMiddleware(handler, middleware.APIConfig())
+}
+
+func (s *Server) withDebugMiddleware(handler http.HandlerFunc) http.HandlerFunc {
+	return s.applyMiddleware(handler, ApplyMiddlewareOptions(middleware.WithRecovery(), middleware.WithAuth()))
+}
+
 // applyMiddleware applies middleware configuration to a handler
 func (s *Server) applyMiddleware(handler http.HandlerFunc, config *MiddlewareConfig) http.HandlerFunc {
 	result := handler
 
+	// Throttling wraps the handler directly, so the writers of outer middleware see paced writes
+	if config.EnableThrottle {
+		result = middleware.ThrottleMiddleware(s.throttle)(result)
+	}
+
 	// Apply middleware in correct order (outermost to innermost)
 	if config.EnableRecovery {
-		result = middleware.RecoveryMiddleware(s.logger)(result)
+		result = middleware.RecoveryMiddleware(s.logger, s.errors)(result)
+	}
+	if config.EnableAuth && s.auth != nil {
+		result = middleware.JWTAuthMiddleware(s.auth, s.logger)(result)
 	}
 	if config.EnableLogging {
 		result = middleware.LoggingMiddleware(s.logger)(result)
+		if s.accessLog != nil {
+			result = middleware.AccessLogMiddleware(s.accessLog)(result)
+		}
 	}
 	if config.EnableCORS {
-		result = middleware.CORSMiddleware(result)
+		result = middleware.CORSMiddleware(s.config.Server.CORSAllowedOrigins)(result)
+	}
+	if config.EnableMetrics {
+		result = middleware.MetricsMiddleware(s.metrics)(result)
 	}
+	if config.EnableTracing && s.tracer != nil {
+		result = middleware.TracingMiddleware(s.tracer)(result)
+	}
+
+	// Security headers and Alt-Svc are a deployment-wide policy rather than a per-route option
+	if s.config.Server.SecurityHeaders {
+		result = middleware.SecurityHeadersMiddleware(result)
+	}
+	if s.config.Server.AltSvc != "" {
+		result = middleware.AltSvcMiddleware(s.config.Server.AltSvc)(result)
+	}
+
+	// Outermost, so every other middleware and the handler log with the request ID
+	result = middleware.RequestIDMiddleware(s.logger)(result)
 
 	return result
 }
 
+// ReloadFailed records a configuration that failed to load or validate on reload; /readyz
+// reports it until a later reload succeeds
+func (s *Server) ReloadFailed(err error) {
+	message := err.Error()
+	s.reloadError.Store(&message)
+}
+
 // ApplyMiddlewareOptions applies functional options to create middleware configuration
 func ApplyMiddlewareOptions(opts ...MiddlewareOption) *MiddlewareConfig {
 	return middleware.ApplyOptions(opts...)
 }
 
+// Reload applies the stream limits from cfg to the running server. Streams in flight are
+// not interrupted: they keep their slot and move to the new bandwidth limits on their next write.
+func (s *Server) Reload(cfg *config.Config) {
+	s.reloadError.Store(nil)
+	s.throttle.SetLimits(cfg.Stream.MaxBandwidth, cfg.Stream.MaxConnBandwidth)
+	s.streams.setLimit(cfg.Stream.MaxStreams, cfg.Stream.BusyRetryAfter)
+	s.logger.Info("Stream limits reloaded",
+		"max_streams", cfg.Stream.MaxStreams,
+		"max_bytes_per_sec", cfg.Stream.MaxBandwidth,
+		"max_conn_bytes_per_sec", cfg.Stream.MaxConnBandwidth)
+}
+
+// startChallengeServer answers ACME HTTP-01 challenges on ACME_HTTP_ADDR, redirects plain
+// HTTP to HTTPS, and keeps the certificate renewed until ctx is cancelled
+func (s *Server) startChallengeServer(ctx context.Context) {
+	s.challengeServer = &http.Server{
+		Addr:              s.config.ACME.HTTPAddr,
+		Handler:           s.certs.HTTPHandler(s.config.Server.ListenPort()),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		s.logger.Info("ACME challenge server starting", "addr", s.challengeServer.Addr)
+		if err := s.challengeServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("ACME challenge server failed to start", "error", err)
+		}
+	}()
+	go s.certs.Run(ctx)
+}
+
 // Stop gracefully shuts down the server
 func (s *Server) Stop(ctx context.Context) error {
 	if s.httpServer == nil {
 		return nil
 	}
-	return s.httpServer.Shutdown(ctx)
+	s.streams.drain()
+	if s.challengeServer != nil {
+		s.challengeServer.Close()
+	}
+	if s.adminServer != nil {
+		s.adminServer.Close()
+	}
+	err := s.httpServer.Shutdown(ctx)
+	s.accessLog.Close()
+	return err
 }
 
-// gracefulShutdown performs graceful server shutdown
+// cleanupTimeout bounds the work after the HTTP server has stopped (jobs, trace export)
+const cleanupTimeout = 10 * time.Second
+
+// gracefulShutdown stops accepting connections and new streams, waits up to DrainTimeout
+// for in-flight requests to finish and then closes any stream still running
 func (s *Server) gracefulShutdown() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	active := s.streams.drain()
+	s.logger.Info("Draining in-flight streams", "active_streams", active, "timeout", s.config.Server.DrainTimeout)
+
+	if s.debugServer != nil {
+		// Long-running profiles would hold up Shutdown, so the debug listener is closed outright
+		s.debugServer.Close()
+	}
+	if s.challengeServer != nil {
+		s.challengeServer.Close()
+	}
+	if s.adminServer != nil {
+		s.adminServer.Close()
+	}
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), s.config.Server.DrainTimeout)
+	defer cancelDrain()
+
+	shutdownErr := s.httpServer.Shutdown(drainCtx)
+	if shutdownErr != nil {
+		s.logger.Warn("Drain timeout reached, closing remaining streams",
+			"active_streams", s.streams.inFlight(),
+			"error", shutdownErr)
+		s.httpServer.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
 	defer cancel()
 
-	if err := s.httpServer.Shutdown(ctx); err != nil {
-		s.logger.Error("Server forced to shutdown", "error", err)
-		return err
+	// Stop background jobs; unfinished ones are recorded as interrupted
+	if s.jobs != nil {
+		if err := s.jobs.Close(ctx); err != nil {
+			s.logger.Warn("Background jobs did not stop in time", "error", err)
+		}
 	}
 
+	// Flush the last spans once in-flight requests have finished
+	if err := s.tracer.Shutdown(ctx); err != nil {
+		s.logger.Warn("Failed to flush traces", "error", err)
+	}
+
+	if err := s.accessLog.Close(); err != nil {
+		s.logger.Warn("Failed to close access log", "error", err)
+	}
+
+	if shutdownErr != nil {
+		return fmt.Errorf("server forced to shut down: %w", shutdownErr)
+	}
 	s.logger.Info("Server exited gracefully")
 	return nil
 }
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"qwiklip/internal/middleware"
+)
+
+// statsResponse is the JSON body of GET /api/stats
+type statsResponse struct {
+	Since            time.Time        `json:"since"`
+	UptimeSeconds    int64            `json:"uptimeSeconds"`
+	Requests         statsRequests    `json:"requests"`
+	BytesStreamed    int64            `json:"bytesStreamed"`
+	UniqueShortcodes int              `json:"uniqueShortcodes"`
+	Failures         map[string]int64 `json:"failures"` // Error responses by error type
+}
+
+// statsRequests counts the HTTP requests answered since startup
+type statsRequests struct {
+	Total  int64 `json:"total"`
+	Failed int64 `json:"failed"` // 5xx responses
+}
+
+// handleStats handles GET /api/stats: counters since startup, for monitoring that
+// does not warrant a metrics stack
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use GET")
+		return
+	}
+
+	snapshot := s.stats.Snapshot()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(statsResponse{
+		Since:         snapshot.Started.UTC().Truncate(time.Second),
+		UptimeSeconds: int64(snapshot.Uptime.Seconds()),
+		Requests: statsRequests{
+			Total:  snapshot.Totals.Requests,
+			Failed: snapshot.Totals.FailedRequests,
+		},
+		BytesStreamed:    snapshot.Totals.BytesStreamed,
+		UniqueShortcodes: snapshot.Totals.UniqueShortcodes,
+		Failures:         snapshot.Totals.FailuresByType,
+	}); err != nil {
+		logger.Error("Failed to encode stats response", "error", err)
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+)
+
+// handleStory handles requests to /stories/{username}/{id}.
+// Story CDN URLs stop working once the story expires, so expired items are refused with 410.
+func (s *Server) handleStory(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/stories/"), "/"), "/")
+	if len(segments) != 2 || segments[0] == "" || segments[1] == "" {
+		s.handleError(w, r, models.NewInvalidURLError(r.URL.Path, fmt.Errorf("expected /stories/{username}/{id}")))
+		return
+	}
+	username, storyID := segments[0], segments[1]
+
+	logger.Info("Processing Instagram story", "username", username, "story_id", storyID)
+
+	mediaInfo, err := s.client.GetStory(r.Context(), username, storyID)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	if mediaInfo.Expired() {
+		s.handleError(w, r, models.NewExpiredError(fmt.Sprintf("story '%s'", storyID)))
+		return
+	}
+
+	s.logMediaMetadata(mediaInfo)
+
+	// Stories are short-lived, so they bypass the video cache
+	if !mediaInfo.ExpiresAt.IsZero() {
+		w.Header().Set("Expires", mediaInfo.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	mediaURL := mediaInfo.MediaURL()
+	if !mediaInfo.IsImage() {
+		if mediaURL, err = s.selectVideoURL(r, mediaInfo); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+	}
+	s.streamVideo(w, r, mediaURL, mediaInfo.FileName, "")
+}
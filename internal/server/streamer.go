@@ -1,37 +1,76 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"qwiklip/internal/config"
 	"qwiklip/internal/instagram"
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/models"
+	"qwiklip/internal/tracing"
+	"qwiklip/internal/videocache"
 )
 
+// conditionalHeaders are forwarded to the CDN so it can answer 304 or ignore a stale range
+var conditionalHeaders = []string{"If-None-Match", "If-Modified-Since", "If-Range"}
+
+// validatorHeaders are copied from CDN responses so clients and caches can revalidate
+var validatorHeaders = []string{"ETag", "Last-Modified"}
+
 // VideoStreamer handles video streaming from Instagram to clients
 type VideoStreamer struct {
-	userAgent string
+	config    *config.StreamConfig
+	metrics   metrics.Recorder
 	logger    *slog.Logger
 	client    *instagram.Client
+	cache     videocache.Store // Optional video cache fed while streaming
+	observers []ProgressObserver
+}
+
+// prefetchResult holds the first chunk of the upstream body read ahead of streaming
+type prefetchResult struct {
+	data []byte
+	err  error
 }
 
-// NewVideoStreamer creates a new video streamer
-func NewVideoStreamer(client *instagram.Client, userAgent string, logger *slog.Logger) *VideoStreamer {
+// NewVideoStreamer creates a new video streamer. CDN requests use the client's user agent.
+func NewVideoStreamer(client *instagram.Client, cfg *config.StreamConfig, cache videocache.Store, recorder metrics.Recorder, logger *slog.Logger) *VideoStreamer {
 	return &VideoStreamer{
-		userAgent: userAgent,
+		config:    cfg,
+		metrics:   recorder,
 		logger:    logger,
 		client:    client,
+		cache:     cache,
+		observers: []ProgressObserver{logProgress{logger: logger}, recordProgress{metrics: recorder}},
 	}
 }
 
-// StreamVideo streams video content from Instagram to the client
-func (vs *VideoStreamer) StreamVideo(w http.ResponseWriter, r *http.Request, videoURL, fileName string) error {
+// StreamVideo streams video content from Instagram to the client.
+// When cacheKey is set and the upstream response carries the whole file, the bytes are
+// also written to the video cache so later requests can be served from disk.
+func (vs *VideoStreamer) StreamVideo(w http.ResponseWriter, r *http.Request, videoURL, fileName, cacheKey string) (err error) {
+	ctx, span := tracing.Start(r.Context(), "stream.video", tracing.KindInternal)
+	body := &eofReader{}
+	defer func() {
+		span.SetAttributes("stream.file_name", fileName, "stream.bytes", body.n, "stream.complete", body.eof)
+		span.RecordError(err)
+		span.End()
+	}()
+
 	vs.logger.Debug("Creating request to Instagram video URL")
 
-	req, err := vs.createVideoRequest(r.Context(), videoURL, r)
+	req, err := vs.createVideoRequest(ctx, videoURL, r)
 	if err != nil {
 		vs.logger.Error("Failed to create video request", "error", err)
 		return err
@@ -39,18 +78,409 @@ func (vs *VideoStreamer) StreamVideo(w http.ResponseWriter, r *http.Request, vid
 
 	resp, err := vs.makeVideoRequest(req)
 	if err != nil {
+		if vs.clientCancelled(ctx, "request") {
+			return nil
+		}
 		vs.logger.Error("Failed to fetch video", "error", err)
 		return err
 	}
 	defer resp.Body.Close()
+	// Tear the CDN response down as soon as the client goes away, so a read blocked on a
+	// slow edge (or the prefetch goroutine) returns at once instead of at the next write
+	stop := context.AfterFunc(ctx, func() { resp.Body.Close() })
+	defer stop()
 
-	if err := vs.validateResponse(resp); err != nil {
+	if handled, err := vs.handleBodilessStatus(w, r, resp); handled {
+		return err
+	}
+
+	body.r = resp.Body
+
+	// Some CDN edges ignore Range and send the whole file. With If-Range a 200 means the
+	// client's copy is stale, so the new file goes through whole rather than as a range.
+	if resp.StatusCode == http.StatusOK && req.Header.Get("Range") != "" && r.Header.Get("If-Range") == "" {
+		requested, _, _ := parseRange(req.Header.Get("Range"))
+		return vs.serveIgnoredRange(w, r, resp, body, requested, fileName, cacheKey)
+	}
+
+	if cacheKey == "" || vs.cache == nil || !isCompleteResponse(resp) {
+		return vs.serveBody(w, resp, body, fileName)
+	}
+
+	cacheWriter, err := vs.cache.NewWriter(cacheKey)
+	if err != nil {
+		vs.logger.Warn("Video cache unavailable, streaming without caching", "error", err)
+		return vs.serveBody(w, resp, body, fileName)
+	}
+
+	body.r = io.TeeReader(resp.Body, cacheWriter)
+	streamErr := vs.serveBody(w, resp, body, fileName)
+
+	// Only keep files that were read to the end and match the advertised size
+	expected := resp.ContentLength
+	if !body.eof || (expected >= 0 && cacheWriter.Written() != expected) {
+		vs.logger.Debug("Discarding incomplete cached video", "key", cacheKey, "bytes", cacheWriter.Written())
+		cacheWriter.Abort()
+	} else if err := cacheWriter.Commit(); err != nil {
+		vs.logger.Warn("Failed to cache video", "key", cacheKey, "error", err)
+	}
+
+	return streamErr
+}
+
+// serveIgnoredRange answers a ranged request whose upstream response was a plain 200.
+// With a video cache the whole file is downloaded into a cache writer, the range served
+// from it and the file committed afterwards; otherwise the leading bytes are discarded
+// and a 206 is synthesized from the file size.
+func (vs *VideoStreamer) serveIgnoredRange(w http.ResponseWriter, r *http.Request, resp *http.Response, body io.Reader, requested byteRange, fileName, cacheKey string) error {
+	total := resp.ContentLength
+	vs.logger.Debug("CDN ignored Range header", "range", requested.String(), "content_length", total)
+
+	if cacheKey != "" && vs.cache != nil {
+		cacheWriter, err := vs.cache.NewWriter(cacheKey)
+		if err == nil {
+			return vs.serveThroughCache(w, r, body, total, cacheWriter, cacheKey)
+		}
+		vs.logger.Warn("Video cache unavailable, skipping to the requested range", "error", err)
+	}
+
+	// Without the size the range can't be located, so the whole file is sent as RFC 9110 allows
+	if total < 0 {
+		return vs.serveBody(w, resp, body, fileName)
+	}
+
+	start, end, ok := requested.resolve(total)
+	if !ok {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", total))
+		return models.NewRangeError(r.Header.Get("Range"), fmt.Errorf("range is beyond the end of the file"))
+	}
+	if _, err := io.CopyN(io.Discard, body, start); err != nil {
+		return models.NewNetworkError("skipping to the requested range", err)
+	}
+
+	resp.StatusCode = http.StatusPartialContent
+	resp.Header.Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	resp.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, total))
+	return vs.serveBody(w, resp, io.LimitReader(body, end-start+1), fileName)
+}
+
+// serveThroughCache downloads the whole upstream body into cacheWriter without sending
+// anything, serves the requested range from it and then commits it for later requests
+func (vs *VideoStreamer) serveThroughCache(w http.ResponseWriter, r *http.Request, body io.Reader, expected int64, cacheWriter *videocache.Writer, cacheKey string) error {
+	written, err := io.Copy(cacheWriter, body)
+	if err != nil {
+		cacheWriter.Abort()
+		if vs.clientCancelled(r.Context(), "request") {
+			return nil
+		}
+		return models.NewNetworkError("downloading video for the range request", err)
+	}
+	if expected >= 0 && written != expected {
+		cacheWriter.Abort()
+		return models.NewNetworkError("downloading video for the range request", fmt.Errorf("got %d of %d bytes", written, expected))
+	}
+
+	if err := cacheWriter.ServeContent(vs.withSlidingDeadline(w), r); err != nil {
+		cacheWriter.Abort()
+		return fmt.Errorf("failed to buffer video %s: %w", cacheKey, err)
+	}
+	if err := cacheWriter.Commit(); err != nil {
+		vs.logger.Warn("Failed to cache video", "key", cacheKey, "error", err)
+	}
+	return nil
+}
+
+// serveBody writes the upstream headers and body to the client
+func (vs *VideoStreamer) serveBody(w http.ResponseWriter, resp *http.Response, body io.Reader, fileName string) error {
+	// Streams replace the server-wide WriteTimeout with a sliding deadline
+	rc := http.NewResponseController(w)
+	vs.extendWriteDeadline(rc)
+
+	if vs.config.PrefetchSize <= 0 {
+		vs.setResponseHeaders(w, resp, fileName)
+		return vs.streamContent(w, rc, resp, body, fileName)
+	}
+
+	// Read the first chunk while headers go out so the player's initial buffer fills immediately
+	prefetch := vs.startPrefetch(body)
+	vs.setResponseHeaders(w, resp, fileName)
+	if err := rc.Flush(); err != nil {
+		vs.logger.Debug("Response writer does not support flushing", "error", err)
+	}
+
+	result := <-prefetch
+	defer putPrefetchBuffer(result.data)
+	if result.err != nil && result.err != io.EOF {
+		if resp.Request != nil && vs.clientCancelled(resp.Request.Context(), "prefetch") {
+			return nil
+		}
+		// The headers are out, so the client can no longer get an error response
+		vs.logger.Error("Error prefetching video", "filename", fileName, "error", result.err)
+		return nil
+	}
+	vs.logger.Debug("Prefetched first chunk", "bytes", len(result.data))
+
+	return vs.streamContent(w, rc, resp, io.MultiReader(bytes.NewReader(result.data), body), fileName)
+}
+
+// eofReader records whether the wrapped reader was consumed to io.EOF
+type eofReader struct {
+	r   io.Reader
+	eof bool
+	n   int64 // Bytes read so far
+}
+
+func (er *eofReader) Read(p []byte) (int, error) {
+	n, err := er.r.Read(p)
+	er.n += int64(n)
+	if err == io.EOF {
+		er.eof = true
+	}
+	return n, err
+}
+
+// isCompleteResponse reports whether the upstream response contains the entire file
+func isCompleteResponse(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusOK {
+		return true
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		return false
+	}
+
+	// Accept "bytes 0-{size-1}/{size}", which players commonly request as "bytes=0-"
+	contentRange := strings.TrimPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	span, total, ok := strings.Cut(contentRange, "/")
+	if !ok {
+		return false
+	}
+	first, last, ok := strings.Cut(span, "-")
+	if !ok || first != "0" {
+		return false
+	}
+	lastByte, err1 := strconv.ParseInt(last, 10, 64)
+	size, err2 := strconv.ParseInt(total, 10, 64)
+	return err1 == nil && err2 == nil && lastByte == size-1
+}
+
+// clientCancelled reports whether the client has gone away, cancelling ctx, and counts
+// the stream as cancelled in phase when it has: nothing more can be sent to it
+func (vs *VideoStreamer) clientCancelled(ctx context.Context, phase string) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	vs.metrics.Count(metrics.StreamCancelled, 1, "phase", phase)
+	vs.logger.Info("Client cancelled stream", "phase", phase)
+	return true
+}
+
+// extendWriteDeadline pushes the connection write deadline forward by WriteIdleTimeout,
+// so slow-but-progressing downloads continue while stalled clients are still cut off
+func (vs *VideoStreamer) extendWriteDeadline(rc *http.ResponseController) {
+	if err := rc.SetWriteDeadline(time.Now().Add(vs.config.WriteIdleTimeout)); err != nil {
+		vs.logger.Debug("Response writer does not support write deadlines", "error", err)
+	}
+}
+
+// deadlineWriter extends the connection write deadline after every successful write
+type deadlineWriter struct {
+	w        io.Writer
+	streamer *VideoStreamer
+	rc       *http.ResponseController
+}
+
+func (dw *deadlineWriter) Write(p []byte) (int, error) {
+	n, err := dw.w.Write(p)
+	if n > 0 {
+		dw.streamer.extendWriteDeadline(dw.rc)
+	}
+	return n, err
+}
+
+// deadlineResponseWriter is a deadlineWriter for handlers that hand the response to
+// http.ServeContent or the video cache rather than copying through streamContent
+type deadlineResponseWriter struct {
+	http.ResponseWriter
+	dw *deadlineWriter
+}
+
+// withSlidingDeadline replaces the server-wide WriteTimeout of w with the sliding deadline
+func (vs *VideoStreamer) withSlidingDeadline(w http.ResponseWriter) http.ResponseWriter {
+	rc := http.NewResponseController(w)
+	vs.extendWriteDeadline(rc)
+	return &deadlineResponseWriter{ResponseWriter: w, dw: &deadlineWriter{w: w, streamer: vs, rc: rc}}
+}
+
+func (drw *deadlineResponseWriter) Write(p []byte) (int, error) {
+	return drw.dw.Write(p)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController (flushing, deadlines)
+func (drw *deadlineResponseWriter) Unwrap() http.ResponseWriter {
+	return drw.ResponseWriter
+}
+
+// Flushing policy of flushWriter: every write is flushed until initialFlushBytes have
+// gone out, so players get the moov atom and first frames without waiting for Go's
+// write buffering, then at most once per flushInterval
+const (
+	initialFlushBytes = 256 * 1024
+	flushInterval     = 500 * time.Millisecond
+)
+
+// flushWriter pushes buffered response data to the client as it streams. Over HTTP/2
+// the server otherwise holds back DATA frames until its write buffer fills.
+type flushWriter struct {
+	w         io.Writer
+	rc        *http.ResponseController
+	n         int64
+	lastFlush time.Time
+	disabled  bool // The writer does not support flushing
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	fw.n += int64(n)
+	if err != nil || fw.disabled {
+		return n, err
+	}
+
+	if fw.n <= initialFlushBytes || time.Since(fw.lastFlush) >= flushInterval {
+		if err := fw.rc.Flush(); err != nil {
+			if errors.Is(err, http.ErrNotSupported) {
+				fw.disabled = true
+				return n, nil
+			}
+			return n, err
+		}
+		fw.lastFlush = time.Now()
+	}
+	return n, nil
+}
+
+// startPrefetch reads up to PrefetchSize bytes of the body in the background
+func (vs *VideoStreamer) startPrefetch(body io.Reader) <-chan prefetchResult {
+	ch := make(chan prefetchResult, 1)
+	go func() {
+		buffer := getPrefetchBuffer(vs.config.PrefetchSize)
+		n, err := io.ReadFull(body, buffer)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF // Video is smaller than the prefetch size
+		}
+		ch <- prefetchResult{data: buffer[:n], err: err}
+	}()
+	return ch
+}
+
+// prefetchBuffers recycles prefetch buffers; entries smaller than the configured size are dropped
+var prefetchBuffers sync.Pool
+
+// getPrefetchBuffer returns a buffer of size bytes, reusing a pooled one when it is large enough
+func getPrefetchBuffer(size int64) []byte {
+	if pooled, ok := prefetchBuffers.Get().(*[]byte); ok && int64(cap(*pooled)) >= size {
+		return (*pooled)[:size]
+	}
+	return make([]byte, size)
+}
+
+// putPrefetchBuffer returns a buffer from getPrefetchBuffer once nothing reads from it anymore
+func putPrefetchBuffer(buffer []byte) {
+	prefetchBuffers.Put(&buffer)
+}
+
+// ProbeVideo answers a HEAD request with the headers StreamVideo would send, without a body.
+// The CDN is asked for the client's range, or just the first byte when there is none, whose
+// Content-Range reveals the full size.
+func (vs *VideoStreamer) ProbeVideo(w http.ResponseWriter, r *http.Request, videoURL, fileName string) error {
+	req, err := vs.createVideoRequest(r.Context(), videoURL, r)
+	if err != nil {
 		return err
 	}
+	clientRange := req.Header.Get("Range")
+	if clientRange == "" {
+		req.Header.Set("Range", "bytes=0-0")
+		req.Header.Del("If-Range") // Would turn the probe into a full download on mismatch
+	}
+
+	resp, err := vs.makeVideoRequest(req)
+	if err != nil {
+		vs.logger.Error("Failed to probe video", "error", err)
+		return err
+	}
+	resp.Body.Close()
+
+	if handled, err := vs.handleBodilessStatus(w, r, resp); handled {
+		return err
+	}
+
+	// A ranged response to the client's own range carries the right headers already
+	if clientRange != "" || resp.StatusCode == http.StatusOK {
+		vs.setResponseHeaders(w, resp, fileName)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", contentTypeFor(fileName))
+	w.Header().Set("Accept-Ranges", "bytes")
+	copyHeaders(w.Header(), resp.Header, validatorHeaders)
+	if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok && total != "*" {
+		w.Header().Set("Content-Length", total)
+	}
+	w.WriteHeader(http.StatusOK)
+	vs.logger.Debug("Answered HEAD request", "content_length", w.Header().Get("Content-Length"))
+	return nil
+}
+
+// handleBodilessStatus deals with CDN responses that carry no media: 304 is passed on with
+// the validators, 416 and error statuses become errors. handled is false for 2xx responses.
+func (vs *VideoStreamer) handleBodilessStatus(w http.ResponseWriter, r *http.Request, resp *http.Response) (handled bool, err error) {
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		vs.logger.Debug("CDN reports media not modified")
+		copyHeaders(w.Header(), resp.Header, validatorHeaders)
+		w.WriteHeader(http.StatusNotModified)
+		return true, nil
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// Ranges past the end of the file: pass on the size the CDN reports as "bytes */{size}"
+		if contentRange := resp.Header.Get("Content-Range"); contentRange != "" {
+			w.Header().Set("Content-Range", contentRange)
+		}
+		return true, models.NewRangeError(r.Header.Get("Range"), fmt.Errorf("range is beyond the end of the file"))
+	}
+	if err := vs.validateResponse(resp); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+// copyHeaders copies the named headers that are set in src to dst
+func copyHeaders(dst, src http.Header, names []string) {
+	for _, name := range names {
+		if value := src.Get(name); value != "" {
+			dst.Set(name, value)
+		}
+	}
+}
+
+// OpenVideo fetches the full video from the CDN without forwarding client headers.
+// The caller is responsible for closing the response body.
+func (vs *VideoStreamer) OpenVideo(ctx context.Context, videoURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", videoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	vs.setBrowserHeaders(req)
 
-	vs.setResponseHeaders(w, resp)
+	resp, err := vs.makeVideoRequest(req)
+	if err != nil {
+		return nil, err
+	}
 
-	return vs.streamContent(w, resp.Body, fileName)
+	if err := vs.validateResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	return resp, nil
 }
 
 // createVideoRequest creates an HTTP request to fetch the video
@@ -62,11 +492,21 @@ func (vs *VideoStreamer) createVideoRequest(ctx context.Context, videoURL string
 
 	vs.setBrowserHeaders(req)
 
-	// Add Range header if present in the original request (for partial content)
+	// Forward the Range header (for partial content) in normalized single-range form.
+	// Malformed headers are dropped so the whole file is served instead.
 	if rangeHeader := originalReq.Header.Get("Range"); rangeHeader != "" {
-		req.Header.Set("Range", rangeHeader)
-		vs.logger.Debug("Range request", "range", rangeHeader)
+		byteRange, ok, err := parseRange(rangeHeader)
+		if err != nil {
+			return nil, models.NewRangeError(rangeHeader, err)
+		}
+		if ok {
+			req.Header.Set("Range", "bytes="+byteRange.String())
+			vs.logger.Debug("Range request", "range", rangeHeader, "forwarded", byteRange.String())
+		} else {
+			vs.logger.Debug("Ignoring malformed Range header", "range", rangeHeader)
+		}
 	}
+	copyHeaders(req.Header, originalReq.Header, conditionalHeaders)
 
 	return req, nil
 }
@@ -74,7 +514,7 @@ func (vs *VideoStreamer) createVideoRequest(ctx context.Context, videoURL string
 // setBrowserHeaders sets headers to mimic a browser request
 func (vs *VideoStreamer) setBrowserHeaders(req *http.Request) {
 	headers := map[string]string{
-		"User-Agent":      vs.userAgent,
+		"User-Agent":      vs.client.UserAgent(),
 		"Accept":          "*/*",
 		"Accept-Language": "en-US,en;q=0.9",
 		"Referer":         "https://www.instagram.com/",
@@ -108,8 +548,15 @@ func (vs *VideoStreamer) makeVideoRequest(req *http.Request) (*http.Response, er
 	return resp, nil
 }
 
+// ErrExpiredURL is returned when the CDN rejects a signed media URL, which happens once its signature expires
+var ErrExpiredURL = errors.New("instagram CDN URL expired")
+
 // validateResponse checks if the Instagram response is valid
 func (vs *VideoStreamer) validateResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusGone {
+		vs.logger.Warn("Instagram CDN rejected media URL", "status", resp.StatusCode)
+		return fmt.Errorf("%w: status %d", ErrExpiredURL, resp.StatusCode)
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		vs.logger.Error("Instagram CDN returned error status", "status", resp.StatusCode)
 		return fmt.Errorf("instagram server responded with status: %d", resp.StatusCode)
@@ -118,9 +565,10 @@ func (vs *VideoStreamer) validateResponse(resp *http.Response) error {
 }
 
 // setResponseHeaders sets appropriate headers on the client response
-func (vs *VideoStreamer) setResponseHeaders(w http.ResponseWriter, resp *http.Response) {
-	w.Header().Set("Content-Type", "video/mp4")
+func (vs *VideoStreamer) setResponseHeaders(w http.ResponseWriter, resp *http.Response, fileName string) {
+	w.Header().Set("Content-Type", contentTypeFor(fileName))
 	w.Header().Set("Accept-Ranges", "bytes")
+	copyHeaders(w.Header(), resp.Header, validatorHeaders)
 
 	// Set Content-Length if available
 	if contentLength := resp.Header.Get("Content-Length"); contentLength != "" {
@@ -144,51 +592,66 @@ func (vs *VideoStreamer) setResponseHeaders(w http.ResponseWriter, resp *http.Re
 	}
 }
 
-// streamContent streams the video content to the client with progress logging
-func (vs *VideoStreamer) streamContent(w http.ResponseWriter, body io.ReadCloser, fileName string) error {
+// contentTypeFor picks the response Content-Type from the file extension; videos are MP4
+func contentTypeFor(fileName string) string {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".webp":
+		return "image/webp"
+	default:
+		return "video/mp4"
+	}
+}
+
+// streamContent copies the body to the client through a pooled buffer.
+// None of the writers in between expose io.ReaderFrom, so no response uses sendfile:
+// cached files served by http.ServeContent are copied in userspace too, through
+// deadlineResponseWriter, which has to see every write to slide the deadline.
+func (vs *VideoStreamer) streamContent(w http.ResponseWriter, rc *http.ResponseController, resp *http.Response, body io.Reader, fileName string) error {
 	vs.logger.Info("Starting video streaming to client")
 
-	buffer := make([]byte, 64*1024) // 64KB buffer
-	totalBytes := 0
-	streamStart := time.Now()
+	buffer := streamBuffers.Get().(*[]byte)
+	defer streamBuffers.Put(buffer)
 
-	for {
-		n, err := body.Read(buffer)
-		if n > 0 {
-			if _, writeErr := w.Write(buffer[:n]); writeErr != nil {
-				vs.logger.Warn("Client disconnected during streaming", "error", writeErr)
-				return nil // Client disconnect is not an error
-			}
-			totalBytes += n
-
-			// Log progress for large files (every 1MB)
-			if totalBytes%(1024*1024) == 0 {
-				elapsed := time.Since(streamStart)
-				rate := float64(totalBytes) / elapsed.Seconds() / 1024 / 1024 // MB/s
-				vs.logger.Info("Stream progress",
-					"streamed_mb", totalBytes/(1024*1024),
-					"filename", fileName,
-					"rate_mbs", fmt.Sprintf("%.2f", rate))
-			}
-		}
+	// Observers registered on the request context, e.g. by a progress API, see the stream too
+	observers := vs.observers
+	if resp.Request != nil {
+		observers = append(observers[:len(observers):len(observers)], progressObserversFromContext(resp.Request.Context())...)
+	}
+	total, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		total = -1
+	}
 
-		if err != nil {
-			if err == io.EOF {
-				totalTime := time.Since(streamStart)
-				avgRate := float64(0)
-				if totalTime.Seconds() > 0 {
-					avgRate = float64(totalBytes) / totalTime.Seconds() / 1024 / 1024 // MB/s
-				}
-				vs.logger.Info("Successfully streamed video",
-					"filename", fileName,
-					"total_bytes", totalBytes,
-					"rate_mbs", fmt.Sprintf("%.2f", avgRate),
-					"duration", totalTime)
-				return nil
-			} else {
-				vs.logger.Error("Error streaming video", "filename", fileName, "error", err)
-				return err
-			}
-		}
+	dw := &deadlineWriter{w: w, streamer: vs, rc: rc}
+	out := newProgressWriter(&flushWriter{w: dw, rc: rc}, observers, fileName, total)
+	_, err = io.CopyBuffer(out, body, *buffer)
+
+	switch {
+	case (err != nil || out.err != nil) && resp.Request != nil && resp.Request.Context().Err() != nil:
+		out.finish(StreamCancelled, context.Cause(resp.Request.Context()))
+		return nil
+	case out.err != nil:
+		out.finish(StreamDisconnected, out.err)
+		return nil // Client disconnect is not an error
+	case err != nil:
+		out.finish(StreamFailed, err)
+		return err
 	}
+	out.finish(StreamComplete, nil)
+	return nil
+}
+
+// streamBufferSize is the copy buffer size of streamContent
+const streamBufferSize = 64 * 1024
+
+// streamBuffers recycles copy buffers between streams
+var streamBuffers = sync.Pool{
+	New: func() any {
+		buffer := make([]byte, streamBufferSize)
+		return &buffer
+	},
 }
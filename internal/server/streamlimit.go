@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/models"
+)
+
+// streamLimiter tracks the video responses in flight and optionally caps them. Requests
+// over the cap, and all new streams once shutdown has begun, are turned away at once with
+// 503 rather than queued, since a waiting player holds its socket just the same.
+type streamLimiter struct {
+	limit      atomic.Int64 // 0 when streams are not capped
+	active     atomic.Int64
+	draining   atomic.Bool
+	retryAfter atomic.Int64 // time.Duration
+	metrics    metrics.Recorder
+}
+
+// newStreamLimiter creates a limiter for limit concurrent streams; 0 only tracks them
+func newStreamLimiter(limit int, retryAfter time.Duration, recorder metrics.Recorder) *streamLimiter {
+	l := &streamLimiter{metrics: recorder}
+	l.setLimit(limit, retryAfter)
+	return l
+}
+
+// setLimit changes the cap and the Retry-After hint. Lowering the cap below the streams
+// in flight lets them finish; only new streams are turned away.
+func (l *streamLimiter) setLimit(limit int, retryAfter time.Duration) {
+	l.limit.Store(int64(max(limit, 0)))
+	l.retryAfter.Store(int64(retryAfter))
+	l.report(l.active.Load())
+}
+
+// acquire takes a stream slot. The returned release func must be called once the response is done.
+// When every slot is taken or the server is draining it sets Retry-After on w and returns an overloaded error.
+func (l *streamLimiter) acquire(w http.ResponseWriter) (release func(), err error) {
+	if l.draining.Load() {
+		l.metrics.Count(metrics.StreamRejections, 1, "reason", "draining")
+		w.Header().Set("Connection", "close")
+		l.setRetryAfter(w)
+		return nil, models.NewOverloadedError("server is shutting down")
+	}
+
+	active := l.active.Add(1)
+	if limit := l.limit.Load(); limit > 0 && active > limit {
+		l.active.Add(-1)
+		l.metrics.Count(metrics.StreamRejections, 1, "reason", "limit")
+		l.setRetryAfter(w)
+		return nil, models.NewOverloadedError("too many video streams in progress")
+	}
+
+	l.report(active)
+	return func() {
+		l.report(l.active.Add(-1))
+	}, nil
+}
+
+// drain refuses new streams from now on and returns the number still in flight
+func (l *streamLimiter) drain() int64 {
+	l.draining.Store(true)
+	return l.active.Load()
+}
+
+// inFlight returns the number of active streams
+func (l *streamLimiter) inFlight() int64 {
+	return l.active.Load()
+}
+
+// setRetryAfter sends the configured delay in whole seconds, at least 1: rounding a
+// sub-second delay would otherwise tell clients to retry immediately
+func (l *streamLimiter) setRetryAfter(w http.ResponseWriter) {
+	seconds := max(int(time.Duration(l.retryAfter.Load()).Round(time.Second)/time.Second), 1)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
+// report publishes the active count together with the limit, so dashboards can chart
+// utilisation without knowing the configuration
+func (l *streamLimiter) report(active int64) {
+	l.metrics.Gauge(metrics.StreamActive, float64(active))
+	if limit := l.limit.Load(); limit > 0 {
+		l.metrics.Gauge(metrics.StreamLimit, float64(limit))
+	}
+}
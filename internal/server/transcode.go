@@ -0,0 +1,347 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/models"
+	"qwiklip/internal/transcode"
+)
+
+// defaultAudioFormat is used when /reel/{shortcode}/audio has no ?format=
+const defaultAudioFormat = "m4a"
+
+// Upper bounds on GIF query parameters; GIFs grow quickly with length and size
+const (
+	maxGIFSeconds = 15
+	maxGIFFPS     = 30
+	maxGIFWidth   = 720
+)
+
+// isAudioPath reports whether the path is /reel/{shortcode}/audio
+func isAudioPath(requestPath string) bool {
+	segments := strings.Split(strings.Trim(requestPath, "/"), "/")
+	return len(segments) == 3 && segments[2] == "audio"
+}
+
+// isGIFPath reports whether the path is /reel/{shortcode}.gif
+func isGIFPath(requestPath string) bool {
+	segments := strings.Split(strings.Trim(requestPath, "/"), "/")
+	return len(segments) == 2 && strings.HasSuffix(segments[1], ".gif") && len(segments[1]) > len(".gif")
+}
+
+// handleReelGIF handles /reel/{shortcode}.gif?seconds=&fps=&width=, converting the start of the
+// reel to an animated GIF. Parameters default to the GIF_* settings.
+func (s *Server) handleReelGIF(w http.ResponseWriter, r *http.Request, instagramURL string) {
+	if s.ffmpeg == nil {
+		s.handleError(w, r, models.NewUnavailableError("GIF conversion", "ffmpeg is not installed on this server"))
+		return
+	}
+
+	opts := transcode.GIFOptions{
+		Seconds: s.config.Stream.GIFSeconds,
+		FPS:     s.config.Stream.GIFFPS,
+		Width:   s.config.Stream.GIFWidth,
+	}
+	query := r.URL.Query()
+	for _, param := range []struct {
+		name     string
+		min, max int
+		value    *int
+	}{
+		{"seconds", 1, maxGIFSeconds, &opts.Seconds},
+		{"fps", 1, maxGIFFPS, &opts.FPS},
+		{"width", 32, maxGIFWidth, &opts.Width},
+	} {
+		raw := query.Get(param.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < param.min || value > param.max {
+			s.handleError(w, r, models.NewInvalidURLError(r.URL.String(),
+				fmt.Errorf("%s must be between %d and %d, got '%s'", param.name, param.min, param.max, raw)))
+			return
+		}
+		*param.value = value
+	}
+
+	shortcode, _ := s.client.ExtractShortcode(instagramURL)
+	s.transcodeReel(w, r, instagramURL, "image/gif", shortcode+".gif",
+		func(ctx context.Context, src io.Reader, dst io.Writer) error {
+			return s.ffmpeg.ToGIF(ctx, src, dst, opts)
+		})
+}
+
+// isClipRequest reports whether the query asks for a segment with ?start= or ?end=
+func isClipRequest(r *http.Request) bool {
+	query := r.URL.Query()
+	return query.Has("start") || query.Has("end")
+}
+
+// handleReelClip handles ?start=&end= on the reel stream, serving only that segment.
+// Times are seconds ("12.5") or [hh:]mm:ss; ?accurate=1 re-encodes for a frame-exact start.
+func (s *Server) handleReelClip(w http.ResponseWriter, r *http.Request, instagramURL string) {
+	if s.ffmpeg == nil {
+		s.handleError(w, r, models.NewUnavailableError("clip trimming", "ffmpeg is not installed on this server"))
+		return
+	}
+
+	query := r.URL.Query()
+	var opts transcode.TrimOptions
+	var err error
+	if raw := query.Get("start"); raw != "" {
+		if opts.Start, err = parseClipTime(raw); err != nil {
+			s.handleError(w, r, models.NewInvalidURLError(r.URL.String(), fmt.Errorf("invalid start: %w", err)))
+			return
+		}
+	}
+	if raw := query.Get("end"); raw != "" {
+		if opts.End, err = parseClipTime(raw); err != nil {
+			s.handleError(w, r, models.NewInvalidURLError(r.URL.String(), fmt.Errorf("invalid end: %w", err)))
+			return
+		}
+		if opts.End <= opts.Start {
+			s.handleError(w, r, models.NewInvalidURLError(r.URL.String(), fmt.Errorf("end must be after start")))
+			return
+		}
+	}
+	opts.Accurate = query.Get("accurate") == "1" || query.Get("accurate") == "true"
+
+	shortcode, _ := s.client.ExtractShortcode(instagramURL)
+	fileName := fmt.Sprintf("%s_%d-%s.mp4", shortcode, int(opts.Start.Seconds()), clipEndLabel(opts.End))
+	s.transcodeReel(w, r, instagramURL, "video/mp4", fileName,
+		func(ctx context.Context, src io.Reader, dst io.Writer) error {
+			return s.ffmpeg.Trim(ctx, src, dst, opts)
+		})
+}
+
+// parseClipTime parses a clip boundary given as seconds ("12.5") or [hh:]mm:ss[.frac]
+func parseClipTime(raw string) (time.Duration, error) {
+	parts := strings.Split(raw, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("expected seconds or [hh:]mm:ss, got '%s'", raw)
+	}
+
+	var total float64
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil || !(value >= 0) || (i > 0 && value >= 60) {
+			return 0, fmt.Errorf("expected seconds or [hh:]mm:ss, got '%s'", raw)
+		}
+		total = total*60 + value
+	}
+	if total > 24*60*60 {
+		return 0, fmt.Errorf("time too large, got '%s'", raw)
+	}
+	return time.Duration(total * float64(time.Second)), nil
+}
+
+// clipEndLabel formats the clip end for file names: whole seconds, or "end" when open-ended
+func clipEndLabel(d time.Duration) string {
+	if d == 0 {
+		return "end"
+	}
+	return strconv.Itoa(int(d.Seconds()))
+}
+
+// handleReelAudio handles /reel/{shortcode}/audio?format=m4a|mp3, piping the video through
+// ffmpeg to send only its soundtrack. Range requests are not supported since the output is produced on the fly.
+func (s *Server) handleReelAudio(w http.ResponseWriter, r *http.Request, instagramURL string) {
+	if s.ffmpeg == nil {
+		s.handleError(w, r, models.NewUnavailableError("audio extraction", "ffmpeg is not installed on this server"))
+		return
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = defaultAudioFormat
+	}
+	audioFormat, ok := transcode.AudioFormats[format]
+	if !ok {
+		s.handleError(w, r, models.NewInvalidURLError(r.URL.String(), fmt.Errorf("audio format must be m4a or mp3, got '%s'", format)))
+		return
+	}
+
+	shortcode, _ := s.client.ExtractShortcode(instagramURL)
+	s.transcodeReel(w, r, instagramURL, audioFormat.ContentType, shortcode+audioFormat.Extension,
+		func(ctx context.Context, src io.Reader, dst io.Writer) error {
+			return s.ffmpeg.ExtractAudio(ctx, src, dst, format)
+		})
+}
+
+// wantsTags reports whether a reel stream or download should carry the post's metadata as
+// MP4 tags: ?tags=1 or ?tags=0 decide, otherwise STREAM_METADATA_TAGS does when ffmpeg is installed
+func (s *Server) wantsTags(r *http.Request) bool {
+	switch r.URL.Query().Get("tags") {
+	case "1", "true":
+		return true
+	case "0", "false":
+		return false
+	}
+	return s.config.Stream.MetadataTags && s.ffmpeg != nil
+}
+
+// streamTagged streams the reel's video remuxed with its title, author, source URL and
+// post date as MP4 tags, so saved files keep their provenance
+func (s *Server) streamTagged(w http.ResponseWriter, r *http.Request, instagramURL string, mediaInfo *models.InstagramMediaInfo, fileName string) {
+	if s.ffmpeg == nil {
+		s.handleError(w, r, models.NewUnavailableError("metadata tags", "ffmpeg is not installed on this server"))
+		return
+	}
+
+	tags := mediaTags(instagramURL, mediaInfo)
+	s.transcodeMedia(w, r, mediaInfo, "video/mp4", fileName,
+		func(ctx context.Context, src io.Reader, dst io.Writer) error {
+			return s.ffmpeg.Tag(ctx, src, dst, tags)
+		})
+}
+
+// maxTitleTag is the length, in characters, of the caption line used as the title tag
+const maxTitleTag = 100
+
+// mediaTags builds the MP4 tags of a post: the first caption line as title, the author as
+// artist, the post URL as comment and the post date
+func mediaTags(instagramURL string, mediaInfo *models.InstagramMediaInfo) transcode.Tags {
+	title, _, _ := strings.Cut(strings.TrimSpace(mediaInfo.Caption), "\n")
+	tags := transcode.Tags{
+		Title:   truncateRunes(strings.TrimSpace(title), maxTitleTag),
+		Artist:  mediaInfo.Username,
+		Comment: strings.TrimSuffix(instagramURL, "/") + "/",
+	}
+	if !mediaInfo.TakenAt.IsZero() {
+		tags.Date = mediaInfo.TakenAt.UTC().Format(time.RFC3339)
+	}
+	return tags
+}
+
+// transcodeReel streams the reel's video through convert (an ffmpeg run) to the client
+func (s *Server) transcodeReel(w http.ResponseWriter, r *http.Request, instagramURL, contentType, fileName string, convert func(ctx context.Context, src io.Reader, dst io.Writer) error) {
+	mediaInfo, err := s.fetchMediaInfo(r.Context(), instagramURL)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	s.transcodeMedia(w, r, mediaInfo, contentType, fileName, convert)
+}
+
+// transcodeMedia streams the video of extracted media info through convert to the client
+func (s *Server) transcodeMedia(w http.ResponseWriter, r *http.Request, mediaInfo *models.InstagramMediaInfo, contentType, fileName string, convert func(ctx context.Context, src io.Reader, dst io.Writer) error) {
+	if mediaInfo.VideoURL == "" {
+		s.handleError(w, r, models.NewUnsupportedError("image (photo posts cannot be converted)"))
+		return
+	}
+
+	videoURL, err := s.selectVideoURL(r, mediaInfo)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	streamer := s.newVideoStreamer()
+	video, err := streamer.OpenVideo(r.Context(), videoURL)
+	if err != nil {
+		s.handleError(w, r, models.NewNetworkError("fetching video from CDN", err))
+		return
+	}
+	defer video.Body.Close()
+
+	s.serveTranscoded(w, r, streamer, contentType, fileName, func(ctx context.Context, dst io.Writer) error {
+		return convert(ctx, video.Body, dst)
+	})
+}
+
+// muxDASH streams the DASH video and audio representations muxed into one MP4
+func (s *Server) muxDASH(w http.ResponseWriter, r *http.Request, mediaInfo *models.InstagramMediaInfo) {
+	streamer := s.newVideoStreamer()
+	video, err := streamer.OpenVideo(r.Context(), mediaInfo.DASH.VideoURL)
+	if err != nil {
+		s.handleError(w, r, models.NewNetworkError("fetching DASH video from CDN", err))
+		return
+	}
+	defer video.Body.Close()
+
+	var audio io.Reader
+	if mediaInfo.DASH.AudioURL != "" {
+		audioResp, err := streamer.OpenVideo(r.Context(), mediaInfo.DASH.AudioURL)
+		if err != nil {
+			s.handleError(w, r, models.NewNetworkError("fetching DASH audio from CDN", err))
+			return
+		}
+		defer audioResp.Body.Close()
+		audio = audioResp.Body
+	}
+
+	s.serveTranscoded(w, r, streamer, "video/mp4", mediaInfo.FileName, func(ctx context.Context, dst io.Writer) error {
+		return s.ffmpeg.Mux(ctx, video.Body, audio, dst)
+	})
+}
+
+// serveTranscoded writes the output of an ffmpeg run to the client.
+// Errors before any output is sent get a regular error response; later ones only end the stream.
+func (s *Server) serveTranscoded(w http.ResponseWriter, r *http.Request, streamer *VideoStreamer, contentType, fileName string, run func(ctx context.Context, dst io.Writer) error) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+	logger.Info("Starting ffmpeg conversion", "file", fileName)
+	start := time.Now()
+
+	w.Header().Set("Content-Type", contentType)
+	if w.Header().Get("Content-Disposition") == "" { // Downloads have already asked for an attachment
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, fileName))
+	}
+	w.Header().Set("Accept-Ranges", "none")
+
+	// The output size is unknown until ffmpeg finishes, so HEAD gets the headers alone
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	release, err := s.streams.acquire(w)
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		s.handleError(w, r, err)
+		return
+	}
+	defer release()
+
+	rc := http.NewResponseController(w)
+	streamer.extendWriteDeadline(rc)
+	out := &countingWriter{w: &flushWriter{w: &deadlineWriter{w: w, streamer: streamer, rc: rc}, rc: rc}}
+
+	if err := run(r.Context(), out); err != nil {
+		if out.n == 0 && r.Context().Err() == nil {
+			// Nothing was sent yet, so the client can still get a proper error
+			w.Header().Del("Content-Disposition")
+			if strings.Contains(err.Error(), "matches no streams") {
+				err = models.NewUnsupportedError("video without the requested track")
+			}
+			s.handleError(w, r, err)
+			return
+		}
+		logger.Warn("ffmpeg conversion aborted", "file", fileName, "bytes", out.n, "error", err)
+		return
+	}
+
+	logger.Info("ffmpeg conversion completed",
+		"file", fileName,
+		"bytes", out.n,
+		"duration", time.Since(start))
+}
+
+// countingWriter counts bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
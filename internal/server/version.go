@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+
+	"qwiklip/internal/middleware"
+)
+
+// versionResponse is the JSON body of GET /version
+type versionResponse struct {
+	Version   string    `json:"version"`
+	Commit    string    `json:"commit"`
+	BuildTime string    `json:"buildTime"`
+	GoVersion string    `json:"goVersion"`
+	Platform  string    `json:"platform"` // GOOS/GOARCH
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// handleVersion handles GET /version: the build of this instance and when it started,
+// to tell the instances of a fleet apart
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		s.sendJSONError(w, http.StatusMethodNotAllowed, "method not allowed, use GET")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(versionResponse{
+		Version:   s.versionInfo.Version,
+		Commit:    s.versionInfo.Commit,
+		BuildTime: s.versionInfo.BuildTime,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		StartedAt: s.started,
+	}); err != nil {
+		logger.Error("Failed to encode version response", "error", err)
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"qwiklip/internal/config"
+	"qwiklip/internal/middleware"
+)
+
+// handleWatch handles GET /watch/{shortcode}: a page for people with the player,
+// author, caption, post date and a download button
+func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	shortcode, mediaInfo, ok := s.fetchPageMedia(w, r, "/watch/")
+	if !ok {
+		return
+	}
+
+	pathType := "reel"
+	if mediaInfo.IsImage() {
+		pathType = "p"
+	}
+	data := struct {
+		VideoURL     string
+		ImageURL     string
+		PosterURL    string
+		Username     string
+		Caption      string
+		AltText      string
+		PostedAt     string
+		PostedISO    string
+		Duration     string
+		Likes        string
+		Comments     string
+		Location     string
+		LocationURL  string
+		Coauthors    []string
+		TaggedUsers  []string
+		DownloadURL  string
+		InstagramURL string
+		Version      string
+		Commit       string
+		Brand        config.BrandingConfig
+	}{
+		Brand:        s.config.Branding,
+		PosterURL:    mediaInfo.ThumbnailURL,
+		Username:     mediaInfo.Username,
+		Caption:      mediaInfo.Caption,
+		AltText:      mediaInfo.AltText,
+		Coauthors:    mediaInfo.Coauthors,
+		TaggedUsers:  mediaInfo.TaggedUsers,
+		DownloadURL:  fmt.Sprintf("/reel/%s/download", shortcode),
+		InstagramURL: fmt.Sprintf("https://www.instagram.com/%s/%s/", pathType, shortcode),
+		Version:      s.versionInfo.Version,
+		Commit:       s.versionInfo.Commit,
+	}
+	if mediaInfo.IsImage() {
+		data.ImageURL = fmt.Sprintf("/p/%s/", shortcode)
+	} else {
+		data.VideoURL = fmt.Sprintf("/reel/%s/", shortcode)
+	}
+	if !mediaInfo.TakenAt.IsZero() {
+		data.PostedAt = mediaInfo.TakenAt.Format("January 2, 2006")
+		data.PostedISO = mediaInfo.TakenAt.Format(time.RFC3339)
+	}
+	if mediaInfo.Duration > 0 {
+		data.Duration = formatDuration(mediaInfo.Duration)
+	}
+	if mediaInfo.LikeCount > 0 {
+		data.Likes = formatCount(mediaInfo.LikeCount, "like")
+	}
+	if mediaInfo.CommentCount > 0 {
+		data.Comments = formatCount(mediaInfo.CommentCount, "comment")
+	}
+	if mediaInfo.Location != nil {
+		data.Location = mediaInfo.Location.Name
+		data.LocationURL = mediaInfo.Location.URL()
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templateSet.Watch.Execute(w, data); err != nil {
+		logger.Error("Failed to execute watch template", "error", err)
+	}
+}
+
+// formatCount renders n with thousands separators and the noun, pluralized
+func formatCount(n int, noun string) string {
+	digits := strconv.Itoa(n)
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if n != 1 {
+		noun += "s"
+	}
+	return b.String() + " " + noun
+}
+
+// formatDuration renders a length in seconds as m:ss
+func formatDuration(seconds float64) string {
+	rounded := int(seconds + 0.5)
+	return fmt.Sprintf("%d:%02d", rounded/60, rounded%60)
+}
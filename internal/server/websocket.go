@@ -0,0 +1,307 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"qwiklip/internal/instagram"
+	"qwiklip/internal/middleware"
+	"qwiklip/internal/websocket"
+)
+
+const (
+	wsMaxMessage   = 4 * 1024         // Upper bound on a client message
+	wsMaxInFlight  = 4                // Extractions one connection may run at once
+	wsQueueSize    = 64               // Events buffered per connection before progress events are dropped
+	wsPingInterval = 30 * time.Second // Keepalive for proxies that drop idle connections
+)
+
+// wsRequest is a client message on /ws; a bare URL is accepted as well
+type wsRequest struct {
+	ID  string `json:"id,omitempty"` // Echoed in every event about this request
+	URL string `json:"url"`
+}
+
+// wsEvent is a server message on /ws
+type wsEvent struct {
+	Type      string         `json:"type"` // accepted, cached, shared, fetch, strategy, media, error or progress
+	ID        string         `json:"id,omitempty"`
+	URL       string         `json:"url,omitempty"`
+	Shortcode string         `json:"shortcode,omitempty"`
+	Strategy  string         `json:"strategy,omitempty"` // Extractor tried, for strategy events
+	Attempt   int            `json:"attempt,omitempty"`  // Page fetch attempt, for fetch events
+	Result    string         `json:"result,omitempty"`   // success or failure for strategy events; the stream result for the last progress event
+	Reason    string         `json:"reason,omitempty"`   // Why a strategy failed or a stream ended early
+	Media     *mediaResponse `json:"media,omitempty"`
+	Error     *batchError    `json:"error,omitempty"`
+	Progress  *wsProgress    `json:"progress,omitempty"`
+}
+
+// wsProgress reports a stream of the media URL handed out in a media event
+type wsProgress struct {
+	FileName       string  `json:"fileName"`
+	Written        int64   `json:"written"`
+	Total          int64   `json:"total"`   // -1 when the CDN did not send a length
+	Percent        float64 `json:"percent"` // -1 when the total is unknown
+	BytesPerSecond float64 `json:"bytesPerSecond"`
+}
+
+// wsSessions tracks open /ws connections and the progress tokens handed out on them
+type wsSessions struct {
+	mu       sync.Mutex
+	sessions map[*wsSession]struct{}
+	tokens   map[string]wsTarget
+}
+
+// wsTarget is where progress of a stream started with a token is reported
+type wsTarget struct {
+	session *wsSession
+	id      string
+}
+
+func newWSSessions() *wsSessions {
+	return &wsSessions{sessions: make(map[*wsSession]struct{}), tokens: make(map[string]wsTarget)}
+}
+
+func (ss *wsSessions) add(session *wsSession) {
+	ss.mu.Lock()
+	ss.sessions[session] = struct{}{}
+	ss.mu.Unlock()
+}
+
+// remove forgets a closed session and its tokens
+func (ss *wsSessions) remove(session *wsSession) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.sessions, session)
+	for token, target := range ss.tokens {
+		if target.session == session {
+			delete(ss.tokens, token)
+		}
+	}
+}
+
+// token returns a new progress token reporting to session under id
+func (ss *wsSessions) token(session *wsSession, id string) string {
+	token := rand.Text()
+	ss.mu.Lock()
+	ss.tokens[token] = wsTarget{session: session, id: id}
+	ss.mu.Unlock()
+	return token
+}
+
+// attach makes streams of r report progress to the session whose token is in ?progress=
+func (ss *wsSessions) attach(r *http.Request) *http.Request {
+	token := r.URL.Query().Get("progress")
+	if token == "" {
+		return r
+	}
+	ss.mu.Lock()
+	target, ok := ss.tokens[token]
+	ss.mu.Unlock()
+	if !ok {
+		return r
+	}
+	return r.WithContext(WithProgressObserver(r.Context(), ProgressFunc(func(p Progress) {
+		event := wsEvent{Type: "progress", ID: target.id, Result: p.Result, Progress: &wsProgress{
+			FileName:       p.FileName,
+			Written:        p.Written,
+			Total:          p.Total,
+			Percent:        p.Percent(),
+			BytesPerSecond: p.Rate(),
+		}}
+		if p.Err != nil {
+			event.Reason = p.Err.Error()
+		}
+		target.session.send(event, p.Done())
+	})))
+}
+
+// closeAll tells every client the server is going away; registered with http.Server.RegisterOnShutdown
+// since hijacked connections are not closed by Shutdown
+func (ss *wsSessions) closeAll() {
+	ss.mu.Lock()
+	sessions := make([]*wsSession, 0, len(ss.sessions))
+	for session := range ss.sessions {
+		sessions = append(sessions, session)
+	}
+	ss.mu.Unlock()
+
+	for _, session := range sessions {
+		session.conn.Close(websocket.CloseGoingAway, "server shutting down")
+	}
+}
+
+// wsSession is one /ws connection. Events are queued and written by one goroutine so
+// extraction and stream observers never wait on a slow client.
+type wsSession struct {
+	conn   *websocket.Conn
+	events chan wsEvent
+	done   chan struct{}
+}
+
+// send queues an event. Progress events are dropped when the queue is full; others
+// (keep) wait for room until the connection closes.
+func (ws *wsSession) send(event wsEvent, keep bool) {
+	if !keep {
+		select {
+		case ws.events <- event:
+		case <-ws.done:
+		default:
+		}
+		return
+	}
+	select {
+	case ws.events <- event:
+	case <-ws.done:
+	}
+}
+
+// writeEvents writes queued events and keepalive pings until the connection closes
+func (ws *wsSession) writeEvents() {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case event := <-ws.events:
+			err = ws.conn.WriteJSON(event)
+		case <-ping.C:
+			err = ws.conn.Ping()
+		case <-ws.done:
+			return
+		}
+		if err != nil {
+			ws.conn.Close(websocket.CloseGoingAway, "write failed")
+			return
+		}
+	}
+}
+
+// handleWebSocket handles /ws: the client sends Instagram URLs and receives events as
+// each is extracted - cache hits, page fetches and extractor attempts - followed by a
+// media or error event. The media URL carries a progress token, so streaming it from
+// this server reports progress events on the same connection.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), s.logger)
+
+	conn, err := websocket.Upgrade(w, r, wsMaxMessage)
+	if err != nil {
+		if errors.Is(err, websocket.ErrNotWebSocket) {
+			w.Header().Set("Upgrade", "websocket")
+			s.sendJSONError(w, http.StatusUpgradeRequired, "websocket upgrade required")
+			return
+		}
+		s.sendJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session := &wsSession{conn: conn, events: make(chan wsEvent, wsQueueSize), done: make(chan struct{})}
+	s.webSockets.add(session)
+	logger.Info("WebSocket connected", "remote_addr", conn.RemoteAddr().String())
+
+	// The request context ends with the handler, so extractions get their own
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		close(session.done)
+		wg.Wait()
+		conn.Close(websocket.CloseNormal, "")
+		s.webSockets.remove(session)
+		logger.Info("WebSocket disconnected", "remote_addr", conn.RemoteAddr().String())
+	}()
+	go session.writeEvents()
+
+	baseURL := requestBaseURL(r)
+	inFlight := make(chan struct{}, wsMaxInFlight)
+	for {
+		text, message, err := conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				logger.Debug("WebSocket read failed", "error", err)
+			}
+			return
+		}
+		if !text {
+			conn.Close(websocket.CloseUnsupportedData, "send text messages")
+			return
+		}
+
+		req, err := parseWSRequest(message)
+		if err != nil {
+			session.send(wsEvent{Type: "error", Error: &batchError{Message: err.Error(), Code: http.StatusBadRequest}}, true)
+			continue
+		}
+		select {
+		case inFlight <- struct{}{}:
+		default:
+			session.send(wsEvent{Type: "error", ID: req.ID, URL: req.URL, Error: &batchError{
+				Message: "too many extractions in flight on this connection",
+				Code:    http.StatusTooManyRequests,
+			}}, true)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-inFlight }()
+			s.extractForWebSocket(ctx, session, baseURL, req)
+		}()
+	}
+}
+
+// parseWSRequest accepts {"id": "...", "url": "..."} or a bare URL
+func parseWSRequest(message []byte) (wsRequest, error) {
+	var req wsRequest
+	trimmed := strings.TrimSpace(string(message))
+	if strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal([]byte(trimmed), &req); err != nil {
+			return req, errors.New("invalid message: expected {\"url\": \"...\"} or a URL")
+		}
+	} else {
+		req.URL = trimmed
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	if req.URL == "" {
+		return req, errors.New("url is required")
+	}
+	return req, nil
+}
+
+// extractForWebSocket runs one extraction, reporting its steps and result to session
+func (s *Server) extractForWebSocket(ctx context.Context, session *wsSession, baseURL string, req wsRequest) {
+	session.send(wsEvent{Type: "accepted", ID: req.ID, URL: req.URL}, true)
+
+	ctx = instagram.WithExtractionObserver(ctx, func(e instagram.ExtractionEvent) {
+		event := wsEvent{Type: e.Stage, ID: req.ID, Shortcode: e.Shortcode, Strategy: e.Strategy, Attempt: e.Attempt}
+		if e.Stage == instagram.StageStrategy {
+			event.Result = "success"
+			if e.Err != nil {
+				event.Result, event.Reason = "failure", e.Err.Error()
+			}
+		}
+		session.send(event, true)
+	})
+
+	result := s.resolveBatchItem(ctx, baseURL, req.URL)
+	if result.Error != nil {
+		session.send(wsEvent{Type: "error", ID: req.ID, URL: req.URL, Error: result.Error}, true)
+		return
+	}
+
+	separator := "?"
+	if strings.Contains(result.Media.URL, "?") {
+		separator = "&"
+	}
+	result.Media.URL += separator + "progress=" + s.webSockets.token(session, req.ID)
+	session.send(wsEvent{Type: "media", ID: req.ID, URL: req.URL, Shortcode: result.Media.Shortcode, Media: result.Media}, true)
+}
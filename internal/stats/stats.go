@@ -0,0 +1,281 @@
+// Package stats keeps in-process aggregates of the application metrics for the admin
+// dashboard and /api/stats. A Collector is a metrics.Recorder, so it sees the same events as the
+// configured metrics sink without any extra instrumentation.
+package stats
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/models"
+)
+
+const (
+	// historyMinutes is how far back the per-minute request history goes
+	historyMinutes = 60
+	// maxRecentErrors is how many failed requests are kept for display
+	maxRecentErrors = 20
+)
+
+// Collector aggregates request, stream, extraction and cache metrics in memory
+type Collector struct {
+	mu         sync.Mutex
+	started    time.Time
+	minutes    [historyMinutes]minuteBucket // Indexed by Unix minute modulo historyMinutes
+	totals     Totals
+	shortcodes map[string]struct{}
+	gauges     map[string]float64
+	extractors map[string]*Ratio
+	caches     map[string]*Ratio
+	errors     []ErrorEvent // Ring buffer, next points at the oldest entry once full
+	next       int
+}
+
+// Totals are the counters since startup
+type Totals struct {
+	Requests         int64
+	FailedRequests   int64 // 5xx responses
+	BytesStreamed    int64 // Bytes proxied from the CDN, including streams cut short
+	UniqueShortcodes int
+	FailuresByType   map[string]int64 // Error responses by models.ErrorType
+}
+
+// minuteBucket counts the requests completed within one wall-clock minute
+type minuteBucket struct {
+	minute   int64 // Unix minute the counts belong to
+	requests int64
+	failures int64 // 5xx responses
+}
+
+// Ratio counts successes and failures, e.g. of an extractor or cache hits and misses
+type Ratio struct {
+	Name    string
+	Success int64
+	Failure int64
+}
+
+// Percent returns the success share in percent, or 0 before anything was counted
+func (r Ratio) Percent() float64 {
+	if total := r.Success + r.Failure; total > 0 {
+		return float64(r.Success) * 100 / float64(total)
+	}
+	return 0
+}
+
+// ErrorEvent is a request that ended in an error response
+type ErrorEvent struct {
+	Time      time.Time
+	Path      string // Empty when the handler did not record it
+	RequestID string
+	Type      string
+	Message   string
+	Status    int
+}
+
+// MinuteStats is the request count of one minute in the history
+type MinuteStats struct {
+	Start    time.Time
+	Requests int64
+	Failures int64
+}
+
+// Snapshot is a consistent copy of the collected statistics
+type Snapshot struct {
+	Started           time.Time
+	Uptime            time.Duration
+	Totals            Totals
+	RequestsPerMinute int64         // Requests completed in the last 60 seconds
+	History           []MinuteStats // Last historyMinutes minutes, oldest first
+	ActiveStreams     int64
+	StreamLimit       int64 // 0 when streams are not capped
+	Extractors        []Ratio
+	Caches            []Ratio // Hits as successes, misses as failures
+	RecentErrors      []ErrorEvent
+}
+
+// New creates an empty collector
+func New() *Collector {
+	return &Collector{
+		started:    time.Now(),
+		totals:     Totals{FailuresByType: make(map[string]int64)},
+		shortcodes: make(map[string]struct{}),
+		gauges:     make(map[string]float64),
+		extractors: make(map[string]*Ratio),
+		caches:     make(map[string]*Ratio),
+	}
+}
+
+// Count implements metrics.Recorder
+func (c *Collector) Count(name string, value int64, tags ...string) {
+	switch name {
+	case metrics.HTTPRequests:
+		c.mu.Lock()
+		bucket := c.bucket(time.Now())
+		bucket.requests += value
+		c.totals.Requests += value
+		if strings.HasPrefix(tag(tags, "status"), "5") {
+			bucket.failures += value
+			c.totals.FailedRequests += value
+		}
+		c.mu.Unlock()
+	case metrics.StreamBytes:
+		c.mu.Lock()
+		c.totals.BytesStreamed += value
+		c.mu.Unlock()
+	case metrics.ExtractorAttempts:
+		c.mu.Lock()
+		count(c.extractors, tag(tags, "extractor"), tag(tags, "result") == "success", value)
+		c.mu.Unlock()
+	case metrics.CacheHits, metrics.CacheMisses:
+		c.mu.Lock()
+		count(c.caches, tag(tags, "cache"), name == metrics.CacheHits, value)
+		c.mu.Unlock()
+	}
+}
+
+// Timing implements metrics.Recorder; timings are left to the metrics sink
+func (c *Collector) Timing(string, time.Duration, ...string) {}
+
+// Gauge implements metrics.Recorder
+func (c *Collector) Gauge(name string, value float64, tags ...string) {
+	if name != metrics.StreamActive && name != metrics.StreamLimit {
+		return
+	}
+	c.mu.Lock()
+	c.gauges[name] = value
+	c.mu.Unlock()
+}
+
+// RecordError keeps err as one of the recent errors
+func (c *Collector) RecordError(path, requestID string, err error) {
+	event := ErrorEvent{
+		Time:      time.Now(),
+		Path:      path,
+		RequestID: requestID,
+		Type:      "internal",
+		Message:   err.Error(),
+		Status:    500,
+	}
+	var appErr *models.AppError
+	if errors.As(err, &appErr) {
+		event.Type = string(appErr.Type)
+		event.Message = appErr.Message
+		event.Status = appErr.HTTPStatusCode()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.totals.FailuresByType[event.Type]++
+	if len(c.errors) < maxRecentErrors {
+		c.errors = append(c.errors, event)
+		return
+	}
+	c.errors[c.next] = event
+	c.next = (c.next + 1) % maxRecentErrors
+}
+
+// RecordShortcode counts shortcode towards the unique shortcodes served
+func (c *Collector) RecordShortcode(shortcode string) {
+	if shortcode == "" {
+		return
+	}
+	c.mu.Lock()
+	c.shortcodes[shortcode] = struct{}{}
+	c.mu.Unlock()
+}
+
+// Snapshot returns the current statistics
+func (c *Collector) Snapshot() Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	current := now.Unix() / 60
+	totals := c.totals
+	totals.UniqueShortcodes = len(c.shortcodes)
+	totals.FailuresByType = make(map[string]int64, len(c.totals.FailuresByType))
+	for errorType, n := range c.totals.FailuresByType {
+		totals.FailuresByType[errorType] = n
+	}
+
+	snapshot := Snapshot{
+		Started:       c.started,
+		Uptime:        now.Sub(c.started).Round(time.Second),
+		Totals:        totals,
+		History:       make([]MinuteStats, 0, historyMinutes),
+		ActiveStreams: int64(c.gauges[metrics.StreamActive]),
+		StreamLimit:   int64(c.gauges[metrics.StreamLimit]),
+		Extractors:    sortedRatios(c.extractors),
+		Caches:        sortedRatios(c.caches),
+		RecentErrors:  make([]ErrorEvent, 0, len(c.errors)),
+	}
+
+	for minute := current - historyMinutes + 1; minute <= current; minute++ {
+		stats := MinuteStats{Start: time.Unix(minute*60, 0).UTC()}
+		if bucket := c.minutes[minute%historyMinutes]; bucket.minute == minute {
+			stats.Requests = bucket.requests
+			stats.Failures = bucket.failures
+		}
+		snapshot.History = append(snapshot.History, stats)
+	}
+	// The minute in progress plus the share of the previous one still inside the window
+	elapsed := float64(now.Unix()%60) / 60
+	previous, latest := snapshot.History[historyMinutes-2], snapshot.History[historyMinutes-1]
+	snapshot.RequestsPerMinute = latest.Requests + int64(float64(previous.Requests)*(1-elapsed))
+
+	// Newest first
+	for i := len(c.errors) - 1; i >= 0; i-- {
+		snapshot.RecentErrors = append(snapshot.RecentErrors, c.errors[(c.next+i)%len(c.errors)])
+	}
+	return snapshot
+}
+
+// bucket returns the bucket for the minute of now, clearing counts left from an hour ago
+func (c *Collector) bucket(now time.Time) *minuteBucket {
+	minute := now.Unix() / 60
+	bucket := &c.minutes[minute%historyMinutes]
+	if bucket.minute != minute {
+		*bucket = minuteBucket{minute: minute}
+	}
+	return bucket
+}
+
+// count adds value to the success or failure side of the named ratio
+func count(ratios map[string]*Ratio, name string, success bool, value int64) {
+	if name == "" {
+		return
+	}
+	ratio, ok := ratios[name]
+	if !ok {
+		ratio = &Ratio{Name: name}
+		ratios[name] = ratio
+	}
+	if success {
+		ratio.Success += value
+	} else {
+		ratio.Failure += value
+	}
+}
+
+func sortedRatios(ratios map[string]*Ratio) []Ratio {
+	sorted := make([]Ratio, 0, len(ratios))
+	for _, ratio := range ratios {
+		sorted = append(sorted, *ratio)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+// tag returns the value of key in alternating key/value tags
+func tag(tags []string, key string) string {
+	for i := 0; i+1 < len(tags); i += 2 {
+		if tags[i] == key {
+			return tags[i+1]
+		}
+	}
+	return ""
+}
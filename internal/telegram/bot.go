@@ -0,0 +1,432 @@
+// Package telegram runs Qwiklip as a Telegram bot: it long-polls the Bot API for
+// messages, extracts the Instagram links in them and replies with the media itself.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"qwiklip/internal/config"
+	"qwiklip/internal/models"
+)
+
+const (
+	maxLinksPerMessage    = 3
+	maxConcurrentMessages = 4
+	maxCaption            = 1024 // Telegram caption limit in characters
+	errorBackoff          = 5 * time.Second
+	requestTimeout        = 30 * time.Second
+	uploadTimeout         = 5 * time.Minute
+)
+
+const helpText = "Send me a link to an Instagram reel or post and I'll reply with the video."
+
+// instagramLink finds post and reel links in message text
+var instagramLink = regexp.MustCompile(`https?://(?:www\.)?instagram\.com/(?:reels?|p|tv)/[A-Za-z0-9_-]+[^\s]*`)
+
+// MediaSource extracts media information; *instagram.Client implements it
+type MediaSource interface {
+	GetMediaInfo(ctx context.Context, instagramURL string) (*models.InstagramMediaInfo, error)
+}
+
+// VideoOpener fetches media from the CDN; the server's VideoStreamer implements it
+type VideoOpener interface {
+	OpenVideo(ctx context.Context, videoURL string) (*http.Response, error)
+}
+
+// Bot answers Telegram messages containing Instagram links
+type Bot struct {
+	cfg        *config.TelegramConfig
+	endpoint   string // Bot API URL including the token
+	source     MediaSource
+	opener     VideoOpener
+	httpClient *http.Client
+	logger     *slog.Logger
+	allowed    map[int64]bool // Empty allows every chat
+}
+
+// New creates a bot; it fails when no token is configured
+func New(cfg *config.TelegramConfig, source MediaSource, opener VideoOpener, logger *slog.Logger) (*Bot, error) {
+	if cfg.BotToken == "" {
+		return nil, errors.New("TELEGRAM_BOT_TOKEN is required in bot mode")
+	}
+
+	allowed := make(map[int64]bool, len(cfg.AllowedChats))
+	for _, chat := range cfg.AllowedChats {
+		id, err := strconv.ParseInt(chat, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chat ID %s: %w", chat, err)
+		}
+		allowed[id] = true
+	}
+
+	return &Bot{
+		cfg:        cfg,
+		endpoint:   cfg.APIURL + "/bot" + cfg.BotToken,
+		source:     source,
+		opener:     opener,
+		httpClient: &http.Client{},
+		logger:     logger,
+		allowed:    allowed,
+	}, nil
+}
+
+// Telegram Bot API objects, reduced to the fields the bot uses
+type update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *message `json:"message"`
+}
+
+type message struct {
+	MessageID int64 `json:"message_id"`
+	Chat      struct {
+		ID   int64  `json:"id"`
+		Type string `json:"type"` // private, group, supergroup or channel
+	} `json:"chat"`
+	Text    string `json:"text"`
+	Caption string `json:"caption"`
+}
+
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// apiError is an error response of the Bot API
+type apiError struct {
+	Code        int
+	Description string
+	RetryAfter  time.Duration // Set on 429 responses
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("telegram API error %d: %s", e.Code, e.Description)
+}
+
+// Run polls for updates until ctx is done, then waits for replies in progress
+func (b *Bot) Run(ctx context.Context) error {
+	var me struct {
+		Username string `json:"username"`
+	}
+	meCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	err := b.call(meCtx, "getMe", struct{}{}, &me)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to reach Telegram Bot API: %w", err)
+	}
+	b.logger.Info("Telegram bot started", "username", me.Username, "allowed_chats", len(b.allowed))
+
+	sem := make(chan struct{}, maxConcurrentMessages)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	var offset int64
+	for {
+		updates, err := b.getUpdates(ctx, offset)
+		if ctx.Err() != nil {
+			b.logger.Info("Telegram bot stopping")
+			return nil
+		}
+		if err != nil {
+			wait := errorBackoff
+			var apiErr *apiError
+			if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+				wait = apiErr.RetryAfter
+			}
+			b.logger.Warn("Failed to get Telegram updates", "error", err, "retry_in", wait)
+			select {
+			case <-time.After(wait):
+				continue
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message == nil {
+				continue
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return nil
+			}
+			wg.Add(1)
+			go func(m *message) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				b.handleMessage(ctx, m)
+			}(u.Message)
+		}
+	}
+}
+
+// getUpdates long-polls for new messages after offset
+func (b *Bot) getUpdates(ctx context.Context, offset int64) ([]update, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.PollTimeout+requestTimeout)
+	defer cancel()
+
+	payload := map[string]any{
+		"offset":          offset,
+		"timeout":         int(b.cfg.PollTimeout.Seconds()),
+		"allowed_updates": []string{"message"},
+	}
+	var updates []update
+	if err := b.call(ctx, "getUpdates", payload, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// handleMessage answers one message: help for commands, the media for each Instagram link
+func (b *Bot) handleMessage(ctx context.Context, m *message) {
+	logger := b.logger.With("chat_id", m.Chat.ID, "message_id", m.MessageID)
+
+	if len(b.allowed) > 0 && !b.allowed[m.Chat.ID] {
+		logger.Debug("Ignoring message from chat that is not allowed")
+		return
+	}
+
+	text := m.Text
+	if text == "" {
+		text = m.Caption
+	}
+	if strings.HasPrefix(text, "/start") || strings.HasPrefix(text, "/help") {
+		b.sendText(ctx, m, helpText)
+		return
+	}
+
+	links := instagramLink.FindAllString(text, maxLinksPerMessage)
+	if len(links) == 0 {
+		// Stay quiet in groups, where most messages are not meant for the bot
+		if m.Chat.Type == "private" {
+			b.sendText(ctx, m, helpText)
+		}
+		return
+	}
+
+	for _, link := range links {
+		start := time.Now()
+		if err := b.replyWithMedia(ctx, m, link); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warn("Failed to answer Instagram link", "url", link, "error", err)
+			b.sendText(ctx, m, userMessage(err))
+			continue
+		}
+		logger.Info("Answered Instagram link", "url", link, "duration", time.Since(start))
+	}
+}
+
+// replyWithMedia extracts the link and uploads the video (or photo) as a reply
+func (b *Bot) replyWithMedia(ctx context.Context, m *message, link string) error {
+	info, err := b.source.GetMediaInfo(ctx, link)
+	if err != nil {
+		return err
+	}
+
+	method, field, action := "sendVideo", "video", "upload_video"
+	if info.IsImage() {
+		method, field, action = "sendPhoto", "photo", "upload_photo"
+	}
+	actionCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	b.call(actionCtx, "sendChatAction", map[string]any{"chat_id": m.Chat.ID, "action": action}, nil)
+	cancel()
+
+	uploadCtx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+
+	resp, err := b.opener.OpenVideo(uploadCtx, info.MediaURL())
+	if err != nil {
+		return models.NewNetworkError("fetching media from CDN", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ContentLength > b.cfg.MaxUploadSize {
+		text := fmt.Sprintf("This video is too large to send here (%d MB).", resp.ContentLength/(1024*1024))
+		if watchURL := b.watchURL(link); watchURL != "" {
+			text += " Watch it at " + watchURL
+		}
+		b.sendText(ctx, m, text)
+		return nil
+	}
+
+	fields := map[string]string{
+		"chat_id":          strconv.FormatInt(m.Chat.ID, 10),
+		"caption":          b.caption(info, link),
+		"reply_parameters": fmt.Sprintf(`{"message_id":%d,"allow_sending_without_reply":true}`, m.MessageID),
+	}
+	if !info.IsImage() {
+		fields["supports_streaming"] = "true"
+		if info.Width > 0 && info.Height > 0 {
+			fields["width"] = strconv.Itoa(info.Width)
+			fields["height"] = strconv.Itoa(info.Height)
+		}
+		if info.Duration > 0 {
+			fields["duration"] = strconv.Itoa(int(info.Duration + 0.5))
+		}
+	}
+	return b.upload(uploadCtx, method, fields, field, info.FileName, resp.Body)
+}
+
+// caption credits the author, links the post and quotes its caption, within Telegram's limit
+func (b *Bot) caption(info *models.InstagramMediaInfo, link string) string {
+	var parts []string
+	if info.Username != "" {
+		parts = append(parts, "@"+info.Username)
+	}
+	if watchURL := b.watchURL(link); watchURL != "" {
+		parts = append(parts, watchURL)
+	} else {
+		parts = append(parts, link)
+	}
+	if info.Caption != "" {
+		parts = append(parts, "", info.Caption)
+	}
+	caption := strings.Join(parts, "\n")
+	if runes := []rune(caption); len(runes) > maxCaption {
+		caption = string(runes[:maxCaption-1]) + "…"
+	}
+	return caption
+}
+
+// watchURL returns the proxy's watch page for the link, or "" without TELEGRAM_PUBLIC_URL
+func (b *Bot) watchURL(link string) string {
+	if b.cfg.PublicURL == "" {
+		return ""
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 {
+		return ""
+	}
+	return b.cfg.PublicURL + "/watch/" + segments[1]
+}
+
+// sendText replies to m with a plain text message; failures are only logged
+func (b *Bot) sendText(ctx context.Context, m *message, text string) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	payload := map[string]any{
+		"chat_id":          m.Chat.ID,
+		"text":             text,
+		"reply_parameters": map[string]any{"message_id": m.MessageID, "allow_sending_without_reply": true},
+	}
+	if err := b.call(ctx, "sendMessage", payload, nil); err != nil && ctx.Err() == nil {
+		b.logger.Warn("Failed to send Telegram message", "chat_id", m.Chat.ID, "error", err)
+	}
+}
+
+// userMessage turns an extraction error into a reply for the user
+func userMessage(err error) string {
+	var appErr *models.AppError
+	if errors.As(err, &appErr) {
+		return "Sorry, I couldn't get that one: " + appErr.Message
+	}
+	return "Sorry, something went wrong while fetching that link."
+}
+
+// call invokes a Bot API method with a JSON payload and decodes its result into result (unless nil)
+func (b *Bot) call(ctx context.Context, method string, payload, result any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return b.do(req, method, result)
+}
+
+// upload invokes a Bot API method as multipart/form-data with file streamed as fileField,
+// so videos are never held in memory
+func (b *Bot) upload(ctx context.Context, method string, fields map[string]string, fileField, fileName string, file io.Reader) error {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	go func() {
+		err := func() error {
+			for name, value := range fields {
+				if err := mw.WriteField(name, value); err != nil {
+					return err
+				}
+			}
+			part, err := mw.CreateFormFile(fileField, path.Base(fileName))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(part, file); err != nil {
+				return err
+			}
+			return mw.Close()
+		}()
+		pw.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint+"/"+method, pr)
+	if err != nil {
+		pr.Close()
+		return fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return b.do(req, method, nil)
+}
+
+// do sends a Bot API request and unwraps the {"ok": ..., "result": ...} envelope
+func (b *Bot) do(req *http.Request, method string, result any) error {
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		// The URL contains the token, so only the method is reported
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s request failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var envelope apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to decode %s response (status %d): %w", method, resp.StatusCode, err)
+	}
+	if !envelope.OK {
+		return &apiError{
+			Code:        envelope.ErrorCode,
+			Description: envelope.Description,
+			RetryAfter:  time.Duration(envelope.Parameters.RetryAfter) * time.Second,
+		}
+	}
+	if result != nil {
+		if err := json.Unmarshal(envelope.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+	}
+	return nil
+}
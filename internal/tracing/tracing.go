@@ -0,0 +1,203 @@
+// Package tracing records request spans and exports them to an OpenTelemetry
+// collector over OTLP/HTTP (JSON encoding).
+//
+// Spans travel in the context: the server middleware starts the root span and
+// everything downstream calls Start with the request context. When tracing is
+// disabled or a request is not sampled, Start returns a nil *Span whose methods
+// do nothing, so instrumented code needs no checks.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	mathrand "math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"qwiklip/internal/config"
+)
+
+// SpanKind describes the relationship of a span to the remote side (OTLP enum values)
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// Tracer creates spans and hands finished ones to the exporter
+type Tracer struct {
+	sampleRatio float64
+	exporter    *exporter
+}
+
+// New creates a tracer for the configured collector, or nil (tracing disabled) without an endpoint
+func New(cfg *config.TracingConfig, logger *slog.Logger) *Tracer {
+	if cfg.Endpoint == "" {
+		return nil
+	}
+	return &Tracer{
+		sampleRatio: cfg.SampleRatio,
+		exporter:    newExporter(cfg, logger),
+	}
+}
+
+// Shutdown flushes buffered spans
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.exporter.shutdown(ctx)
+}
+
+// Span is one timed operation in a trace
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     SpanKind
+	start    time.Time
+
+	mu         sync.Mutex
+	end        time.Time
+	attributes []attribute
+	errMessage string
+	ended      bool
+}
+
+type attribute struct {
+	key   string
+	value any
+}
+
+type spanContextKey struct{}
+
+// FromContext returns the active span, or nil
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// StartRoot starts a server span for an incoming request, continuing the trace named by
+// a W3C traceparent header when present. Unsampled requests get a nil span.
+func (t *Tracer) StartRoot(ctx context.Context, name, traceparent string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{tracer: t, name: name, kind: KindServer, start: time.Now()}
+	if traceID, parentID, sampled, ok := parseTraceparent(traceparent); ok {
+		// Respect the caller's sampling decision
+		if !sampled {
+			return ctx, nil
+		}
+		span.traceID, span.parentID = traceID, parentID
+	} else {
+		if mathrand.Float64() >= t.sampleRatio {
+			return ctx, nil
+		}
+		rand.Read(span.traceID[:])
+	}
+	rand.Read(span.spanID[:])
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// Start starts a child of the span in ctx. Without an active span it returns a nil span.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer:   parent.tracer,
+		traceID:  parent.traceID,
+		parentID: parent.spanID,
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+	}
+	rand.Read(span.spanID[:])
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// SetAttributes adds alternating key/value pairs, mirroring slog attributes.
+// Values may be strings, bools, integers or floats; anything else is formatted as a string.
+func (s *Span) SetAttributes(keyvals ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			continue
+		}
+		s.attributes = append(s.attributes, attribute{key: key, value: keyvals[i+1]})
+	}
+}
+
+// RecordError marks the span as failed; a nil err is ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errMessage = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export. Calls after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	s.tracer.exporter.enqueue(s)
+}
+
+// TraceID returns the hex trace ID, or "" for a nil span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// parseTraceparent decodes a W3C traceparent header: version-traceid-parentid-flags
+func parseTraceparent(header string) (traceID [16]byte, parentID [8]byte, sampled bool, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return traceID, parentID, false, false
+	}
+
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || traceID == [16]byte{} {
+		return traceID, parentID, false, false
+	}
+	if _, err := hex.Decode(parentID[:], []byte(parts[2])); err != nil || parentID == [8]byte{} {
+		return traceID, parentID, false, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return traceID, parentID, false, false
+	}
+	return traceID, parentID, flags[0]&0x01 == 1, true
+}
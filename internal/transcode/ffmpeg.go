@@ -0,0 +1,272 @@
+// Package transcode converts streamed media with an external ffmpeg binary.
+// Media is piped through ffmpeg's stdin/stdout, so nothing is written to disk.
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxStderr bounds how much ffmpeg diagnostic output is kept for error messages
+const maxStderr = 4096
+
+// AudioFormat describes an audio container ffmpeg can produce from a video
+type AudioFormat struct {
+	Extension   string
+	ContentType string
+	args        []string // ffmpeg output options
+}
+
+// AudioFormats are the formats accepted by ExtractAudio, keyed by name
+var AudioFormats = map[string]AudioFormat{
+	// Instagram audio is already AAC, so it is copied into a fragmented MP4
+	// (a regular MP4 needs a seekable output to write its index)
+	"m4a": {
+		Extension:   ".m4a",
+		ContentType: "audio/mp4",
+		args:        []string{"-c:a", "copy", "-movflags", "frag_keyframe+empty_moov", "-f", "ipod"},
+	},
+	"mp3": {
+		Extension:   ".mp3",
+		ContentType: "audio/mpeg",
+		args:        []string{"-c:a", "libmp3lame", "-q:a", "2", "-f", "mp3"},
+	},
+}
+
+// FFmpeg runs a detected ffmpeg binary
+type FFmpeg struct {
+	path    string
+	version string
+	logger  *slog.Logger
+}
+
+// Detect looks up ffmpeg by name or path and reads its version.
+// It returns nil when ffmpeg is not installed or cannot run.
+func Detect(ctx context.Context, name string, logger *slog.Logger) *FFmpeg {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		logger.Warn("ffmpeg not found, audio extraction disabled", "path", name, "error", err)
+		return nil
+	}
+
+	out, err := exec.CommandContext(ctx, path, "-hide_banner", "-version").Output()
+	if err != nil {
+		logger.Warn("ffmpeg failed to run, audio extraction disabled", "path", path, "error", err)
+		return nil
+	}
+
+	// First line: "ffmpeg version 6.1.1-3ubuntu5 Copyright (c) ..."
+	version := "unknown"
+	if fields := strings.Fields(string(out)); len(fields) >= 3 && fields[1] == "version" {
+		version = fields[2]
+	}
+
+	logger.Info("ffmpeg detected", "path", path, "version", version)
+	return &FFmpeg{path: path, version: version, logger: logger}
+}
+
+// Version returns the detected ffmpeg version string
+func (f *FFmpeg) Version() string {
+	return f.version
+}
+
+// ExtractAudio reads a video from src and writes its audio track to dst in the given format
+func (f *FFmpeg) ExtractAudio(ctx context.Context, src io.Reader, dst io.Writer, format string) error {
+	audioFormat, ok := AudioFormats[format]
+	if !ok {
+		return fmt.Errorf("unsupported audio format '%s'", format)
+	}
+
+	args := append([]string{"-vn", "-map", "0:a:0"}, audioFormat.args...)
+	return f.run(ctx, []io.Reader{src}, dst, args)
+}
+
+// GIFOptions controls GIF conversion
+type GIFOptions struct {
+	Seconds int // Length taken from the start of the video
+	FPS     int
+	Width   int // Output width in pixels; height keeps the aspect ratio
+}
+
+// ToGIF reads a video from src and writes the first opts.Seconds of it to dst as a looping GIF.
+// A palette is generated from the clip itself, which keeps colours accurate at small sizes.
+func (f *FFmpeg) ToGIF(ctx context.Context, src io.Reader, dst io.Writer, opts GIFOptions) error {
+	filter := fmt.Sprintf("fps=%d,scale=%d:-2:flags=lanczos,split[a][b];[a]palettegen=stats_mode=diff[p];[b][p]paletteuse=dither=bayer", opts.FPS, opts.Width)
+	args := []string{"-t", strconv.Itoa(opts.Seconds), "-an", "-vf", filter, "-loop", "0", "-f", "gif"}
+	return f.run(ctx, []io.Reader{src}, dst, args)
+}
+
+// TrimOptions selects a segment of a video
+type TrimOptions struct {
+	Start time.Duration
+	End   time.Duration // 0 keeps everything after Start
+	// Accurate re-encodes so the clip starts exactly at Start; stream copy (the default)
+	// is much cheaper but starts at the keyframe before Start.
+	Accurate bool
+}
+
+// Trim reads a video from src and writes the selected segment to dst as a fragmented MP4
+func (f *FFmpeg) Trim(ctx context.Context, src io.Reader, dst io.Writer, opts TrimOptions) error {
+	args := []string{"-ss", formatSeconds(opts.Start)}
+	if opts.End > 0 {
+		args = append(args, "-t", formatSeconds(opts.End-opts.Start))
+	}
+	if opts.Accurate {
+		args = append(args, "-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-c:a", "aac")
+	} else {
+		args = append(args, "-c", "copy", "-avoid_negative_ts", "make_zero")
+	}
+	args = append(args, "-movflags", "frag_keyframe+empty_moov", "-f", "mp4")
+	return f.run(ctx, []io.Reader{src}, dst, args)
+}
+
+// Mux combines separate video and audio streams (e.g. DASH representations) into a
+// fragmented MP4 without re-encoding. audio may be nil for videos without sound.
+func (f *FFmpeg) Mux(ctx context.Context, video, audio io.Reader, dst io.Writer) error {
+	inputs := []io.Reader{video}
+	args := []string{"-map", "0:v:0"}
+	if audio != nil {
+		inputs = append(inputs, audio)
+		args = append(args, "-map", "1:a:0")
+	}
+	args = append(args, "-c", "copy", "-movflags", "frag_keyframe+empty_moov", "-f", "mp4")
+	return f.run(ctx, inputs, dst, args)
+}
+
+// Tags are metadata tags written into an MP4 by Tag; empty ones are left out
+type Tags struct {
+	Title   string
+	Artist  string
+	Comment string
+	Date    string
+}
+
+// Tag reads a video from src and writes it to dst as a fragmented MP4 carrying tags,
+// without re-encoding. Tags of the source are kept unless tags replaces them.
+func (f *FFmpeg) Tag(ctx context.Context, src io.Reader, dst io.Writer, tags Tags) error {
+	args := []string{"-map", "0", "-c", "copy"}
+	for _, tag := range []struct{ key, value string }{
+		{"title", tags.Title},
+		{"artist", tags.Artist},
+		{"comment", tags.Comment},
+		{"date", tags.Date},
+	} {
+		if tag.value != "" {
+			args = append(args, "-metadata", tag.key+"="+tag.value)
+		}
+	}
+	args = append(args, "-movflags", "frag_keyframe+empty_moov", "-f", "mp4")
+	return f.run(ctx, []io.Reader{src}, dst, args)
+}
+
+// HLS playlist and segment file names written by SegmentHLS
+const (
+	HLSPlaylist      = "index.m3u8"
+	hlsSegmentFormat = "segment%03d.ts"
+)
+
+// SegmentHLS reads a video from src and repackages it, without re-encoding, into an HLS
+// VOD playlist (HLSPlaylist) and MPEG-TS segments of about segmentSeconds in dir.
+// Segments can only start on keyframes, so their length varies with the source.
+func (f *FFmpeg) SegmentHLS(ctx context.Context, src io.Reader, dir string, segmentSeconds int) error {
+	args := []string{
+		"-c", "copy",
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(segmentSeconds),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(dir, hlsSegmentFormat),
+		filepath.Join(dir, HLSPlaylist),
+	}
+	return f.run(ctx, []io.Reader{src}, nil, args)
+}
+
+// formatSeconds renders d as fractional seconds, the format ffmpeg time options accept
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// run pipes inputs through ffmpeg with the given output options and writes the result to dst.
+// The first input is fed on stdin, further ones on extra pipes (fd 3, 4, ...).
+// With a nil dst the output options must end with the output file.
+// The process is killed when ctx is done.
+func (f *FFmpeg) run(ctx context.Context, inputs []io.Reader, dst io.Writer, outputArgs []string) error {
+	args := []string{"-hide_banner", "-loglevel", "error", "-i", "pipe:0"}
+	for i := 1; i < len(inputs); i++ {
+		args = append(args, "-i", fmt.Sprintf("pipe:%d", 2+i))
+	}
+	args = append(args, outputArgs...)
+	if dst != nil {
+		args = append(args, "pipe:1")
+	}
+
+	stderr := &limitedBuffer{max: maxStderr}
+	cmd := exec.CommandContext(ctx, f.path, args...)
+	cmd.Stdin = inputs[0]
+	cmd.Stdout = dst
+	cmd.Stderr = stderr
+
+	// exec only copies stdin itself; extra inputs get an os.Pipe each
+	var writers []*os.File
+	defer func() {
+		for _, pw := range writers {
+			pw.Close()
+		}
+	}()
+	for range inputs[1:] {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			return fmt.Errorf("failed to create ffmpeg input pipe: %w", err)
+		}
+		defer pr.Close()
+		cmd.ExtraFiles = append(cmd.ExtraFiles, pr)
+		writers = append(writers, pw)
+	}
+
+	f.logger.Debug("Running ffmpeg", "args", strings.Join(args, " "))
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run ffmpeg: %w", err)
+	}
+	for i, pw := range writers {
+		// A failed write (ffmpeg exited) ends the copy; the caller closes the source
+		go func(pw *os.File, src io.Reader) {
+			io.Copy(pw, src)
+			pw.Close()
+		}(pw, inputs[i+1])
+	}
+	writers = nil
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("ffmpeg exited with status %d: %s", exitErr.ExitCode(), strings.TrimSpace(stderr.String()))
+		}
+		return fmt.Errorf("failed to run ffmpeg: %w", err)
+	}
+	return nil
+}
+
+// limitedBuffer keeps the first max bytes written and discards the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
@@ -0,0 +1,56 @@
+package videocache
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"qwiklip/internal/config"
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/webhook"
+)
+
+// Supported video cache backends
+const (
+	BackendDisk = "disk"
+	BackendS3   = "s3"
+)
+
+const (
+	fileExt = ".mp4"
+	tempExt = ".tmp"
+)
+
+// validKey restricts cache keys to shortcode-like names that are safe as file and object names
+var validKey = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Store holds complete cached videos keyed by shortcode
+type Store interface {
+	// Serve writes the cached video for key to w honouring Range; it returns false on a miss
+	Serve(w http.ResponseWriter, r *http.Request, key string) bool
+	// NewWriter starts caching a video; it only becomes visible once the writer commits
+	NewWriter(key string) (*Writer, error)
+	// Purge removes a cached video
+	Purge(key string)
+}
+
+// committer finalizes a completed temp file into a store
+type committer interface {
+	commit(key, tempPath string, size int64) error
+}
+
+// New creates the configured video store, or returns nil when the cache is disabled
+func New(cfg *config.VideoCacheConfig, recorder metrics.Recorder, notifier webhook.Notifier, logger *slog.Logger) (Store, error) {
+	switch cfg.Backend {
+	case BackendDisk:
+		if cfg.Dir == "" {
+			return nil, nil
+		}
+		return NewDisk(cfg, recorder, notifier, logger)
+	case BackendS3:
+		return NewS3(&cfg.S3, recorder, logger)
+	default:
+		return nil, fmt.Errorf("unknown video cache backend: %s", cfg.Backend)
+	}
+}
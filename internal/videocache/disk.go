@@ -0,0 +1,247 @@
+package videocache
+
+import (
+	"container/list"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"qwiklip/internal/config"
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/webhook"
+)
+
+// Disk stores complete video files on disk keyed by shortcode, evicting least recently used files
+type Disk struct {
+	dir      string
+	maxSize  int64
+	metrics  metrics.Recorder
+	notifier webhook.Notifier
+	logger   *slog.Logger
+
+	mu    sync.Mutex
+	size  int64
+	lru   *list.List               // Front is most recently used
+	index map[string]*list.Element // key -> element holding *entry
+}
+
+// entry is a single cached video file
+type entry struct {
+	key  string
+	size int64
+}
+
+// NewDisk creates a disk cache, rebuilding its index from files already in the directory
+func NewDisk(cfg *config.VideoCacheConfig, recorder metrics.Recorder, notifier webhook.Notifier, logger *slog.Logger) (*Disk, error) {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create video cache directory: %w", err)
+	}
+
+	c := &Disk{
+		dir:      cfg.Dir,
+		maxSize:  cfg.MaxSize,
+		metrics:  recorder,
+		notifier: notifier,
+		logger:   logger,
+		lru:      list.New(),
+		index:    make(map[string]*list.Element),
+	}
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Video cache ready",
+		"dir", c.dir,
+		"files", c.lru.Len(),
+		"size_bytes", c.size,
+		"max_size_bytes", c.maxSize)
+	return c, nil
+}
+
+// load indexes existing cache files, oldest modification time first, and removes stale temp files
+func (c *Disk) load() error {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read video cache directory: %w", err)
+	}
+
+	type found struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []found
+
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if strings.HasSuffix(name, tempExt) {
+			os.Remove(filepath.Join(c.dir, name)) // Interrupted write from a previous run
+			continue
+		}
+		if dirEntry.IsDir() || !strings.HasSuffix(name, fileExt) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, found{strings.TrimSuffix(name, fileExt), info.Size(), info.ModTime()})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		c.index[f.key] = c.lru.PushFront(&entry{key: f.key, size: f.size})
+		c.size += f.size
+	}
+
+	c.mu.Lock()
+	c.evictLocked()
+	c.mu.Unlock()
+	return nil
+}
+
+// Serve writes the cached video for key with full range support
+func (c *Disk) Serve(w http.ResponseWriter, r *http.Request, key string) bool {
+	file, info, ok := c.open(key)
+	if !ok {
+		return false
+	}
+	defer file.Close()
+
+	c.logger.Info("Serving video from disk cache", "key", key, "size_bytes", info.Size())
+	w.Header().Set("Content-Type", "video/mp4")
+	// Committed files never change, so size and mtime identify the content; a strong ETag
+	// lets ServeContent honour If-None-Match and If-Range as well as the date validators
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano()))
+	http.ServeContent(w, r, key+fileExt, info.ModTime(), file)
+	return true
+}
+
+// open returns the cached file for key and marks it as recently used.
+// The caller must close the returned file.
+func (c *Disk) open(key string) (*os.File, os.FileInfo, bool) {
+	if !validKey.MatchString(key) {
+		return nil, nil, false
+	}
+
+	c.mu.Lock()
+	elem, ok := c.index[key]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		c.metrics.Count(metrics.CacheMisses, 1, "cache", "video")
+		return nil, nil, false
+	}
+
+	path := c.path(key)
+	file, err := os.Open(path)
+	if err != nil {
+		c.logger.Warn("Cached video missing on disk, dropping entry", "key", key, "error", err)
+		c.remove(key)
+		c.metrics.Count(metrics.CacheMisses, 1, "cache", "video")
+		return nil, nil, false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		c.remove(key)
+		c.metrics.Count(metrics.CacheMisses, 1, "cache", "video")
+		return nil, nil, false
+	}
+
+	// Persist recency across restarts
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	c.metrics.Count(metrics.CacheHits, 1, "cache", "video")
+	return file, info, true
+}
+
+// NewWriter starts writing a video for key into a temporary file in the cache directory
+func (c *Disk) NewWriter(key string) (*Writer, error) {
+	return newWriter(c, c.dir, key)
+}
+
+// Purge removes a cached video
+func (c *Disk) Purge(key string) {
+	c.remove(key)
+}
+
+// commit moves a completed temp file into place and accounts for its size
+func (c *Disk) commit(key, tempPath string, size int64) error {
+	if c.maxSize > 0 && size > c.maxSize {
+		os.Remove(tempPath)
+		return fmt.Errorf("video larger than cache (%d > %d bytes)", size, c.maxSize)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.Rename(tempPath, c.path(key)); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to store cached video: %w", err)
+	}
+
+	// Replacing an existing entry (e.g. two first clients racing)
+	if elem, ok := c.index[key]; ok {
+		c.size -= elem.Value.(*entry).size
+		c.lru.Remove(elem)
+	}
+
+	c.index[key] = c.lru.PushFront(&entry{key: key, size: size})
+	c.size += size
+	c.evictLocked()
+
+	c.logger.Info("Video cached", "key", key, "size_bytes", size, "cache_size_bytes", c.size)
+	return nil
+}
+
+// remove deletes a key from the index and disk
+func (c *Disk) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.index[key]; ok {
+		c.size -= elem.Value.(*entry).size
+		c.lru.Remove(elem)
+		delete(c.index, key)
+	}
+	os.Remove(c.path(key))
+}
+
+// evictLocked removes least recently used files until the cache fits within maxSize
+func (c *Disk) evictLocked() {
+	for c.maxSize > 0 && c.size > c.maxSize {
+		elem := c.lru.Back()
+		if elem == nil {
+			return
+		}
+		victim := elem.Value.(*entry)
+		c.lru.Remove(elem)
+		delete(c.index, victim.key)
+		c.size -= victim.size
+
+		// Files still being served stay readable until closed
+		if err := os.Remove(c.path(victim.key)); err != nil && !os.IsNotExist(err) {
+			c.logger.Warn("Failed to evict cached video", "key", victim.key, "error", err)
+		}
+		c.metrics.Count(metrics.CacheEvictions, 1, "cache", "video")
+		c.notifier.Notify(webhook.CacheEvicted, "cache", "video", "key", victim.key, "size_bytes", victim.size)
+		c.logger.Debug("Evicted cached video", "key", victim.key, "size_bytes", victim.size)
+	}
+}
+
+// path returns the on-disk location for key
+func (c *Disk) path(key string) string {
+	return filepath.Join(c.dir, key+fileExt)
+}
@@ -0,0 +1,299 @@
+package videocache
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"qwiklip/internal/config"
+	"qwiklip/internal/metrics"
+)
+
+const (
+	s3Service         = "s3"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3UploadTimeout   = 10 * time.Minute
+)
+
+// s3PassthroughHeaders are copied from the client request to the object GET
+// so S3 handles ranges and conditional requests itself
+var s3PassthroughHeaders = []string{"Range", "If-Range", "If-None-Match", "If-Modified-Since"}
+
+// s3ResponseHeaders are copied from the object GET back to the client
+var s3ResponseHeaders = []string{"Content-Length", "Content-Range", "Accept-Ranges", "ETag", "Last-Modified"}
+
+// S3 stores cached videos in an S3-compatible bucket (AWS S3, MinIO, R2, ...).
+// Size limits and expiry are left to the bucket's lifecycle rules, so several
+// stateless instances can share one bucket.
+type S3 struct {
+	endpoint   *url.URL
+	region     string
+	bucket     string
+	prefix     string
+	accessKey  string
+	secretKey  string
+	pathStyle  bool
+	httpClient *http.Client
+	metrics    metrics.Recorder
+	logger     *slog.Logger
+}
+
+// NewS3 creates an S3-backed video store
+func NewS3(cfg *config.S3Config, recorder metrics.Recorder, logger *slog.Logger) (*S3, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint: %s", cfg.Endpoint)
+	}
+
+	store := &S3{
+		endpoint:  endpoint,
+		region:    cfg.Region,
+		bucket:    cfg.Bucket,
+		prefix:    strings.Trim(cfg.Prefix, "/"),
+		accessKey: cfg.AccessKeyID,
+		secretKey: cfg.SecretAccessKey,
+		pathStyle: cfg.PathStyle,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				DialContext:           (&net.Dialer{Timeout: 10 * time.Second}).DialContext,
+				ResponseHeaderTimeout: 15 * time.Second,
+				MaxIdleConnsPerHost:   16,
+			},
+		},
+		metrics: recorder,
+		logger:  logger,
+	}
+
+	logger.Info("Video cache ready", "backend", BackendS3, "endpoint", endpoint.Host, "bucket", cfg.Bucket)
+	return store, nil
+}
+
+// Serve proxies the cached object to the client, forwarding range and validator headers
+func (s *S3) Serve(w http.ResponseWriter, r *http.Request, key string) bool {
+	if !validKey.MatchString(key) {
+		return false
+	}
+
+	// HEAD requests only need the object's headers
+	method := http.MethodGet
+	if r.Method == http.MethodHead {
+		method = http.MethodHead
+	}
+	req, err := s.newRequest(r.Context(), method, key, nil)
+	if err != nil {
+		s.logger.Warn("Failed to build S3 request", "key", key, "error", err)
+		return false
+	}
+	for _, header := range s3PassthroughHeaders {
+		if value := r.Header.Get(header); value != "" {
+			req.Header.Set(header, value)
+		}
+	}
+	s.sign(req)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.logger.Warn("S3 cache lookup failed", "key", key, "error", err)
+		s.metrics.Count(metrics.CacheMisses, 1, "cache", "video")
+		return false
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent, http.StatusNotModified, http.StatusRequestedRangeNotSatisfiable:
+	default:
+		if resp.StatusCode != http.StatusNotFound {
+			s.logger.Warn("S3 cache returned unexpected status", "key", key, "status", resp.StatusCode)
+		}
+		s.metrics.Count(metrics.CacheMisses, 1, "cache", "video")
+		return false
+	}
+
+	s.metrics.Count(metrics.CacheHits, 1, "cache", "video")
+	s.logger.Info("Serving video from S3 cache", "key", key, "status", resp.StatusCode)
+
+	w.Header().Set("Content-Type", "video/mp4")
+	for _, header := range s3ResponseHeaders {
+		if value := resp.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		s.logger.Warn("Client disconnected while serving from S3 cache", "key", key, "error", err)
+	}
+	return true
+}
+
+// NewWriter buffers the video in a local temp file until it is uploaded on commit
+func (s *S3) NewWriter(key string) (*Writer, error) {
+	return newWriter(s, os.TempDir(), key)
+}
+
+// Purge deletes the cached object
+func (s *S3) Purge(key string) {
+	if !validKey.MatchString(key) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return
+	}
+	s.sign(req)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.logger.Warn("Failed to purge S3 cached video", "key", key, "error", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// commit uploads the completed temp file in the background so the request can finish
+func (s *S3) commit(key, tempPath string, size int64) error {
+	go func() {
+		defer os.Remove(tempPath)
+		if err := s.upload(key, tempPath, size); err != nil {
+			s.logger.Warn("Failed to upload video to S3 cache", "key", key, "error", err)
+			return
+		}
+		s.logger.Info("Video cached", "backend", BackendS3, "key", key, "size_bytes", size)
+	}()
+	return nil
+}
+
+// upload PUTs a local file as the object for key
+func (s *S3) upload(key, path string, size int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3UploadTimeout)
+	defer cancel()
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "video/mp4")
+	s.sign(req)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("S3 responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// newRequest builds an unsigned request for the object holding key
+func (s *S3) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	objectPath := key + fileExt
+	if s.prefix != "" {
+		objectPath = s.prefix + "/" + objectPath
+	}
+
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path = "/" + s.bucket + "/" + objectPath
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = "/" + objectPath
+	}
+
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// sign adds AWS Signature Version 4 headers to req.
+// Payloads are sent unsigned, which S3 and MinIO accept for streaming uploads.
+func (s *S3) sign(req *http.Request) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+
+	// Sign host and all x-amz-* headers
+	headerValues := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") {
+			headerValues[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	headerNames := make([]string, 0, len(headerValues))
+	for name := range headerValues {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + headerValues[name] + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+
+	scope := strings.Join([]string{dateStamp, s.region, s3Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.secretKey), dateStamp)
+	signingKey = hmacSHA256(signingKey, s.region)
+	signingKey = hmacSHA256(signingKey, s3Service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
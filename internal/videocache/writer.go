@@ -0,0 +1,80 @@
+package videocache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Writer tees video bytes into a temporary cache file.
+// Write never fails so it can sit behind io.TeeReader without disturbing the client stream;
+// any disk error is remembered and surfaces from Commit instead.
+type Writer struct {
+	store   committer
+	key     string
+	file    *os.File
+	written int64
+	err     error
+}
+
+// newWriter creates a writer backed by a temp file in dir
+func newWriter(store committer, dir, key string) (*Writer, error) {
+	if !validKey.MatchString(key) {
+		return nil, fmt.Errorf("invalid video cache key: %q", key)
+	}
+
+	file, err := os.CreateTemp(dir, key+"-*"+tempExt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create video cache file: %w", err)
+	}
+
+	return &Writer{store: store, key: key, file: file}, nil
+}
+
+// Write appends p to the temporary file
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err == nil {
+		n, err := w.file.Write(p)
+		w.written += int64(n)
+		w.err = err
+	}
+	return len(p), nil
+}
+
+// Written returns the number of bytes stored so far
+func (w *Writer) Written() int64 {
+	return w.written
+}
+
+// ServeContent answers r from the bytes written so far, honouring Range, before the video
+// is committed: a store may not hold it right after Commit (S3 uploads in the background)
+// or may refuse it (larger than the disk cache)
+func (w *Writer) ServeContent(rw http.ResponseWriter, r *http.Request) error {
+	if w.err != nil {
+		return w.err
+	}
+	rw.Header().Set("Content-Type", "video/mp4")
+	http.ServeContent(rw, r, w.key+fileExt, time.Time{}, io.NewSectionReader(w.file, 0, w.written))
+	return nil
+}
+
+// Commit makes the cached video available for subsequent requests
+func (w *Writer) Commit() error {
+	if w.err != nil {
+		w.Abort()
+		return w.err
+	}
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.file.Name())
+		return err
+	}
+	return w.store.commit(w.key, w.file.Name(), w.written)
+}
+
+// Abort discards the partially written video
+func (w *Writer) Abort() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"qwiklip/internal/config"
+)
+
+const (
+	queueSize    = 256
+	retryBackoff = time.Second // Doubled after each failed attempt
+)
+
+// Dispatcher POSTs events to every configured URL, signing them with HMAC-SHA256 and
+// retrying failed deliveries with exponential backoff. Events are queued and sent in
+// the background; when the queue is full new events are dropped.
+type Dispatcher struct {
+	urls       []string
+	secret     []byte
+	events     []string
+	maxRetries int
+	httpClient *http.Client
+	logger     *slog.Logger
+
+	queue chan Event
+	done  chan struct{}
+	once  sync.Once
+	wg    sync.WaitGroup
+}
+
+// Event is the JSON body of a webhook delivery
+type Event struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`
+	Timestamp time.Time      `json:"timestamp"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// NewDispatcher creates a dispatcher and starts its delivery worker
+func NewDispatcher(cfg *config.WebhookConfig, logger *slog.Logger) *Dispatcher {
+	d := &Dispatcher{
+		urls:       cfg.URLs,
+		secret:     []byte(cfg.Secret),
+		events:     cfg.Events,
+		maxRetries: cfg.MaxRetries,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		logger:     logger,
+		queue:      make(chan Event, queueSize),
+		done:       make(chan struct{}),
+	}
+
+	d.wg.Add(1)
+	go d.run()
+	return d
+}
+
+// Notify queues an event unless its type is filtered out by WEBHOOK_EVENTS
+func (d *Dispatcher) Notify(event string, data ...any) {
+	if len(d.events) > 0 && !slices.Contains(d.events, event) {
+		return
+	}
+
+	var id [16]byte
+	rand.Read(id[:])
+	e := Event{
+		ID:        hex.EncodeToString(id[:]),
+		Type:      event,
+		Timestamp: time.Now().UTC(),
+	}
+	if len(data) > 1 {
+		e.Data = make(map[string]any, len(data)/2)
+		for i := 0; i+1 < len(data); i += 2 {
+			if key, ok := data[i].(string); ok {
+				e.Data[key] = data[i+1]
+			}
+		}
+	}
+
+	select {
+	case d.queue <- e:
+	default:
+		d.logger.Warn("Webhook queue full, dropping event", "event", event, "event_id", e.ID)
+	}
+}
+
+// Close delivers queued events, waiting at most until ctx is done
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.once.Do(func() { close(d.done) })
+
+	finished := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer d.wg.Done()
+	for {
+		select {
+		case e := <-d.queue:
+			d.deliver(e)
+		case <-d.done:
+			for {
+				select {
+				case e := <-d.queue:
+					d.deliver(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver sends e to every URL, each with its own retries
+func (d *Dispatcher) deliver(e Event) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		d.logger.Warn("Failed to encode webhook event", "event", e.Type, "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, url := range d.urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliverTo(url, e, body)
+		}()
+	}
+	wg.Wait()
+}
+
+// deliverTo posts body to url until it is accepted or the retries are used up.
+// Client errors other than 408 and 429 are not retried, since resending will not help.
+func (d *Dispatcher) deliverTo(url string, e Event, body []byte) {
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		status, err := d.post(url, e, body)
+		if err == nil && status < 300 {
+			return
+		}
+
+		retryable := err != nil || status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+		if !retryable || attempt >= d.maxRetries {
+			d.logger.Warn("Webhook delivery failed",
+				"url", url, "event", e.Type, "event_id", e.ID,
+				"attempts", attempt+1, "status", status, "error", err)
+			return
+		}
+
+		// Shutting down shortens the wait but still makes the remaining attempts
+		select {
+		case <-time.After(backoff):
+		case <-d.done:
+		}
+		backoff *= 2
+	}
+}
+
+// post sends one delivery attempt and returns the response status
+func (d *Dispatcher) post(url string, e Event, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(e.Timestamp.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "qwiklip-webhook")
+	req.Header.Set("X-Qwiklip-Event", e.Type)
+	req.Header.Set("X-Qwiklip-Delivery", e.ID)
+	req.Header.Set("X-Qwiklip-Timestamp", timestamp)
+	if len(d.secret) > 0 {
+		req.Header.Set("X-Qwiklip-Signature", "sha256="+Sign(d.secret, timestamp, body))
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "{timestamp}.{body}". Receivers recompute it
+// with the shared secret and the X-Qwiklip-Timestamp header to verify a delivery.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,41 @@
+// Package webhook notifies external systems of proxy activity by POSTing JSON
+// events to configured URLs. Without URLs the no-op notifier is used.
+package webhook
+
+import (
+	"context"
+	"log/slog"
+
+	"qwiklip/internal/config"
+)
+
+// Event types, matching config.WebhookEvents
+const (
+	ExtractionSucceeded = "extraction.succeeded"
+	ExtractionFailed    = "extraction.failed"
+	RateLimited         = "rate_limited"
+	CacheEvicted        = "cache.evicted"
+)
+
+// Notifier delivers events to webhooks.
+// Data is passed as alternating key/value pairs, mirroring slog attributes.
+type Notifier interface {
+	// Notify queues an event without blocking; delivery happens in the background
+	Notify(event string, data ...any)
+	// Close delivers queued events, waiting at most until ctx is done
+	Close(ctx context.Context) error
+}
+
+// New creates a notifier for the configured URLs, falling back to a no-op notifier
+func New(cfg *config.WebhookConfig, logger *slog.Logger) Notifier {
+	if len(cfg.URLs) == 0 {
+		return Nop{}
+	}
+	return NewDispatcher(cfg, logger)
+}
+
+// Nop is a notifier that discards all events
+type Nop struct{}
+
+func (Nop) Notify(string, ...any)       {}
+func (Nop) Close(context.Context) error { return nil }
@@ -0,0 +1,321 @@
+package instagram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"qwiklip/internal/cache"
+	"qwiklip/internal/config"
+	"qwiklip/internal/errorreport"
+	internal "qwiklip/internal/instagram"
+	"qwiklip/internal/metrics"
+	"qwiklip/internal/server"
+	"qwiklip/internal/webhook"
+)
+
+// Default option values
+const (
+	DefaultTimeout             = 30 * time.Second
+	DefaultPrefetchSize        = 2 * 1024 * 1024 // 2MB
+	DefaultWriteIdleTimeout    = 30 * time.Second
+	DefaultAccountCooldown     = 15 * time.Minute
+	DefaultProxyCooldown       = time.Minute
+	DefaultQueueTimeout        = 10 * time.Second
+	DefaultRateLimitCooldown   = time.Minute
+	DefaultRequestBurst        = 5
+	DefaultRetryBackoff        = 500 * time.Millisecond
+	DefaultRetryDeadline       = 20 * time.Second
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 16
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+	DefaultAdaptiveExplore     = 0.1
+)
+
+// Options configures a Client. The zero value is ready to use.
+type Options struct {
+	// Timeout bounds each Instagram page fetch (default 30s)
+	Timeout time.Duration
+	// UserAgent is sent to the CDN when streaming (default: a desktop Chrome agent)
+	UserAgent string
+	// Extractors lists the built-in strategies to try, in order (default: all)
+	Extractors []string
+	// GraphQLDocID overrides the doc_id of the web GraphQL post query, which Meta rotates
+	GraphQLDocID string
+	// AdaptiveExtractors tries the recently most successful strategy first instead of the Extractors order
+	AdaptiveExtractors bool
+	// AdaptiveExplore is how often another strategy is tried first when adaptive (default 0.1)
+	AdaptiveExplore float64
+	// Headers are set on requests to instagram.com, replacing the client's own; Cookie adds to the session cookies
+	Headers map[string]string
+	// CDNHeaders are set on media requests to the Instagram CDN
+	CDNHeaders map[string]string
+	// SessionID is the sessionid cookie of a logged-in account, needed for stories
+	SessionID string
+	// CookiesFile is a Netscape cookies.txt to load; refreshed cookies are written back on Close
+	CookiesFile string
+	// SessionIDs and CookiesFiles add more accounts; extractions rotate across all of them
+	SessionIDs   []string
+	CookiesFiles []string
+	// ProxyURL routes Instagram and CDN traffic through an http(s):// or socks5(h):// proxy
+	ProxyURL string
+	// ProxyURLs adds "<url> [weight]" entries to the proxy pool; requests rotate across all proxies
+	ProxyURLs []string
+	// ProxyStrategy is "round-robin" (default) or "weighted"
+	ProxyStrategy string
+	// ProxyCooldown benches a failing or rate-limited proxy (default 1m)
+	ProxyCooldown time.Duration
+	// MaxConcurrent caps requests in flight to Instagram (0 = unlimited, the default).
+	// Up to QueueSize callers wait at most QueueTimeout (default 10s) for a slot.
+	MaxConcurrent int
+	QueueSize     int
+	QueueTimeout  time.Duration
+	// RateLimitCooldown pauses anonymous requests after a 429 without Retry-After (default 1m);
+	// until it passes they fail fast, and requests with a WithRateLimitWait context wait
+	RateLimitCooldown time.Duration
+	// RequestsPerMinute spaces requests to Instagram to a budget per account and proxy (0 = none,
+	// the default), with up to RequestBurst (default 5) back to back. Requests over budget wait
+	// at most QueueTimeout for their turn and fail with an overloaded error otherwise.
+	RequestsPerMinute int
+	RequestBurst      int
+	// MaxRetries repeats a page fetch after network errors or Instagram 5xx responses (default 0).
+	// Retries wait RetryBackoff (default 500ms), doubling with jitter, and none starts after RetryDeadline (default 20s).
+	MaxRetries    int
+	RetryBackoff  time.Duration
+	RetryDeadline time.Duration
+	// MaxIdleConns (default 100) and MaxIdleConnsPerHost (default 16) keep Instagram and CDN
+	// connections warm for IdleConnTimeout (default 90s) instead of re-handshaking per request
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	// TLSHandshakeTimeout bounds each TLS handshake (default 10s)
+	TLSHandshakeTimeout time.Duration
+	// DisableHTTP2 keeps connections on HTTP/1.1
+	DisableHTTP2 bool
+	// AccountCooldown benches an account after a rate limit or challenge, doubling per strike (default 15m)
+	AccountCooldown time.Duration
+	// CacheTTL keeps extracted media info in memory; zero disables caching
+	CacheTTL time.Duration
+	// CacheNegativeTTL remembers posts that are missing, expired or behind the login wall,
+	// so repeated lookups fail without contacting Instagram; zero disables it. It needs CacheTTL.
+	CacheNegativeTTL time.Duration
+	// CacheMaxEntries bounds the in-memory cache (default 1000)
+	CacheMaxEntries int
+	// PrefetchSize is read from the CDN before response headers are sent (default 2MB, negative disables)
+	PrefetchSize int64
+	// WriteIdleTimeout cuts off StreamVideo clients that stop reading (default 30s)
+	WriteIdleTimeout time.Duration
+	// Logger receives diagnostic logs (default: discarded)
+	Logger *slog.Logger
+	// Debug enables verbose extraction logging
+	Debug bool
+}
+
+// Client extracts Instagram media and streams it. It is safe for concurrent use.
+type Client struct {
+	client   *internal.Client
+	streamer *server.VideoStreamer
+}
+
+// New creates a Client from opts
+func New(opts Options) (*Client, error) {
+	if opts.Timeout < 0 || opts.WriteIdleTimeout < 0 {
+		return nil, fmt.Errorf("timeouts must not be negative")
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = internal.DefaultUserAgent
+	}
+	if opts.CacheMaxEntries == 0 {
+		opts.CacheMaxEntries = 1000
+	}
+	switch {
+	case opts.PrefetchSize == 0:
+		opts.PrefetchSize = DefaultPrefetchSize
+	case opts.PrefetchSize < 0:
+		opts.PrefetchSize = 0
+	}
+	if opts.AccountCooldown <= 0 {
+		opts.AccountCooldown = DefaultAccountCooldown
+	}
+	if opts.ProxyStrategy == "" {
+		opts.ProxyStrategy = "round-robin"
+	}
+	if opts.ProxyCooldown <= 0 {
+		opts.ProxyCooldown = DefaultProxyCooldown
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultRetryBackoff
+	}
+	if opts.RetryDeadline <= 0 {
+		opts.RetryDeadline = DefaultRetryDeadline
+	}
+	if opts.QueueTimeout <= 0 {
+		opts.QueueTimeout = DefaultQueueTimeout
+	}
+	if opts.RateLimitCooldown <= 0 {
+		opts.RateLimitCooldown = DefaultRateLimitCooldown
+	}
+	if opts.RequestBurst <= 0 {
+		opts.RequestBurst = DefaultRequestBurst
+	}
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = DefaultMaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost <= 0 {
+		opts.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout <= 0 {
+		opts.IdleConnTimeout = DefaultIdleConnTimeout
+	}
+	if opts.TLSHandshakeTimeout <= 0 {
+		opts.TLSHandshakeTimeout = DefaultTLSHandshakeTimeout
+	}
+	if opts.AdaptiveExplore == 0 {
+		opts.AdaptiveExplore = DefaultAdaptiveExplore
+	}
+	if opts.WriteIdleTimeout == 0 {
+		opts.WriteIdleTimeout = DefaultWriteIdleTimeout
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.New(slog.DiscardHandler)
+	}
+
+	var mediaCache cache.Cache = cache.Nop{}
+	if opts.CacheTTL > 0 {
+		mediaCache = cache.NewMemory(opts.CacheTTL, opts.CacheNegativeTTL, opts.CacheMaxEntries)
+	}
+
+	client, err := internal.NewClient(&config.InstagramConfig{
+		Timeout:             opts.Timeout,
+		UserAgent:           opts.UserAgent,
+		Debug:               opts.Debug,
+		Extractors:          opts.Extractors,
+		SessionID:           opts.SessionID,
+		CookiesFile:         opts.CookiesFile,
+		SessionIDs:          opts.SessionIDs,
+		CookiesFiles:        opts.CookiesFiles,
+		AccountCooldown:     opts.AccountCooldown,
+		ProxyURL:            opts.ProxyURL,
+		ProxyURLs:           opts.ProxyURLs,
+		ProxyStrategy:       opts.ProxyStrategy,
+		ProxyCooldown:       opts.ProxyCooldown,
+		MaxConcurrent:       opts.MaxConcurrent,
+		QueueSize:           opts.QueueSize,
+		QueueTimeout:        opts.QueueTimeout,
+		RateLimitCooldown:   opts.RateLimitCooldown,
+		RequestsPerMinute:   opts.RequestsPerMinute,
+		RequestBurst:        opts.RequestBurst,
+		MaxRetries:          opts.MaxRetries,
+		RetryBackoff:        opts.RetryBackoff,
+		RetryDeadline:       opts.RetryDeadline,
+		MaxIdleConns:        opts.MaxIdleConns,
+		MaxIdleConnsPerHost: opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:     opts.IdleConnTimeout,
+		TLSHandshakeTimeout: opts.TLSHandshakeTimeout,
+		HTTP2:               !opts.DisableHTTP2,
+		GraphQLDocID:        opts.GraphQLDocID,
+		AdaptiveOrder:       opts.AdaptiveExtractors,
+		AdaptiveExplore:     opts.AdaptiveExplore,
+		ExtraHeaders:        opts.Headers,
+		CDNHeaders:          opts.CDNHeaders,
+	}, mediaCache, opts.Logger, metrics.Nop{}, errorreport.Nop{}, webhook.Nop{})
+	if err != nil {
+		return nil, err
+	}
+
+	streamer := server.NewVideoStreamer(client, &config.StreamConfig{
+		PrefetchSize:     opts.PrefetchSize,
+		WriteIdleTimeout: opts.WriteIdleTimeout,
+	}, nil, metrics.Nop{}, opts.Logger)
+
+	return &Client{client: client, streamer: streamer}, nil
+}
+
+// Close saves refreshed cookies when Options.CookiesFile is set
+func (c *Client) Close() error {
+	return c.client.Close()
+}
+
+// GetMediaInfo extracts media information from an Instagram post or reel URL.
+// Cancelling ctx aborts the outbound requests.
+func (c *Client) GetMediaInfo(ctx context.Context, instagramURL string) (*MediaInfo, error) {
+	return c.client.GetMediaInfo(ctx, instagramURL)
+}
+
+// WithRateLimitWait marks ctx as background work: while Instagram rate limits anonymous requests,
+// GetMediaInfo waits for the window to pass and retries after 429s instead of failing fast
+func WithRateLimitWait(ctx context.Context) context.Context {
+	return internal.WithRateLimitWait(ctx)
+}
+
+// GetStory extracts a story item of a user. It requires Options.SessionID;
+// check MediaInfo.Expired before using the result.
+func (c *Client) GetStory(ctx context.Context, username, storyID string) (*MediaInfo, error) {
+	return c.client.GetStory(ctx, username, storyID)
+}
+
+// GetHighlight extracts a highlight reel; its items are listed in MediaInfo.Items.
+// It requires Options.SessionID.
+func (c *Client) GetHighlight(ctx context.Context, highlightID string) (*MediaInfo, error) {
+	return c.client.GetHighlight(ctx, highlightID)
+}
+
+// GetUserPosts lists a page of a user's recent posts; pass PostPage.NextCursor to continue
+func (c *Client) GetUserPosts(ctx context.Context, username, cursor string, count int) (*PostPage, error) {
+	return c.client.GetUserPosts(ctx, username, cursor, count)
+}
+
+// ExtractShortcode returns the shortcode of an Instagram post or reel URL
+func (c *Client) ExtractShortcode(instagramURL string) (string, error) {
+	return c.client.ExtractShortcode(instagramURL)
+}
+
+// ResolveURL returns the canonical URL of an Instagram post URL, following the redirects
+// of share links (instagram.com/share/...) and instagr.am short links
+func (c *Client) ResolveURL(ctx context.Context, instagramURL string) (string, error) {
+	return c.client.ResolveURL(ctx, instagramURL)
+}
+
+// RegisterExtractor adds a custom extraction strategy after the configured ones.
+// An extractor with the same name as an existing one replaces it. It must not be
+// called concurrently with GetMediaInfo.
+func (c *Client) RegisterExtractor(extractor Extractor) {
+	c.client.RegisterExtractor(extractor)
+}
+
+// Extractors returns the enabled extraction strategies in their configured order
+func (c *Client) Extractors() []string {
+	return c.client.Extractors()
+}
+
+// StreamVideo proxies the video (or photo) to w, forwarding r's Range header for seeking
+func (c *Client) StreamVideo(w http.ResponseWriter, r *http.Request, info *MediaInfo) error {
+	return c.streamer.StreamVideo(w, r, info.MediaURL(), info.FileName, "")
+}
+
+// OpenVideo fetches the whole video (or photo) from the CDN. The caller must close the body.
+func (c *Client) OpenVideo(ctx context.Context, info *MediaInfo) (io.ReadCloser, error) {
+	resp, err := c.streamer.OpenVideo(ctx, info.MediaURL())
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Download copies the whole video to dst and returns the number of bytes written
+func (c *Client) Download(ctx context.Context, info *MediaInfo, dst io.Writer) (int64, error) {
+	body, err := c.OpenVideo(ctx, info)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	return io.Copy(dst, body)
+}
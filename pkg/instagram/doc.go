@@ -0,0 +1,28 @@
+// Package instagram is the public Go API for Qwiklip's Instagram extraction and streaming.
+//
+// It lets bots and other services embed the same extractor the HTTP server uses
+// without running the server:
+//
+//	client, err := instagram.New(instagram.Options{})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	info, err := client.GetMediaInfo(ctx, "https://www.instagram.com/reel/ABC123/")
+//	if err != nil {
+//		var appErr *instagram.Error
+//		if errors.As(err, &appErr) && appErr.Type == instagram.ErrorTypeNotFound {
+//			// handle missing or private content
+//		}
+//		return err
+//	}
+//
+//	// Proxy the video to an HTTP client with Range support...
+//	err = client.StreamVideo(w, r, info)
+//
+//	// ...or download it to any writer
+//	n, err := client.Download(ctx, info, file)
+//
+// Everything exported here is covered by the module's compatibility promise;
+// the internal/ packages it wraps may change at any time.
+package instagram
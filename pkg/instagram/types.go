@@ -0,0 +1,56 @@
+package instagram
+
+import (
+	internal "qwiklip/internal/instagram"
+	"qwiklip/internal/models"
+)
+
+// MediaInfo is the media information extracted for a post or reel
+type MediaInfo = models.InstagramMediaInfo
+
+// MediaItem is one entry of a carousel or highlight
+type MediaItem = models.MediaItem
+
+// VideoVersion is one rendition of a video; see MediaInfo.SelectVersion
+type VideoVersion = models.VideoVersion
+
+// PostSummary is one post of a profile feed listing
+type PostSummary = models.PostSummary
+
+// PostPage is one page of a user's recent posts
+type PostPage = models.PostPage
+
+// Error is the structured error returned by extraction and streaming
+type Error = models.AppError
+
+// ErrorType classifies an Error
+type ErrorType = models.ErrorType
+
+// Error types reported in Error.Type
+const (
+	ErrorTypeInvalidURL     = models.ErrorTypeInvalidURL
+	ErrorTypeNetwork        = models.ErrorTypeNetwork
+	ErrorTypeExtraction     = models.ErrorTypeExtraction
+	ErrorTypeParsing        = models.ErrorTypeParsing
+	ErrorTypeNotFound       = models.ErrorTypeNotFound
+	ErrorTypeUnsupported    = models.ErrorTypeUnsupported
+	ErrorTypeAuthentication = models.ErrorTypeAuthentication
+	ErrorTypeRateLimited    = models.ErrorTypeRateLimited
+	ErrorTypeExpired        = models.ErrorTypeExpired
+	ErrorTypeOverloaded     = models.ErrorTypeOverloaded
+	ErrorTypeUnavailable    = models.ErrorTypeUnavailable
+)
+
+// Extractor is a custom extraction strategy; see Client.RegisterExtractor
+type Extractor = internal.Extractor
+
+// Built-in extraction strategy names for Options.Extractors
+const (
+	ExtractorJSON      = internal.ExtractorJSON
+	ExtractorDirect    = internal.ExtractorDirect
+	ExtractorFallback  = internal.ExtractorFallback
+	ExtractorPreloader = internal.ExtractorPreloader
+	ExtractorImage     = internal.ExtractorImage
+	ExtractorEmbed     = internal.ExtractorEmbed
+	ExtractorGraphQL   = internal.ExtractorGraphQL
+)
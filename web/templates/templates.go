@@ -11,14 +11,18 @@ var templateFiles embed.FS
 
 // TemplateSet holds the parsed HTML templates
 type TemplateSet struct {
-	Index *template.Template
-	Error *template.Template
+	Index     *template.Template
+	Error     *template.Template
+	Preview   *template.Template
+	Embed     *template.Template
+	Watch     *template.Template
+	Dashboard *template.Template
 }
 
 // Load parses and validates all required templates
 func Load() (*TemplateSet, error) {
 	// Parse all templates from embedded filesystem
-	tmpl, err := template.ParseFS(templateFiles, "index.html", "error.html")
+	tmpl, err := template.ParseFS(templateFiles, "index.html", "error.html", "preview.html", "embed.html", "watch.html", "dashboard.html")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse embedded templates: %w", err)
 	}
@@ -26,6 +30,10 @@ func Load() (*TemplateSet, error) {
 	// Extract and validate individual templates
 	indexTemplate := tmpl.Lookup("index.html")
 	errorTemplate := tmpl.Lookup("error.html")
+	previewTemplate := tmpl.Lookup("preview.html")
+	embedTemplate := tmpl.Lookup("embed.html")
+	watchTemplate := tmpl.Lookup("watch.html")
+	dashboardTemplate := tmpl.Lookup("dashboard.html")
 
 	if indexTemplate == nil {
 		return nil, fmt.Errorf("index.html template not found in embedded filesystem")
@@ -33,9 +41,25 @@ func Load() (*TemplateSet, error) {
 	if errorTemplate == nil {
 		return nil, fmt.Errorf("error.html template not found in embedded filesystem")
 	}
+	if previewTemplate == nil {
+		return nil, fmt.Errorf("preview.html template not found in embedded filesystem")
+	}
+	if embedTemplate == nil {
+		return nil, fmt.Errorf("embed.html template not found in embedded filesystem")
+	}
+	if watchTemplate == nil {
+		return nil, fmt.Errorf("watch.html template not found in embedded filesystem")
+	}
+	if dashboardTemplate == nil {
+		return nil, fmt.Errorf("dashboard.html template not found in embedded filesystem")
+	}
 
 	return &TemplateSet{
-		Index: indexTemplate,
-		Error: errorTemplate,
+		Index:     indexTemplate,
+		Error:     errorTemplate,
+		Preview:   previewTemplate,
+		Embed:     embedTemplate,
+		Watch:     watchTemplate,
+		Dashboard: dashboardTemplate,
 	}, nil
 }